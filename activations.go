@@ -10,6 +10,15 @@ type Activation interface {
 	Backward(float64) float64
 }
 
+// A ParamActivation is an Activation with trainable parameters, e.g. PRelu.
+// Params returns the parameters by name. They're added to the unit's weights,
+// so they're updated by the optimizer along with the rest of the unit's
+// parameters. Backward should accumulate their gradients with Param.AddGrad.
+type ParamActivation interface {
+	Activation
+	Params() map[string]*Param
+}
+
 // Relu activation function.
 type Relu struct {
 	value float64
@@ -29,6 +38,118 @@ func (a *Relu) Backward(grad float64) float64 {
 	return grad
 }
 
+// LeakyRelu activation function. Negative inputs are scaled by Slope instead
+// of zeroed, so units can't get stuck with zero gradient.
+type LeakyRelu struct {
+	Slope float64
+	value float64
+}
+
+// Forward LeakyRelu activation
+func (a *LeakyRelu) Forward(value float64) float64 {
+	a.value = value
+	if value <= 0 {
+		return a.Slope * value
+	}
+	return value
+}
+
+// Backward pass of LeakyRelu gradient
+func (a *LeakyRelu) Backward(grad float64) float64 {
+	if a.value <= 0 {
+		grad *= a.Slope
+	}
+	return grad
+}
+
+// PRelu is a leaky Relu activation with a trainable negative slope.
+type PRelu struct {
+	Alpha *Param
+	value float64
+}
+
+// NewPRelu creates a new PRelu activation with initial slope alpha.
+func NewPRelu(alpha float64) *PRelu {
+	return &PRelu{Alpha: &Param{Data: alpha, RequiresGrad: true}}
+}
+
+// Forward PRelu activation
+func (a *PRelu) Forward(value float64) float64 {
+	a.value = value
+	if value <= 0 {
+		return a.Alpha.Data * value
+	}
+	return value
+}
+
+// Backward pass of PRelu gradient
+func (a *PRelu) Backward(grad float64) float64 {
+	if a.value <= 0 {
+		a.Alpha.AddGrad(grad * a.value)
+		grad *= a.Alpha.Data
+	}
+	return grad
+}
+
+// Params returns the PRelu slope parameter.
+func (a *PRelu) Params() map[string]*Param {
+	return map[string]*Param{"alpha": a.Alpha}
+}
+
+// clone creates a copy of the activation with its own slope parameter.
+func (a *PRelu) clone() Activation {
+	return &PRelu{Alpha: &Param{Data: a.Alpha.Data, RequiresGrad: a.Alpha.RequiresGrad}}
+}
+
+// Elu activation function, Alpha * (exp(x) - 1) for negative inputs.
+type Elu struct {
+	Alpha  float64
+	value  float64
+	output float64
+}
+
+// Forward Elu activation
+func (a *Elu) Forward(value float64) float64 {
+	a.value = value
+	if value <= 0 {
+		a.output = a.Alpha * (math.Exp(value) - 1.0)
+	} else {
+		a.output = value
+	}
+	return a.output
+}
+
+// Backward pass of Elu gradient
+func (a *Elu) Backward(grad float64) float64 {
+	if a.value <= 0 {
+		grad *= a.output + a.Alpha
+	}
+	return grad
+}
+
+// Gelu activation function, x * Phi(x) where Phi is the standard normal CDF.
+type Gelu struct {
+	value float64
+}
+
+// Forward Gelu activation
+func (a *Gelu) Forward(value float64) float64 {
+	a.value = value
+	return value * normCDF(value)
+}
+
+// Backward pass of Gelu gradient
+func (a *Gelu) Backward(grad float64) float64 {
+	x := a.value
+	pdf := math.Exp(-0.5*x*x) / math.Sqrt(2.0*math.Pi)
+	return grad * (normCDF(x) + x*pdf)
+}
+
+// normCDF is the standard normal CDF.
+func normCDF(x float64) float64 {
+	return 0.5 * (1.0 + math.Erf(x/math.Sqrt2))
+}
+
 // Identity activation function
 type Identity struct{}
 
@@ -41,3 +162,54 @@ func (a *Identity) Forward(value float64) float64 {
 func (a *Identity) Backward(grad float64) float64 {
 	return grad
 }
+
+// Sigmoid activation function.
+type Sigmoid struct {
+	output float64
+}
+
+// Forward Sigmoid activation
+func (a *Sigmoid) Forward(value float64) float64 {
+	a.output = 1.0 / (1.0 + math.Exp(-value))
+	return a.output
+}
+
+// Backward pass of Sigmoid gradient
+func (a *Sigmoid) Backward(grad float64) float64 {
+	return grad * a.output * (1.0 - a.output)
+}
+
+// Tanh activation function.
+type Tanh struct {
+	output float64
+}
+
+// Forward Tanh activation
+func (a *Tanh) Forward(value float64) float64 {
+	a.output = math.Tanh(value)
+	return a.output
+}
+
+// Backward pass of Tanh gradient
+func (a *Tanh) Backward(grad float64) float64 {
+	return grad * (1.0 - a.output*a.output)
+}
+
+// GradReversal is a gradient reversal pseudo-activation. It's the identity in
+// the forward pass but negates and scales the gradient by Lambda in the
+// backward pass. Placed in front of an adversarial head, it lets the upstream
+// units learn features that the head can't discriminate, e.g. for domain
+// adaptation.
+type GradReversal struct {
+	Lambda float64
+}
+
+// Forward GradReversal activation
+func (a *GradReversal) Forward(value float64) float64 {
+	return value
+}
+
+// Backward pass of GradReversal gradient
+func (a *GradReversal) Backward(grad float64) float64 {
+	return -a.Lambda * grad
+}
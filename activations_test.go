@@ -1,6 +1,7 @@
 package neuron
 
 import (
+	"math"
 	"testing"
 )
 
@@ -22,3 +23,140 @@ func TestReluActivation(t *testing.T) {
 		t.Errorf("Invalid Relu")
 	}
 }
+
+// Test Sigmoid
+func TestSigmoidActivation(t *testing.T) {
+	sig := new(Sigmoid)
+
+	z := sig.Forward(0.0)
+	g := sig.Backward(1.0)
+	if z != 0.5 || g != 0.25 {
+		t.Errorf("Invalid Sigmoid")
+	}
+
+	x := 2.0
+	z = sig.Forward(x)
+	g = sig.Backward(1.0)
+	if !almostEqual(z, 1.0/(1.0+math.Exp(-x))) || !almostEqual(g, z*(1.0-z)) {
+		t.Errorf("Invalid Sigmoid")
+	}
+}
+
+// Test Tanh
+func TestTanhActivation(t *testing.T) {
+	tanh := new(Tanh)
+
+	z := tanh.Forward(0.0)
+	g := tanh.Backward(1.0)
+	if z != 0.0 || g != 1.0 {
+		t.Errorf("Invalid Tanh")
+	}
+
+	x := -0.5
+	z = tanh.Forward(x)
+	g = tanh.Backward(2.0)
+	if !almostEqual(z, math.Tanh(x)) || !almostEqual(g, 2.0*(1.0-z*z)) {
+		t.Errorf("Invalid Tanh")
+	}
+}
+
+// Test LeakyRelu
+func TestLeakyReluActivation(t *testing.T) {
+	leaky := &LeakyRelu{Slope: 0.1}
+
+	z := leaky.Forward(2.0)
+	g := leaky.Backward(1.0)
+	if z != 2.0 || g != 1.0 {
+		t.Errorf("Invalid LeakyRelu")
+	}
+
+	z = leaky.Forward(-2.0)
+	g = leaky.Backward(1.0)
+	if !almostEqual(z, -0.2) || !almostEqual(g, 0.1) {
+		t.Errorf("Invalid LeakyRelu")
+	}
+}
+
+// Test PRelu
+func TestPReluActivation(t *testing.T) {
+	prelu := NewPRelu(0.25)
+
+	z := prelu.Forward(-2.0)
+	g := prelu.Backward(1.0)
+	if z != -0.5 || g != 0.25 || prelu.Alpha.grad != -2.0 {
+		t.Errorf("Invalid PRelu")
+	}
+
+	// The slope is trained along with the unit weights.
+	arch := []int{1, 2, 1}
+	opt := NewSGD(0.1, 0.0, 0.0)
+	n := MustNewMLP(arch, opt, WithActivations([]Activation{NewPRelu(0.25), nil}))
+	u1, u2 := n.Layers[1][0], n.Layers[1][1]
+	a1 := u1.Activation().(*PRelu).Alpha
+	if a1 == u2.Activation().(*PRelu).Alpha {
+		t.Errorf("Units share a PRelu slope")
+	}
+	if u1.W.Params[activPrefix+"alpha"] != a1 {
+		t.Errorf("PRelu slope not added to unit params")
+	}
+	u1.W.Params[biasID].Data = -1.0
+	w := n.Layers[2][0].W.Params[u1.ID].Data
+	n.Start(true, 1)
+	n.MustForward([]float64{0.0})
+	n.MustBackward([]float64{1.0})
+	// d output / d alpha = w * pre-activation = -w
+	want := 0.25 + 0.1*w
+	if !almostEqual(a1.Data, want) {
+		t.Errorf("PRelu slope is %.5f; expected %.5f", a1.Data, want)
+	}
+}
+
+// Test Elu
+func TestEluActivation(t *testing.T) {
+	elu := &Elu{Alpha: 0.5}
+
+	z := elu.Forward(2.0)
+	g := elu.Backward(1.0)
+	if z != 2.0 || g != 1.0 {
+		t.Errorf("Invalid Elu")
+	}
+
+	x := -1.0
+	z = elu.Forward(x)
+	g = elu.Backward(1.0)
+	if !almostEqual(z, 0.5*(math.Exp(x)-1.0)) || !almostEqual(g, 0.5*math.Exp(x)) {
+		t.Errorf("Invalid Elu")
+	}
+}
+
+// Test Gelu
+func TestGeluActivation(t *testing.T) {
+	gelu := new(Gelu)
+
+	z := gelu.Forward(0.0)
+	g := gelu.Backward(1.0)
+	if z != 0.0 || g != 0.5 {
+		t.Errorf("Invalid Gelu")
+	}
+
+	// Compare against central differences.
+	const eps = 1e-6
+	for _, x := range []float64{-2.0, -0.3, 0.7, 3.0} {
+		want := (gelu.Forward(x+eps) - gelu.Forward(x-eps)) / (2 * eps)
+		gelu.Forward(x)
+		if g := gelu.Backward(1.0); !almostEqualTol(g, want, 1e-6) {
+			t.Errorf("Gelu grad at %.2f is %.6f; expected %.6f", x, g, want)
+		}
+	}
+}
+
+// Test gradient reversal
+func TestGradReversalActivation(t *testing.T) {
+	rev := &GradReversal{Lambda: 0.5}
+
+	z := rev.Forward(2.0)
+	g := rev.Backward(1.0)
+	if z != 2.0 || g != -0.5 {
+		t.Errorf("Invalid GradReversal")
+	}
+}
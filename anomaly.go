@@ -0,0 +1,79 @@
+package neuron
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// WithAnomalyDetection checks every activation and gradient computed by the
+// units for NaN and Inf values, which otherwise propagate silently through the
+// network, e.g. after exploding gradients. When a pass produces one, the pass
+// still completes, but Forward or Backward (or their batch, sequence, and
+// context variants, and Predict) returns an *AnomalyError reporting the unit
+// that produced the first one. Forward variants return the output along with
+// the error, and in training mode the pass still needs a matching Backward.
+//
+// Units don't check values computed by the dense engine.
+func WithAnomalyDetection() Option {
+	return func(c *netConfig) {
+		c.anomaly = true
+	}
+}
+
+// An AnomalyError reports the first NaN or Inf value in a pass, see
+// WithAnomalyDetection.
+type AnomalyError struct {
+	// The unit that produced the value.
+	ID string
+	// The pass the value was produced in, "forward" for an activation or
+	// "backward" for a gradient.
+	Pass  string
+	Value float64
+}
+
+func (e *AnomalyError) Error() string {
+	what := "activation"
+	if e.Pass == "backward" {
+		what = "gradient"
+	}
+	return fmt.Sprintf("unit %s produced %s %v in %s pass", e.ID, what, e.Value, e.Pass)
+}
+
+// anomalyState records the first anomaly in a pass. It's shared by the units
+// of a network.
+type anomalyState struct {
+	mu  sync.Mutex
+	err *AnomalyError
+}
+
+// checkAnomaly records an anomaly if v is NaN or Inf, unless one was already
+// recorded in the pass.
+func (u *Unit) checkAnomaly(pass string, v float64) {
+	if u.anomaly == nil || !(math.IsNaN(v) || math.IsInf(v, 0)) {
+		return
+	}
+	a := u.anomaly
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err == nil {
+		a.err = &AnomalyError{ID: u.ID, Pass: pass, Value: v}
+		logf(1, "Anomaly: %s\n", a.err)
+	}
+}
+
+// anomalyErr returns and clears the anomaly recorded by the last pass, or nil
+// if there was none.
+func (n *Net) anomalyErr() error {
+	if n.anomaly == nil {
+		return nil
+	}
+	n.anomaly.mu.Lock()
+	defer n.anomaly.mu.Unlock()
+	err := n.anomaly.err
+	n.anomaly.err = nil
+	if err == nil {
+		return nil
+	}
+	return err
+}
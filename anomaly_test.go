@@ -0,0 +1,54 @@
+package neuron
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Test that anomalies are reported by the unit that produced them.
+func TestAnomalyDetection(t *testing.T) {
+	rand.Seed(9)
+	arch := []int{2, 3, 1}
+	n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), WithAnomalyDetection())
+	n.Start(true, 0)
+	defer n.Stop()
+
+	// Clean passes report nothing.
+	if _, err := n.Forward([]float64{1.0, 2.0}); err != nil {
+		t.Fatalf("Forward returned an error: %v", err)
+	}
+	if err := n.Backward([]float64{1.0}); err != nil {
+		t.Fatalf("Backward returned an error: %v", err)
+	}
+
+	// An infinite weight makes the hidden unit's activation infinite.
+	u := n.Layers[1][1]
+	u.W.Params[n.Layers[0][0].ID].Data = math.Inf(1)
+	output, err := n.Forward([]float64{1.0, 2.0})
+	var anomaly *AnomalyError
+	if !errors.As(err, &anomaly) {
+		t.Fatalf("Forward returned %v; expected an AnomalyError", err)
+	}
+	if anomaly.ID != u.ID || anomaly.Pass != "forward" {
+		t.Errorf("Anomaly in unit %s %s pass; expected %s forward pass", anomaly.ID,
+			anomaly.Pass, u.ID)
+	}
+	if output == nil {
+		t.Errorf("Forward did not return the output")
+	}
+	n.Backward([]float64{0.0})
+	u.W.Params[n.Layers[0][0].ID].Data = 0.0
+
+	// A NaN gradient is reported by the output unit.
+	n.MustForward([]float64{1.0, 2.0})
+	err = n.Backward([]float64{math.NaN()})
+	if !errors.As(err, &anomaly) {
+		t.Fatalf("Backward returned %v; expected an AnomalyError", err)
+	}
+	if anomaly.ID != n.Layers[2][0].ID || anomaly.Pass != "backward" {
+		t.Errorf("Anomaly in unit %s %s pass; expected %s backward pass", anomaly.ID,
+			anomaly.Pass, n.Layers[2][0].ID)
+	}
+}
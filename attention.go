@@ -0,0 +1,141 @@
+package neuron
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// attnPrefix prefixes the IDs of attention parameters. The query weight on
+// the input from unit k has ID attnPrefix + "q_" + k, and its key weight
+// attnPrefix + "k_" + k.
+const attnPrefix = "_ATTN_"
+
+// attnID returns the ID of the attention parameter of role "q" or "k" for
+// the input from unit id.
+func attnID(role, id string) string {
+	return attnPrefix + role + "_" + id
+}
+
+// isAttn checks whether a parameter ID refers to an attention parameter.
+func isAttn(id string) bool {
+	return strings.HasPrefix(id, attnPrefix)
+}
+
+// An attention holds the query and key weights of an attention unit, see
+// AttentionKind. They're stored as Params of the unit's Weight, so the unit's
+// optimizer updates them along with the connection weights.
+//
+// Given the inputs x, the unit's query is q = sum_j Q_j x_j, and input k
+// scores s_k = q K_k x_k. Instead of the plain sum of its weighted inputs,
+// the unit sums them weighted by the softmax a of the scores:
+//
+//	y = sum_k a_k w_k x_k
+//
+// Pruned inputs are left out of the softmax.
+type attention struct {
+	// Input unit IDs, in the order they're summed so that passes are
+	// reproducible, and the query and key weight of each input.
+	ids        []string
+	query, key []*Param
+}
+
+// newAttentionUnit creates a hidden unit that attends over its inputs.
+func newAttentionUnit(id string, opt Optimizer) *Unit {
+	u := newHiddenUnit(id, opt)
+	u.attn = new(attention)
+	return u
+}
+
+// connect adds the query and key weights on the input from unit id, drawn
+// uniformly from [-1, 1] so that the scores depend on the input from the
+// start.
+func (a *attention) connect(u *Unit, id string, rng *rand.Rand) {
+	for _, role := range []string{"q", "k"} {
+		u.W.init(attnID(role, id), randUnif(rng, -1.0, 1.0), true)
+	}
+	a.ids = append(a.ids, id)
+	a.query = append(a.query, u.W.Params[attnID("q", id)])
+	a.key = append(a.key, u.W.Params[attnID("k", id)])
+}
+
+// attentionLayer checks whether any unit of layer l is an attention unit.
+func attentionLayer(l []*Unit) bool {
+	for _, u := range l {
+		if u.attn != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// weigh returns the inputs, weighted inputs, attention weights, query, and
+// weighted sum of the unit's inputs. The connection weights must have
+// recorded their inputs.
+func (a *attention) weigh(u *Unit) (x, v, att []float64, q, y float64) {
+	n := len(a.ids)
+	x, v, att = make([]float64, n), make([]float64, n), make([]float64, n)
+	for kk, k := range a.ids {
+		a.query[kk].pullTied()
+		a.key[kk].pullTied()
+		if p := u.W.Params[k]; !p.masked {
+			x[kk] = p.value
+			v[kk] = u.W.forward(k, p.value)
+			q += a.query[kk].Data * x[kk]
+		}
+	}
+	// Softmax of the scores, shifted by the max score for stability.
+	maxScore := math.Inf(-1)
+	for kk, k := range a.ids {
+		if !u.W.Params[k].masked {
+			att[kk] = q * a.key[kk].Data * x[kk]
+			maxScore = math.Max(maxScore, att[kk])
+		}
+	}
+	sum := 0.0
+	for kk, k := range a.ids {
+		if !u.W.Params[k].masked {
+			att[kk] = math.Exp(att[kk] - maxScore)
+			sum += att[kk]
+		}
+	}
+	if sum == 0.0 {
+		return x, v, att, q, 0.0
+	}
+	for kk := range att {
+		att[kk] /= sum
+		y += att[kk] * v[kk]
+	}
+	return x, v, att, q, y
+}
+
+// forward returns the attention-weighted sum of the unit's inputs, which
+// replaces their plain sum.
+func (a *attention) forward(u *Unit) float64 {
+	_, _, _, _, y := a.weigh(u)
+	return y
+}
+
+// backprop back-propagates the gradient grad of the attention-weighted sum
+// through the attention and connection weights, and passes the gradient for
+// each input k to emit. The attention weights are recomputed from the
+// recorded inputs, so sequences and batches need no extra state.
+func (a *attention) backprop(u *Unit, grad float64, emit func(k string, gradi float64)) {
+	x, v, att, q, y := a.weigh(u)
+	// Gradients of the scores and the query.
+	ds := make([]float64, len(att))
+	dq := 0.0
+	for kk := range att {
+		ds[kk] = grad * att[kk] * (v[kk] - y)
+		dq += ds[kk] * a.key[kk].Data * x[kk]
+	}
+	for kk, k := range a.ids {
+		gradi := u.W.backward(k, grad*att[kk])
+		if !u.W.Params[k].masked {
+			a.key[kk].AddGrad(ds[kk] * q * x[kk])
+			a.query[kk].AddGrad(dq * x[kk])
+			gradi += ds[kk]*q*a.key[kk].Data + dq*a.query[kk].Data
+		}
+		emit(k, gradi)
+	}
+}
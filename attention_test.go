@@ -0,0 +1,175 @@
+package neuron
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// newAttentionNet creates a network with a linear hidden layer of attention
+// units.
+func newAttentionNet(opts ...Option) *Net {
+	opts = append(opts, WithUnitKinds([]string{InputKind, AttentionKind, OutputKind}),
+		WithActivations([]Activation{new(Identity), nil}))
+	return MustNewMLP([]int{3, 2, 2}, NewSGD(0.1, 0.0, 0.0), opts...)
+}
+
+// Test that attention units sum their weighted inputs with softmax weights
+// of the query and key scores.
+func TestAttention(t *testing.T) {
+	rand.Seed(73)
+	n := newAttentionNet()
+	x := []float64{0.5, -1.0, 2.0}
+	n.Start(false, 0)
+	defer n.Stop()
+	_, acts, err := n.ForwardWithActivations(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range n.Layers[1] {
+		if len(u.attn.ids) != 3 {
+			t.Fatalf("Unit %s attends over %d inputs; expected 3", u.ID, len(u.attn.ids))
+		}
+		q := 0.0
+		for ii, u2 := range n.Layers[0] {
+			q += u.W.Params[attnID("q", u2.ID)].Data * x[ii]
+		}
+		sum, y := 0.0, 0.0
+		for ii, u2 := range n.Layers[0] {
+			e := math.Exp(q * u.W.Params[attnID("k", u2.ID)].Data * x[ii])
+			sum += e
+			y += e * u.W.Params[u2.ID].Data * x[ii]
+		}
+		want := y/sum + u.W.Params[biasID].Data
+		if got := acts[u.ID]; !almostEqual(got, want) {
+			t.Errorf("Unit %s output is %.6f; expected %.6f", u.ID, got, want)
+		}
+	}
+}
+
+// Test the attention gradients against central differences on both engines,
+// and that batches accumulate the gradients of each sample.
+func TestAttentionGrads(t *testing.T) {
+	x := [][]float64{{0.5, -1.0, 2.0}, {-0.3, 0.8, 0.1}}
+	targets := [][]float64{{0.3, -0.2}, {1.0, 0.5}}
+	loss, _ := GetLoss("mse")
+	for _, layers := range []bool{false, true} {
+		rand.Seed(79)
+		var opts []Option
+		if layers {
+			opts = append(opts, WithLayerEngine())
+		}
+		n := newAttentionNet(opts...)
+		n.Start(true, 0)
+		relErr, _, err := GradCheck(n, x[0], targets[0], loss, 1.0e-05)
+		if err != nil {
+			t.Fatal(err)
+		}
+		attnParams := 0
+		for uid, ue := range relErr {
+			for id, e := range ue {
+				if isAttn(id) {
+					attnParams++
+				}
+				if e > 1.0e-05 {
+					t.Errorf("(layers=%v) Relative error of %s/%s is %g; expected < 1e-5",
+						layers, uid, id, e)
+				}
+			}
+		}
+		if attnParams != 12 {
+			t.Errorf("(layers=%v) Checked %d attention parameters; expected 12", layers,
+				attnParams)
+		}
+
+		// Sum of the gradients of each sample.
+		want := make(ParamVector)
+		for ii := range x {
+			n.zeroGrad()
+			out := n.MustForward(x[ii])
+			loss.Forward(out, targets[ii])
+			n.MustBackward(loss.Backward())
+			for uid, ug := range n.Grads() {
+				if want[uid] == nil {
+					want[uid] = make(map[string]float64)
+				}
+				for id, g := range ug {
+					want[uid][id] += g
+				}
+			}
+		}
+		n.zeroGrad()
+		outs, _ := n.ForwardBatch(x)
+		grads := make([][]float64, len(x))
+		for ii, out := range outs {
+			loss.Forward(out, targets[ii])
+			grads[ii] = loss.Backward()
+		}
+		if err := n.BackwardBatch(grads); err != nil {
+			t.Fatal(err)
+		}
+		for uid, ug := range n.Grads() {
+			for id, g := range ug {
+				if !almostEqualTol(g, want[uid][id], 1.0e-09) {
+					t.Errorf("(layers=%v) Batch grad of %s/%s is %.6e; expected %.6e",
+						layers, uid, id, g, want[uid][id])
+				}
+			}
+		}
+		n.Stop()
+	}
+}
+
+// Test that attention units learn to pick out the largest of their inputs.
+func TestAttentionTraining(t *testing.T) {
+	rand.Seed(83)
+	n := MustNewMLP([]int{3, 4, 1}, NewSGD(0.02, 0.5, 0.0),
+		WithUnitKinds([]string{InputKind, AttentionKind, OutputKind}))
+	n.Start(true, 1)
+	defer n.Stop()
+	loss, _ := GetLoss("mse")
+	var first, last float64
+	for epoch := 0; epoch < 100; epoch++ {
+		total := 0.0
+		for ii := 0; ii < 50; ii++ {
+			x := []float64{rand.Float64(), rand.Float64(), rand.Float64()}
+			target := math.Max(x[0], math.Max(x[1], x[2]))
+			out := n.MustForward(x)
+			l, _ := loss.Forward(out, []float64{target})
+			n.MustBackward(loss.Backward())
+			total += l
+		}
+		if epoch == 0 {
+			first = total
+		}
+		last = total
+	}
+	if last > 0.2*first {
+		t.Errorf("Loss didn't decrease: %.4f -> %.4f", first, last)
+	}
+}
+
+// Test the engines and operations attention units don't support.
+func TestAttentionErrors(t *testing.T) {
+	n := newAttentionNet()
+	if _, err := NewDense(n); err == nil {
+		t.Error("Dense engine with attention units didn't fail")
+	}
+	if _, err := n.AddUnit(1); err == nil {
+		t.Error("Adding a unit to an attention layer didn't fail")
+	}
+	n = MustNewMLP([]int{2, 3, 2, 1}, NewSGD(0.1, 0.0, 0.0),
+		WithUnitKinds([]string{InputKind, HiddenKind, AttentionKind, OutputKind}))
+	if _, err := n.AddUnit(1); err == nil {
+		t.Error("Adding an input to an attention layer didn't fail")
+	}
+	if err := n.WidenLayer(1, 4); err == nil {
+		t.Error("Widening the input of an attention layer didn't fail")
+	}
+	if err := n.PruneUnit(unitID(1, 0)); err == nil {
+		t.Error("Pruning an input of an attention layer didn't fail")
+	}
+	if err := n.PruneConnection(unitID(1, 0), unitID(2, 0)); err == nil {
+		t.Error("Pruning a connection into an attention unit didn't fail")
+	}
+}
@@ -0,0 +1,180 @@
+package neuron
+
+import (
+	"errors"
+	"fmt"
+)
+
+// WithPipelining pipelines the samples of a batch through the network.
+// Every unit runs independently, so several samples can be in flight at once,
+// e.g. sample k+1 in the first hidden layer while sample k is in the second.
+// Batches are then no longer latency bound, and throughput scales with the
+// depth of the network. Signals are tagged with their sample, so that units
+// can hold back signals of later samples that overtake the current one.
+func WithPipelining() Option {
+	return func(c *netConfig) {
+		c.pipeline = true
+	}
+}
+
+// ForwardBatch feeds a batch of samples through the network, returning the
+// output for each sample. Each sample is fed in as soon as the previous one
+// reaches the output layer, or right away with WithPipelining, and the units
+// only synchronize with the network once the whole batch has been processed,
+// which amortizes the per-sample synchronization of Forward.
+//
+// In training mode ForwardBatch must be followed by a BackwardBatch with one
+// gradient per sample. The network must have been started with Start. A unit
+// that fails mid-batch emits zero for the rest of the batch, like in single
+// passes.
+func (n *Net) ForwardBatch(data [][]float64) ([][]float64, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty batch")
+	}
+	for _, x := range data {
+		if err := n.checkInput(x); err != nil {
+			return nil, err
+		}
+	}
+	if n.seq {
+		return nil, errors.New("batches need a network started with Start")
+	}
+
+	logf(2, "MLP Forward batch\n")
+	done, stop := n.watch()
+	defer stop()
+	if !n.acquire(done) {
+		return nil, n.deadlock("forward")
+	}
+	n.applyLR()
+	// Units read the batch size once the pass has started.
+	n.batch = len(data)
+	n.probs = n.probs[:0]
+	outputs, ok := n.feedBatch(data, done)
+	ok = ok && (n.train || n.syncDone(done))
+	n.endForward(ok)
+	if !ok {
+		return nil, n.deadlock("forward")
+	}
+	return outputs, n.anomalyErr()
+}
+
+// BackwardBatch back-propagates one loss gradient per sample of the last
+// ForwardBatch. Gradients accumulate over the batch like separate Backward
+// calls, and each sample counts towards updateFreq, so with updateFreq equal
+// to the batch size the weights are updated once per batch. If the batch
+// crosses more than one multiple of updateFreq, the weights are still only
+// updated once, at the end of the batch.
+func (n *Net) BackwardBatch(grads [][]float64) error {
+	if n.batch <= 1 || len(grads) != n.batch {
+		return fmt.Errorf("got %d grads for a batch of %d samples", len(grads), n.batch)
+	}
+	for _, grad := range grads {
+		if err := n.checkGrad(grad); err != nil {
+			return err
+		}
+	}
+
+	logf(2, "MLP Backward batch\n")
+	done, stop := n.watch()
+	defer stop()
+	// Units back-propagate the samples in reverse order, see
+	// Unit.backwardBatch.
+	sources := n.sources()
+	for k := len(grads) - 1; k >= 0; k-- {
+		grad := grads[k]
+		if n.softmax {
+			var err error
+			if grad, err = n.backwardSoftmax(grad); err != nil {
+				return err
+			}
+		}
+		if !n.feedGrad(grad, k, done) {
+			return n.deadlock("backward")
+		}
+		if k > 0 && !n.pipeline && !n.syncUnits(sources, done) {
+			return n.deadlock("backward")
+		}
+	}
+	if !n.syncDone(done) {
+		return n.deadlock("backward")
+	}
+	n.stepped(n.batch)
+	n.batch = 1
+	n.endBackward()
+	return n.anomalyErr()
+}
+
+// feedBatch feeds each sample of a batch in and collects the outputs. When
+// pipelining, samples are fed in from a separate goroutine so that the output
+// layer never waits on the network.
+func (n *Net) feedBatch(data [][]float64, done <-chan struct{}) (outputs [][]float64, ok bool) {
+	outputs = make([][]float64, len(data))
+	if !n.pipeline {
+		for k, x := range data {
+			if outputs[k], ok = n.feedForward(x, k, done); !ok {
+				return nil, false
+			}
+		}
+		return outputs, true
+	}
+
+	// The feeder is stopped if collecting the outputs gives up first.
+	quit := make(chan struct{})
+	fed := make(chan struct{})
+	go func() {
+		defer close(fed)
+		for k, x := range data {
+			if !n.feedInput(x, k, quit) {
+				return
+			}
+		}
+	}()
+	for k := range data {
+		if outputs[k], ok = n.collectOutput(done); !ok {
+			stuck := n.stuck
+			close(quit)
+			<-fed
+			n.stuck = stuck
+			return nil, false
+		}
+	}
+	<-fed
+	return outputs, true
+}
+
+// sources counts the units without upstream connections, i.e. the input
+// units.
+func (n *Net) sources() int {
+	count := 0
+	for _, l := range n.Layers {
+		for _, u := range l {
+			if len(u.outputB) == 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// backwardBatch back-propagates each sample of a batch, starting from the
+// last, restoring the state recorded by each forward pass in turn.
+//
+// Unless pipelining, signals from different samples mustn't mix on a unit's
+// inputB channel, so the next gradient is only fed in once the previous one
+// has been fully back-propagated. The units without upstream connections are
+// the last to receive each gradient, so they signal done after each sample
+// but the last, in addition to the usual done at the end of the pass.
+func (u *Unit) backwardBatch(batch int) {
+	for k := batch - 1; k >= 0; k-- {
+		// Set before rewinding, so that a failure is recovered at sample k.
+		u.tag, u.received, u.sent = k, 0, false
+		if k < batch-1 {
+			u.rewind()
+		}
+		u.backward()
+		if k > 0 && len(u.outputB) == 0 && !u.pipeline {
+			u.done()
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test that a batch matches the same samples passed one at a time.
+func TestBatch(t *testing.T) {
+	arch := []int{2, 3, 2}
+	data := [][]float64{{1.0, -1.0}, {0.5, 2.0}, {-1.5, 0.3}, {0.1, 0.1}}
+	grads := [][]float64{{1.0, 0.0}, {-0.5, 1.0}, {0.2, 0.3}, {1.0, -1.0}}
+	for _, opts := range [][]Option{nil, {WithSoftmaxOutput()}, {WithWeightNorm()},
+		{WithPipelining()}, {WithPipelining(), WithSoftmaxOutput()}} {
+		rand.Seed(5)
+		n := MustNewMLP(arch, NewSGD(0.1, 0.9, 0.0), opts...)
+		rand.Seed(5)
+		nb := MustNewMLP(arch, NewSGD(0.1, 0.9, 0.0), opts...)
+		n.Start(true, 4)
+		nb.Start(true, 4)
+
+		for epoch := 0; epoch < 2; epoch++ {
+			want := make([][]float64, len(data))
+			for ii := range data {
+				want[ii] = n.MustForward(data[ii])
+				n.MustBackward(grads[ii])
+			}
+			got, err := nb.ForwardBatch(data)
+			if err != nil {
+				t.Fatalf("ForwardBatch failed: %v", err)
+			}
+			if err := nb.BackwardBatch(grads); err != nil {
+				t.Fatalf("BackwardBatch failed: %v", err)
+			}
+			for ii := range want {
+				for jj := range want[ii] {
+					if !almostEqual(got[ii][jj], want[ii][jj]) {
+						t.Errorf("Batch output %d/%d is %.6f; expected %.6f", ii, jj,
+							got[ii][jj], want[ii][jj])
+					}
+				}
+			}
+		}
+
+		// Both nets were updated at the same steps.
+		theta, thetaB := n.Data(), nb.Data()
+		for uid, ud := range theta {
+			for id, v := range ud {
+				if !almostEqual(thetaB[uid][id], v) {
+					t.Errorf("Param %s/%s is %.6f; expected %.6f", uid, id,
+						thetaB[uid][id], v)
+				}
+			}
+		}
+
+		// Batches in evaluation mode.
+		nb.Eval()
+		got, _ := nb.ForwardBatch(data)
+		n.Eval()
+		for ii := range data {
+			if want := n.MustForward(data[ii]); !almostEqual(got[ii][0], want[0]) {
+				t.Errorf("Eval batch output %d is %.6f; expected %.6f", ii, got[ii][0],
+					want[0])
+			}
+		}
+		n.Stop()
+		nb.Stop()
+	}
+}
+
+// Test that mismatched batch calls are checked.
+func TestBatchErrors(t *testing.T) {
+	n := MustNewMLP([]int{2, 3, 1}, NewSGD(0.1, 0.0, 0.0))
+	n.Start(true, 1)
+	defer n.Stop()
+	if _, err := n.ForwardBatch(nil); err == nil {
+		t.Errorf("ForwardBatch did not return an error")
+	}
+	if err := n.BackwardBatch([][]float64{{1.0}, {1.0}}); err == nil {
+		t.Errorf("BackwardBatch did not return an error")
+	}
+	n.MustForward([]float64{1.0, 1.0})
+	n.MustBackward([]float64{1.0})
+	n.ForwardBatch([][]float64{{1.0, 1.0}, {0.0, 1.0}})
+	if err := n.Backward([]float64{1.0}); err == nil {
+		t.Errorf("Backward after ForwardBatch did not return an error")
+	}
+	if err := n.BackwardBatch([][]float64{{1.0}}); err == nil {
+		t.Errorf("BackwardBatch did not return an error")
+	}
+	if err := n.BackwardBatch([][]float64{{1.0}, {1.0}}); err != nil {
+		t.Errorf("BackwardBatch failed: %v", err)
+	}
+}
+
+// Test pipelining a large batch through a deeper network.
+func TestPipelining(t *testing.T) {
+	arch := []int{3, 5, 5, 5, 2}
+	data := make([][]float64, 32)
+	grads := make([][]float64, len(data))
+	for ii := range data {
+		data[ii] = []float64{rand.NormFloat64(), rand.NormFloat64(), rand.NormFloat64()}
+		grads[ii] = []float64{rand.NormFloat64(), rand.NormFloat64()}
+	}
+	rand.Seed(7)
+	n := MustNewMLP(arch, NewSGD(0.01, 0.0, 0.0))
+	rand.Seed(7)
+	np := MustNewMLP(arch, NewSGD(0.01, 0.0, 0.0), WithPipelining())
+	n.Start(true, len(data))
+	np.Start(true, len(data))
+	defer n.Stop()
+	defer np.Stop()
+
+	for epoch := 0; epoch < 3; epoch++ {
+		want, _ := n.ForwardBatch(data)
+		n.BackwardBatch(grads)
+		got, err := np.ForwardBatch(data)
+		if err != nil {
+			t.Fatalf("ForwardBatch failed: %v", err)
+		}
+		if err := np.BackwardBatch(grads); err != nil {
+			t.Fatalf("BackwardBatch failed: %v", err)
+		}
+		for ii := range want {
+			for jj := range want[ii] {
+				if !almostEqual(got[ii][jj], want[ii][jj]) {
+					t.Fatalf("Pipelined output %d/%d is %.6f; expected %.6f", ii, jj,
+						got[ii][jj], want[ii][jj])
+				}
+			}
+		}
+	}
+}
+
+// Benchmark forward/backward passes over batches, with and without
+// pipelining.
+func BenchmarkBatch(b *testing.B) {
+	Verbosity = 0
+	arch := []int{32, 64, 64, 64, 64, 1}
+	data := make([][]float64, 32)
+	grads := make([][]float64, len(data))
+	for ii := range data {
+		data[ii] = make([]float64, arch[0])
+		for jj := range data[ii] {
+			data[ii][jj] = rand.NormFloat64()
+		}
+		grads[ii] = []float64{1.0}
+	}
+	for _, pipeline := range []bool{false, true} {
+		var opts []Option
+		name := "Sequential"
+		if pipeline {
+			opts = append(opts, WithPipelining())
+			name = "Pipelined"
+		}
+		b.Run(name, func(b *testing.B) {
+			n := MustNewMLP(arch, NewSGD(1.0e-03, 0.0, 0.0), opts...)
+			n.Start(true, len(data))
+			defer n.Stop()
+			b.ResetTimer()
+			for ii := 0; ii < b.N; ii++ {
+				n.ForwardBatch(data)
+				n.BackwardBatch(grads)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package neuron
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// logVarPrefix prefixes the IDs of the log-variance Params of Bayesian
+// connection weights.
+const logVarPrefix = "_LOGVAR_"
+
+// isLogVar checks whether a parameter ID refers to a log-variance Param.
+func isLogVar(id string) bool {
+	return strings.HasPrefix(id, logVarPrefix)
+}
+
+// logVarID returns the ID of the log-variance Param of connection id.
+func logVarID(id string) string {
+	return logVarPrefix + id
+}
+
+// bayesian makes the connection weights Bayesian (Bayes by backprop). Each
+// connection Param holds the mean of a Gaussian posterior over the weight,
+// with a companion Param holding its log-variance. A new weight is sampled
+// for every pass, and gradients are estimated with the reparameterization
+// trick. The KL divergence to a N(0, priorStd^2) prior, scaled by klWeight, is
+// added to the gradients of every sample.
+func (w *Weight) bayesian(priorStd, klWeight, initStd float64) {
+	w.bayes = true
+	w.eps = make(map[string]float64)
+	w.priorVar = priorStd * priorStd
+	w.klWeight = klWeight
+	for k := range w.Params {
+		if isConn(k) {
+			w.init(logVarID(k), 2*math.Log(initStd), true)
+		}
+	}
+}
+
+// sigma returns the posterior standard deviation of connection id.
+func (w *Weight) sigma(id string) float64 {
+	return math.Exp(0.5 * w.Params[logVarID(id)].Data)
+}
+
+// prepareBayes samples the noise for this pass's weights.
+func (w *Weight) prepareBayes() {
+	for _, k := range weightKeys(w) {
+		w.eps[k] = w.normFloat64()
+	}
+}
+
+// sample returns the sampled value of connection weight p for this pass.
+func (w *Weight) sample(id string, p *Param) float64 {
+	return p.Data + w.sigma(id)*w.eps[id]
+}
+
+// backwardBayes accumulates the log-variance gradient of connection id, given
+// the gradient g with respect to the sampled weight. The mean gradient is just
+// g.
+func (w *Weight) backwardBayes(id string, g float64) {
+	lv := w.Params[logVarID(id)]
+	if lv.trainable() {
+		lv.grad += g * w.eps[id] * 0.5 * w.sigma(id)
+	}
+}
+
+// finishBayes adds the gradient of the weighted KL term:
+//
+//	KL = log(s / sigma) + (sigma^2 + mu^2) / (2 s^2) - 1/2
+func (w *Weight) finishBayes() {
+	for k, p := range w.Params {
+		if !isConn(k) || p.masked {
+			continue
+		}
+		lv := w.Params[logVarID(k)]
+		if p.trainable() {
+			p.grad += w.klWeight * p.Data / w.priorVar
+		}
+		if lv.trainable() {
+			lv.grad += w.klWeight * 0.5 * (math.Exp(lv.Data)/w.priorVar - 1.0)
+		}
+	}
+}
+
+// kl computes the KL divergence of the weight posterior from the prior.
+func (w *Weight) kl() float64 {
+	kl := 0.0
+	for k, p := range w.Params {
+		if !isConn(k) || p.masked {
+			continue
+		}
+		lv := w.Params[logVarID(k)].Data
+		kl += 0.5*(math.Log(w.priorVar)-lv) +
+			(math.Exp(lv)+p.Data*p.Data)/(2*w.priorVar) - 0.5
+	}
+	return kl
+}
+
+// WithBayes makes the connection weights of all units after the input layer
+// Bayesian (Bayes by backprop). Each weight has a learned Gaussian posterior,
+// initialized with standard deviation initStd, and a new set of weights is
+// sampled for every pass. Repeated forward passes on the same input therefore
+// give a distribution of outputs, whose spread estimates the predictive
+// uncertainty.
+//
+// The training loss includes the KL divergence to a N(0, priorStd^2) prior,
+// scaled by klWeight (typically 1 / dataset size) for every sample. Use
+// Net.KL to report it.
+func WithBayes(priorStd, klWeight, initStd float64) Option {
+	return func(c *netConfig) {
+		c.bayes = &bayesConfig{priorStd, klWeight, initStd}
+	}
+}
+
+// bayesConfig holds the settings for WithBayes.
+type bayesConfig struct {
+	priorStd, klWeight, initStd float64
+}
+
+// bayesNet makes all units after the input layer Bayesian.
+func (n *Net) bayesNet(c *bayesConfig) {
+	for _, l := range n.Layers[1:] {
+		for _, u := range l {
+			u.W.bayesian(c.priorStd, c.klWeight, c.initStd)
+		}
+	}
+}
+
+// checkBayes checks the WithBayes settings.
+func checkBayes(c *netConfig) error {
+	if c.bayes == nil {
+		return nil
+	}
+	if c.bayes.priorStd <= 0 || c.bayes.initStd <= 0 {
+		return fmt.Errorf("bayesian prior and init std need to be > 0")
+	}
+	if c.weightNorm {
+		return fmt.Errorf("bayesian units don't support weight normalization")
+	}
+	return nil
+}
+
+// KL returns the (unscaled) KL divergence of the Bayesian weight posteriors
+// from the prior, summed over all units. It's zero if the network isn't
+// Bayesian. It should only be called while the network is idle.
+func (n *Net) KL() float64 {
+	kl := 0.0
+	for _, l := range n.Layers {
+		for _, u := range l {
+			if u.W.bayes {
+				kl += u.W.kl()
+			}
+		}
+	}
+	return kl
+}
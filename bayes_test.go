@@ -0,0 +1,74 @@
+package neuron
+
+import (
+	"math"
+	"testing"
+)
+
+// Test Bayesian weight sampling.
+func TestBayes(t *testing.T) {
+	n := MustNewMLP([]int{2, 4, 1}, NewSGD(1e-2, 0.0, 0.0), WithBayes(1.0, 1e-3, 0.1))
+	for _, u := range n.Layers[1] {
+		for _, k := range weightKeys(u.W) {
+			lv, ok := u.W.Params[logVarID(k)]
+			if !ok {
+				t.Fatalf("unit %s missing log-variance for %s", u.ID, k)
+			}
+			if !almostEqual(lv.Data, 2*math.Log(0.1)) {
+				t.Errorf("log-variance init %.3f; expected %.3f", lv.Data, 2*math.Log(0.1))
+			}
+		}
+		if _, ok := u.W.Params[logVarID(biasID)]; ok {
+			t.Errorf("bias shouldn't be Bayesian")
+		}
+	}
+	if kl := n.KL(); kl <= 0 {
+		t.Errorf("KL %.3f; expected > 0", kl)
+	}
+
+	// Weights are resampled every pass, so outputs vary.
+	n.Start(true, 0)
+	data := []float64{1.0, -1.0}
+	first := n.MustForward(data)
+	n.MustBackward([]float64{0.0})
+	same := true
+	for ii := 0; ii < 5; ii++ {
+		out := n.MustForward(data)
+		n.MustBackward([]float64{0.0})
+		if !almostEqual(out[0], first[0]) {
+			same = false
+		}
+	}
+	if same {
+		t.Errorf("outputs didn't vary across passes")
+	}
+}
+
+// Test the KL gradients of Bayesian weights.
+func TestBayesGrads(t *testing.T) {
+	// With zero loss gradient only the KL term contributes.
+	klWeight := 0.5
+	n := MustNewMLP([]int{1, 1, 1}, NewSGD(1e-2, 0.0, 0.0), WithBayes(2.0, klWeight, 0.5))
+	u := n.Layers[1][0]
+	k := weightKeys(u.W)[0]
+	mu := u.W.Params[k].Data
+	n.Start(true, 0)
+	n.MustForward([]float64{1.0})
+	n.MustBackward([]float64{0.0})
+	g := n.Grads()[u.ID]
+	if !almostEqual(g[k], klWeight*mu/4.0) {
+		t.Errorf("mean grad %.5f; expected %.5f", g[k], klWeight*mu/4.0)
+	}
+	if want := klWeight * 0.5 * (0.25/4.0 - 1.0); !almostEqual(g[logVarID(k)], want) {
+		t.Errorf("log-variance grad %.5f; expected %.5f", g[logVarID(k)], want)
+	}
+}
+
+// Test that Bayesian units reject weight normalization.
+func TestBayesWeightNorm(t *testing.T) {
+	_, err := NewMLP([]int{2, 2, 1}, NewSGD(1e-2, 0.0, 0.0), WithBayes(1.0, 1e-3, 0.1),
+		WithWeightNorm())
+	if err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+}
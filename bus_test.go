@@ -0,0 +1,129 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test that batched signals give the same outputs and updates as single
+// signals.
+func TestSignalBatching(t *testing.T) {
+	arch := []int{2, 4, 3, 2}
+	data := [][]float64{{1.0, -1.0}, {0.5, 2.0}, {-1.5, 0.3}, {0.1, 0.1}}
+	grads := [][]float64{{1.0, 0.0}, {-0.5, 1.0}, {0.2, 0.3}, {1.0, -1.0}}
+	for _, opts := range [][]Option{nil, {WithPipelining()}, {WithSoftmaxOutput()}} {
+		rand.Seed(3)
+		n := MustNewMLP(arch, NewSGD(0.1, 0.9, 0.0), opts...)
+		rand.Seed(3)
+		nb := MustNewMLP(arch, NewSGD(0.1, 0.9, 0.0), append(opts, WithSignalBatching())...)
+		n.Start(true, len(data))
+		nb.Start(true, len(data))
+
+		for ii := range data {
+			want, got := n.MustForward(data[ii]), nb.MustForward(data[ii])
+			for jj := range want {
+				if !almostEqual(got[jj], want[jj]) {
+					t.Errorf("Batched signal output %d is %.6f; expected %.6f", jj,
+						got[jj], want[jj])
+				}
+			}
+			n.MustBackward(grads[ii])
+			nb.MustBackward(grads[ii])
+		}
+		if _, err := nb.ForwardBatch(data); err != nil {
+			t.Fatalf("ForwardBatch failed: %v", err)
+		}
+		if err := nb.BackwardBatch(grads); err != nil {
+			t.Fatalf("BackwardBatch failed: %v", err)
+		}
+		for ii := range data {
+			n.MustForward(data[ii])
+			n.MustBackward(grads[ii])
+		}
+
+		theta, thetaB := n.Data(), nb.Data()
+		for uid, ud := range theta {
+			for id, v := range ud {
+				if !almostEqual(thetaB[uid][id], v) {
+					t.Errorf("Param %s/%s is %.6f; expected %.6f", uid, id,
+						thetaB[uid][id], v)
+				}
+			}
+		}
+		n.Stop()
+		nb.Stop()
+	}
+}
+
+// Test sequences and unit restarts with batched signals.
+func TestSignalBatchingSequence(t *testing.T) {
+	arch := []int{1, 3, 1}
+	kinds := []string{InputKind, RecurrentKind, OutputKind}
+	seq := [][]float64{{1.0}, {-0.5}, {2.0}}
+	lossGrad := func(t int, output []float64) []float64 { return output }
+	rand.Seed(4)
+	n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), WithUnitKinds(kinds))
+	rand.Seed(4)
+	nb := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), WithUnitKinds(kinds),
+		WithSignalBatching())
+	n.StartSequence(true, 1)
+	nb.StartSequence(true, 1)
+	want, _ := n.TrainSequence(seq, 2, lossGrad)
+	got, err := nb.TrainSequence(seq, 2, lossGrad)
+	if err != nil {
+		t.Fatalf("TrainSequence failed: %v", err)
+	}
+	for ii := range want {
+		if !almostEqual(got[ii][0], want[ii][0]) {
+			t.Errorf("Step %d output is %.6f; expected %.6f", ii, got[ii][0],
+				want[ii][0])
+		}
+	}
+	n.Stop()
+	nb.Stop()
+
+	RegisterUnitKind("flaky", func(id string, opt Optimizer) *Unit {
+		return NewUnit(id, new(flakyActivation), opt)
+	})
+	nf := MustNewMLP([]int{2, 2, 1}, NewSGD(1.0, 0.0, 0.0),
+		WithUnitKinds([]string{InputKind, "flaky", OutputKind}), WithSignalBatching())
+	nf.Start(true, 1)
+	defer nf.Stop()
+	if out := nf.MustForward([]float64{1.0, 1.0}); out[0] != 0.0 {
+		t.Errorf("Output is %.4f; expected 0", out[0])
+	}
+	nf.MustBackward([]float64{1.0})
+	nf.MustForward([]float64{1.0, 1.0})
+	nf.MustBackward([]float64{1.0})
+	if r := nf.Restarts(); r != 2 {
+		t.Errorf("Got %d restarts; expected 2", r)
+	}
+}
+
+func BenchmarkSignalBatching(b *testing.B) {
+	Verbosity = 0
+	arch := []int{64, 128, 128, 1}
+	input := make([]float64, arch[0])
+	for ii := range input {
+		input[ii] = rand.Float64()
+	}
+	grad := []float64{1.0}
+	for _, batching := range []bool{false, true} {
+		var opts []Option
+		name := "Single"
+		if batching {
+			opts = append(opts, WithSignalBatching())
+			name = "Batched"
+		}
+		b.Run(name, func(b *testing.B) {
+			n := MustNewMLP(arch, NewSGD(0.0, 0.0, 0.0), opts...)
+			n.Start(true, 1)
+			defer n.Stop()
+			b.ResetTimer()
+			for ii := 0; ii < b.N; ii++ {
+				n.MustForward(input)
+				n.MustBackward(grad)
+			}
+		})
+	}
+}
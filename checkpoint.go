@@ -0,0 +1,153 @@
+package neuron
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A Checkpoint periodically saves a training network to a file, so that a
+// long training run can be resumed after a crash. A checkpoint holds the
+// network's parameters and optimizer state, as saved by Net.Save, along with
+// the state of an optional random source, the step count, and the mode the
+// network was started in. If the network was built with WithSeed, the state
+// of its random sources is saved too, so that resumed training draws the
+// same noise. Typical use is
+//
+//	c := &Checkpoint{Path: "train.ckpt", Every: 1000, Rand: src}
+//	n, err := c.Resume(opt)
+//	if errors.Is(err, os.ErrNotExist) {
+//		n, err = NewMLP(arch, opt)
+//		n.Start(true, updateFreq)
+//	}
+//	for !done {
+//		n.Forward(x)
+//		n.Backward(grad)
+//		if err := c.Update(n); err != nil {
+//			...
+//		}
+//	}
+//
+// Gradients accumulated since the last weight update aren't saved, so Every
+// should be a multiple of the network's updateFreq.
+type Checkpoint struct {
+	// File the checkpoint is written to. Each checkpoint replaces the last.
+	Path string
+	// A checkpoint is saved every Every steps, i.e. back-propagated samples.
+	Every int
+	// Random source whose state is saved with the network, e.g. the source
+	// used to shuffle the training data. Optional.
+	Rand *RandSource
+	// Step count at the last call to Update.
+	steps int
+}
+
+// checkpointState is the serializable state written by Checkpoint.Save.
+type checkpointState struct {
+	Net        *netState
+	Rand       *RandState
+	NetRand    *RandState
+	UnitRand   map[string]RandState
+	Steps      int
+	Train      bool
+	UpdateFreq int
+}
+
+// Update should be called after each Backward. It saves a checkpoint each
+// time the network's step count passes a multiple of Every.
+func (c *Checkpoint) Update(n *Net) error {
+	if c.Every <= 0 {
+		return fmt.Errorf("checkpoint needs Every >= 1; got %d", c.Every)
+	}
+	steps := n.Steps()
+	if steps/c.Every <= c.steps/c.Every {
+		c.steps = steps
+		return nil
+	}
+	c.steps = steps
+	return c.Save(n)
+}
+
+// Save writes a checkpoint of n right away. It should only be called while
+// the network is idle, e.g. after Backward returns. The checkpoint is
+// written to a temporary file first and then renamed, so a crash mid-write
+// leaves the previous checkpoint intact.
+func (c *Checkpoint) Save(n *Net) error {
+	s := checkpointState{
+		Net:        n.state(),
+		Steps:      n.Steps(),
+		Train:      n.train,
+		UpdateFreq: n.updateFreq,
+	}
+	if c.Rand != nil {
+		st := c.Rand.State()
+		s.Rand = &st
+	}
+	s.NetRand, s.UnitRand = n.randState()
+
+	err := writeFileAtomic(c.Path, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(&s)
+	})
+	if err == nil {
+		logf(1, "Saved checkpoint at step %d to %s\n", s.Steps, c.Path)
+	}
+	return err
+}
+
+// writeFileAtomic writes a file with write. The file is written to a
+// temporary file first and then renamed, so a crash mid-write leaves the
+// previous file intact.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Resume reconstructs a network from the last checkpoint and starts it in
+// the saved mode, so that training continues where it left off. Like
+// LoadNet, the network is constructed with NewMLP(arch, opt, opts...), so
+// opts should match the options of the checkpointed network. The state of
+// c.Rand and the network's random sources is restored too. If there is no
+// checkpoint yet, the error satisfies errors.Is(err, os.ErrNotExist).
+func (c *Checkpoint) Resume(opt Optimizer, opts ...Option) (*Net, error) {
+	f, err := os.Open(c.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var s checkpointState
+	if err := gob.NewDecoder(f).Decode(&s); err != nil {
+		return nil, fmt.Errorf("reading checkpoint %s: %v", c.Path, err)
+	}
+	if s.Net == nil {
+		return nil, fmt.Errorf("checkpoint %s has no network", c.Path)
+	}
+	n, err := s.Net.restore(opt, opts)
+	if err != nil {
+		return nil, err
+	}
+	if c.Rand != nil && s.Rand != nil {
+		c.Rand.Restore(*s.Rand)
+	}
+	n.setRandState(s.NetRand, s.UnitRand)
+	n.setSteps(s.Steps)
+	c.steps = s.Steps
+	logf(1, "Resuming from checkpoint at step %d\n", s.Steps)
+	n.Start(s.Train, s.UpdateFreq)
+	return n, nil
+}
@@ -0,0 +1,107 @@
+package neuron
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test that training resumed from a checkpoint matches uninterrupted training.
+func TestCheckpoint(t *testing.T) {
+	arch := []int{2, 3, 1}
+	opt := NewSGD(0.1, 0.9, 0.0)
+	train := func(n *Net, c *Checkpoint, r *rand.Rand, steps int) {
+		for n.Steps() < steps {
+			n.MustForward([]float64{r.NormFloat64(), r.NormFloat64()})
+			n.MustBackward([]float64{r.NormFloat64()})
+			if c != nil {
+				if err := c.Update(n); err != nil {
+					t.Fatalf("Update failed: %v", err)
+				}
+			}
+		}
+	}
+
+	rand.Seed(8)
+	want := MustNewMLP(arch, opt)
+	want.Start(true, 3)
+	train(want, nil, rand.New(NewRandSource(1)), 9)
+	want.Stop()
+
+	c := &Checkpoint{Path: filepath.Join(t.TempDir(), "train.ckpt"), Every: 3,
+		Rand: NewRandSource(1)}
+	if _, err := c.Resume(opt); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Resume without a checkpoint returned %v", err)
+	}
+	rand.Seed(8)
+	n := MustNewMLP(arch, opt)
+	n.Start(true, 3)
+	// Crash after the checkpoint at step 6.
+	train(n, c, rand.New(c.Rand), 7)
+	n.Stop()
+
+	resumed, err := c.Resume(opt)
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if s := resumed.Steps(); s != 6 {
+		t.Errorf("Resumed at step %d; expected 6", s)
+	}
+	train(resumed, c, rand.New(c.Rand), 9)
+	resumed.Stop()
+
+	theta, thetaR := want.Data(), resumed.Data()
+	for uid, ud := range theta {
+		for id, v := range ud {
+			if !almostEqual(thetaR[uid][id], v) {
+				t.Errorf("Param %s/%s is %.6f; expected %.6f", uid, id,
+					thetaR[uid][id], v)
+			}
+		}
+	}
+}
+
+// Test that a checkpoint restores the network's random sources, so that
+// resumed training draws the same weight noise.
+func TestCheckpointRand(t *testing.T) {
+	arch := []int{2, 3, 1}
+	opt := NewSGD(0.1, 0.0, 0.0)
+	opts := []Option{WithSeed(5), WithWeightNoise(1, 0.5)}
+	x, grad := []float64{0.5, -1.0}, []float64{0.2}
+	train := func(n *Net, c *Checkpoint, steps int) []float64 {
+		var outs []float64
+		for n.Steps() < steps {
+			outs = append(outs, n.MustForward(x)[0])
+			n.MustBackward(grad)
+			if c != nil {
+				if err := c.Update(n); err != nil {
+					t.Fatalf("Update failed: %v", err)
+				}
+			}
+		}
+		return outs
+	}
+
+	want := MustNewMLP(arch, opt, opts...)
+	want.Start(true, 1)
+	outs := train(want, nil, 4)
+	want.Stop()
+
+	c := &Checkpoint{Path: filepath.Join(t.TempDir(), "train.ckpt"), Every: 2}
+	n := MustNewMLP(arch, opt, opts...)
+	n.Start(true, 1)
+	train(n, c, 3)
+	n.Stop()
+	resumed, err := c.Resume(opt, opts...)
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	defer resumed.Stop()
+	for ii, out := range train(resumed, c, 4) {
+		if !almostEqual(out, outs[ii+2]) {
+			t.Errorf("Output at step %d is %.6f; expected %.6f", ii+2, out, outs[ii+2])
+		}
+	}
+}
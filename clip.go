@@ -0,0 +1,57 @@
+package neuron
+
+// WithGradClipValue clips each accumulated gradient to [-clip, clip] before
+// every weight update.
+func WithGradClipValue(clip float64) Option {
+	return func(c *netConfig) {
+		c.clipValue = clip
+	}
+}
+
+// WithGradClipNorm rescales the accumulated gradients so that their global
+// norm over all units is at most maxNorm before every weight update.
+//
+// Since the norm depends on every unit's gradients, units don't update their
+// own weights. Instead, the Net gathers the gradients once all units have
+// finished the backward pass, computes the norm, and steps every unit while
+// they're idle.
+func WithGradClipNorm(maxNorm float64) Option {
+	return func(c *netConfig) {
+		c.clipNorm = maxNorm
+	}
+}
+
+// gradNorm computes the global norm of the accumulated gradients.
+func (n *Net) gradNorm() float64 {
+	return globalNorm(n.layerNorms(func(p *Param) float64 { return p.grad }))
+}
+
+// clipStep clips the global gradient norm and updates the weights of every
+// unit. It must be called while the units are idle. The gradients of tied
+// copies are added to their owners first, and left out of the norm, so that
+// a twin (see NewTwin) doesn't clip the gradients it shares.
+func (n *Net) clipStep() {
+	n.forEachParam(func(u *Unit, id string, p *Param) {
+		p.mergeTies()
+	})
+	norm := globalNorm(n.layerNorms(func(p *Param) float64 {
+		if p.tied != nil {
+			return 0.0
+		}
+		return p.grad
+	}))
+	if norm > n.clipNorm {
+		scale := n.clipNorm / norm
+		n.forEachParam(func(u *Unit, id string, p *Param) {
+			if p.tied == nil {
+				p.grad *= scale
+			}
+		})
+	}
+	for _, l := range n.Layers {
+		for _, u := range l {
+			u.step()
+			u.save()
+		}
+	}
+}
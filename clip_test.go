@@ -0,0 +1,51 @@
+package neuron
+
+import (
+	"math"
+	"testing"
+)
+
+// Test clipping gradients by value.
+func TestGradClipValue(t *testing.T) {
+	arch := []int{1, 1, 1}
+	n := MustNewMLP(arch, NewSGD(1.0, 0.0, 0.0), WithGradClipValue(0.5))
+	out := n.Layers[2][0]
+	bias := out.W.Params[biasID].Data
+
+	n.Start(true, 1)
+	n.MustForward([]float64{1.0})
+	n.MustBackward([]float64{3.0})
+	if b := out.W.Params[biasID].Data; !almostEqual(b, bias-0.5) {
+		t.Errorf("Output bias is %.4f; expected %.4f", b, bias-0.5)
+	}
+}
+
+// Test clipping gradients by global norm.
+func TestGradClipNorm(t *testing.T) {
+	arch := []int{2, 3, 2}
+	const maxNorm = 0.1
+	n := MustNewMLP(arch, NewSGD(1.0, 0.0, 0.0), WithGradClipNorm(maxNorm))
+	before := n.Data()
+
+	n.Start(true, 1)
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{5.0, -5.0})
+
+	// The update with lr 1 is the clipped gradient.
+	after := n.Data()
+	norm := 0.0
+	for uid, params := range after {
+		for id, v := range params {
+			d := v - before[uid][id]
+			norm += d * d
+		}
+	}
+	if norm = math.Sqrt(norm); !almostEqual(norm, maxNorm) {
+		t.Errorf("Update norm is %.4f; expected %.4f", norm, maxNorm)
+	}
+
+	// Gradients are reset after the update.
+	if g := n.gradNorm(); g != 0.0 {
+		t.Errorf("Grad norm after update is %.4f; expected 0", g)
+	}
+}
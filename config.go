@@ -0,0 +1,220 @@
+package neuron
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A Config declares a network and how to train it, so experiments can be
+// defined in a file rather than in code, e.g.
+//
+//	{
+//	  "arch": [64, 128, 1],
+//	  "activations": ["tanh", "identity"],
+//	  "initializer": "xavier_uniform",
+//	  "optimizer": {"name": "sgd", "lr": 0.1, "momentum": 0.9},
+//	  "loss": "margin",
+//	  "train": {"epochs": 10, "batch_size": 32, "shuffle": true}
+//	}
+//
+// Unset fields keep the defaults of NewMLP and Trainer.
+type Config struct {
+	Arch []int `json:"arch"`
+	// Registered unit kind of each layer, see WithUnitKinds.
+	Kinds []string `json:"kinds,omitempty"`
+	// Activation of each layer after the input layer, see WithActivations:
+	// "relu", "leaky_relu" (slope 0.01), "prelu" (initial slope 0.25), "elu"
+	// (alpha 1), "gelu", "sigmoid", "tanh", or "identity". An empty name keeps
+	// the default.
+	Activations []string `json:"activations,omitempty"`
+	// One of "xavier_uniform", "xavier_normal", "he_uniform", or "he_normal".
+	Initializer string `json:"initializer,omitempty"`
+	// Seed of the network's random source, see WithSeed.
+	Seed *int64 `json:"seed,omitempty"`
+	// Softmax output and global gradient norm clipping, see
+	// WithSoftmaxOutput and WithGradClipNorm.
+	SoftmaxOutput bool    `json:"softmax_output,omitempty"`
+	GradClipNorm  float64 `json:"grad_clip_norm,omitempty"`
+	// Run a goroutine per layer, see WithLayerEngine.
+	LayerEngine bool            `json:"layer_engine,omitempty"`
+	Optimizer   OptimizerConfig `json:"optimizer"`
+	// Registered loss name, see GetLoss.
+	Loss  string      `json:"loss"`
+	Train TrainConfig `json:"train"`
+}
+
+// An OptimizerConfig declares an optimizer by name, one of "sgd", "rmsprop",
+// or "adagrad". Alpha and Eps default to 0.99 and 1e-8 for RMSProp, and Eps
+// to 1e-10 for Adagrad.
+type OptimizerConfig struct {
+	Name        string  `json:"name"`
+	LR          float64 `json:"lr"`
+	Momentum    float64 `json:"momentum,omitempty"`
+	Alpha       float64 `json:"alpha,omitempty"`
+	Eps         float64 `json:"eps,omitempty"`
+	WeightDecay float64 `json:"weight_decay,omitempty"`
+}
+
+// A TrainConfig holds the Trainer settings of a Config.
+type TrainConfig struct {
+	Epochs        int  `json:"epochs"`
+	BatchSize     int  `json:"batch_size,omitempty"`
+	Shuffle       bool `json:"shuffle,omitempty"`
+	Workers       int  `json:"workers,omitempty"`
+	ValidateEvery int  `json:"validate_every,omitempty"`
+	Accuracy      bool `json:"accuracy,omitempty"`
+}
+
+// LoadConfig reads a JSON Config, and builds its network and a trainer for
+// it, see Config.Build. Unknown fields are an error, to catch typos.
+func LoadConfig(r io.Reader) (*Config, *Trainer, error) {
+	var c Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, nil, fmt.Errorf("config: %w", err)
+	}
+	t, err := c.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &c, t, nil
+}
+
+// Build constructs the network of the config, and returns a trainer for it
+// with the config's loss and training settings. The trainer's Data, and
+// Validation if any, need to be set before calling
+//
+//	t.Fit(c.Train.Epochs)
+func (c *Config) Build() (*Trainer, error) {
+	opt, err := c.Optimizer.build()
+	if err != nil {
+		return nil, err
+	}
+	loss, ok := GetLoss(c.Loss)
+	if !ok {
+		return nil, fmt.Errorf("config: unknown loss %q", c.Loss)
+	}
+
+	var opts []Option
+	if c.Kinds != nil {
+		opts = append(opts, WithUnitKinds(c.Kinds))
+	}
+	if c.Activations != nil {
+		activs := make([]Activation, len(c.Activations))
+		for ii, name := range c.Activations {
+			if activs[ii], err = configActivation(name); err != nil {
+				return nil, err
+			}
+		}
+		opts = append(opts, WithActivations(activs))
+	}
+	if c.Initializer != "" {
+		init, err := configInitializer(c.Initializer)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithInitializer(init))
+	}
+	if c.Seed != nil {
+		opts = append(opts, WithSeed(*c.Seed))
+	}
+	if c.SoftmaxOutput {
+		opts = append(opts, WithSoftmaxOutput())
+	}
+	if c.GradClipNorm > 0 {
+		opts = append(opts, WithGradClipNorm(c.GradClipNorm))
+	}
+	if c.LayerEngine {
+		opts = append(opts, WithLayerEngine())
+	}
+	n, err := NewMLP(c.Arch, opt, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	if c.Train.Epochs < 0 {
+		return nil, fmt.Errorf("config: epochs needs to be >= 0; got %d", c.Train.Epochs)
+	}
+	return &Trainer{
+		Net:           n,
+		Loss:          loss,
+		BatchSize:     c.Train.BatchSize,
+		Shuffle:       c.Train.Shuffle,
+		Workers:       c.Train.Workers,
+		ValidateEvery: c.Train.ValidateEvery,
+		Accuracy:      c.Train.Accuracy,
+	}, nil
+}
+
+// build constructs the optimizer.
+func (c OptimizerConfig) build() (Optimizer, error) {
+	if c.LR <= 0 {
+		return nil, fmt.Errorf("config: optimizer lr needs to be > 0; got %g", c.LR)
+	}
+	switch c.Name {
+	case "sgd":
+		return NewSGD(c.LR, c.Momentum, c.WeightDecay), nil
+	case "rmsprop":
+		alpha, eps := c.Alpha, c.Eps
+		if alpha == 0 {
+			alpha = 0.99
+		}
+		if eps == 0 {
+			eps = 1.0e-08
+		}
+		return NewRMSProp(c.LR, alpha, eps, c.WeightDecay), nil
+	case "adagrad":
+		eps := c.Eps
+		if eps == 0 {
+			eps = 1.0e-10
+		}
+		return NewAdagrad(c.LR, eps, c.WeightDecay), nil
+	case "":
+		return nil, errors.New("config: missing optimizer name")
+	}
+	return nil, fmt.Errorf("config: unknown optimizer %q", c.Name)
+}
+
+// configActivation returns a new activation by name, see Config.Activations.
+// An empty name returns nil, keeping the unit kind's default.
+func configActivation(name string) (Activation, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "relu":
+		return new(Relu), nil
+	case "leaky_relu":
+		return &LeakyRelu{Slope: 0.01}, nil
+	case "prelu":
+		return NewPRelu(0.25), nil
+	case "elu":
+		return &Elu{Alpha: 1.0}, nil
+	case "gelu":
+		return new(Gelu), nil
+	case "sigmoid":
+		return new(Sigmoid), nil
+	case "tanh":
+		return new(Tanh), nil
+	case "identity":
+		return new(Identity), nil
+	}
+	return nil, fmt.Errorf("config: unknown activation %q", name)
+}
+
+// configInitializer returns an initializer by name.
+func configInitializer(name string) (Initializer, error) {
+	switch name {
+	case "xavier_uniform":
+		return XavierUniform{}, nil
+	case "xavier_normal":
+		return XavierNormal{}, nil
+	case "he_uniform":
+		return HeUniform{}, nil
+	case "he_normal":
+		return HeNormal{}, nil
+	}
+	return nil, fmt.Errorf("config: unknown initializer %q", name)
+}
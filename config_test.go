@@ -0,0 +1,58 @@
+package neuron
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test building and training a network from a config.
+func TestLoadConfig(t *testing.T) {
+	const cfg = `{
+		"arch": [2, 8, 1],
+		"activations": ["tanh", ""],
+		"initializer": "xavier_uniform",
+		"seed": 3,
+		"optimizer": {"name": "sgd", "lr": 0.01, "momentum": 0.9},
+		"loss": "mse",
+		"train": {"epochs": 20, "batch_size": 8, "shuffle": true}
+	}`
+	c, tr, err := LoadConfig(strings.NewReader(cfg))
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+	n := tr.Net
+	defer n.Stop()
+	if _, ok := n.Layers[1][0].activ.(*Tanh); !ok {
+		t.Errorf("Hidden activation is %T; expected *Tanh", n.Layers[1][0].activ)
+	}
+	if _, ok := n.Layers[2][0].activ.(*Identity); !ok {
+		t.Errorf("Output activation is %T; expected *Identity", n.Layers[2][0].activ)
+	}
+	if tr.BatchSize != 8 || !tr.Shuffle || n.GetLR() != 0.01 {
+		t.Errorf("Incorrect trainer settings %+v", tr)
+	}
+
+	tr.Data = linearData(50)
+	hist, err := tr.Fit(c.Train.Epochs)
+	if err != nil {
+		t.Fatalf("Fit returned an error: %v", err)
+	}
+	if first, last := hist.Epochs[0].Loss, hist.Epochs[len(hist.Epochs)-1].Loss; last > 0.2*first {
+		t.Errorf("Loss went from %.3e to %.3e", first, last)
+	}
+
+	bad := []string{
+		`{"arch": [2, 1], "optimizer": {"name": "sgd", "lr": 0.1}, "loss": "nope"}`,
+		`{"arch": [2, 1], "optimizer": {"name": "adam", "lr": 0.1}, "loss": "mse"}`,
+		`{"arch": [2, 1], "optimizer": {"name": "sgd", "lr": 0.1}, "loss": "mse",
+			"activations": ["swish"]}`,
+		`{"arch": [2, 1], "optimizer": {"name": "sgd", "lr": 0.1}, "loss": "mse",
+			"initializr": "he_normal"}`,
+		`{"arch": [2], "optimizer": {"name": "sgd", "lr": 0.1}, "loss": "mse"}`,
+	}
+	for ii, cfg := range bad {
+		if _, _, err := LoadConfig(strings.NewReader(cfg)); err == nil {
+			t.Errorf("(%d) Expected an error for config %s", ii, cfg)
+		}
+	}
+}
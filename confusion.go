@@ -0,0 +1,89 @@
+package neuron
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// ConfusionMatrix counts the predictions of each class against the targets,
+// with a classification report of the per-class precision, recall, F1, and
+// support (number of targets). Targets need to be a single class label, like
+// for Accuracy.
+//
+// ConfusionMatrix is a Metric scoring the accuracy, so it can be accumulated
+// during evaluation by adding it to Trainer.Metrics, and read back after.
+type ConfusionMatrix struct {
+	c classCounts
+}
+
+// Name returns "accuracy".
+func (m *ConfusionMatrix) Name() string { return "accuracy" }
+
+// Update adds a prediction.
+func (m *ConfusionMatrix) Update(pred, target []float64) { m.c.update(pred, target) }
+
+// Compute returns the accuracy.
+func (m *ConfusionMatrix) Compute() float64 {
+	correct := 0
+	for _, class := range m.Classes() {
+		correct += m.Count(class, class)
+	}
+	return ratio(correct, m.c.total())
+}
+
+// Reset clears the predictions.
+func (m *ConfusionMatrix) Reset() { m.c.reset() }
+
+// Classes returns the classes seen as a target or prediction in sorted order.
+func (m *ConfusionMatrix) Classes() []int { return m.c.seen() }
+
+// Count returns the number of samples of class target predicted as pred.
+func (m *ConfusionMatrix) Count(target, pred int) int {
+	return m.c.counts[[2]int{target, pred}]
+}
+
+// Matrix returns the counts with rows indexed by target and columns by
+// prediction, in the order of Classes.
+func (m *ConfusionMatrix) Matrix() [][]int {
+	classes := m.Classes()
+	counts := make([][]int, len(classes))
+	for ii, target := range classes {
+		counts[ii] = make([]int, len(classes))
+		for jj, pred := range classes {
+			counts[ii][jj] = m.Count(target, pred)
+		}
+	}
+	return counts
+}
+
+// String formats the matrix followed by the classification report.
+func (m *ConfusionMatrix) String() string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', tabwriter.AlignRight)
+	classes := m.Classes()
+
+	fmt.Fprint(tw, "target\\pred\t")
+	for _, class := range classes {
+		fmt.Fprintf(tw, "%d\t", class)
+	}
+	fmt.Fprintln(tw)
+	for ii, row := range m.Matrix() {
+		fmt.Fprintf(tw, "%d\t", classes[ii])
+		for _, v := range row {
+			fmt.Fprintf(tw, "%d\t", v)
+		}
+		fmt.Fprintln(tw)
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "class\tprecision\trecall\tf1\tsupport\t")
+	for _, class := range classes {
+		tp, fp, fn := m.c.stats(class)
+		fmt.Fprintf(tw, "%d\t%.3f\t%.3f\t%.3f\t%d\t\n", class, precision(tp, fp, fn),
+			recall(tp, fp, fn), f1(tp, fp, fn), tp+fn)
+	}
+	fmt.Fprintf(tw, "accuracy\t\t\t%.3f\t%d\t\n", m.Compute(), m.c.total())
+	tw.Flush()
+	return b.String()
+}
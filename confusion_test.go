@@ -0,0 +1,48 @@
+package neuron
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test confusion matrix counts and report.
+func TestConfusionMatrix(t *testing.T) {
+	m := &ConfusionMatrix{}
+	pred := [][]float64{{1, 0, 0}, {0, 0, 1}, {0, 0, 1}, {0, 1, 0}, {0, 0, 1}}
+	target := []float64{0, 1, 2, 2, 2}
+	for ii := range pred {
+		m.Update(pred[ii], []float64{target[ii]})
+	}
+
+	if classes := m.Classes(); !reflect.DeepEqual(classes, []int{0, 1, 2}) {
+		t.Errorf("Classes are %v; expected [0 1 2]", classes)
+	}
+	want := [][]int{{1, 0, 0}, {0, 0, 1}, {0, 1, 2}}
+	if counts := m.Matrix(); !reflect.DeepEqual(counts, want) {
+		t.Errorf("Matrix is %v; expected %v", counts, want)
+	}
+	if acc := m.Compute(); !almostEqual(acc, 0.6) {
+		t.Errorf("Accuracy is %.3f; expected 0.6", acc)
+	}
+
+	report := m.String()
+	var lines []string
+	for _, line := range strings.Split(report, "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	for _, line := range []string{"2 0.667 0.667 0.667 3", "accuracy 0.600 5"} {
+		found := false
+		for _, l := range lines {
+			found = found || l == line
+		}
+		if !found {
+			t.Errorf("Report is missing %q:\n%s", line, report)
+		}
+	}
+
+	m.Reset()
+	if counts := m.Matrix(); len(counts) != 0 {
+		t.Errorf("Matrix after reset is %v; expected empty", counts)
+	}
+}
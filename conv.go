@@ -0,0 +1,147 @@
+package neuron
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// A conv1D is the kernel of a convolutional layer, see WithConv1D.
+type conv1D struct {
+	kernel, stride int
+}
+
+// positions returns the number of positions of the kernel over in inputs.
+func (cv conv1D) positions(in int) int {
+	return (in-cv.kernel)/cv.stride + 1
+}
+
+// WithConv1D makes layer a 1D convolution over the previous layer, treated as
+// a signal, e.g. for simple signal-processing models. Each unit is connected
+// to a window of kernel consecutive units, starting at a multiple of stride,
+// and the units at every position share the same kernel weights and bias, so
+// the layer has few weights however long the signal is. With
+//
+//	positions = (arch[layer-1] - kernel)/stride + 1
+//
+// the layer's units are split into arch[layer]/positions filters, each with
+// its own kernel, of positions units in order, e.g.
+//
+//	NewMLP([]int{16, 28, 1}, opt, WithConv1D(1, 4, 2))
+//
+// has 2 filters of 4 weights over 7 positions. Trailing inputs that don't
+// fill a window are left out. It can be passed for more than one layer.
+//
+// Like tied weights, see WithTiedWeights, the shared parameters are owned by
+// the unit at the first position of each filter: the gradients of every
+// position are summed into the owner's gradient before each update, and the
+// other positions pick up the owner's value in the next forward pass. Since
+// the positions run concurrently, the Net updates the units of the layer once
+// every unit has finished the backward pass. Every position shows up in
+// Net.Data, and the option should be passed to LoadNet to load the network.
+// Convolutions don't support sparse connections, tied weights, or pipelining.
+func WithConv1D(layer, kernel, stride int) Option {
+	return func(c *netConfig) {
+		if c.convs == nil {
+			c.convs = make(map[int]conv1D)
+		}
+		c.convs[layer] = conv1D{kernel, stride}
+	}
+}
+
+// checkConvs checks the convolution settings. It must be called after
+// checkTies.
+func checkConvs(c *netConfig, arch []int) error {
+	if len(c.convs) == 0 {
+		return nil
+	}
+	if c.sparse() || c.pipeline {
+		return errors.New("convolutions don't support sparse connections or pipelining")
+	}
+	for ii, cv := range c.convs {
+		if ii < 1 || ii >= len(arch) {
+			return fmt.Errorf("convolution layer %d out of range", ii)
+		}
+		if cv.kernel < 1 || cv.kernel > arch[ii-1] || cv.stride < 1 {
+			return fmt.Errorf("convolution layer %d needs 1 <= kernel <= %d and stride >= 1; got %d and %d",
+				ii, arch[ii-1], cv.kernel, cv.stride)
+		}
+		if positions := cv.positions(arch[ii-1]); arch[ii]%positions != 0 {
+			return fmt.Errorf("convolution layer %d needs a multiple of %d positions; got %d units",
+				ii, positions, arch[ii])
+		}
+		for _, t := range c.ties {
+			if t[0] == ii || t[1] == ii {
+				return fmt.Errorf("convolution layer %d can't have tied weights", ii)
+			}
+		}
+	}
+	return nil
+}
+
+// connect connects each unit in down to its window of up.
+func (cv conv1D) connect(up, down []*Unit, rng *rand.Rand) {
+	positions := cv.positions(len(up))
+	// Connect in the same order as full connectivity, upstream units first.
+	for ii, u1 := range up {
+		for jj, u2 := range down {
+			start := (jj % positions) * cv.stride
+			if ii >= start && ii < start+cv.kernel {
+				u1.connect(u2, rng)
+			}
+		}
+	}
+}
+
+// shareKernels ties the kernel weights and biases of every position of each
+// convolution layer to the first position of its filter, see WithConv1D. The
+// units of convolution layers are stepped by the Net, see stepShared.
+func (n *Net) shareKernels(convs map[int]conv1D) {
+	n.convs = convs
+	for ii, cv := range convs {
+		prev, l := n.Layers[ii-1], n.Layers[ii]
+		positions := cv.positions(len(prev))
+		for jj, u := range l {
+			u.netStep = true
+			pos := jj % positions
+			if pos == 0 {
+				continue
+			}
+			owner := l[jj-pos]
+			var ps, owned []*Param
+			for k := 0; k < cv.kernel; k++ {
+				ps = append(ps, u.W.Params[prev[pos*cv.stride+k].ID])
+				owned = append(owned, owner.W.Params[prev[k].ID])
+			}
+			if p, ok := u.W.Params[biasID]; ok {
+				ps, owned = append(ps, p), append(owned, owner.W.Params[biasID])
+			}
+			for k, p := range ps {
+				o := owned[k]
+				p.Data, p.tied = o.Data, o
+				o.ties = append(o.ties, p)
+			}
+		}
+	}
+}
+
+// inConv checks whether the unit with the given ID is in a convolution layer.
+func (n *Net) inConv(id string) bool {
+	ii, _, ok := n.findUnit(id)
+	_, conv := n.convs[ii]
+	return ok && conv
+}
+
+// stepShared updates the weights of the units stepped by the Net, i.e. of
+// convolution layers, see WithConv1D. It must be called while the units are
+// idle.
+func (n *Net) stepShared() {
+	for _, l := range n.Layers {
+		for _, u := range l {
+			if u.netStep {
+				u.step()
+				u.save()
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/clane9/go-neuron/data"
+)
+
+// newConvNet creates a network with a linear convolution layer of 2 filters
+// of 3 weights over 3 positions.
+func newConvNet(opts ...Option) *Net {
+	opts = append(opts, WithConv1D(1, 3, 2),
+		WithActivations([]Activation{new(Identity), new(Identity)}))
+	return MustNewMLP([]int{7, 6, 1}, NewSGD(0.1, 0.0, 0.0), opts...)
+}
+
+// Test that the positions of a convolution share their kernel, and compute
+// the convolution of the input.
+func TestConv1D(t *testing.T) {
+	rand.Seed(61)
+	n := newConvNet()
+	prev, l := n.Layers[0], n.Layers[1]
+	for jj, u := range l {
+		conns := 0
+		for k := range u.W.Params {
+			if isConn(k) {
+				conns++
+			}
+		}
+		if conns != 3 {
+			t.Errorf("Unit %s has %d connections; expected 3", u.ID, conns)
+		}
+		owner := l[jj/3*3]
+		for k := 0; k < 3; k++ {
+			p := u.W.Params[prev[jj%3*2+k].ID]
+			if o := owner.W.Params[prev[k].ID]; p.Data != o.Data || (u != owner) != (p.tied == o) {
+				t.Errorf("Weight %d of unit %s isn't shared with %s", k, u.ID, owner.ID)
+			}
+		}
+	}
+
+	x := []float64{0.5, -1.0, 2.0, 0.3, -0.7, 1.5, 0.8}
+	n.Start(false, 0)
+	defer n.Stop()
+	_, acts, err := n.ForwardWithActivations(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for f := 0; f < 2; f++ {
+		w := l[f*3].W
+		for pos := 0; pos < 3; pos++ {
+			want := w.Params[biasID].Data
+			for k := 0; k < 3; k++ {
+				want += w.Params[prev[k].ID].Data * x[pos*2+k]
+			}
+			if got := acts[l[f*3+pos].ID]; !almostEqual(got, want) {
+				t.Errorf("Filter %d at position %d is %.6f; expected %.6f", f, pos, got, want)
+			}
+		}
+	}
+}
+
+// Test that the gradients of every position are summed into the kernel, and
+// that the positions stay shared after updates, on every engine.
+func TestConv1DGrads(t *testing.T) {
+	x := []float64{0.5, -1.0, 2.0, 0.3, -0.7, 1.5, 0.8}
+	target := []float64{0.4}
+	loss, _ := GetLoss("mse")
+	for _, layers := range []bool{false, true} {
+		rand.Seed(67)
+		var opts []Option
+		if layers {
+			opts = append(opts, WithLayerEngine())
+		}
+		n := newConvNet(opts...)
+		n.Start(true, 0)
+		relErr, maxErr, err := GradCheck(n, x, target, loss, 1.0e-05)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if maxErr > 1.0e-05 {
+			t.Errorf("(layers=%v) Max relative error is %g; expected < 1e-5", layers, maxErr)
+		}
+		// Only the owners are checked.
+		if k := len(relErr[unitID(1, 1)]); k != 0 {
+			t.Errorf("(layers=%v) Checked %d shared parameters", layers, k)
+		}
+
+		n.Stop()
+		n.Start(true, 1)
+		for ii := 0; ii < 3; ii++ {
+			out := n.MustForward(x)
+			loss.Forward(out, target)
+			n.MustBackward(loss.Backward())
+		}
+		n.Stop()
+		// The positions pick up the kernel in the next pass.
+		n.Start(false, 0)
+		n.MustForward(x)
+		n.Stop()
+		l, id := n.Layers[1], unitID(0, 2)
+		w := l[0].W.Params[id].Data
+		if w2 := l[1].W.Params[unitID(0, 4)].Data; w2 != w {
+			t.Errorf("(layers=%v) Shared weight is %v at position 1; expected %v", layers, w2, w)
+		}
+		if w3 := l[2].W.Params[unitID(0, 6)].Data; w3 != w {
+			t.Errorf("(layers=%v) Shared weight is %v at position 2; expected %v", layers, w3, w)
+		}
+	}
+
+	// The dense engine steps the shared bias once, with the sum of the
+	// gradients of every position.
+	rand.Seed(67)
+	n := newConvNet()
+	d, err := NewDense(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := d.Forward(x)
+	loss.Forward(out, target)
+	d.Backward(loss.Backward())
+	l := n.Layers[1]
+	b, grad := l[0].W.Params[biasID].Data, 0.0
+	for _, u := range l[:3] {
+		grad += u.W.Params[biasID].grad
+	}
+	d.Step()
+	want := b - 0.1*grad
+	for _, u := range l[:3] {
+		if got := u.W.Params[biasID].Data; !almostEqual(got, want) {
+			t.Errorf("Dense step left the bias of %s at %.6f; expected %.6f", u.ID, got, want)
+		}
+	}
+}
+
+// Test that a convolution learns a smoothing filter.
+func TestConv1DTraining(t *testing.T) {
+	rand.Seed(71)
+	ds := &data.VectorDataset{}
+	for ii := 0; ii < 64; ii++ {
+		x := make([]float64, 8)
+		for jj := range x {
+			x[jj] = rand.NormFloat64()
+		}
+		y := make([]float64, 6)
+		for jj := range y {
+			y[jj] = 0.25*x[jj] + 0.5*x[jj+1] + 0.25*x[jj+2]
+		}
+		ds.Inputs, ds.Targets = append(ds.Inputs, x), append(ds.Targets, y)
+	}
+	// The output layer is the convolution.
+	n := MustNewMLP([]int{8, 8, 6}, NewSGD(0.02, 0.5, 0.0), WithConv1D(2, 3, 1),
+		WithConv1D(1, 1, 1), WithActivations([]Activation{new(Identity), new(Identity)}))
+	defer n.Stop()
+	loss, _ := GetLoss("mse")
+	tr := &Trainer{Net: n, Loss: loss, Data: ds, BatchSize: 4, Shuffle: true}
+	h, err := tr.Fit(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, last := h.Epochs[0].Loss, h.Epochs[19].Loss; last > 0.05*first {
+		t.Errorf("Loss didn't decrease: %.4f -> %.4f", first, last)
+	}
+}
+
+// Test the convolution option errors.
+func TestConv1DErrors(t *testing.T) {
+	opt := NewSGD(0.1, 0.0, 0.0)
+	arch := []int{7, 6, 1}
+	bad := [][]Option{
+		{WithConv1D(0, 3, 2)},
+		{WithConv1D(3, 3, 2)},
+		{WithConv1D(1, 8, 1)},
+		{WithConv1D(1, 3, 0)},
+		{WithConv1D(1, 3, 1)},
+		{WithConv1D(1, 3, 2), WithFanIn(2)},
+		{WithConv1D(1, 3, 2), WithPipelining()},
+	}
+	for ii, opts := range bad {
+		if _, err := NewMLP(arch, opt, opts...); err == nil {
+			t.Errorf("Bad convolution options %d didn't fail", ii)
+		}
+	}
+	if _, err := NewMLP([]int{3, 3, 3}, opt, WithConv1D(2, 1, 1), WithTiedWeights(1, 2)); err == nil {
+		t.Error("Convolution with tied weights didn't fail")
+	}
+
+	n := newConvNet()
+	if _, err := n.AddUnit(1); err == nil {
+		t.Error("Adding a unit to a convolution didn't fail")
+	}
+	if err := n.PruneUnit(unitID(1, 0)); err == nil {
+		t.Error("Pruning a unit of a convolution didn't fail")
+	}
+	if err := n.PruneConnection(unitID(0, 0), unitID(1, 0)); err == nil {
+		t.Error("Pruning a connection into a convolution didn't fail")
+	}
+	n = MustNewMLP([]int{2, 3, 3, 1}, opt, WithConv1D(2, 1, 1))
+	if err := n.WidenLayer(1, 4); err == nil {
+		t.Error("Widening the input of a convolution didn't fail")
+	}
+	if err := n.PruneUnit(unitID(1, 0)); err == nil {
+		t.Error("Pruning an input of a convolution didn't fail")
+	}
+}
@@ -0,0 +1,98 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+)
+
+// OneHot encodes integer class labels in [0, classes) as one-hot vectors.
+func OneHot(labels []int, classes int) ([][]float64, error) {
+	out := make([][]float64, len(labels))
+	for ii, label := range labels {
+		if label < 0 || label >= classes {
+			return nil, fmt.Errorf("label %d of sample %d not in [0, %d)", label, ii, classes)
+		}
+		out[ii] = make([]float64, classes)
+		out[ii][label] = 1.0
+	}
+	return out, nil
+}
+
+// ClassTargets converts integer class labels to single-element targets, as
+// expected by the "cross_entropy" loss and the classification metrics.
+func ClassTargets(labels []int) [][]float64 {
+	out := make([][]float64, len(labels))
+	for ii, label := range labels {
+		out[ii] = []float64{float64(label)}
+	}
+	return out
+}
+
+// A LabelEncoder maps string class labels to integer indices and back. Classes
+// holds the label of each index, and can be saved, e.g. as JSON, to decode
+// the predictions of a trained network later.
+type LabelEncoder struct {
+	Classes []string
+	index   map[string]int
+}
+
+// NewLabelEncoder creates an encoder for the distinct labels, indexed in
+// sorted order.
+func NewLabelEncoder(labels []string) *LabelEncoder {
+	seen := make(map[string]bool)
+	var classes []string
+	for _, label := range labels {
+		if !seen[label] {
+			seen[label] = true
+			classes = append(classes, label)
+		}
+	}
+	sort.Strings(classes)
+	return &LabelEncoder{Classes: classes}
+}
+
+// Encode returns the index of each label.
+func (e *LabelEncoder) Encode(labels []string) ([]int, error) {
+	if e.index == nil {
+		e.index = make(map[string]int, len(e.Classes))
+		for ii, class := range e.Classes {
+			e.index[class] = ii
+		}
+	}
+	out := make([]int, len(labels))
+	for ii, label := range labels {
+		idx, ok := e.index[label]
+		if !ok {
+			return nil, fmt.Errorf("unknown label %q", label)
+		}
+		out[ii] = idx
+	}
+	return out, nil
+}
+
+// Decode returns the label of each index.
+func (e *LabelEncoder) Decode(indices []int) ([]string, error) {
+	out := make([]string, len(indices))
+	for ii, idx := range indices {
+		if idx < 0 || idx >= len(e.Classes) {
+			return nil, fmt.Errorf("index %d not in [0, %d)", idx, len(e.Classes))
+		}
+		out[ii] = e.Classes[idx]
+	}
+	return out, nil
+}
+
+// DecodeOutput returns the label predicted by a network output with a score
+// per class, the label of the largest score.
+func (e *LabelEncoder) DecodeOutput(output []float64) (string, error) {
+	if len(output) != len(e.Classes) {
+		return "", fmt.Errorf("expected %d scores; got %d", len(e.Classes), len(output))
+	}
+	best := 0
+	for ii, s := range output {
+		if s > output[best] {
+			best = ii
+		}
+	}
+	return e.Classes[best], nil
+}
@@ -0,0 +1,52 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Test one-hot encoding and class targets.
+func TestOneHot(t *testing.T) {
+	got, err := OneHot([]int{2, 0}, 3)
+	if err != nil {
+		t.Fatalf("OneHot returned an error: %v", err)
+	}
+	if want := [][]float64{{0, 0, 1}, {1, 0, 0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("One-hot encoding is %v; expected %v", got, want)
+	}
+	if _, err := OneHot([]int{3}, 3); err == nil {
+		t.Errorf("Expected an error for an out of range label")
+	}
+	if got := ClassTargets([]int{2, 0}); !reflect.DeepEqual(got, [][]float64{{2}, {0}}) {
+		t.Errorf("Class targets are %v", got)
+	}
+}
+
+// Test encoding string labels and decoding predictions.
+func TestLabelEncoder(t *testing.T) {
+	e := NewLabelEncoder([]string{"dog", "cat", "dog", "bird"})
+	if want := []string{"bird", "cat", "dog"}; !reflect.DeepEqual(e.Classes, want) {
+		t.Errorf("Classes are %v; expected %v", e.Classes, want)
+	}
+	idx, err := e.Encode([]string{"cat", "dog"})
+	if err != nil || !reflect.DeepEqual(idx, []int{1, 2}) {
+		t.Errorf("Encoded to %v (%v); expected [1 2]", idx, err)
+	}
+	labels, err := e.Decode(idx)
+	if err != nil || !reflect.DeepEqual(labels, []string{"cat", "dog"}) {
+		t.Errorf("Decoded to %v (%v); expected [cat dog]", labels, err)
+	}
+	if label, _ := e.DecodeOutput([]float64{0.1, -2.0, 0.3}); label != "dog" {
+		t.Errorf("Decoded output to %q; expected dog", label)
+	}
+
+	if _, err := e.Encode([]string{"fish"}); err == nil {
+		t.Errorf("Expected an error for an unknown label")
+	}
+	if _, err := e.Decode([]int{3}); err == nil {
+		t.Errorf("Expected an error for an out of range index")
+	}
+	if _, err := e.DecodeOutput([]float64{1.0}); err == nil {
+		t.Errorf("Expected an error for the wrong number of scores")
+	}
+}
@@ -0,0 +1,131 @@
+package data
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// IDX magic numbers of unsigned byte data, with the number of dimensions in
+// the last byte.
+const (
+	idxLabels = 0x00000801
+	idxImages = 0x00000803
+)
+
+// LoadMNIST loads an MNIST split from its images and labels files in the
+// idx-ubyte format, e.g. "train-images-idx3-ubyte" and
+// "train-labels-idx1-ubyte". Files ending in ".gz" are decompressed. Each
+// input is a flattened 28x28 image, with pixels scaled to [0, 1], and each
+// target is the digit class label, as expected by the "cross_entropy" loss.
+func LoadMNIST(imagesPath, labelsPath string) (*VectorDataset, error) {
+	var images [][]float64
+	if err := readFile(imagesPath, func(r io.Reader) (err error) {
+		images, err = ReadIDXImages(r)
+		return
+	}); err != nil {
+		return nil, err
+	}
+	var labels []float64
+	if err := readFile(labelsPath, func(r io.Reader) (err error) {
+		labels, err = ReadIDXLabels(r)
+		return
+	}); err != nil {
+		return nil, err
+	}
+	if len(images) != len(labels) {
+		return nil, fmt.Errorf("got %d images and %d labels", len(images), len(labels))
+	}
+	ds := &VectorDataset{Inputs: images, Targets: make([][]float64, len(labels))}
+	for ii, label := range labels {
+		ds.Targets[ii] = []float64{label}
+	}
+	return ds, nil
+}
+
+// ReadIDXImages reads images in the idx3-ubyte format, and returns each image
+// flattened in row-major order, with pixels scaled to [0, 1].
+func ReadIDXImages(r io.Reader) ([][]float64, error) {
+	dims, err := readIDXHeader(r, idxImages)
+	if err != nil {
+		return nil, err
+	}
+	size := dims[1] * dims[2]
+	buf := make([]byte, size)
+	images := make([][]float64, dims[0])
+	for ii := range images {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("image %d: %w", ii, err)
+		}
+		images[ii] = make([]float64, size)
+		for jj, b := range buf {
+			images[ii][jj] = float64(b) / 255.0
+		}
+	}
+	return images, nil
+}
+
+// ReadIDXLabels reads class labels in the idx1-ubyte format.
+func ReadIDXLabels(r io.Reader) ([]float64, error) {
+	dims, err := readIDXHeader(r, idxLabels)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, dims[0])
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, fmt.Errorf("labels: %w", err)
+	}
+	labels := make([]float64, len(buf))
+	for ii, b := range buf {
+		labels[ii] = float64(b)
+	}
+	return labels, nil
+}
+
+// readIDXHeader reads the magic number and dimensions of an IDX file, checking
+// the magic number matches.
+func readIDXHeader(r io.Reader, magic uint32) ([]int, error) {
+	var got uint32
+	if err := binary.Read(r, binary.BigEndian, &got); err != nil {
+		return nil, fmt.Errorf("idx header: %w", err)
+	}
+	if got != magic {
+		return nil, fmt.Errorf("expected idx magic number %#08x; got %#08x", magic, got)
+	}
+	raw := make([]uint32, magic&0xff)
+	if err := binary.Read(r, binary.BigEndian, raw); err != nil {
+		return nil, fmt.Errorf("idx header: %w", err)
+	}
+	dims := make([]int, len(raw))
+	for ii, d := range raw {
+		dims[ii] = int(d)
+	}
+	return dims, nil
+}
+
+// readFile opens path, decompressing it if it ends in ".gz", and passes it to
+// read.
+func readFile(path string, read func(r io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = bufio.NewReader(f)
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	if err := read(r); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
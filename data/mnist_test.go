@@ -0,0 +1,60 @@
+package data
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// idxFile encodes unsigned byte data in the IDX format.
+func idxFile(magic uint32, dims []uint32, values []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, magic)
+	binary.Write(&buf, binary.BigEndian, dims)
+	buf.Write(values)
+	return buf.Bytes()
+}
+
+// Test loading MNIST-format images and labels, plain and gzipped.
+func TestLoadMNIST(t *testing.T) {
+	dir := t.TempDir()
+	images := idxFile(idxImages, []uint32{2, 2, 2}, []byte{0, 255, 51, 0, 255, 255, 0, 0})
+	labels := idxFile(idxLabels, []uint32{2}, []byte{7, 3})
+
+	imagesPath := filepath.Join(dir, "images-idx3-ubyte")
+	if err := os.WriteFile(imagesPath, images, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(labels)
+	w.Close()
+	labelsPath := filepath.Join(dir, "labels-idx1-ubyte.gz")
+	if err := os.WriteFile(labelsPath, gz.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := LoadMNIST(imagesPath, labelsPath)
+	if err != nil {
+		t.Fatalf("LoadMNIST returned an error: %v", err)
+	}
+	if ds.Len() != 2 {
+		t.Fatalf("Got %d samples; expected 2", ds.Len())
+	}
+	x, y := ds.Get(0)
+	if len(x) != 4 || x[1] != 1.0 || x[2] != 0.2 || y[0] != 7.0 {
+		t.Errorf("Incorrect sample (%v, %v)", x, y)
+	}
+
+	// Swapped files have the wrong magic number.
+	if _, err := LoadMNIST(labelsPath, imagesPath); err == nil {
+		t.Errorf("Expected an error for swapped files")
+	}
+	// Truncated images.
+	if _, err := ReadIDXImages(bytes.NewReader(images[:len(images)-1])); err == nil {
+		t.Errorf("Expected an error for truncated images")
+	}
+}
@@ -0,0 +1,121 @@
+// Package data provides datasets and preprocessing utilities for training
+// neuron networks.
+package data
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// A SequenceDataset is a collection of variable-length sequences. Each
+// sequence has an input vector and a target vector per step.
+type SequenceDataset struct {
+	Inputs  [][][]float64
+	Targets [][][]float64
+}
+
+// Len returns the number of sequences.
+func (d *SequenceDataset) Len() int {
+	return len(d.Inputs)
+}
+
+// Get returns the inputs and targets of sequence i.
+func (d *SequenceDataset) Get(i int) (x, y [][]float64) {
+	return d.Inputs[i], d.Targets[i]
+}
+
+// Lengths returns the number of steps in each sequence.
+func (d *SequenceDataset) Lengths() []int {
+	lengths := make([]int, len(d.Inputs))
+	for ii, seq := range d.Inputs {
+		lengths[ii] = len(seq)
+	}
+	return lengths
+}
+
+// Pad pads (or truncates) each sequence to length steps by appending copies of
+// a step filled with value. mask[i][t] is true if step t of sequence i is
+// real rather than padding. If length is <= 0, sequences are padded to the
+// longest one. The input sequences are not modified.
+func Pad(seqs [][][]float64, length int, value float64) (padded [][][]float64, mask [][]bool) {
+	if length <= 0 {
+		for _, seq := range seqs {
+			if len(seq) > length {
+				length = len(seq)
+			}
+		}
+	}
+
+	padded = make([][][]float64, len(seqs))
+	mask = make([][]bool, len(seqs))
+	for ii, seq := range seqs {
+		padded[ii] = make([][]float64, length)
+		mask[ii] = make([]bool, length)
+		dim := 0
+		if len(seq) > 0 {
+			dim = len(seq[0])
+		}
+		for t := 0; t < length; t++ {
+			if t < len(seq) {
+				padded[ii][t] = seq[t]
+				mask[ii][t] = true
+				continue
+			}
+			step := make([]float64, dim)
+			for jj := range step {
+				step[jj] = value
+			}
+			padded[ii][t] = step
+		}
+	}
+	return
+}
+
+// ApplyMask zeroes the gradients of masked (padding) steps in place, so that
+// padding doesn't contribute to training.
+func ApplyMask(grads [][]float64, mask []bool) {
+	for t, g := range grads {
+		if t < len(mask) && mask[t] {
+			continue
+		}
+		for jj := range g {
+			g[jj] = 0.0
+		}
+	}
+}
+
+// BucketByLength groups sequence indices into batches of at most batchSize
+// sequences with similar lengths, which minimizes the padding needed per
+// batch. If rng is not nil, sequences of equal length and the order of the
+// batches are shuffled.
+func BucketByLength(lengths []int, batchSize int, rng *rand.Rand) [][]int {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	idx := make([]int, len(lengths))
+	for ii := range idx {
+		idx[ii] = ii
+	}
+	if rng != nil {
+		rng.Shuffle(len(idx), func(i, j int) { idx[i], idx[j] = idx[j], idx[i] })
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return lengths[idx[i]] < lengths[idx[j]]
+	})
+
+	var batches [][]int
+	for start := 0; start < len(idx); start += batchSize {
+		end := start + batchSize
+		if end > len(idx) {
+			end = len(idx)
+		}
+		batches = append(batches, idx[start:end])
+	}
+	if rng != nil {
+		rng.Shuffle(len(batches), func(i, j int) {
+			batches[i], batches[j] = batches[j], batches[i]
+		})
+	}
+	return batches
+}
@@ -0,0 +1,72 @@
+package data
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// Test sequence padding and masking.
+func TestPad(t *testing.T) {
+	seqs := [][][]float64{
+		{{1.0, 1.0}},
+		{{2.0, 2.0}, {3.0, 3.0}, {4.0, 4.0}},
+	}
+	padded, mask := Pad(seqs, 0, -1.0)
+	if len(padded[0]) != 3 || len(padded[1]) != 3 {
+		t.Fatalf("Padded lengths are (%d, %d); expected (3, 3)", len(padded[0]),
+			len(padded[1]))
+	}
+	if padded[0][2][1] != -1.0 || padded[1][2][1] != 4.0 {
+		t.Errorf("Incorrect padding values")
+	}
+	if !mask[0][0] || mask[0][1] || !mask[1][2] {
+		t.Errorf("Incorrect mask %v", mask)
+	}
+
+	// Truncation.
+	padded, _ = Pad(seqs, 2, 0.0)
+	if len(padded[1]) != 2 {
+		t.Errorf("Truncated length is %d; expected 2", len(padded[1]))
+	}
+
+	grads := [][]float64{{1.0}, {1.0}, {1.0}}
+	ApplyMask(grads, mask[0])
+	if grads[0][0] != 1.0 || grads[1][0] != 0.0 || grads[2][0] != 0.0 {
+		t.Errorf("Incorrect masked grads %v", grads)
+	}
+}
+
+// Test bucketing sequences by length.
+func TestBucketByLength(t *testing.T) {
+	lengths := []int{5, 1, 4, 2, 3, 1}
+	batches := BucketByLength(lengths, 2, nil)
+	want := [][]int{{1, 5}, {3, 4}, {2, 0}}
+	if len(batches) != len(want) {
+		t.Fatalf("Got %d batches; expected %d", len(batches), len(want))
+	}
+	for ii := range want {
+		for jj := range want[ii] {
+			if batches[ii][jj] != want[ii][jj] {
+				t.Errorf("Batches are %v; expected %v", batches, want)
+			}
+		}
+	}
+
+	// Shuffled batches still cover every sequence once, grouped by length.
+	batches = BucketByLength(lengths, 2, rand.New(rand.NewSource(12)))
+	var all []int
+	for _, b := range batches {
+		if len(b) == 2 && lengths[b[0]] > lengths[b[1]]+1 {
+			t.Errorf("Batch %v has mixed lengths", b)
+		}
+		all = append(all, b...)
+	}
+	sort.Ints(all)
+	for ii, v := range all {
+		if v != ii {
+			t.Errorf("Shuffled batches %v don't cover all sequences", batches)
+			break
+		}
+	}
+}
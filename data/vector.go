@@ -0,0 +1,18 @@
+package data
+
+// A VectorDataset is a collection of samples, each with an input vector and a
+// target vector. It implements neuron.Dataset.
+type VectorDataset struct {
+	Inputs  [][]float64
+	Targets [][]float64
+}
+
+// Len returns the number of samples.
+func (d *VectorDataset) Len() int {
+	return len(d.Inputs)
+}
+
+// Get returns the input and target of sample i.
+func (d *VectorDataset) Get(i int) (x, y []float64) {
+	return d.Inputs[i], d.Targets[i]
+}
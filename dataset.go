@@ -0,0 +1,298 @@
+package neuron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// A Dataset is a collection of samples, each with an input and a target.
+// Get may be called concurrently by a DataLoader's workers.
+type Dataset interface {
+	Len() int
+	Get(i int) (x, y []float64)
+}
+
+// A Batch is a mini-batch of samples loaded by a DataLoader.
+type Batch struct {
+	// Dataset indices of the samples.
+	Indices []int
+	Inputs  [][]float64
+	Targets [][]float64
+}
+
+// Len returns the number of samples in the batch.
+func (b Batch) Len() int {
+	return len(b.Indices)
+}
+
+// A DataLoader iterates over a dataset in mini-batches. Typical use is
+//
+//	l := &DataLoader{Data: ds, BatchSize: 32, Shuffle: true, Workers: 4}
+//	for b := range l.Batches(ctx) {
+//		...
+//	}
+//
+// Batches are loaded in the background by Workers goroutines, each loading a
+// whole batch, with up to Prefetch batches buffered ahead of the consumer.
+// Batches are always delivered in order, so with the same Rand, the sample
+// order doesn't depend on the number of workers.
+type DataLoader struct {
+	Data Dataset
+	// Number of samples per batch. Defaults to 1. If the dataset size isn't a
+	// multiple of BatchSize, the last batch is smaller.
+	BatchSize int
+	// Shuffle the samples every epoch, drawing from Rand, or from the global
+	// source if Rand is nil.
+	Shuffle bool
+	Rand    *rand.Rand
+	// Number of loading goroutines. Defaults to 1.
+	Workers int
+	// Number of batches buffered ahead of the consumer. Defaults to Workers.
+	Prefetch int
+	// Augmentation applied to each sample's input as it's loaded. Optional.
+	// Each batch draws from its own random source, seeded from Rand, so
+	// augmentations are reproducible regardless of the number of workers.
+	Transform Transform
+}
+
+// NumBatches returns the number of batches per epoch.
+func (l *DataLoader) NumBatches() int {
+	size := l.batchSize()
+	return (l.Data.Len() + size - 1) / size
+}
+
+// Batches starts loading one epoch of batches, and returns a channel
+// delivering them in order. The channel is closed after the last batch, or
+// once ctx is done, in which case the workers exit without loading the
+// remaining batches. Cancel ctx when stopping early, so the workers aren't
+// left blocked.
+func (l *DataLoader) Batches(ctx context.Context) <-chan Batch {
+	size, workers := l.batchSize(), l.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	prefetch := l.Prefetch
+	if prefetch <= 0 {
+		prefetch = workers
+	}
+	order := l.order()
+
+	// Each batch gets its own result channel, queued in order, so batches
+	// loaded out of order by the workers are delivered in order.
+	type job struct {
+		indices []int
+		seed    int64
+		result  chan Batch
+	}
+	jobs := make(chan job)
+	queue := make(chan chan Batch, prefetch)
+	out := make(chan Batch)
+
+	var wg sync.WaitGroup
+	for ii := 0; ii < workers; ii++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				j.result <- l.load(j.indices, j.seed)
+			}
+		}()
+	}
+	go func() {
+		defer close(queue)
+		defer close(jobs)
+		for start := 0; start < len(order); start += size {
+			end := start + size
+			if end > len(order) {
+				end = len(order)
+			}
+			j := job{indices: order[start:end], result: make(chan Batch, 1)}
+			if l.Transform != nil {
+				j.seed = l.int63()
+			}
+			select {
+			case queue <- j.result:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		defer close(out)
+		defer wg.Wait()
+		for result := range queue {
+			var b Batch
+			select {
+			case b = <-result:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case out <- b:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// batchSize returns the batch size, with the default applied.
+func (l *DataLoader) batchSize() int {
+	if l.BatchSize <= 0 {
+		return 1
+	}
+	return l.BatchSize
+}
+
+// order returns the sample order of an epoch.
+func (l *DataLoader) order() []int {
+	n := l.Data.Len()
+	if !l.Shuffle {
+		order := make([]int, n)
+		for ii := range order {
+			order[ii] = ii
+		}
+		return order
+	}
+	if l.Rand != nil {
+		return l.Rand.Perm(n)
+	}
+	return rand.Perm(n)
+}
+
+// int63 draws from Rand, or the global source if Rand is nil.
+func (l *DataLoader) int63() int64 {
+	if l.Rand != nil {
+		return l.Rand.Int63()
+	}
+	return rand.Int63()
+}
+
+// load gets the samples of a batch, augmenting them with a random source
+// seeded by seed.
+func (l *DataLoader) load(indices []int, seed int64) Batch {
+	b := Batch{
+		Indices: indices,
+		Inputs:  make([][]float64, len(indices)),
+		Targets: make([][]float64, len(indices)),
+	}
+	var rng *rand.Rand
+	if l.Transform != nil {
+		rng = rand.New(rand.NewSource(seed))
+	}
+	for ii, idx := range indices {
+		b.Inputs[ii], b.Targets[ii] = l.Data.Get(idx)
+		if rng != nil {
+			b.Inputs[ii] = l.Transform.Apply(b.Inputs[ii], rng)
+		}
+	}
+	return b
+}
+
+// A Subset is a view of the samples of a dataset at Indices, without copying
+// them.
+type Subset struct {
+	Data    Dataset
+	Indices []int
+}
+
+// Len returns the number of samples.
+func (s *Subset) Len() int {
+	return len(s.Indices)
+}
+
+// Get returns the input and target of sample i of the subset.
+func (s *Subset) Get(i int) (x, y []float64) {
+	return s.Data.Get(s.Indices[i])
+}
+
+// SplitDataset randomly splits a dataset into subsets with the given
+// fractions of the samples, e.g. {0.8, 0.1, 0.1} for train, validation, and
+// test splits. The fractions need to sum to 1, and rounding leftovers go to
+// the last split. The split only depends on seed, so it's reproducible.
+func SplitDataset(ds Dataset, fractions []float64, seed int64) ([]*Subset, error) {
+	if len(fractions) == 0 {
+		return nil, errors.New("split needs at least one fraction")
+	}
+	sum := 0.0
+	for _, f := range fractions {
+		if f < 0.0 {
+			return nil, fmt.Errorf("split fractions need to be >= 0; got %v", fractions)
+		}
+		sum += f
+	}
+	if math.Abs(sum-1.0) > 1.0e-09 {
+		return nil, fmt.Errorf("split fractions need to sum to 1; got %v", fractions)
+	}
+	order := rand.New(rand.NewSource(seed)).Perm(ds.Len())
+	splits := make([]*Subset, len(fractions))
+	start, cum := 0, 0.0
+	for ii, f := range fractions {
+		cum += f
+		end := int(math.Round(cum * float64(len(order))))
+		if ii == len(fractions)-1 {
+			end = len(order)
+		}
+		splits[ii] = &Subset{Data: ds, Indices: order[start:end]}
+		start = end
+	}
+	return splits, nil
+}
+
+// KFold iterates over the folds of k-fold cross-validation, with each sample
+// in the validation split of exactly one fold. Typical use is
+//
+//	kf, err := NewKFold(ds, 5, seed)
+//	for kf.Next() {
+//		train, val := kf.Split()
+//		...
+//	}
+type KFold struct {
+	ds    Dataset
+	order []int
+	k     int
+	fold  int
+}
+
+// NewKFold creates a k-fold iterator over a randomly shuffled dataset, see
+// SplitDataset.
+func NewKFold(ds Dataset, k int, seed int64) (*KFold, error) {
+	if k < 2 || k > ds.Len() {
+		return nil, fmt.Errorf("k-fold needs 2 <= k <= %d; got %d", ds.Len(), k)
+	}
+	order := rand.New(rand.NewSource(seed)).Perm(ds.Len())
+	return &KFold{ds: ds, order: order, k: k, fold: -1}, nil
+}
+
+// Next advances to the next fold, and returns false after the last.
+func (f *KFold) Next() bool {
+	if f.fold < f.k {
+		f.fold++
+	}
+	return f.fold < f.k
+}
+
+// Fold returns the index of the current fold.
+func (f *KFold) Fold() int {
+	return f.fold
+}
+
+// Split returns the training and validation splits of the current fold.
+func (f *KFold) Split() (train, val *Subset) {
+	n := len(f.order)
+	start, end := f.fold*n/f.k, (f.fold+1)*n/f.k
+	trainIdx := make([]int, 0, n-(end-start))
+	trainIdx = append(append(trainIdx, f.order[:start]...), f.order[end:]...)
+	return &Subset{Data: f.ds, Indices: trainIdx},
+		&Subset{Data: f.ds, Indices: f.order[start:end]}
+}
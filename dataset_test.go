@@ -0,0 +1,129 @@
+package neuron
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Test batching, shuffling, and prefetching with a data loader.
+func TestDataLoader(t *testing.T) {
+	ds := linearData(10)
+	epoch := func(l *DataLoader) []Batch {
+		var batches []Batch
+		for b := range l.Batches(context.Background()) {
+			batches = append(batches, b)
+		}
+		return batches
+	}
+
+	l := &DataLoader{Data: ds, BatchSize: 4}
+	batches := epoch(l)
+	if len(batches) != 3 || l.NumBatches() != 3 {
+		t.Fatalf("Got %d batches (NumBatches %d); expected 3", len(batches),
+			l.NumBatches())
+	}
+	if batches[2].Len() != 2 || !reflect.DeepEqual(batches[1].Indices, []int{4, 5, 6, 7}) {
+		t.Errorf("Incorrect batches %v", batches)
+	}
+	x, y := ds.Get(5)
+	if !reflect.DeepEqual(batches[1].Inputs[1], x) || !reflect.DeepEqual(batches[1].Targets[1], y) {
+		t.Errorf("Incorrect sample in batch %+v", batches[1])
+	}
+
+	// The order only depends on the random source, not the number of workers.
+	l = &DataLoader{Data: ds, BatchSize: 3, Shuffle: true, Rand: rand.New(rand.NewSource(1))}
+	want := epoch(l)
+	l = &DataLoader{Data: ds, BatchSize: 3, Shuffle: true, Rand: rand.New(rand.NewSource(1)),
+		Workers: 4, Prefetch: 2}
+	if got := epoch(l); !reflect.DeepEqual(got, want) {
+		t.Errorf("Batches with 4 workers are %v; expected %v", got, want)
+	}
+	seen := make(map[int]bool)
+	for _, b := range want {
+		for _, ii := range b.Indices {
+			seen[ii] = true
+		}
+	}
+	if len(seen) != ds.Len() {
+		t.Errorf("Epoch covered %d samples; expected %d", len(seen), ds.Len())
+	}
+
+	// Cancelling stops the workers.
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	l = &DataLoader{Data: ds, BatchSize: 1, Workers: 3}
+	<-l.Batches(ctx)
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := runtime.NumGoroutine(); got > before {
+		t.Errorf("%d goroutines left after cancel; expected %d", got, before)
+	}
+}
+
+// Test random splits and k-fold cross-validation.
+func TestSplitDataset(t *testing.T) {
+	ds := linearData(10)
+	splits, err := SplitDataset(ds, []float64{0.6, 0.2, 0.2}, 1)
+	if err != nil {
+		t.Fatalf("SplitDataset returned an error: %v", err)
+	}
+	seen := make(map[int]bool)
+	for ii, want := range []int{6, 2, 2} {
+		if splits[ii].Len() != want {
+			t.Errorf("Split %d has %d samples; expected %d", ii, splits[ii].Len(), want)
+		}
+		for jj, idx := range splits[ii].Indices {
+			seen[idx] = true
+			x, _ := splits[ii].Get(jj)
+			if xw, _ := ds.Get(idx); !reflect.DeepEqual(x, xw) {
+				t.Errorf("Split %d sample %d is %v; expected %v", ii, jj, x, xw)
+			}
+		}
+	}
+	if len(seen) != 10 {
+		t.Errorf("Splits cover %d samples; expected 10", len(seen))
+	}
+	again, _ := SplitDataset(ds, []float64{0.6, 0.2, 0.2}, 1)
+	if !reflect.DeepEqual(again[0].Indices, splits[0].Indices) {
+		t.Errorf("Splits with the same seed differ")
+	}
+	if _, err := SplitDataset(ds, []float64{0.6, 0.6}, 1); err == nil {
+		t.Errorf("Expected an error for fractions not summing to 1")
+	}
+
+	kf, err := NewKFold(ds, 3, 1)
+	if err != nil {
+		t.Fatalf("NewKFold returned an error: %v", err)
+	}
+	counts := make(map[int]int)
+	folds := 0
+	for kf.Next() {
+		train, val := kf.Split()
+		if train.Len()+val.Len() != 10 || val.Len() < 3 || val.Len() > 4 {
+			t.Errorf("Fold %d has %d training and %d validation samples", kf.Fold(),
+				train.Len(), val.Len())
+		}
+		for _, idx := range val.Indices {
+			counts[idx]++
+		}
+		folds++
+	}
+	if folds != 3 || kf.Next() {
+		t.Errorf("Iterated over %d folds; expected 3", folds)
+	}
+	for ii := 0; ii < 10; ii++ {
+		if counts[ii] != 1 {
+			t.Errorf("Sample %d validated %d times; expected once", ii, counts[ii])
+		}
+	}
+	if _, err := NewKFold(ds, 11, 1); err == nil {
+		t.Errorf("Expected an error for k > dataset size")
+	}
+}
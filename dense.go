@@ -0,0 +1,202 @@
+package neuron
+
+import (
+	"fmt"
+)
+
+// A Dense runs passes through a network as dense matrix-vector products in
+// the calling goroutine, instead of exchanging signals between units. It
+// shares the Params of the network's units, so it computes the same outputs
+// and accumulates the same gradients as the concurrent engines, and serves as
+// a simple reference implementation for them.
+//
+// A Dense must only be used while the network isn't running, i.e. before
+// Start or after Stop.
+type Dense struct {
+	n *Net
+	// Layers with connections into each layer, and the parameters of each
+	// layer, see NewDense.
+	up    [][]int
+	w     [][][]*Param
+	bias  [][]*Param
+	input []*Param
+	// Dense copies of the weights, and the inputs and outputs of each layer
+	// for the backward pass.
+	wd    [][][]float64
+	xs    [][]float64
+	hs    [][]float64
+	probs []float64
+}
+
+// NewDense creates a dense engine for n. Reparameterized weights, e.g. with
+// WithWeightNorm, gated units, e.g. GRUKind, attention units, and embeddings
+// aren't supported.
+func NewDense(n *Net) (*Dense, error) {
+	d := &Dense{
+		n:     n,
+		up:    make([][]int, len(n.Layers)),
+		w:     make([][][]*Param, len(n.Layers)),
+		bias:  make([][]*Param, len(n.Layers)),
+		input: make([]*Param, len(n.Layers[0])),
+		wd:    make([][][]float64, len(n.Layers)),
+		xs:    make([][]float64, len(n.Layers)),
+		hs:    make([][]float64, len(n.Layers)),
+	}
+	for ii, l := range n.Layers {
+		d.bias[ii] = make([]*Param, len(l))
+		// The inputs of a layer are the outputs of its upstream layers,
+		// concatenated in order.
+		var prev []*Unit
+		if ii > 0 {
+			d.up[ii] = n.upstream(ii)
+			for _, jj := range d.up[ii] {
+				prev = append(prev, n.Layers[jj]...)
+			}
+			d.w[ii] = make([][]*Param, len(l))
+			d.wd[ii] = make([][]float64, len(l))
+		}
+		for jj, u := range l {
+			if u.W.reparam() {
+				return nil, fmt.Errorf("unit %s has reparameterized weights", u.ID)
+			}
+			if u.cell != nil {
+				return nil, fmt.Errorf("unit %s is a gated unit", u.ID)
+			}
+			if u.embed != nil {
+				return nil, fmt.Errorf("unit %s is an embedding unit", u.ID)
+			}
+			if u.attn != nil {
+				return nil, fmt.Errorf("unit %s is an attention unit", u.ID)
+			}
+			d.bias[ii][jj] = u.W.Params[biasID]
+			if ii == 0 {
+				d.input[jj] = u.W.Params[inputID]
+				continue
+			}
+			d.w[ii][jj] = make([]*Param, len(prev))
+			d.wd[ii][jj] = make([]float64, len(prev))
+			for kk, u2 := range prev {
+				d.w[ii][jj][kk] = u.W.Params[u2.ID]
+			}
+		}
+	}
+	return d, nil
+}
+
+// paramValue returns the value of p, treating missing and masked parameters as
+// zero.
+func paramValue(p *Param) float64 {
+	if p == nil || p.masked {
+		return 0.0
+	}
+	return p.Data
+}
+
+// addParamGrad accumulates a gradient for p, if it exists.
+func addParamGrad(p *Param, grad float64) {
+	if p != nil {
+		p.AddGrad(grad)
+	}
+}
+
+// Forward feeds a data sample through the network and returns the output,
+// like Net.Forward.
+func (d *Dense) Forward(data []float64) ([]float64, error) {
+	if err := d.n.checkInput(data); err != nil {
+		return nil, err
+	}
+	var h []float64
+	for ii, l := range d.n.Layers {
+		x := data
+		if len(d.up[ii]) == 1 {
+			x = d.hs[d.up[ii][0]]
+		} else if ii > 0 {
+			x = nil
+			for _, jj := range d.up[ii] {
+				x = append(x, d.hs[jj]...)
+			}
+		}
+		d.xs[ii] = x
+		h = make([]float64, len(l))
+		for jj, u := range l {
+			var act float64
+			if ii == 0 {
+				act = paramValue(d.input[jj]) * x[jj]
+			} else {
+				wd := d.wd[ii][jj]
+				for kk, p := range d.w[ii][jj] {
+					wd[kk] = paramValue(p)
+					act += wd[kk] * x[kk]
+				}
+			}
+			act += paramValue(d.bias[ii][jj])
+			h[jj] = u.activ.Forward(act)
+		}
+		d.hs[ii] = h
+	}
+	if d.n.softmax {
+		d.probs = softmaxSpans(h, d.n.softmaxSpans)
+		h = make([]float64, len(d.probs))
+		copy(h, d.probs)
+	}
+	return h, nil
+}
+
+// Backward back-propagates a loss gradient through the last Forward,
+// accumulating the parameter gradients like Net.Backward. Weights are only
+// updated by Step.
+func (d *Dense) Backward(grad []float64) error {
+	if err := d.n.checkGrad(grad); err != nil {
+		return err
+	}
+	g := grad
+	if d.n.softmax {
+		g = softmaxJVP(grad, d.probs, d.n.softmaxSpans)
+	}
+
+	// Gradients of the outputs of each layer, summed over the layers they
+	// feed into.
+	gs := make([][]float64, len(d.n.Layers))
+	for ii, l := range d.n.Layers {
+		gs[ii] = make([]float64, len(l))
+	}
+	copy(gs[len(gs)-1], g)
+	for ii := len(d.n.Layers) - 1; ii >= 0; ii-- {
+		l, x, g := d.n.Layers[ii], d.xs[ii], gs[ii]
+		var gin []float64
+		if ii > 0 {
+			gin = make([]float64, len(x))
+		}
+		for jj, u := range l {
+			delta := u.activ.Backward(g[jj])
+			addParamGrad(d.bias[ii][jj], delta)
+			if ii == 0 {
+				continue
+			}
+			wd := d.wd[ii][jj]
+			for kk, p := range d.w[ii][jj] {
+				addParamGrad(p, delta*x[kk])
+				gin[kk] += wd[kk] * delta
+			}
+		}
+		for _, kk := range d.up[ii] {
+			for jj := range gs[kk] {
+				gs[kk][jj] += gin[jj]
+			}
+			gin = gin[len(gs[kk]):]
+		}
+	}
+	return nil
+}
+
+// Step updates the weights of every unit with the accumulated gradients.
+func (d *Dense) Step() {
+	for _, l := range d.n.Layers {
+		for _, u := range l {
+			u.step()
+		}
+	}
+	d.n.forEachParam(func(u *Unit, id string, p *Param) {
+		p.pullTied()
+	})
+}
@@ -0,0 +1,94 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test that the concurrent engines compute the same outputs and gradients as
+// the dense engine for random networks.
+func TestDenseEquivalence(t *testing.T) {
+	rand.Seed(11)
+	for trial := 0; trial < 10; trial++ {
+		arch := make([]int, 3+rand.Intn(3))
+		for ii := range arch {
+			arch[ii] = 1 + rand.Intn(6)
+		}
+		var opts []Option
+		if trial%2 == 1 {
+			opts = append(opts, WithLayerEngine())
+		}
+		if trial%3 == 2 {
+			opts = append(opts, WithSoftmaxOutput(),
+				WithMixedLayer(1, HiddenKind, TanhKind, SigmoidKind))
+		}
+		n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), opts...)
+		// Random weights, so that no unit is dead.
+		theta := n.Data()
+		for _, ud := range theta {
+			for id := range ud {
+				if isConn(id) {
+					ud[id] = rand.NormFloat64()
+				}
+			}
+		}
+		n.SetData(theta)
+
+		data := make([]float64, arch[0])
+		for ii := range data {
+			data[ii] = rand.NormFloat64()
+		}
+		grad := make([]float64, arch[len(arch)-1])
+		for ii := range grad {
+			grad[ii] = rand.NormFloat64()
+		}
+
+		n.Start(true, 0)
+		want := n.MustForward(data)
+		n.MustBackward(grad)
+		n.Stop()
+		wantGrads := n.Grads()
+		n.zeroGrad()
+
+		d, err := NewDense(n)
+		if err != nil {
+			t.Fatalf("NewDense failed: %v", err)
+		}
+		got, err := d.Forward(data)
+		if err != nil {
+			t.Fatalf("Dense Forward failed: %v", err)
+		}
+		if err := d.Backward(grad); err != nil {
+			t.Fatalf("Dense Backward failed: %v", err)
+		}
+		for ii := range want {
+			if !almostEqualTol(got[ii], want[ii], 1.0e-06) {
+				t.Errorf("Arch %v: dense output %d is %.6f; expected %.6f", arch, ii,
+					got[ii], want[ii])
+			}
+		}
+		gotGrads := n.Grads()
+		for uid, ug := range wantGrads {
+			for id, g := range ug {
+				if !almostEqualTol(gotGrads[uid][id], g, 1.0e-06) {
+					t.Errorf("Arch %v: dense grad %s/%s is %.6f; expected %.6f", arch,
+						uid, id, gotGrads[uid][id], g)
+				}
+			}
+		}
+	}
+}
+
+func TestDenseErrors(t *testing.T) {
+	n := MustNewMLP([]int{2, 3, 1}, NewSGD(0.1, 0.0, 0.0), WithWeightNorm())
+	if _, err := NewDense(n); err == nil {
+		t.Errorf("NewDense did not return an error")
+	}
+	d, _ := NewDense(MustNewMLP([]int{2, 3, 1}, NewSGD(0.1, 0.0, 0.0)))
+	if _, err := d.Forward([]float64{1.0}); err == nil {
+		t.Errorf("Forward did not return an error")
+	}
+	if err := d.Backward([]float64{1.0, 2.0}); err == nil {
+		t.Errorf("Backward did not return an error")
+	}
+}
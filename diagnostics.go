@@ -0,0 +1,172 @@
+package neuron
+
+import (
+	"math"
+	"sort"
+)
+
+// SymmetryStats summarizes the pairwise correlations between the incoming
+// weight vectors of the units in a layer.
+type SymmetryStats struct {
+	// Mean absolute correlation over all pairs of units.
+	Mean float64
+	// Maximum absolute correlation over all pairs of units.
+	Max float64
+}
+
+// LayerSymmetry computes the pairwise weight-vector correlations for each
+// layer. Values near 1 mean the units in a layer have (nearly) identical
+// incoming weights and are likely to learn the same feature, a sign of
+// pathological initialization. The input layer, and any layer with fewer than
+// two units, reports zeros.
+//
+// LayerSymmetry reads the unit weights directly, so it should only be called
+// while the network is idle, e.g. after Backward returns.
+func (n *Net) LayerSymmetry() []SymmetryStats {
+	stats := make([]SymmetryStats, len(n.Layers))
+	for ii := 1; ii < len(n.Layers); ii++ {
+		stats[ii] = layerSymmetry(n.Layers[ii])
+	}
+	return stats
+}
+
+// layerSymmetry computes the symmetry stats for a single layer.
+func layerSymmetry(l []*Unit) SymmetryStats {
+	var stats SymmetryStats
+	if len(l) < 2 {
+		return stats
+	}
+
+	// Incoming weight vectors, using a fixed ordering of the inputs.
+	keys := weightKeys(l[0].W)
+	vecs := make([][]float64, len(l))
+	for ii, u := range l {
+		vecs[ii] = make([]float64, len(keys))
+		for jj, k := range keys {
+			if p, ok := u.W.Params[k]; ok {
+				vecs[ii][jj] = p.Data
+			}
+		}
+	}
+
+	npairs := 0
+	for ii := 0; ii < len(vecs); ii++ {
+		for jj := ii + 1; jj < len(vecs); jj++ {
+			r, ok := correlation(vecs[ii], vecs[jj])
+			if !ok {
+				continue
+			}
+			r = math.Abs(r)
+			stats.Mean += r
+			stats.Max = math.Max(stats.Max, r)
+			npairs++
+		}
+	}
+	if npairs > 0 {
+		stats.Mean /= float64(npairs)
+	}
+	return stats
+}
+
+// weightKeys returns the sorted IDs of the connection weights in w, excluding
+// bias, gain, and input weights.
+func weightKeys(w *Weight) []string {
+	keys := make([]string, 0, len(w.Params))
+	for k := range w.Params {
+		if isConn(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// correlation computes the Pearson correlation between a and b. ok is false if
+// either vector has zero variance.
+func correlation(a, b []float64) (r float64, ok bool) {
+	if len(a) < 2 {
+		return 0.0, false
+	}
+	var meanA, meanB float64
+	for ii := range a {
+		meanA += a[ii]
+		meanB += b[ii]
+	}
+	meanA /= float64(len(a))
+	meanB /= float64(len(b))
+
+	var cov, varA, varB float64
+	for ii := range a {
+		da, db := a[ii]-meanA, b[ii]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return 0.0, false
+	}
+	return cov / math.Sqrt(varA*varB), true
+}
+
+// logSymmetry reports the per-layer weight symmetry.
+func (n *Net) logSymmetry() {
+	for ii, s := range n.LayerSymmetry() {
+		if ii == 0 {
+			continue
+		}
+		logf(1, "Layer %d symmetry: mean=%.3f max=%.3f\n", ii, s.Mean, s.Max)
+	}
+}
+
+// GradNorm returns the global L2 norm of the gradients accumulated since the
+// last weight update, e.g. to log it or to detect divergence. Units clear
+// their gradients as soon as they update their weights, so after a Backward
+// that triggers an update the norm is zero. Like LayerSymmetry, it should only
+// be called while the network is idle.
+func (n *Net) GradNorm() float64 {
+	return n.gradNorm()
+}
+
+// LayerGradNorms returns the L2 norm of the accumulated gradients of each
+// layer, see GradNorm.
+func (n *Net) LayerGradNorms() []float64 {
+	return n.layerNorms(func(p *Param) float64 { return p.grad })
+}
+
+// WeightNorm returns the global L2 norm of the trainable parameters.
+func (n *Net) WeightNorm() float64 {
+	return globalNorm(n.LayerWeightNorms())
+}
+
+// LayerWeightNorms returns the L2 norm of the trainable parameters of each
+// layer.
+func (n *Net) LayerWeightNorms() []float64 {
+	return n.layerNorms(func(p *Param) float64 { return p.Data })
+}
+
+// layerNorms computes the L2 norm of value over the trainable, unmasked
+// parameters of each layer.
+func (n *Net) layerNorms(value func(p *Param) float64) []float64 {
+	norms := make([]float64, len(n.Layers))
+	for ii, l := range n.Layers {
+		for _, u := range l {
+			for _, p := range u.W.Params {
+				if p.RequiresGrad && !p.masked {
+					v := value(p)
+					norms[ii] += v * v
+				}
+			}
+		}
+		norms[ii] = math.Sqrt(norms[ii])
+	}
+	return norms
+}
+
+// globalNorm combines per-layer norms into a global norm.
+func globalNorm(norms []float64) float64 {
+	sum := 0.0
+	for _, v := range norms {
+		sum += v * v
+	}
+	return math.Sqrt(sum)
+}
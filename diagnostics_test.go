@@ -0,0 +1,95 @@
+package neuron
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Test pairwise weight correlations.
+func TestCorrelation(t *testing.T) {
+	r, ok := correlation([]float64{1, 2, 3}, []float64{2, 4, 6})
+	if !ok || !almostEqual(r, 1.0) {
+		t.Errorf("Correlation is %.3f; expected 1.0", r)
+	}
+	r, ok = correlation([]float64{1, 2, 3}, []float64{3, 2, 1})
+	if !ok || !almostEqual(r, -1.0) {
+		t.Errorf("Correlation is %.3f; expected -1.0", r)
+	}
+	if _, ok = correlation([]float64{1, 1, 1}, []float64{1, 2, 3}); ok {
+		t.Errorf("Expected zero variance correlation to be undefined")
+	}
+}
+
+// Test layer weight symmetry diagnostics.
+func TestLayerSymmetry(t *testing.T) {
+	rand.Seed(12)
+
+	arch := []int{8, 4, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	n := MustNewMLP(arch, opt)
+
+	stats := n.LayerSymmetry()
+	if len(stats) != len(arch) {
+		t.Fatalf("Got %d layer stats; expected %d", len(stats), len(arch))
+	}
+	if stats[1].Max > 0.99 {
+		t.Errorf("Random init has max symmetry %.3f", stats[1].Max)
+	}
+	if stats[2].Max != 0.0 {
+		t.Errorf("Single unit layer has max symmetry %.3f", stats[2].Max)
+	}
+
+	// Copy the weights of the first hidden unit to all the others.
+	src := n.Layers[1][0].W.Params
+	for _, u := range n.Layers[1][1:] {
+		for k, p := range u.W.Params {
+			p.Data = 2.0 * src[k].Data
+		}
+	}
+	stats = n.LayerSymmetry()
+	if !almostEqual(stats[1].Mean, 1.0) || !almostEqual(stats[1].Max, 1.0) {
+		t.Errorf("Symmetric layer has symmetry %+v; expected 1.0", stats[1])
+	}
+}
+
+// Test gradient and weight norms.
+func TestNorms(t *testing.T) {
+	rand.Seed(13)
+	n := MustNewMLP([]int{2, 3, 1}, NewSGD(0.1, 0.0, 0.0))
+	n.Start(true, 0)
+	defer n.Stop()
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{1.0})
+
+	for _, c := range []struct {
+		name   string
+		global float64
+		layers []float64
+		vec    ParamVector
+	}{
+		{"Grad", n.GradNorm(), n.LayerGradNorms(), n.Grads()},
+		{"Weight", n.WeightNorm(), n.LayerWeightNorms(), n.Data()},
+	} {
+		want := make([]float64, len(n.Layers))
+		for ii, l := range n.Layers {
+			for _, u := range l {
+				for _, v := range c.vec[u.ID] {
+					want[ii] += v * v
+				}
+			}
+		}
+		total := 0.0
+		for ii := range want {
+			total += want[ii]
+			want[ii] = math.Sqrt(want[ii])
+			if !almostEqualTol(c.layers[ii], want[ii], 1.0e-12) {
+				t.Errorf("%s norm of layer %d is %v; expected %v", c.name, ii,
+					c.layers[ii], want[ii])
+			}
+		}
+		if c.global == 0 || !almostEqual(c.global, math.Sqrt(total)) {
+			t.Errorf("%s norm is %v; expected %v", c.name, c.global, math.Sqrt(total))
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package neuron
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// ToDOT writes the network's connection graph in Graphviz DOT format, e.g. to
+// render with
+//
+//	dot -Tsvg net.dot > net.svg
+//
+// Each layer is a cluster of units labeled with their ID and activation, and
+// each connection is an edge labeled with its weight. Pruned connections are
+// dashed. It's meant for visualizing small networks and debugging their
+// wiring; large networks make for unreadable graphs. ToDOT reads the weights
+// directly, so it should only be called while the network is idle.
+func (n *Net) ToDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph net {")
+	fmt.Fprintln(bw, "\trankdir=LR;")
+	fmt.Fprintln(bw, "\tnode [shape=circle];")
+	for ii, l := range n.Layers {
+		fmt.Fprintf(bw, "\tsubgraph cluster_%d {\n", ii)
+		fmt.Fprintf(bw, "\t\tlabel=\"layer %d\";\n", ii)
+		for _, u := range l {
+			fmt.Fprintf(bw, "\t\t\"%s\" [label=\"%s\\n%s\"];\n", u.ID, u.ID,
+				activationName(u.activ))
+		}
+		fmt.Fprintln(bw, "\t}")
+	}
+	for _, l := range n.Layers {
+		for _, u := range l {
+			for _, k := range weightKeys(u.W) {
+				p := u.W.Params[k]
+				style := ""
+				if p.masked {
+					style = ", style=dashed"
+				}
+				fmt.Fprintf(bw, "\t\"%s\" -> \"%s\" [label=\"%.3g\"%s];\n", k, u.ID,
+					p.Data, style)
+			}
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// activationName returns the type name of an activation, e.g. "Relu".
+func activationName(a Activation) string {
+	t := reflect.TypeOf(a)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
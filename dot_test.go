@@ -0,0 +1,34 @@
+package neuron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestToDOT(t *testing.T) {
+	n := MustNewMLP([]int{2, 2, 1}, NewSGD(0.1, 0.0, 0.0))
+	n.Layers[1][0].W.Params["000_000001"].Data = 0.5
+	n.SetMask(Mask{"002_000000": {"001_000001": false}})
+
+	var buf bytes.Buffer
+	if err := n.ToDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	for _, want := range []string{
+		"digraph net {",
+		"subgraph cluster_1 {",
+		`"001_000000" [label="001_000000\nRelu"];`,
+		`"000_000001" -> "001_000000" [label="0.5"];`,
+		`"001_000001" -> "002_000000" [label=`,
+		"style=dashed];",
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output is missing %q:\n%s", want, dot)
+		}
+	}
+	if c := strings.Count(dot, "->"); c != 6 {
+		t.Errorf("DOT output has %d edges; expected 6", c)
+	}
+}
@@ -0,0 +1,172 @@
+package neuron
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// embedPrefix prefixes the IDs of embedding parameters. The parameter of
+// category c has ID embedPrefix + c.
+const embedPrefix = "_EMBED_"
+
+// isEmbed checks whether a parameter ID refers to an embedding parameter.
+func isEmbed(id string) bool {
+	return strings.HasPrefix(id, embedPrefix)
+}
+
+// An embedding is a span of input units embedding a categorical input, see
+// WithEmbedding.
+type embedding struct {
+	first, dim, categories int
+}
+
+// WithEmbedding makes the dim input units starting at unit first an
+// embedding of a single categorical input, e.g. for mixed categorical and
+// numeric inputs. The input is a category ID in [0, categories), and each of
+// the units outputs its learned value for the category, i.e. the units map
+// the category to a learned dim-dimensional vector, which feeds into the first
+// hidden layer like any other input. Embedding values are drawn from a
+// standard normal distribution.
+//
+// The network then takes one input value for each embedding, in place of its
+// units, so e.g.
+//
+//	NewMLP([]int{5, 8, 1}, opt, WithEmbedding(0, 4, 10))
+//
+// takes inputs {category, x}, and input heads, see WithInputHeads, split
+// these. It can be passed more than once, for spans that don't overlap.
+// Embeddings don't support Dense.
+func WithEmbedding(first, dim, categories int) Option {
+	return func(c *netConfig) {
+		c.embeds = append(c.embeds, embedding{first, dim, categories})
+	}
+}
+
+// checkEmbeddings checks the embedding settings, and sorts them by first
+// unit.
+func checkEmbeddings(c *netConfig, arch []int) error {
+	sort.Slice(c.embeds, func(i, j int) bool {
+		return c.embeds[i].first < c.embeds[j].first
+	})
+	end := 0
+	for _, e := range c.embeds {
+		if e.dim < 1 || e.categories < 1 {
+			return fmt.Errorf("embeddings need >= 1 unit and category; got %d and %d",
+				e.dim, e.categories)
+		}
+		if e.first < end || e.first+e.dim > arch[0] {
+			return fmt.Errorf("embedding of units %d to %d overlaps or is out of range",
+				e.first, e.first+e.dim-1)
+		}
+		end = e.first + e.dim
+	}
+	return nil
+}
+
+// An embedTable holds the embedding parameters of an input unit, one for
+// each category.
+type embedTable struct {
+	params []*Param
+}
+
+// embedInputs sets up the embedding units, drawing their embeddings from the
+// network's random source.
+func (n *Net) embedInputs(embeds []embedding) {
+	n.embeds = embeds
+	for _, e := range embeds {
+		for _, u := range n.Layers[0][e.first : e.first+e.dim] {
+			t := &embedTable{params: make([]*Param, e.categories)}
+			for ii := range t.params {
+				id := embedPrefix + strconv.Itoa(ii)
+				u.W.init(id, n.rand().NormFloat64(), true)
+				t.params[ii] = u.W.Params[id]
+			}
+			u.embed = t
+		}
+	}
+}
+
+// forward returns the embedding of category v. Only the category's parameter
+// records an input, of 1, so only it accumulates a gradient. The inputs of
+// all categories are recorded, since sequences and batches restore them for
+// each step, see Unit.rewind.
+func (t *embedTable) forward(v float64) float64 {
+	for _, p := range t.params {
+		p.value = 0.0
+	}
+	p := t.params[int(v)]
+	p.value = 1.0
+	return p.Data
+}
+
+// weighInput weights the network input v of an input unit, or looks up its
+// embedding.
+func (u *Unit) weighInput(v float64) float64 {
+	if u.embed != nil {
+		return u.embed.forward(v)
+	}
+	return u.W.forward(inputID, v)
+}
+
+// embeddedDim returns the input dimension of units input units with the given
+// embeddings, with one value for each embedding.
+func embeddedDim(embeds []embedding, units int) int {
+	for _, e := range embeds {
+		units -= e.dim - 1
+	}
+	return units
+}
+
+// inputDim returns the dimension of the network's input.
+func (n *Net) inputDim() int {
+	return embeddedDim(n.embeds, n.Arch[0])
+}
+
+// inputIndex returns the index in the network's input of the input of each
+// input unit. The units of an embedding share the index of their category.
+func (n *Net) inputIndex() []int {
+	idx := make([]int, n.Arch[0])
+	next, ee := 0, 0
+	for jj := range idx {
+		idx[jj] = next
+		if ee < len(n.embeds) && jj >= n.embeds[ee].first {
+			if e := n.embeds[ee]; jj < e.first+e.dim-1 {
+				continue
+			}
+			ee++
+		}
+		next++
+	}
+	return idx
+}
+
+// expandInput returns the input of each input unit, repeating the category
+// of each embedding for each of its units.
+func (n *Net) expandInput(data []float64) []float64 {
+	if len(n.embeds) == 0 {
+		return data
+	}
+	x := make([]float64, n.Arch[0])
+	for jj, ii := range n.inputIndex() {
+		x[jj] = data[ii]
+	}
+	return x
+}
+
+// checkCategories checks that the input of each embedding is a valid
+// category.
+func (n *Net) checkCategories(data []float64) error {
+	offset := 0
+	for _, e := range n.embeds {
+		ii := e.first - offset
+		if v := data[ii]; v != math.Trunc(v) || v < 0 || int(v) >= e.categories {
+			return fmt.Errorf("embedding input %d needs a category in [0, %d); got %v",
+				ii, e.categories, v)
+		}
+		offset += e.dim - 1
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/clane9/go-neuron/data"
+)
+
+// Test that embedding units output the embedding of their category, and
+// that only its parameters get gradients, on both engines.
+func TestEmbedding(t *testing.T) {
+	loss, _ := GetLoss("mse")
+	for _, layers := range []bool{false, true} {
+		rand.Seed(53)
+		opts := []Option{WithEmbedding(1, 3, 4)}
+		if layers {
+			opts = append(opts, WithLayerEngine())
+		}
+		n := MustNewMLP([]int{5, 4, 1}, NewSGD(0.1, 0.0, 0.0), opts...)
+		if dim := n.inputDim(); dim != 3 {
+			t.Fatalf("Input dim is %d; expected 3", dim)
+		}
+
+		// The same network taking the embedding as input.
+		plain := MustNewMLP([]int{5, 4, 1}, NewSGD(0.1, 0.0, 0.0))
+		theta := n.Data()
+		for _, ud := range theta {
+			for id := range ud {
+				if isEmbed(id) {
+					delete(ud, id)
+				}
+			}
+		}
+		plain.SetData(theta)
+		x := []float64{0.5, 2.0, -1.0}
+		xp := []float64{0.5, 0.0, 0.0, 0.0, -1.0}
+		for jj := 0; jj < 3; jj++ {
+			xp[jj+1] = n.Layers[0][jj+1].W.Params[embedPrefix+"2"].Data
+		}
+
+		n.Start(true, 0)
+		plain.Start(false, 0)
+		out := n.MustForward(x)
+		want := plain.MustForward(xp)
+		if !almostEqual(out[0], want[0]) {
+			t.Errorf("(layers=%v) Output is %.6f; expected %.6f", layers, out[0], want[0])
+		}
+		loss.Forward(out, []float64{1.0})
+		n.MustBackward(loss.Backward())
+		grads := n.Grads()
+		for jj := 1; jj < 4; jj++ {
+			for id, g := range grads[unitID(0, jj)] {
+				if used := id == embedPrefix+"2"; isEmbed(id) && used != (g != 0.0) {
+					t.Errorf("(layers=%v) Grad of %s/%s is %v", layers, unitID(0, jj), id, g)
+				}
+			}
+		}
+		if g, err := n.InputGradients(); err != nil || len(g) != 3 || g[1] != 0.0 {
+			t.Errorf("(layers=%v) Input gradients are %v, %v; expected 3 with a zero category",
+				layers, g, err)
+		}
+
+		relErr, _, err := GradCheck(n, x, []float64{1.0}, loss, 1.0e-05)
+		if err != nil {
+			t.Fatalf("GradCheck returned an error: %v", err)
+		}
+		for uid, ue := range relErr {
+			for id, e := range ue {
+				// Tiny gradients are dominated by rounding errors.
+				if grads[uid][id] != 0.0 && e > 1.0e-04 {
+					t.Errorf("(layers=%v) Relative error of %s/%s is %g; expected < 1e-4",
+						layers, uid, id, e)
+				}
+			}
+		}
+		n.Stop()
+		plain.Stop()
+	}
+}
+
+// Test learning the embeddings of a categorical input alongside a numeric
+// one.
+func TestEmbeddingTraining(t *testing.T) {
+	rand.Seed(59)
+	offsets := []float64{-1.0, 0.5, 1.0, -0.5}
+	ds := &data.VectorDataset{}
+	for ii := 0; ii < 80; ii++ {
+		c, x := rand.Intn(len(offsets)), rand.NormFloat64()
+		ds.Inputs = append(ds.Inputs, []float64{x, float64(c)})
+		ds.Targets = append(ds.Targets, []float64{0.5*x + offsets[c]})
+	}
+	n := MustNewMLP([]int{3, 8, 1}, NewSGD(0.01, 0.5, 0.0), WithEmbedding(1, 2, 4),
+		WithInitializer(HeNormal{}))
+	defer n.Stop()
+	loss, _ := GetLoss("mse")
+	tr := &Trainer{Net: n, Loss: loss, Data: ds, BatchSize: 8, Shuffle: true}
+	h, err := tr.Fit(30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, last := h.Epochs[0].Loss, h.Epochs[29].Loss; last > 0.1*first {
+		t.Errorf("Loss didn't decrease: %.4f -> %.4f", first, last)
+	}
+}
+
+// Test the embedding option and input errors.
+func TestEmbeddingErrors(t *testing.T) {
+	opt := NewSGD(0.1, 0.0, 0.0)
+	badOpts := [][]Option{
+		{WithEmbedding(0, 0, 4)},
+		{WithEmbedding(0, 2, 0)},
+		{WithEmbedding(2, 2, 4)},
+		{WithEmbedding(0, 2, 4), WithEmbedding(1, 2, 4)},
+		{WithEmbedding(0, 2, 4), WithInputHeads(Head{Name: "a", Size: 3})},
+	}
+	for ii, opts := range badOpts {
+		if _, err := NewMLP([]int{3, 2, 1}, opt, opts...); err == nil {
+			t.Errorf("Bad embedding options %d didn't fail", ii)
+		}
+	}
+
+	n := MustNewMLP([]int{3, 2, 1}, opt, WithEmbedding(2, 1, 4), WithEmbedding(0, 2, 3))
+	n.Start(false, 0)
+	defer n.Stop()
+	// Wrong dim, and categories out of range or not integers.
+	bad := [][]float64{{2.0, 1.0, 1.0}, {3.0, 1.0}, {2.0, 4.0}, {0.5, 1.0}, {-1.0, 1.0}}
+	for _, x := range bad {
+		if _, err := n.Forward(x); err == nil {
+			t.Errorf("Input %v didn't fail", x)
+		}
+	}
+	if _, err := n.Forward([]float64{2.0, 1.0}); err != nil {
+		t.Errorf("Valid categories failed: %v", err)
+	}
+	if _, err := NewDense(n); err == nil {
+		t.Error("Dense engine with embeddings didn't fail")
+	}
+}
@@ -0,0 +1,170 @@
+package neuron
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Aggregations of the member predictions of an Ensemble.
+const (
+	// Mean of the member outputs.
+	MeanAggregation = "mean"
+	// Mean of the member class probabilities, i.e. the softmax of their
+	// outputs. For members with WithSoftmaxOutput, it's the same as
+	// MeanAggregation.
+	SoftmaxAggregation = "softmax"
+	// Fraction of members voting for each class, i.e. with the largest
+	// output. For members with a single output, the vote is the sign of the
+	// output, and the prediction is the mean vote in [-1, 1].
+	VoteAggregation = "vote"
+)
+
+// An Ensemble combines the predictions of several independently initialized
+// networks with the same inputs and outputs. Typical use is
+//
+//	e, err := NewEnsemble(5, arch, opt)
+//	hists, err := e.Fit(func(ii int, n *Net) *Trainer {
+//		return &Trainer{Net: n, Loss: loss, Data: ds, BatchSize: 32, Shuffle: true}
+//	}, 10)
+//	probs, err := e.Predict(x)
+type Ensemble struct {
+	Nets []*Net
+	// MeanAggregation, SoftmaxAggregation, or VoteAggregation. Defaults to
+	// MeanAggregation.
+	Aggregation string
+	// Train the members at the same time, see Fit.
+	Parallel bool
+}
+
+// NewEnsemble creates an ensemble of size networks constructed with
+// NewMLP(arch, opt, opts...). Each network draws its own initial weights, from
+// the source of WithRand if given. If opt is Scheduled, each network gets its
+// own clone, so that the schedules advance independently. Scheduled
+// optimizers passed to WithLayerOptimizer are still shared.
+func NewEnsemble(size int, arch []int, opt Optimizer, opts ...Option) (*Ensemble, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("ensembles need >= 1 network; got %d", size)
+	}
+	e := &Ensemble{Nets: make([]*Net, size)}
+	for ii := range e.Nets {
+		memberOpt := opt
+		if s, ok := opt.(*Scheduled); ok {
+			memberOpt = s.fresh()
+		}
+		n, err := NewMLP(arch, memberOpt, opts...)
+		if err != nil {
+			return nil, err
+		}
+		e.Nets[ii] = n
+	}
+	return e, nil
+}
+
+// Fit trains each network for epochs passes with the Trainer returned by
+// newTrainer for member ii, and returns their training histories. Trainers
+// shouldn't share Losses, Callbacks, Metrics, or random sources, since with
+// Parallel the members are trained concurrently. Like Trainer.Fit, the networks
+// are left running in training mode. If training a member fails, the histories
+// so far are returned along with the first error.
+func (e *Ensemble) Fit(newTrainer func(ii int, n *Net) *Trainer,
+	epochs int) ([]*History, error) {
+	hists := make([]*History, len(e.Nets))
+	errs := make([]error, len(e.Nets))
+	fit := func(ii int) {
+		hists[ii], errs[ii] = newTrainer(ii, e.Nets[ii]).Fit(epochs)
+	}
+	if e.Parallel {
+		var wg sync.WaitGroup
+		for ii := range e.Nets {
+			wg.Add(1)
+			go func(ii int) {
+				defer wg.Done()
+				fit(ii)
+			}(ii)
+		}
+		wg.Wait()
+	} else {
+		for ii := range e.Nets {
+			if fit(ii); errs[ii] != nil {
+				break
+			}
+		}
+	}
+	for ii, err := range errs {
+		if err != nil {
+			return hists, fmt.Errorf("ensemble member %d: %w", ii, err)
+		}
+	}
+	return hists, nil
+}
+
+// Start starts every network, see Net.Start.
+func (e *Ensemble) Start(train bool, updateFreq int) {
+	for _, n := range e.Nets {
+		n.Start(train, updateFreq)
+	}
+}
+
+// Stop stops every network.
+func (e *Ensemble) Stop() {
+	for _, n := range e.Nets {
+		n.Stop()
+	}
+}
+
+// Predict runs an inference-only forward pass through every network, see
+// Net.Predict, and aggregates their outputs. The networks run concurrently,
+// and must have been started.
+func (e *Ensemble) Predict(data []float64) ([]float64, error) {
+	if len(e.Nets) == 0 {
+		return nil, errors.New("empty ensemble")
+	}
+	outputs := make([][]float64, len(e.Nets))
+	errs := make([]error, len(e.Nets))
+	var wg sync.WaitGroup
+	for ii, n := range e.Nets {
+		wg.Add(1)
+		go func(ii int, n *Net) {
+			defer wg.Done()
+			outputs[ii], errs[ii] = n.Predict(data)
+		}(ii, n)
+	}
+	wg.Wait()
+	for ii, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("ensemble member %d: %w", ii, err)
+		}
+	}
+	return aggregate(outputs, e.Aggregation)
+}
+
+// aggregate combines the outputs of the members of an ensemble.
+func aggregate(outputs [][]float64, aggregation string) ([]float64, error) {
+	dim := len(outputs[0])
+	agg := make([]float64, dim)
+	for _, out := range outputs {
+		switch aggregation {
+		case MeanAggregation, "":
+		case SoftmaxAggregation:
+			out = Softmax(out, 1.0)
+		case VoteAggregation:
+			vote := predictClass(out)
+			out = make([]float64, dim)
+			if dim == 1 {
+				out[0] = vote
+			} else {
+				out[int(vote)] = 1.0
+			}
+		default:
+			return nil, fmt.Errorf("unknown ensemble aggregation %q", aggregation)
+		}
+		for ii, v := range out {
+			agg[ii] += v
+		}
+	}
+	for ii := range agg {
+		agg[ii] /= float64(len(outputs))
+	}
+	return agg, nil
+}
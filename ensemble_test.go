@@ -0,0 +1,143 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test training an ensemble, sequentially and in parallel.
+func TestEnsemble(t *testing.T) {
+	rand.Seed(31)
+	ds := linearData(50)
+	for _, parallel := range []bool{false, true} {
+		e, err := NewEnsemble(3, []int{2, 8, 1}, NewSGD(0.01, 0.5, 0.0),
+			WithInitializer(HeNormal{}), WithRand(rand.New(rand.NewSource(7))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w0 := e.Nets[0].Layers[1][0].W.Params[unitID(0, 0)].Data
+		w1 := e.Nets[1].Layers[1][0].W.Params[unitID(0, 0)].Data
+		if w0 == w1 {
+			t.Errorf("Ensemble members have the same initial weight %v", w0)
+		}
+
+		e.Parallel = parallel
+		hists, err := e.Fit(func(ii int, n *Net) *Trainer {
+			loss, _ := GetLoss("mse")
+			return &Trainer{Net: n, Loss: loss, Data: ds, BatchSize: 8, Shuffle: true,
+				Rand: rand.New(rand.NewSource(int64(ii)))}
+		}, 10)
+		if err != nil {
+			t.Fatalf("(parallel=%v) Fit returned an error: %v", parallel, err)
+		}
+		for ii, h := range hists {
+			if first, last := h.Epochs[0].Loss, h.Epochs[9].Loss; last > 0.1*first {
+				t.Errorf("(parallel=%v) Member %d loss didn't decrease: %.4f -> %.4f",
+					parallel, ii, first, last)
+			}
+		}
+
+		x := []float64{0.3, -0.6}
+		mean := 0.0
+		for _, n := range e.Nets {
+			out, _ := n.Predict(x)
+			mean += out[0] / 3
+		}
+		out, err := e.Predict(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !almostEqual(out[0], mean) {
+			t.Errorf("(parallel=%v) Ensemble predicted %.6f; expected %.6f", parallel,
+				out[0], mean)
+		}
+		e.Stop()
+	}
+
+	if _, err := NewEnsemble(0, []int{2, 2, 1}, NewSGD(0.1, 0.0, 0.0)); err == nil {
+		t.Error("Empty ensemble didn't fail")
+	}
+	e := &Ensemble{Nets: []*Net{MustNewMLP([]int{2, 2, 1}, NewSGD(0.1, 0.0, 0.0))}}
+	if _, err := e.Predict([]float64{1.0}); err == nil {
+		t.Error("Predict on a stopped ensemble with bad input didn't fail")
+	}
+}
+
+// Test that the members of an ensemble follow their own learning rate
+// schedules.
+func TestEnsembleScheduled(t *testing.T) {
+	opt := NewScheduled(NewSGD(1.0, 0.0, 0.0), StepLR{StepSize: 1, Gamma: 0.5}, 1)
+	e, err := NewEnsemble(3, []int{2, 3, 1}, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+	n := e.Nets[0]
+	n.Start(true, 1)
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{0.0})
+	if lr := n.GetLR(); !almostEqual(lr, 0.5) {
+		t.Errorf("Trained member learning rate is %.4f; expected 0.5", lr)
+	}
+	for ii, n := range e.Nets[1:] {
+		if lr := n.GetLR(); lr != 1.0 {
+			t.Errorf("Member %d learning rate is %.4f; expected 1", ii+1, lr)
+		}
+	}
+	if u := opt.Updates(); u != 0 {
+		t.Errorf("Ensemble advanced the prototype optimizer %d updates", u)
+	}
+}
+
+// Test the aggregations of member outputs.
+func TestEnsembleAggregation(t *testing.T) {
+	outputs := [][]float64{
+		{2.0, 0.0, 1.0},
+		{0.0, 1.0, 0.5},
+		{3.0, 0.0, 0.0},
+	}
+	cases := []struct {
+		aggregation string
+		want        []float64
+	}{
+		{MeanAggregation, []float64{5.0 / 3, 1.0 / 3, 0.5}},
+		{"", []float64{5.0 / 3, 1.0 / 3, 0.5}},
+		{VoteAggregation, []float64{2.0 / 3, 1.0 / 3, 0.0}},
+	}
+	for _, c := range cases {
+		got, err := aggregate(outputs, c.aggregation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for ii := range got {
+			if !almostEqualTol(got[ii], c.want[ii], 1e-9) {
+				t.Errorf("%q aggregation is %v; expected %v", c.aggregation, got, c.want)
+				break
+			}
+		}
+	}
+
+	got, _ := aggregate(outputs, SoftmaxAggregation)
+	sum := 0.0
+	for ii, p := range got {
+		want := 0.0
+		for _, out := range outputs {
+			want += Softmax(out, 1.0)[ii] / 3
+		}
+		if !almostEqual(p, want) {
+			t.Errorf("Softmax aggregation %d is %.6f; expected %.6f", ii, p, want)
+		}
+		sum += p
+	}
+	if !almostEqual(sum, 1.0) {
+		t.Errorf("Softmax aggregation sums to %.6f", sum)
+	}
+
+	got, _ = aggregate([][]float64{{0.5}, {-2.0}, {1.0}}, VoteAggregation)
+	if !almostEqual(got[0], 1.0/3) {
+		t.Errorf("Single output vote is %.4f; expected %.4f", got[0], 1.0/3)
+	}
+	if _, err := aggregate(outputs, "median"); err == nil {
+		t.Error("Unknown aggregation didn't fail")
+	}
+}
@@ -0,0 +1,100 @@
+// Train an autoencoder to compress synthetic data lying near a
+// low-dimensional subspace, with decoder weights optionally tied to the
+// encoder.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+
+	"github.com/clane9/go-neuron"
+	"github.com/clane9/go-neuron/data"
+)
+
+var tied = flag.Bool("tied", true, "tie the decoder weights to the encoder")
+
+func main() {
+	flag.Parse()
+	rand.Seed(2020)
+
+	const (
+		samples = 2000
+		inDim   = 16
+		rank    = 3
+		noise   = 0.05
+	)
+	neuron.Verbosity = 0
+
+	// Random linear map from the latent space to the inputs.
+	basis := make([][]float64, inDim)
+	for ii := range basis {
+		basis[ii] = make([]float64, rank)
+		for jj := range basis[ii] {
+			basis[ii][jj] = rand.NormFloat64() / 2.0
+		}
+	}
+	train, test := &data.VectorDataset{}, &data.VectorDataset{}
+	for ii := 0; ii < samples; ii++ {
+		x := lowRankData(basis, noise)
+		ds := train
+		if ii%5 == 0 {
+			ds = test
+		}
+		// The target is the input itself.
+		ds.Inputs = append(ds.Inputs, x)
+		ds.Targets = append(ds.Targets, x)
+	}
+
+	// Encoder {16, 8, 3}, with a linear code layer, mirrored by the decoder.
+	n, err := neuron.NewAutoencoder([]int{inDim, 8, rank},
+		neuron.NewSGD(2.0e-03, 0.9, 0.0), *tied,
+		neuron.WithInitializer(neuron.XavierNormal{}),
+		neuron.WithActivations([]neuron.Activation{new(neuron.Tanh),
+			new(neuron.Identity), new(neuron.Tanh), nil}))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Architecture %v, tied=%v\n", n.Arch, *tied)
+	loss, _ := neuron.GetLoss("mse")
+
+	trainer := &neuron.Trainer{
+		Net:        n,
+		Loss:       loss,
+		Data:       train,
+		BatchSize:  8,
+		Shuffle:    true,
+		Validation: test,
+		Callbacks: []neuron.Callback{neuron.CallbackFuncs{
+			EpochEnd: func(tr *neuron.Trainer, epoch int, loss float64) error {
+				fmt.Printf("epoch=%02d\ttrain=%.5e\ttest=%.5e\n", epoch, loss,
+					tr.LastValidation.Loss)
+				return nil
+			},
+		}},
+	}
+	if _, err := trainer.Fit(20); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	n.Stop()
+}
+
+// lowRankData generates a sample in the span of basis, plus noise.
+func lowRankData(basis [][]float64, noise float64) []float64 {
+	z := make([]float64, len(basis[0]))
+	for ii := range z {
+		z[ii] = rand.NormFloat64()
+	}
+	x := make([]float64, len(basis))
+	for ii, row := range basis {
+		for jj, v := range row {
+			x[ii] += v * z[jj]
+		}
+		x[ii] += noise * rand.NormFloat64()
+	}
+	return x
+}
@@ -3,14 +3,24 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"math/rand"
+	"os"
 	"time"
 
 	"github.com/clane9/go-neuron"
+	"github.com/clane9/go-neuron/data"
+)
+
+var (
+	optName  = flag.String("opt", "sgd", "optimizer: sgd, rmsprop, or adagrad")
+	lossName = flag.String("loss", "margin", "loss: margin or focal")
+	posPrior = flag.Float64("pos", 0.5, "prior probability of the positive class")
 )
 
 func main() {
+	flag.Parse()
 	rand.Seed(2020)
 
 	const (
@@ -22,42 +32,94 @@ func main() {
 
 	// MLP with two 128-dim hidden layers.
 	arch := []int{inDim, 128, 128, outDim}
-	opt := neuron.NewSGD(1.0e-01, 0.9, 1.0e-05)
-	n := neuron.NewMLP(arch, opt)
-	// Start the network running for training. Gradients accumulate for 32 inputs
-	// before updating. (This is equivalent to mini-batch gradient descent.)
-	n.Start(true, 32)
+	var opt neuron.Optimizer
+	switch *optName {
+	case "sgd":
+		opt = neuron.NewSGD(1.0e-01, 0.9, 1.0e-05)
+	case "rmsprop":
+		opt = neuron.NewRMSProp(1.0e-02, 0.99, 1.0e-08, 1.0e-05)
+	case "adagrad":
+		opt = neuron.NewAdagrad(5.0e-02, 1.0e-10, 1.0e-05)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown optimizer %q\n", *optName)
+		os.Exit(2)
+	}
+	var loss neuron.Loss
+	switch *lossName {
+	case "margin":
+		loss, _ = neuron.GetLoss("margin")
+	case "focal":
+		// Weight the classes by their inverse prior.
+		loss = &focalLoss{alpha: 1.0 - *posPrior}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown loss %q\n", *lossName)
+		os.Exit(2)
+	}
+	n, err := neuron.NewMLP(arch, opt)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-	var (
-		data   []float64
-		score  []float64
-		target int
-		loss   float64
-		grad   float64
-	)
+	train := &data.VectorDataset{}
+	for ii := 0; ii < steps; ii++ {
+		x, target := gaussianData(inDim, *posPrior)
+		train.Inputs = append(train.Inputs, x)
+		train.Targets = append(train.Targets, []float64{float64(target)})
+	}
+
+	// Gradients accumulate for 32 inputs before updating. (This is equivalent to
+	// mini-batch gradient descent.)
+	trainer := &neuron.Trainer{
+		Net:       n,
+		Loss:      loss,
+		Data:      train,
+		BatchSize: 32,
+		Callbacks: []neuron.Callback{neuron.CallbackFuncs{
+			BatchEnd: func(tr *neuron.Trainer, batch int, loss float64) error {
+				t := time.Now()
+				fmt.Printf("(%s)\tstep=%06d\tloss=%.5e\n",
+					t.Format("15:04:05.999"), n.Steps(), loss)
+				return nil
+			},
+		}},
+	}
 
 	// Training loop
 	start := time.Now()
-	for ii := 1; ii <= steps; ii++ {
-		data, target = gaussianData(inDim)
-		score = n.Forward(data)
-		loss, grad = neuron.MarginLoss(score[0], target)
-		n.Backward([]float64{grad})
-
-		if ii%10 == 0 {
-			t := time.Now()
-			fmt.Printf("(%s)\tstep=%06d\tloss=%.5e\tgradL=%.5e\n",
-				t.Format("15:04:05.999"), ii, loss, grad)
-		}
+	if _, err := trainer.Fit(1); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 	elapsed := time.Since(start)
 	fmt.Printf("Done %d steps in %.2fs (%.2f steps/s)\n",
 		steps, elapsed.Seconds(), float64(steps)/elapsed.Seconds())
 }
 
-// Generate a random data sample drawn from a two class Gaussian mixture.
-func gaussianData(n int) (data []float64, target int) {
-	target = 2*rand.Intn(2) - 1
+// focalLoss is a neuron.Loss computing the focal loss with gamma = 2 and a
+// custom alpha.
+type focalLoss struct {
+	alpha float64
+	grad  []float64
+}
+
+func (l *focalLoss) Forward(scores []float64, target []float64) (float64, error) {
+	loss, grad, err := neuron.FocalLoss(scores[0], int(target[0]), 2.0, l.alpha)
+	l.grad = []float64{grad}
+	return loss, err
+}
+
+func (l *focalLoss) Backward() []float64 {
+	return l.grad
+}
+
+// Generate a random data sample drawn from a two class Gaussian mixture, where
+// the positive class has prior probability pos.
+func gaussianData(n int, pos float64) (data []float64, target int) {
+	target = -1
+	if rand.Float64() < pos {
+		target = 1
+	}
 	data = make([]float64, n)
 	for ii := 0; ii < n; ii++ {
 		data[ii] = rand.NormFloat64() + 2.0*float64(target)
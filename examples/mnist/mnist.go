@@ -0,0 +1,107 @@
+// Train an MLP to classify MNIST digits.
+//
+// Download the four idx-ubyte files (gzipped is fine) from
+// http://yann.lecun.com/exdb/mnist/ into a directory, and run
+//
+//	go run ./examples/mnist -dir path/to/mnist
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/clane9/go-neuron"
+	"github.com/clane9/go-neuron/data"
+)
+
+var (
+	dir    = flag.String("dir", "mnist", "directory of the MNIST idx-ubyte files")
+	epochs = flag.Int("epochs", 3, "number of training epochs")
+	layer  = flag.Bool("layer", true,
+		"use the layer engine, much faster than a goroutine per unit at this size")
+)
+
+func main() {
+	flag.Parse()
+	rand.Seed(2020)
+	neuron.Verbosity = 0
+
+	train, err := loadSplit("train")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	test, err := loadSplit("t10k")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Loaded %d training and %d test images\n", train.Len(), test.Len())
+
+	// MLP with two 128-dim hidden layers, and a raw score output per digit.
+	arch := []int{784, 128, 128, 10}
+	opts := []neuron.Option{neuron.WithInitializer(neuron.HeNormal{})}
+	if *layer {
+		opts = append(opts, neuron.WithLayerEngine())
+	}
+	n, err := neuron.NewMLP(arch, neuron.NewSGD(1.0e-02, 0.9, 1.0e-05), opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	loss, _ := neuron.GetLoss("cross_entropy")
+
+	trainer := &neuron.Trainer{
+		Net:        n,
+		Loss:       loss,
+		Data:       train,
+		BatchSize:  32,
+		Shuffle:    true,
+		Workers:    2,
+		Validation: test,
+		Accuracy:   true,
+		Callbacks: []neuron.Callback{neuron.CallbackFuncs{
+			BatchEnd: func(tr *neuron.Trainer, batch int, loss float64) error {
+				if batch%200 == 0 {
+					t := time.Now()
+					fmt.Printf("(%s)\tstep=%06d\tloss=%.5e\n",
+						t.Format("15:04:05.999"), n.Steps(), loss)
+				}
+				return nil
+			},
+			Validation: func(tr *neuron.Trainer, e neuron.Evaluation) error {
+				fmt.Printf("Test loss=%.5e accuracy=%.4f\n", e.Loss, e.Accuracy)
+				return nil
+			},
+		}},
+	}
+
+	start := time.Now()
+	if _, err := trainer.Fit(*epochs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	n.Stop()
+	elapsed := time.Since(start)
+	steps := *epochs * train.Len()
+	fmt.Printf("Done %d steps in %.2fs (%.2f steps/s)\n",
+		steps, elapsed.Seconds(), float64(steps)/elapsed.Seconds())
+}
+
+// loadSplit loads the "train" or "t10k" split, from either plain or gzipped
+// files.
+func loadSplit(split string) (*data.VectorDataset, error) {
+	path := func(kind string) string {
+		p := filepath.Join(*dir, split+"-"+kind)
+		if _, err := os.Stat(p); err != nil {
+			return p + ".gz"
+		}
+		return p
+	}
+	return data.LoadMNIST(path("images-idx3-ubyte"), path("labels-idx1-ubyte"))
+}
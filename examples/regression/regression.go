@@ -0,0 +1,107 @@
+// Train an MLP with several outputs on a synthetic vector-valued regression
+// task.
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/clane9/go-neuron"
+	"github.com/clane9/go-neuron/data"
+)
+
+func main() {
+	rand.Seed(2020)
+
+	const (
+		steps     = 2000
+		evalSteps = 200
+		inDim     = 8
+		outDim    = 3
+		noise     = 0.1
+	)
+	neuron.Verbosity = 0
+
+	// Random linear map generating the targets.
+	truth := make([][]float64, outDim)
+	for ii := range truth {
+		truth[ii] = make([]float64, inDim)
+		for jj := range truth[ii] {
+			truth[ii][jj] = rand.NormFloat64() / 2.0
+		}
+	}
+
+	// MLP with one 32-dim hidden layer.
+	arch := []int{inDim, 32, outDim}
+	opt := neuron.NewSGD(2.0e-03, 0.9, 1.0e-05)
+	n, err := neuron.NewMLP(arch, opt)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	train := &data.VectorDataset{}
+	for ii := 0; ii < steps; ii++ {
+		x, y := regressionData(truth, noise)
+		train.Inputs = append(train.Inputs, x)
+		train.Targets = append(train.Targets, y)
+	}
+	loss, _ := neuron.GetLoss("mse")
+
+	// Gradients accumulate for 8 inputs before updating.
+	trainer := &neuron.Trainer{
+		Net:       n,
+		Loss:      loss,
+		Data:      train,
+		BatchSize: 8,
+		Callbacks: []neuron.Callback{neuron.CallbackFuncs{
+			BatchEnd: func(tr *neuron.Trainer, batch int, loss float64) error {
+				if step := n.Steps(); step%200 == 0 {
+					t := time.Now()
+					fmt.Printf("(%s)\tstep=%06d\tloss=%.5e\n",
+						t.Format("15:04:05.999"), step, loss)
+				}
+				return nil
+			},
+		}},
+	}
+
+	// Training loop
+	start := time.Now()
+	if _, err := trainer.Fit(1); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("Done %d steps in %.2fs (%.2f steps/s)\n",
+		steps, elapsed.Seconds(), float64(steps)/elapsed.Seconds())
+
+	// Evaluate on held-out samples, switching the running network to
+	// evaluation mode so that no backward pass is needed.
+	n.Eval()
+	metrics := neuron.NewRegressionMetrics(outDim)
+	for ii := 0; ii < evalSteps; ii++ {
+		x, y := regressionData(truth, noise)
+		metrics.Update(n.MustForward(x), y)
+	}
+	fmt.Printf("Eval MAE=%.3f RMSE=%.3f\n", metrics.MAE(), metrics.RMSE())
+}
+
+// Generate a random data sample with targets given by a noisy linear map.
+func regressionData(truth [][]float64, noise float64) (data, target []float64) {
+	data = make([]float64, len(truth[0]))
+	for ii := range data {
+		data[ii] = rand.NormFloat64()
+	}
+	target = make([]float64, len(truth))
+	for ii, w := range truth {
+		for jj, x := range data {
+			target[ii] += w[jj] * x
+		}
+		target[ii] += noise * rand.NormFloat64()
+	}
+	return
+}
@@ -0,0 +1,58 @@
+package neuron
+
+import (
+	"fmt"
+)
+
+// Freeze stops the unit's parameters from being trained, e.g. to fine-tune
+// only the output layer of a pretrained network. Frozen units still
+// back-propagate gradients to the units below, but don't accumulate gradients
+// for their own parameters, and their optimizers skip them. Freeze should only
+// be called while the network is idle.
+func (u *Unit) Freeze() {
+	u.setFrozen(true)
+}
+
+// Unfreeze resumes training the unit's parameters after Freeze.
+func (u *Unit) Unfreeze() {
+	u.setFrozen(false)
+}
+
+// Frozen checks whether the unit is frozen.
+func (u *Unit) Frozen() bool {
+	for _, p := range u.W.Params {
+		if p.RequiresGrad {
+			return p.frozen
+		}
+	}
+	return false
+}
+
+func (u *Unit) setFrozen(frozen bool) {
+	for _, p := range u.W.Params {
+		p.frozen = frozen
+		if frozen {
+			p.grad = 0.0
+		}
+	}
+}
+
+// Freeze freezes every unit in a layer, see Unit.Freeze.
+func (n *Net) Freeze(layer int) error {
+	return n.setFrozen(layer, true)
+}
+
+// Unfreeze unfreezes every unit in a layer.
+func (n *Net) Unfreeze(layer int) error {
+	return n.setFrozen(layer, false)
+}
+
+func (n *Net) setFrozen(layer int, frozen bool) error {
+	if layer < 0 || layer >= len(n.Layers) {
+		return fmt.Errorf("layer %d out of range", layer)
+	}
+	for _, u := range n.Layers[layer] {
+		u.setFrozen(frozen)
+	}
+	return nil
+}
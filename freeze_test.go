@@ -0,0 +1,62 @@
+package neuron
+
+import (
+	"testing"
+)
+
+// Test that frozen layers keep their weights while the others train.
+func TestFreeze(t *testing.T) {
+	arch := []int{2, 4, 3, 2}
+	data := []float64{1.0, -1.0}
+	for _, opts := range [][]Option{nil, {WithLayerEngine()}, {WithWeightNorm()}} {
+		n := MustNewMLP(arch, NewSGD(0.5, 0.9, 0.0), opts...)
+		if err := n.Freeze(2); err != nil {
+			t.Fatalf("Freeze failed: %v", err)
+		}
+		if !n.Layers[2][0].Frozen() || n.Layers[1][0].Frozen() {
+			t.Errorf("Frozen units are wrong")
+		}
+		before := n.Data()
+		n.Start(true, 1)
+		for ii := 0; ii < 3; ii++ {
+			n.MustForward(data)
+			n.MustBackward([]float64{1.0, -1.0})
+		}
+		n.Stop()
+		after := n.Data()
+		trained := false
+		for uid, ud := range before {
+			for id, v := range ud {
+				changed := !almostEqual(after[uid][id], v)
+				if changed && uid[:3] == "002" {
+					t.Errorf("Frozen param %s/%s changed from %.6f to %.6f", uid, id, v,
+						after[uid][id])
+				}
+				if changed && uid[:3] == "001" {
+					trained = true
+				}
+			}
+		}
+		if !trained {
+			t.Errorf("Layer below frozen layer didn't train")
+		}
+
+		// Training resumes after Unfreeze.
+		if err := n.Unfreeze(2); err != nil {
+			t.Fatalf("Unfreeze failed: %v", err)
+		}
+		n.Start(true, 1)
+		n.MustForward(data)
+		n.MustBackward([]float64{1.0, -1.0})
+		n.Stop()
+		p := unitID(1, 0)
+		if v := n.Data()[unitID(2, 0)][p]; almostEqual(v, after[unitID(2, 0)][p]) {
+			t.Errorf("Unfrozen param unchanged after training")
+		}
+	}
+
+	n := MustNewMLP(arch, NewSGD(0.5, 0.0, 0.0))
+	if err := n.Freeze(4); err == nil {
+		t.Errorf("Freeze did not return an error")
+	}
+}
@@ -0,0 +1,233 @@
+package neuron
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// gatePrefix prefixes the IDs of gate parameters. The weight of gate g on
+// the input from unit k has ID gatePrefix + g + "_" + k, and similarly for
+// the gate's bias and recurrent weight, e.g. "_GATE_z__BIAS".
+const gatePrefix = "_GATE_"
+
+// gateID returns the ID of the parameter of gate g matching id.
+func gateID(g, id string) string {
+	return gatePrefix + g + "_" + id
+}
+
+// isGate checks whether a parameter ID refers to a gate parameter.
+func isGate(id string) bool {
+	return strings.HasPrefix(id, gatePrefix)
+}
+
+// A gatedCell holds the gates and state of a gated recurrent unit, see
+// GRUKind and LSTMKind. Each gate has its own weights on the unit's inputs,
+// bias, and recurrent weight, stored as Params of the unit's Weight, so the
+// unit's optimizer updates them along with the candidate's weights.
+//
+// Like RecurrentKind, a gated unit only feeds back its own previous output,
+// i.e. the recurrent weight matrices of the layer are diagonal.
+type gatedCell struct {
+	lstm  bool
+	gates []string
+	// Parameters of each gate: weights by input unit ID, biases, and
+	// recurrent weights. They're also in the unit's Weight. Inputs are
+	// summed in the order of ids, so that passes are reproducible.
+	ids   []string
+	w     map[string][]*Param
+	bias  []*Param
+	recur []*Param
+	// State of the current step, see cellStep, and the LSTM cell state
+	// carried over to the next step.
+	cellStep
+	c float64
+	// Gradient of the LSTM cell state carried back to the previous step.
+	carryC float64
+}
+
+// A cellStep is the state of a gated unit at one forward step, recorded for
+// the matching backward step of a sequence.
+type cellStep struct {
+	// Previous output and cell state.
+	hprev, cprev float64
+	// Gate activations, candidate activation, and new cell state.
+	g    []float64
+	cand float64
+	cnew float64
+}
+
+// save returns a copy of the state of the current step.
+func (s cellStep) save() cellStep {
+	s.g = append([]float64(nil), s.g...)
+	return s
+}
+
+// newGRUUnit creates a gated recurrent unit with update gate z and reset
+// gate r:
+//
+//	h = (1 - z) * hprev + z * tanh(w.x + b + u * (r * hprev))
+func newGRUUnit(id string, opt Optimizer) *Unit {
+	return newGatedUnit(id, opt, false, "z", "r")
+}
+
+// newLSTMUnit creates a long short-term memory unit with input gate i,
+// forget gate f, and output gate o:
+//
+//	c = f * cprev + i * tanh(w.x + b + u * hprev)
+//	h = o * tanh(c)
+//
+// The forget gate's bias starts at 1, so the unit remembers by default.
+func newLSTMUnit(id string, opt Optimizer) *Unit {
+	u := newGatedUnit(id, opt, true, "i", "f", "o")
+	u.cell.bias[1].Data = 1.0
+	return u
+}
+
+// newGatedUnit creates a gated unit with the given gates. The candidate has
+// the unit's regular weights, bias, and recurrent weight.
+func newGatedUnit(id string, opt Optimizer, lstm bool, gates ...string) *Unit {
+	u := NewUnit(id, new(Identity), opt)
+	u.SetBias(0.0)
+	u.SetRecurrent(0.5)
+	c := &gatedCell{
+		lstm:  lstm,
+		gates: gates,
+		w:     make(map[string][]*Param),
+		bias:  make([]*Param, len(gates)),
+		recur: make([]*Param, len(gates)),
+	}
+	c.g = make([]float64, len(gates))
+	for gi, g := range gates {
+		c.bias[gi] = c.param(u, gateID(g, biasID), 0.0)
+		c.recur[gi] = c.param(u, gateID(g, recurID), 0.0)
+	}
+	u.cell = c
+	return u
+}
+
+// param adds a gate parameter to the unit's Weight.
+func (c *gatedCell) param(u *Unit, id string, value float64) *Param {
+	u.W.init(id, value, true)
+	return u.W.Params[id]
+}
+
+// connect adds the gate weights on the input from unit id, drawn like the
+// connection weight, see Unit.connect.
+func (c *gatedCell) connect(u *Unit, id string, rng *rand.Rand) {
+	w := make([]*Param, len(c.gates))
+	for gi, g := range c.gates {
+		w[gi] = c.param(u, gateID(g, id), randUnif(rng, -0.01, 0.01))
+	}
+	c.ids, c.w[id] = append(c.ids, id), w
+}
+
+// gatedLayer checks whether any unit of layer l is a gated unit.
+func gatedLayer(l []*Unit) bool {
+	for _, u := range l {
+		if u.cell != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// forward computes the gates and the unit's output given the weighted input
+// act of the candidate, including its bias. The connection weights must have
+// recorded their inputs.
+func (c *gatedCell) forward(u *Unit, act float64) float64 {
+	h := u.hprev
+	c.hprev, c.cprev, c.carryC = h, c.c, 0.0
+	for gi := range c.gates {
+		c.g[gi] = c.bias[gi].Data + c.recur[gi].Data*h
+	}
+	for _, k := range c.ids {
+		p := u.W.Params[k]
+		if p.masked {
+			continue
+		}
+		for gi, gp := range c.w[k] {
+			c.g[gi] += gp.Data * p.value
+		}
+	}
+	for gi, a := range c.g {
+		c.g[gi] = 1.0 / (1.0 + math.Exp(-a))
+	}
+
+	var out float64
+	if c.lstm {
+		i, f, o := c.g[0], c.g[1], c.g[2]
+		act += u.W.forward(recurID, h)
+		u.pre = act
+		c.cand = math.Tanh(act)
+		c.cnew = f*c.cprev + i*c.cand
+		out = o * math.Tanh(c.cnew)
+	} else {
+		z, r := c.g[0], c.g[1]
+		act += u.W.forward(recurID, r*h)
+		u.pre = act
+		c.cand = math.Tanh(act)
+		out = (1.0-z)*h + z*c.cand
+	}
+	if u.seq {
+		c.c = c.cnew
+	}
+	return out
+}
+
+// backprop back-propagates the gradient dh of the unit's output through the
+// gates and candidate, and passes the gradient for each input k to emit. In
+// sequence mode, the gradients of the previous output and cell state are
+// carried back to the previous step.
+func (c *gatedCell) backprop(u *Unit, dh float64, emit func(k string, gradi float64)) {
+	h, uc := c.hprev, paramValue(u.W.Params[recurID])
+	dg := make([]float64, len(c.gates))
+	var da, dhprev, dcprev float64
+	if c.lstm {
+		i, f, o := c.g[0], c.g[1], c.g[2]
+		tc := math.Tanh(c.cnew)
+		dc := dh*o*(1.0-tc*tc) + c.carryC
+		da = dc * i * (1.0 - c.cand*c.cand)
+		dg[0] = dc * c.cand * i * (1.0 - i)
+		dg[1] = dc * c.cprev * f * (1.0 - f)
+		dg[2] = dh * tc * o * (1.0 - o)
+		dhprev = uc * da
+		dcprev = dc * f
+	} else {
+		z, r := c.g[0], c.g[1]
+		da = dh * z * (1.0 - c.cand*c.cand)
+		dg[0] = dh * (c.cand - h) * z * (1.0 - z)
+		dg[1] = uc * da * h * r * (1.0 - r)
+		dhprev = dh*(1.0-z) + uc*da*r
+	}
+
+	for gi := range c.gates {
+		c.bias[gi].AddGrad(dg[gi])
+		c.recur[gi].AddGrad(dg[gi] * h)
+		dhprev += c.recur[gi].Data * dg[gi]
+	}
+	for k, p := range u.W.Params {
+		if isGate(k) {
+			continue
+		}
+		gradi := u.W.backward(k, da)
+		if k == recurID {
+			continue
+		}
+		if w, ok := c.w[k]; ok && !p.masked {
+			for gi, gp := range w {
+				gp.AddGrad(dg[gi] * p.value)
+				gradi += gp.Data * dg[gi]
+			}
+		}
+		emit(k, gradi)
+	}
+	if u.seq {
+		u.carry, c.carryC = dhprev, dcprev
+	}
+}
+
+// reset clears the state carried between steps, see Net.ResetState.
+func (c *gatedCell) reset() {
+	c.c, c.carryC = 0.0, 0.0
+}
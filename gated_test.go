@@ -0,0 +1,173 @@
+package neuron
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// newGatedNet creates a network with a hidden layer of gated units, with
+// random parameters so that every gate is in play.
+func newGatedNet(kind string, opts ...Option) *Net {
+	kinds := []string{InputKind, kind, OutputKind}
+	opts = append(opts, WithUnitKinds(kinds))
+	n := MustNewMLP([]int{2, 3, 1}, NewSGD(0.1, 0.0, 0.0), opts...)
+	theta := n.Data()
+	for _, ud := range theta {
+		for id := range ud {
+			ud[id] = rand.NormFloat64()
+		}
+	}
+	n.SetData(theta)
+	return n
+}
+
+// Test backpropagation through time of gated units against central
+// differences.
+func TestGatedSequenceGrads(t *testing.T) {
+	for _, kind := range []string{GRUKind, LSTMKind} {
+		rand.Seed(41)
+		n := newGatedNet(kind)
+		n.StartSequence(true, 0)
+
+		// Loss is the sum of the outputs over the sequence.
+		seq := [][]float64{{1.0, 0.5}, {-0.5, 0.2}, {2.0, -1.0}}
+		ones := [][]float64{{1.0}, {1.0}, {1.0}}
+		zeros := [][]float64{{0.0}, {0.0}, {0.0}}
+		n.ForwardSequence(seq)
+		n.BackwardSequence(ones)
+		grads := n.Grads()
+		theta := n.Data()
+		// Weights on both inputs, bias, and recurrent weight of each gate.
+		gateParams := 0
+		for id := range grads[unitID(1, 0)] {
+			if isGate(id) {
+				gateParams++
+			}
+		}
+		if want := 4 * len(n.Layers[1][0].cell.gates); gateParams != want {
+			t.Fatalf("(%s) Got gradients of %d gate parameters; expected %d", kind,
+				gateParams, want)
+		}
+
+		const eps = 1.0e-06
+		loss := func() float64 {
+			n.ResetState()
+			out, _ := n.ForwardSequence(seq)
+			n.BackwardSequence(zeros)
+			return out[0][0] + out[1][0] + out[2][0]
+		}
+		for uid, g := range grads {
+			for id := range g {
+				v := theta[uid][id]
+				n.SetData(ParamVector{uid: {id: v + eps}})
+				lossPlus := loss()
+				n.SetData(ParamVector{uid: {id: v - eps}})
+				lossMinus := loss()
+				n.SetData(ParamVector{uid: {id: v}})
+
+				numeric := (lossPlus - lossMinus) / (2 * eps)
+				// Tiny gradients are dominated by rounding errors.
+				if !almostEqualTol(g[id], numeric, 1.0e-04) && math.Abs(g[id]-numeric) > 1.0e-08 {
+					t.Errorf("(%s) Grad[%s][%s] is %.6e; expected %.6e", kind, uid, id,
+						g[id], numeric)
+				}
+			}
+		}
+		n.Stop()
+	}
+}
+
+// Test gated units on single passes, on both engines.
+func TestGatedGradCheck(t *testing.T) {
+	data := []float64{0.5, -1.0}
+	target := []float64{0.3}
+	loss, _ := GetLoss("mse")
+	for _, kind := range []string{GRUKind, LSTMKind} {
+		for _, layers := range []bool{false, true} {
+			rand.Seed(43)
+			var opts []Option
+			if layers {
+				opts = append(opts, WithLayerEngine())
+			}
+			n := newGatedNet(kind, opts...)
+			n.Start(true, 0)
+			output := n.MustForward(data)
+			loss.Forward(output, target)
+			n.MustBackward(loss.Backward())
+			grads := n.Grads()
+			relErr, _, err := GradCheck(n, data, target, loss, 1.0e-05)
+			n.Stop()
+			if err != nil {
+				t.Fatalf("GradCheck returned an error: %v", err)
+			}
+			for uid, ue := range relErr {
+				for id, e := range ue {
+					// Without a previous step, the gradients of the recurrent
+					// weights and some gates vanish, leaving only rounding
+					// errors.
+					if grads[uid][id] == 0.0 {
+						continue
+					}
+					if e > 1.0e-05 {
+						t.Errorf("(%s, layers=%v) Relative error of %s/%s is %g; expected < 1e-5",
+							kind, layers, uid, id, e)
+					}
+				}
+			}
+		}
+	}
+
+	n := newGatedNet(LSTMKind)
+	if _, err := NewDense(n); err == nil {
+		t.Error("Dense engine with gated units didn't fail")
+	}
+	if err := n.WidenLayer(1, 4); err == nil {
+		t.Error("Widening a gated layer didn't fail")
+	}
+	n = MustNewMLP([]int{2, 3, 2, 1}, NewSGD(0.1, 0.0, 0.0),
+		WithUnitKinds([]string{InputKind, HiddenKind, GRUKind, OutputKind}))
+	if err := n.PruneUnit(unitID(1, 0)); err == nil {
+		t.Error("Pruning an input of a gated layer didn't fail")
+	}
+	if err := n.PruneConnection(unitID(1, 0), unitID(2, 0)); err == nil {
+		t.Error("Pruning a connection into a gated unit didn't fail")
+	}
+}
+
+// Test that the LSTM forget gate starts open, and that gated units learn a
+// sequence.
+func TestGatedTraining(t *testing.T) {
+	rand.Seed(47)
+	u := newLSTMUnit("u", NewSGD(0.1, 0.0, 0.0))
+	if b := u.W.Params[gateID("f", biasID)].Data; b != 1.0 {
+		t.Errorf("LSTM forget gate bias is %v; expected 1", b)
+	}
+
+	seq := [][]float64{{1.0}, {0.0}, {0.0}, {-1.0}, {0.0}, {0.0}}
+	targets := []float64{0.0, 0.5, 0.5, 0.0, -0.5, -0.5}
+	for _, kind := range []string{GRUKind, LSTMKind} {
+		kinds := []string{InputKind, kind, OutputKind}
+		n := MustNewMLP([]int{1, 4, 1}, NewSGD(0.05, 0.5, 0.0), WithUnitKinds(kinds),
+			WithInitializer(XavierUniform{}))
+		n.StartSequence(true, 1)
+		var first, loss float64
+		for ii := 0; ii < 200; ii++ {
+			loss = 0.0
+			n.ResetState()
+			n.TrainSequence(seq, len(seq), func(t int, output []float64) []float64 {
+				l, g := MSELoss(output[0], targets[t])
+				loss += l
+				return []float64{g}
+			})
+			if ii == 0 {
+				first = loss
+			}
+		}
+		n.Stop()
+		if loss > 0.2*first {
+			t.Errorf("(%s) Sequence loss didn't decrease: %.4f -> %.4f", kind, first,
+				loss)
+		}
+	}
+}
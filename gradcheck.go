@@ -0,0 +1,118 @@
+package neuron
+
+import (
+	"errors"
+	"math"
+)
+
+// GradCheck compares the gradients of loss for a single data sample computed
+// by the backward pass against central finite difference estimates
+//
+//	dL/dw ~= (L(w + eps) - L(w - eps)) / (2*eps)
+//
+// e.g. to validate a custom activation or loss. It returns the relative error
+// |analytic - numeric| / max(|analytic|, |numeric|) of every trainable
+// parameter, and the largest of them. Errors below ~1e-6 are typical with
+// eps = 1e-5. Activations with kinks, like ReLU, can give large errors for
+// units whose pre-activation is within eps of the kink.
+//
+// GradCheck runs two forward passes per parameter, so it's meant for small
+// networks. Like HessianVectorProduct, the network must be running in training
+// mode with updateFreq 0, and parameter values and accumulated gradients are
+// restored before returning. Noise and dropout should be disabled, since they
+// make the loss stochastic. The gradient of tied weights (see
+// WithTiedWeights) is checked on the owner, summed over both uses, and the
+// tied copies are skipped.
+func GradCheck(n *Net, data, target []float64, loss Loss,
+	eps float64) (relErr ParamVector, maxErr float64, err error) {
+	if !n.train || n.updateFreq != 0 {
+		return nil, 0.0, errors.New("gradient check needs a network in training mode with updateFreq 0")
+	}
+	if err := n.checkInput(data); err != nil {
+		return nil, 0.0, err
+	}
+	saved := n.Grads()
+
+	// lossAt runs a full pass and returns the loss, leaving the gradients of
+	// the loss accumulated.
+	lossAt := func() (float64, error) {
+		output, err := n.Forward(data)
+		if err != nil {
+			n.finishPass(output)
+			return 0.0, err
+		}
+		l, err := loss.Forward(output, target)
+		if err != nil {
+			n.finishPass(output)
+			return 0.0, err
+		}
+		return l, n.Backward(loss.Backward())
+	}
+
+	n.zeroGrad()
+	if _, err := lossAt(); err != nil {
+		n.restoreGrads(saved)
+		return nil, 0.0, err
+	}
+	analytic := make(map[*Param]float64)
+	n.forEachParam(func(u *Unit, id string, p *Param) {
+		analytic[p] = p.grad
+	})
+
+	relErr = make(ParamVector)
+	n.forEachParam(func(u *Unit, id string, p *Param) {
+		if err != nil || p.masked || p.frozen || p.tied != nil {
+			return
+		}
+		w := p.Data
+		var lPlus, lMinus float64
+		p.Data = w + eps
+		lPlus, err = lossAt()
+		if err == nil {
+			p.Data = w - eps
+			lMinus, err = lossAt()
+		}
+		p.Data = w
+		if err != nil {
+			return
+		}
+
+		a := analytic[p]
+		for _, t := range p.ties {
+			a += analytic[t]
+		}
+		num := (lPlus - lMinus) / (2 * eps)
+		e := 0.0
+		if scale := math.Max(math.Abs(a), math.Abs(num)); scale > 0 {
+			e = math.Abs(a-num) / scale
+		}
+		if relErr[u.ID] == nil {
+			relErr[u.ID] = make(map[string]float64)
+		}
+		relErr[u.ID][id] = e
+		maxErr = math.Max(maxErr, e)
+	})
+	n.restoreGrads(saved)
+	if err != nil {
+		return nil, 0.0, err
+	}
+	return relErr, maxErr, nil
+}
+
+// finishPass finishes a training pass whose forward pass returned output
+// along with an error, so that it releases the pass ticket. Forward passes
+// that failed without output have already released it.
+func (n *Net) finishPass(output []float64) {
+	if output != nil {
+		n.Backward(make([]float64, len(output)))
+	}
+}
+
+// restoreGrads sets the accumulated gradients back to saved, and the tied
+// copies of weights back to their owner's value.
+func (n *Net) restoreGrads(saved ParamVector) {
+	n.forEachParam(func(u *Unit, id string, p *Param) {
+		p.grad = saved[u.ID][id]
+		p.pullTied()
+	})
+}
@@ -0,0 +1,97 @@
+package neuron
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// badTanh is a tanh activation with a wrong gradient.
+type badTanh struct {
+	Tanh
+}
+
+func (a *badTanh) Backward(grad float64) float64 {
+	return 2 * a.Tanh.Backward(grad)
+}
+
+// Test gradient checking against correct and broken activations.
+func TestGradCheck(t *testing.T) {
+	rand.Seed(3)
+	arch := []int{3, 4, 2}
+	data := []float64{0.5, -1.0, 2.0}
+	target := []float64{1.0, -1.0}
+	loss, _ := GetLoss("mse")
+	for _, activ := range []Activation{new(Tanh), new(badTanh)} {
+		n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), WithInitializer(XavierNormal{}),
+			WithActivations([]Activation{activ, new(Identity)}))
+		before := n.Data()
+		n.Start(true, 0)
+		_, maxErr, err := GradCheck(n, data, target, loss, 1.0e-05)
+		n.Stop()
+		if err != nil {
+			t.Fatalf("GradCheck returned an error: %v", err)
+		}
+		_, bad := activ.(*badTanh)
+		if !bad && maxErr > 1.0e-06 {
+			t.Errorf("Max relative error is %g; expected < 1e-6", maxErr)
+		}
+		if bad && maxErr < 0.1 {
+			t.Errorf("Max relative error with a wrong gradient is %g; expected > 0.1",
+				maxErr)
+		}
+		for uid, d := range n.Data() {
+			for id, v := range d {
+				if v != before[uid][id] {
+					t.Errorf("Param[%s][%s] not restored", uid, id)
+				}
+			}
+		}
+		for uid, g := range n.Grads() {
+			for id, v := range g {
+				if v != 0.0 {
+					t.Errorf("Grad[%s][%s] is %g; expected 0", uid, id, v)
+				}
+			}
+		}
+	}
+}
+
+// Test that GradCheck needs training mode with updateFreq 0, and that it
+// returns the errors of its passes without holding on to the pass ticket.
+func TestGradCheckErrors(t *testing.T) {
+	rand.Seed(4)
+	data, target := []float64{1.0, 2.0}, []float64{0.5}
+	loss, _ := GetLoss("mse")
+	n := MustNewMLP([]int{2, 3, 1}, NewSGD(0.1, 0.0, 0.0), WithAnomalyDetection())
+	n.Start(false, 0)
+	if _, _, err := GradCheck(n, data, target, loss, 1.0e-05); err == nil {
+		t.Error("GradCheck in eval mode didn't fail")
+	}
+	n.Stop()
+	n.Start(true, 1)
+	if _, _, err := GradCheck(n, data, target, loss, 1.0e-05); err == nil {
+		t.Error("GradCheck with updateFreq 1 didn't fail")
+	}
+	if s := n.Steps(); s != 0 {
+		t.Errorf("GradCheck took %d steps; expected 0", s)
+	}
+	n.Stop()
+
+	n.Start(true, 0)
+	defer n.Stop()
+	p := n.Layers[1][1].W.Params[n.Layers[0][0].ID]
+	w := p.Data
+	p.Data = math.Inf(1)
+	var anomaly *AnomalyError
+	if _, _, err := GradCheck(n, data, target, loss, 1.0e-05); !errors.As(err, &anomaly) {
+		t.Errorf("GradCheck returned %v; expected an AnomalyError", err)
+	}
+	p.Data = w
+	if _, err := n.ForwardTimeout(data, time.Second); err != nil {
+		t.Fatalf("Forward after GradCheck failed: %v", err)
+	}
+	n.MustBackward([]float64{0.0})
+}
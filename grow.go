@@ -0,0 +1,125 @@
+package neuron
+
+import (
+	"fmt"
+)
+
+// AddUnit adds a new unit to a hidden layer and returns it, e.g. for
+// constructive training like cascade correlation. The new unit has the same
+// activation, optimizer, bias, and weight settings as the last unit in the
+// layer. It's connected to every unit in the layers feeding into the layer,
+// with weights drawn by the layer's initializer, and to every unit in the
+// layers the layer feeds into, with zero weights, so that the network computes
+// the same function until it's trained.
+//
+// AddUnit can be called while the network is running, in which case it waits
+// for the pass in progress, stops the network, and starts it again with the
+// new unit. It mustn't be called between a Forward and its Backward.
+func (n *Net) AddUnit(layer int) (*Unit, error) {
+	if layer < 1 || layer >= len(n.Layers)-1 {
+		return nil, fmt.Errorf("can only add units to hidden layers; got layer %d", layer)
+	}
+	tmpl := n.Layers[layer][len(n.Layers[layer])-1]
+	if tmpl.W.bayes {
+		return nil, fmt.Errorf("can't add units to Bayesian layer %d", layer)
+	}
+	if gatedLayer(n.Layers[layer]) {
+		return nil, fmt.Errorf("can't add units to gated layer %d", layer)
+	}
+	if _, ok := n.convs[layer]; ok {
+		return nil, fmt.Errorf("can't add units to convolution layer %d", layer)
+	}
+	for jj := layer; jj < len(n.Layers); jj++ {
+		// A new input would change the attention weights.
+		if attentionLayer(n.Layers[jj]) && (jj == layer || containsInt(n.upstream(jj), layer)) {
+			return nil, fmt.Errorf("can't add units to or into attention layer %d", jj)
+		}
+	}
+
+	var u *Unit
+	n.modify(func() {
+		u = NewUnit(n.freeUnitID(layer), cloneActivation(tmpl.activ), tmpl.opt.New())
+		u.stepDone = n.stepDone
+		u.clipValue, u.netStep = tmpl.clipValue, tmpl.netStep
+		u.noise, u.W.noise = tmpl.noise, tmpl.W.noise
+		u.steps = n.steps
+		for k, p := range tmpl.W.Params {
+			if !isConn(k) && k != gainID && !isActivParam(k) {
+				u.W.init(k, p.Data, p.RequiresGrad)
+			}
+		}
+		logf(1, "Adding unit %s\n", u.ID)
+
+		for _, jj := range n.upstream(layer) {
+			for _, u1 := range n.Layers[jj] {
+				u1.connect(u, n.rand())
+			}
+		}
+		for jj := layer + 1; jj < len(n.Layers); jj++ {
+			if !containsInt(n.upstream(jj), layer) {
+				continue
+			}
+			for _, u2 := range n.Layers[jj] {
+				u.connect(u2, n.rand())
+				u2.W.Params[u.ID].Data = 0.0
+				if u2.cell != nil {
+					for _, p := range u2.cell.w[u.ID] {
+						p.Data = 0.0
+					}
+				}
+			}
+		}
+		n.Layers[layer] = append(n.Layers[layer], u)
+		n.Arch[layer]++
+		n.initUnit(layer, u)
+		n.seedUnit(u)
+		if tmpl.W.norm {
+			u.W.normalize()
+		}
+		if tmpl.W.qbits > 0 {
+			u.W.quantize(tmpl.W.qbits)
+		}
+		u.setFrozen(tmpl.Frozen())
+		n.sched = schedStates(n.Layers)
+	})
+	return u, nil
+}
+
+// modify runs f, which changes the network's units or connections, while the
+// network is stopped. If the network is running, it waits for the pass in
+// progress and starts the network again afterwards in the same mode.
+func (n *Net) modify(f func()) {
+	if n.quit == nil {
+		f()
+		return
+	}
+	n.acquire(nil)
+	defer n.release()
+	train, updateFreq := n.train, n.updateFreq
+	n.Stop()
+	f()
+	n.Start(train, updateFreq)
+}
+
+// freeUnitID returns an unused unit ID for a new unit in layer.
+func (n *Net) freeUnitID(layer int) string {
+	ids := make(map[string]bool, len(n.Layers[layer]))
+	for _, u := range n.Layers[layer] {
+		ids[u.ID] = true
+	}
+	jj := len(n.Layers[layer])
+	for ids[unitID(layer, jj)] {
+		jj++
+	}
+	return unitID(layer, jj)
+}
+
+// containsInt checks whether s contains v.
+func containsInt(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package neuron
+
+import (
+	"testing"
+)
+
+// Test adding units to a running network.
+func TestAddUnit(t *testing.T) {
+	arch := []int{2, 3, 3, 2}
+	data := []float64{1.0, -1.0}
+	grad := []float64{1.0, -1.0}
+	for _, opts := range [][]Option{nil, {WithLayerEngine()}, {WithSignalBatching()},
+		{WithSkipConnections(1, 3), WithWeightNorm()}} {
+		n := MustNewMLP(arch, NewSGD(0.5, 0.0, 0.0), opts...)
+		n.Start(true, 1)
+		n.MustForward(data)
+		n.MustBackward(grad)
+
+		n.Eval()
+		want := n.MustForward(data)
+		u, err := n.AddUnit(1)
+		if err != nil {
+			t.Fatalf("AddUnit failed: %v", err)
+		}
+		if u.ID != unitID(1, 3) || n.Arch[1] != 4 {
+			t.Errorf("Added unit %s; arch is %v", u.ID, n.Arch)
+		}
+		if _, err := n.AddUnit(2); err != nil {
+			t.Fatalf("AddUnit failed: %v", err)
+		}
+		got := n.MustForward(data)
+		for ii := range want {
+			if !almostEqual(got[ii], want[ii]) {
+				t.Errorf("Output %d is %.6f after adding units; expected %.6f", ii,
+					got[ii], want[ii])
+			}
+		}
+
+		// The new unit's outgoing weights train.
+		n.Train()
+		n.MustForward(data)
+		n.MustBackward(grad)
+		n.Stop()
+		if err := n.Validate(); err != nil {
+			t.Errorf("Validate failed: %v", err)
+		}
+		if w := n.Layers[2][0].W.Params[u.ID].Data; w == 0.0 {
+			t.Errorf("Outgoing weight of new unit didn't train")
+		}
+	}
+
+	n := MustNewMLP(arch, NewSGD(0.5, 0.0, 0.0))
+	for _, layer := range []int{0, 3} {
+		if _, err := n.AddUnit(layer); err == nil {
+			t.Errorf("AddUnit did not return an error")
+		}
+	}
+}
+
+// Test that added units get their own activation parameters, and the frozen
+// state of their layer.
+func TestAddUnitPRelu(t *testing.T) {
+	n := MustNewMLP([]int{2, 3, 1}, NewSGD(0.5, 0.0, 0.0),
+		WithActivations([]Activation{NewPRelu(0.25), new(Identity)}))
+	u, err := n.AddUnit(1)
+	if err != nil {
+		t.Fatalf("AddUnit failed: %v", err)
+	}
+	alpha := u.Activation().(*PRelu).Alpha
+	if p := u.W.Params[activPrefix+"alpha"]; p != alpha {
+		t.Fatalf("Slope param of new unit isn't its activation's")
+	}
+	if u.Frozen() {
+		t.Errorf("New unit of an unfrozen layer is frozen")
+	}
+
+	// Negative inputs make every hidden pre-activation negative.
+	for _, u2 := range n.Layers[1] {
+		for k, p := range u2.W.Params {
+			if isConn(k) {
+				p.Data = 1.0
+			}
+		}
+		u2.W.Params[biasID].Data = 0.0
+	}
+	n.Layers[2][0].W.Params[u.ID].Data = 1.0
+	n.Start(true, 1)
+	n.MustForward([]float64{-1.0, -1.0})
+	n.MustBackward([]float64{1.0})
+	n.Stop()
+	if alpha.Data == 0.25 {
+		t.Errorf("Slope of new unit didn't train")
+	}
+
+	if err := n.Freeze(1); err != nil {
+		t.Fatal(err)
+	}
+	if u, err = n.AddUnit(1); err != nil {
+		t.Fatalf("AddUnit failed: %v", err)
+	}
+	if !u.Frozen() {
+		t.Errorf("New unit of a frozen layer isn't frozen")
+	}
+}
@@ -0,0 +1,172 @@
+package neuron
+
+import (
+	"fmt"
+)
+
+// Default head names of networks without WithInputHeads or WithOutputHeads.
+const (
+	InputHead  = "input"
+	OutputHead = "output"
+)
+
+// A Head is a named group of consecutive units in the input or output layer,
+// e.g. the classification and regression outputs of a network with a shared
+// trunk. Softmax adds a softmax over the units of an output head, like
+// WithSoftmaxOutput does for the whole output layer.
+type Head struct {
+	Name    string
+	Size    int
+	Softmax bool
+}
+
+// WithInputHeads splits the input layer into named groups, in order, which
+// are fed with ForwardHeads. The sizes must add up to the size of the input
+// layer, or with WithEmbedding, to the network's input dimension.
+func WithInputHeads(heads ...Head) Option {
+	return func(c *netConfig) {
+		c.inHeads = heads
+	}
+}
+
+// WithOutputHeads splits the output layer into named heads, in order, which
+// are returned by ForwardHeads and back-propagated with BackwardHeads. The
+// sizes must add up to the size of the output layer.
+func WithOutputHeads(heads ...Head) Option {
+	return func(c *netConfig) {
+		c.outHeads = heads
+	}
+}
+
+// checkHeads checks the head settings, and fills in the default heads.
+func checkHeads(c *netConfig, arch []int) error {
+	inDim := embeddedDim(c.embeds, arch[0])
+	if c.inHeads == nil {
+		c.inHeads = []Head{{Name: InputHead, Size: inDim}}
+	}
+	if c.outHeads == nil {
+		c.outHeads = []Head{{Name: OutputHead, Size: arch[len(arch)-1]}}
+	}
+	for _, h := range c.inHeads {
+		if h.Softmax {
+			return fmt.Errorf("input head %q can't have a softmax", h.Name)
+		}
+	}
+	for _, h := range c.outHeads {
+		if h.Softmax && c.softmax {
+			return fmt.Errorf("output head %q can't have a softmax with WithSoftmaxOutput",
+				h.Name)
+		}
+	}
+	for ii, heads := range [][]Head{c.inHeads, c.outHeads} {
+		seen := make(map[string]bool)
+		size := 0
+		for _, h := range heads {
+			if h.Name == "" || seen[h.Name] {
+				return fmt.Errorf("heads need unique, non-empty names; got %q", h.Name)
+			}
+			if h.Size < 1 {
+				return fmt.Errorf("head %q needs >= 1 unit; got %d", h.Name, h.Size)
+			}
+			seen[h.Name] = true
+			size += h.Size
+		}
+		want := inDim
+		if ii == 1 {
+			want = arch[len(arch)-1]
+		}
+		if size != want {
+			return fmt.Errorf("head sizes add up to %d; expected %d", size, want)
+		}
+	}
+	return nil
+}
+
+// headSpans returns the softmax spans of the output layer.
+func headSpans(c *netConfig, outDim int) [][2]int {
+	if c.softmax {
+		return [][2]int{{0, outDim}}
+	}
+	var spans [][2]int
+	start := 0
+	for _, h := range c.outHeads {
+		if h.Softmax {
+			spans = append(spans, [2]int{start, start + h.Size})
+		}
+		start += h.Size
+	}
+	return spans
+}
+
+// InputHeads returns the network's input groups.
+func (n *Net) InputHeads() []Head {
+	return append([]Head(nil), n.inHeads...)
+}
+
+// OutputHeads returns the network's output heads.
+func (n *Net) OutputHeads() []Head {
+	return append([]Head(nil), n.outHeads...)
+}
+
+// ForwardHeads is like Forward, but takes the input of each input group by
+// name and returns the output of each head by name. Every input group needs
+// an input.
+func (n *Net) ForwardHeads(inputs map[string][]float64) (map[string][]float64, error) {
+	data, err := joinHeads(n.inHeads, inputs, false)
+	if err != nil {
+		return nil, err
+	}
+	output, err := n.Forward(data)
+	if output == nil {
+		return nil, err
+	}
+	return splitHeads(n.outHeads, output), err
+}
+
+// BackwardHeads is like Backward, but takes the loss gradient of each output
+// head by name. Heads without a gradient, e.g. heads with no label for the
+// sample, get a zero gradient.
+func (n *Net) BackwardHeads(grads map[string][]float64) error {
+	grad, err := joinHeads(n.outHeads, grads, true)
+	if err != nil {
+		return err
+	}
+	return n.Backward(grad)
+}
+
+// joinHeads concatenates the values of each head in order. Missing heads are
+// zero if optional, and an error otherwise.
+func joinHeads(heads []Head, values map[string][]float64, optional bool) ([]float64, error) {
+	known := make(map[string]bool, len(heads))
+	var joined []float64
+	for _, h := range heads {
+		known[h.Name] = true
+		v, ok := values[h.Name]
+		if !ok && optional {
+			v = make([]float64, h.Size)
+		} else if !ok {
+			return nil, fmt.Errorf("missing head %q", h.Name)
+		}
+		if len(v) != h.Size {
+			return nil, fmt.Errorf("head %q dim (%d) not equal to head size (%d)",
+				h.Name, len(v), h.Size)
+		}
+		joined = append(joined, v...)
+	}
+	for k := range values {
+		if !known[k] {
+			return nil, fmt.Errorf("unknown head %q", k)
+		}
+	}
+	return joined, nil
+}
+
+// splitHeads splits values into the heads.
+func splitHeads(heads []Head, values []float64) map[string][]float64 {
+	split := make(map[string][]float64, len(heads))
+	for _, h := range heads {
+		split[h.Name] = values[:h.Size:h.Size]
+		values = values[h.Size:]
+	}
+	return split
+}
@@ -0,0 +1,114 @@
+package neuron
+
+import (
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestHeadErrors(t *testing.T) {
+	arch := []int{3, 4, 4}
+	for _, opts := range [][]Option{
+		{WithInputHeads(Head{Name: "a", Size: 2})},
+		{WithInputHeads(Head{Name: "a", Size: 2}, Head{Name: "a", Size: 1})},
+		{WithInputHeads(Head{Name: "a", Size: 3, Softmax: true})},
+		{WithOutputHeads(Head{Name: "", Size: 4})},
+		{WithOutputHeads(Head{Name: "a", Size: 4}, Head{Name: "b", Size: 0})},
+		{WithSoftmaxOutput(), WithOutputHeads(Head{Name: "a", Size: 4, Softmax: true})}} {
+		if _, err := NewMLP(arch, NewSGD(0.1, 0.0, 0.0), opts...); err == nil {
+			t.Errorf("NewMLP did not return an error")
+		}
+	}
+
+	n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0),
+		WithOutputHeads(Head{Name: "a", Size: 3, Softmax: true}, Head{Name: "b", Size: 1}))
+	if err := n.ExportONNX(io.Discard); err == nil {
+		t.Errorf("ExportONNX did not return an error")
+	}
+	n.Start(true, 0)
+	defer n.Stop()
+	for _, inputs := range []map[string][]float64{{}, {InputHead: {1.0}},
+		{InputHead: {1.0, 2.0, 3.0}, "x": {1.0}}} {
+		if _, err := n.ForwardHeads(inputs); err == nil {
+			t.Errorf("ForwardHeads did not return an error")
+		}
+	}
+	n.MustForward([]float64{1.0, 2.0, 3.0})
+	if err := n.BackwardHeads(map[string][]float64{"a": {1.0}}); err == nil {
+		t.Errorf("BackwardHeads did not return an error")
+	}
+	n.MustBackward(make([]float64, 4))
+}
+
+// Test a shared trunk with a classification and a regression head.
+func TestHeads(t *testing.T) {
+	rand.Seed(8)
+	arch := []int{3, 5, 4}
+	n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0),
+		WithInputHeads(Head{Name: "x", Size: 2}, Head{Name: "y", Size: 1}),
+		WithOutputHeads(Head{Name: "class", Size: 3, Softmax: true},
+			Head{Name: "reg", Size: 1}))
+	if heads := n.OutputHeads(); len(heads) != 2 || heads[1].Name != "reg" {
+		t.Errorf("Output heads are %v", heads)
+	}
+	theta := n.Data()
+	for _, ud := range theta {
+		for id := range ud {
+			if isConn(id) {
+				ud[id] = rand.NormFloat64()
+			}
+		}
+	}
+	n.SetData(theta)
+
+	n.Start(true, 0)
+	out, err := n.ForwardHeads(map[string][]float64{"x": {1.0, -0.5}, "y": {2.0}})
+	if err != nil {
+		t.Fatalf("ForwardHeads failed: %v", err)
+	}
+	if len(out["class"]) != 3 || len(out["reg"]) != 1 {
+		t.Fatalf("Got outputs %v", out)
+	}
+	sum := 0.0
+	for _, p := range out["class"] {
+		sum += p
+	}
+	if !almostEqual(sum, 1.0) {
+		t.Errorf("Class probabilities sum to %.4f; expected 1", sum)
+	}
+	// Only the classification head has a loss.
+	if err := n.BackwardHeads(map[string][]float64{"class": {0.0, -1.0 / out["class"][1], 0.0}}); err != nil {
+		t.Fatalf("BackwardHeads failed: %v", err)
+	}
+	n.Stop()
+	gotGrads := n.Grads()
+	n.zeroGrad()
+
+	d, _ := NewDense(n)
+	want, _ := d.Forward([]float64{1.0, -0.5, 2.0})
+	got := append(append([]float64(nil), out["class"]...), out["reg"]...)
+	for ii := range want {
+		if !almostEqualTol(got[ii], want[ii], 1.0e-06) {
+			t.Errorf("Output %d is %.6f; expected %.6f", ii, got[ii], want[ii])
+		}
+	}
+	d.Backward([]float64{0.0, -1.0 / want[1], 0.0, 0.0})
+	for uid, ug := range n.Grads() {
+		for id, g := range ug {
+			if !almostEqualTol(gotGrads[uid][id], g, 1.0e-06) {
+				t.Errorf("Grad %s/%s is %.6f; expected %.6f", uid, id,
+					gotGrads[uid][id], g)
+			}
+		}
+	}
+	// Cross-entropy gradient through the softmax is p - y.
+	for ii, p := range want[:3] {
+		y := 0.0
+		if ii == 1 {
+			y = 1.0
+		}
+		if g := gotGrads[unitID(2, ii)][biasID]; !almostEqualTol(g, p-y, 1.0e-06) {
+			t.Errorf("Class score grad %d is %.6f; expected %.6f", ii, g, p-y)
+		}
+	}
+}
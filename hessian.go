@@ -0,0 +1,131 @@
+package neuron
+
+import (
+	"errors"
+)
+
+// A ParamVector holds one value per trainable network parameter, keyed by unit
+// ID and then parameter ID. It's used to represent gradients and directions in
+// parameter space.
+type ParamVector map[string]map[string]float64
+
+// forEachParam calls f for every trainable parameter in the network.
+func (n *Net) forEachParam(f func(u *Unit, id string, p *Param)) {
+	for _, l := range n.Layers {
+		for _, u := range l {
+			for id, p := range u.W.Params {
+				if p.RequiresGrad {
+					f(u, id, p)
+				}
+			}
+		}
+	}
+}
+
+// Grads returns a copy of the gradients accumulated since the last step. It
+// should only be called while the network is idle, e.g. after Backward
+// returns.
+func (n *Net) Grads() ParamVector {
+	g := make(ParamVector)
+	n.forEachParam(func(u *Unit, id string, p *Param) {
+		if g[u.ID] == nil {
+			g[u.ID] = make(map[string]float64)
+		}
+		g[u.ID][id] = p.grad
+	})
+	return g
+}
+
+// Data returns a copy of the current parameter values.
+func (n *Net) Data() ParamVector {
+	d := make(ParamVector)
+	n.forEachParam(func(u *Unit, id string, p *Param) {
+		if d[u.ID] == nil {
+			d[u.ID] = make(map[string]float64)
+		}
+		d[u.ID][id] = p.Data
+	})
+	return d
+}
+
+// SetData sets the parameter values from d. Parameters missing from d are left
+// unchanged.
+func (n *Net) SetData(d ParamVector) {
+	n.forEachParam(func(u *Unit, id string, p *Param) {
+		if v, ok := d[u.ID][id]; ok {
+			p.Data = v
+		}
+	})
+}
+
+// HessianVectorProduct estimates the product of the loss Hessian with v for a
+// single data sample by central finite differences over the backward pass:
+//
+//	Hv ~= (g(w + eps*v) - g(w - eps*v)) / (2*eps)
+//
+// lossGrad maps the network output to the gradient of the loss, e.g. the
+// gradient returned by MarginLoss.
+//
+// The network must be running in training mode with updateFreq 0 so that the
+// extra passes don't trigger a step. Parameter values and accumulated
+// gradients are restored before returning.
+func (n *Net) HessianVectorProduct(data []float64,
+	lossGrad func(output []float64) []float64, v ParamVector,
+	eps float64) (ParamVector, error) {
+	if !n.train || n.updateFreq != 0 {
+		return nil, errors.New("Hessian-vector product needs a network in training mode with updateFreq 0")
+	}
+	if err := n.checkInput(data); err != nil {
+		return nil, err
+	}
+	saved := n.Grads()
+	savedData := n.Data()
+
+	gradAt := func(scale float64) (ParamVector, error) {
+		n.perturb(v, scale)
+		defer n.SetData(savedData)
+		n.zeroGrad()
+		output, err := n.Forward(data)
+		if err != nil {
+			n.finishPass(output)
+			return nil, err
+		}
+		if err := n.Backward(lossGrad(output)); err != nil {
+			return nil, err
+		}
+		return n.Grads(), nil
+	}
+	var gMinus ParamVector
+	gPlus, err := gradAt(eps)
+	if err == nil {
+		gMinus, err = gradAt(-eps)
+	}
+	if err != nil {
+		n.restoreGrads(saved)
+		return nil, err
+	}
+
+	hv := make(ParamVector)
+	n.forEachParam(func(u *Unit, id string, p *Param) {
+		if hv[u.ID] == nil {
+			hv[u.ID] = make(map[string]float64)
+		}
+		hv[u.ID][id] = (gPlus[u.ID][id] - gMinus[u.ID][id]) / (2 * eps)
+		p.grad = saved[u.ID][id]
+	})
+	return hv, nil
+}
+
+// perturb adds scale * v to the network parameters.
+func (n *Net) perturb(v ParamVector, scale float64) {
+	n.forEachParam(func(u *Unit, id string, p *Param) {
+		p.Data += scale * v[u.ID][id]
+	})
+}
+
+// zeroGrad resets all accumulated gradients.
+func (n *Net) zeroGrad() {
+	n.forEachParam(func(u *Unit, id string, p *Param) {
+		p.grad = 0.0
+	})
+}
@@ -0,0 +1,78 @@
+package neuron
+
+import (
+	"testing"
+)
+
+// Test finite difference Hessian-vector products on a tiny network.
+func TestHessianVectorProduct(t *testing.T) {
+	arch := []int{1, 1, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	n := MustNewMLP(arch, opt)
+
+	// out = w2 * relu(w1 * x + b1) + b2
+	hidden, out := n.Layers[1][0], n.Layers[2][0]
+	hidden.W.Params["000_000000"].Data = 0.5
+	hidden.W.Params[biasID].Data = 0.1
+	out.W.Params["001_000000"].Data = 2.0
+	out.W.Params[biasID].Data = 0.3
+
+	n.Start(true, 0)
+
+	// Squared loss 0.5 * out^2.
+	lossGrad := func(output []float64) []float64 { return output }
+	v := ParamVector{hidden.ID: {"000_000000": 1.0}}
+	hv, _ := n.HessianVectorProduct([]float64{1.5}, lossGrad, v, 1.0e-03)
+
+	want := ParamVector{
+		hidden.ID: {"000_000000": 9.0, biasID: 6.0},
+		out.ID:    {"001_000000": 5.55, biasID: 3.0},
+	}
+	for uid, w := range want {
+		for id, hvWant := range w {
+			if !almostEqual(hv[uid][id], hvWant) {
+				t.Errorf("Hv[%s][%s] is %.6f; expected %.6f", uid, id, hv[uid][id],
+					hvWant)
+			}
+		}
+	}
+
+	// Parameters and grads should be restored.
+	if hidden.W.Params["000_000000"].Data != 0.5 {
+		t.Errorf("Parameters not restored")
+	}
+	for uid, g := range n.Grads() {
+		for id, v := range g {
+			if v != 0.0 {
+				t.Errorf("Grad[%s][%s] is %.6f; expected 0", uid, id, v)
+			}
+		}
+	}
+}
+
+// Test that Hessian-vector products need training mode with updateFreq 0,
+// and leave the network alone otherwise.
+func TestHessianVectorProductErrors(t *testing.T) {
+	n := MustNewMLP([]int{1, 1, 1}, NewSGD(1.0, 0.0, 0.0))
+	before := n.Data()
+	lossGrad := func(output []float64) []float64 { return output }
+	v := ParamVector{n.Layers[1][0].ID: {biasID: 1.0}}
+	for _, updateFreq := range []int{0, 1} {
+		n.Start(updateFreq > 0, updateFreq)
+		if _, err := n.HessianVectorProduct([]float64{1.5}, lossGrad, v, 1.0e-03); err == nil {
+			t.Errorf("Hessian-vector product with train %v and updateFreq %d didn't fail",
+				updateFreq > 0, updateFreq)
+		}
+		n.Stop()
+	}
+	if s := n.Steps(); s != 0 {
+		t.Errorf("Network took %d steps; expected 0", s)
+	}
+	for uid, d := range n.Data() {
+		for id, w := range d {
+			if w != before[uid][id] {
+				t.Errorf("Param[%s][%s] changed", uid, id)
+			}
+		}
+	}
+}
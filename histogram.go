@@ -0,0 +1,153 @@
+package neuron
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// A Histogram counts the values of a layer's weights or activations in equal
+// width bins between Min and Max.
+type Histogram struct {
+	// Step count of the network when the histogram was taken.
+	Step  int
+	Layer int
+	// "weight" or "activation".
+	Kind     string
+	Min, Max float64
+	Counts   []int
+}
+
+// newHistogram bins values into bins equal width bins.
+func newHistogram(values []float64, bins int) Histogram {
+	h := Histogram{Counts: make([]int, bins)}
+	if len(values) == 0 {
+		return h
+	}
+	h.Min, h.Max = math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		h.Min = math.Min(h.Min, v)
+		h.Max = math.Max(h.Max, v)
+	}
+	width := (h.Max - h.Min) / float64(bins)
+	for _, v := range values {
+		b := 0
+		if width > 0 {
+			b = int((v - h.Min) / width)
+		}
+		if b >= bins {
+			// The max goes in the last bin.
+			b = bins - 1
+		}
+		h.Counts[b]++
+	}
+	return h
+}
+
+// WeightHistograms returns a histogram of the connection weights of each layer
+// after the input layer, with bins bins. Like LayerSymmetry, it should only be
+// called while the network is idle.
+func (n *Net) WeightHistograms(bins int) []Histogram {
+	hists := make([]Histogram, 0, len(n.Layers)-1)
+	for ii := 1; ii < len(n.Layers); ii++ {
+		var values []float64
+		for _, u := range n.Layers[ii] {
+			for _, k := range weightKeys(u.W) {
+				if p := u.W.Params[k]; !p.masked {
+					values = append(values, p.Data)
+				}
+			}
+		}
+		h := newHistogram(values, bins)
+		h.Step, h.Layer, h.Kind = n.steps, ii, "weight"
+		hists = append(hists, h)
+	}
+	return hists
+}
+
+// ActivationHistograms returns a histogram of the activations of each layer in
+// the most recent forward pass, with bins bins. See Activations.
+func (n *Net) ActivationHistograms(bins int) []Histogram {
+	hists := make([]Histogram, len(n.Layers))
+	for ii := range n.Layers {
+		acts, _ := n.LayerActivations(ii)
+		hists[ii] = newHistogram(acts, bins)
+		hists[ii].Step, hists[ii].Layer, hists[ii].Kind = n.steps, ii, "activation"
+	}
+	return hists
+}
+
+// A HistogramLogger periodically writes the weight and activation histograms
+// of every layer of a training network, so that training dynamics can be
+// inspected offline. Histograms are written as CSV with one row per bin,
+//
+//	step,layer,kind,bin_min,bin_max,count
+//
+// or, if JSON is set, as one JSON encoded Histogram per line. Like a
+// Checkpoint, Update should be called after each Backward.
+type HistogramLogger struct {
+	// Writer the histograms are written to.
+	W io.Writer
+	// Histograms are written every Every steps, i.e. back-propagated samples.
+	Every int
+	// Number of bins of each histogram.
+	Bins int
+	// Write JSON lines instead of CSV.
+	JSON bool
+	// Step count at the last call to Update.
+	steps  int
+	header bool
+}
+
+// Update writes the histograms each time the network's step count passes a
+// multiple of Every.
+func (l *HistogramLogger) Update(n *Net) error {
+	if l.Every <= 0 {
+		return fmt.Errorf("histogram logger needs Every >= 1; got %d", l.Every)
+	}
+	steps := n.Steps()
+	if steps/l.Every <= l.steps/l.Every {
+		l.steps = steps
+		return nil
+	}
+	l.steps = steps
+	return l.Write(n)
+}
+
+// Write writes the histograms of n right away. It should only be called
+// while the network is idle, e.g. after Backward returns.
+func (l *HistogramLogger) Write(n *Net) error {
+	if l.Bins <= 0 {
+		return fmt.Errorf("histogram logger needs Bins >= 1; got %d", l.Bins)
+	}
+	hists := append(n.WeightHistograms(l.Bins), n.ActivationHistograms(l.Bins)...)
+	if l.JSON {
+		enc := json.NewEncoder(l.W)
+		for _, h := range hists {
+			if err := enc.Encode(h); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := csv.NewWriter(l.W)
+	if !l.header {
+		w.Write([]string{"step", "layer", "kind", "bin_min", "bin_max", "count"})
+		l.header = true
+	}
+	fmtFloat := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	for _, h := range hists {
+		width := (h.Max - h.Min) / float64(len(h.Counts))
+		for b, count := range h.Counts {
+			w.Write([]string{strconv.Itoa(h.Step), strconv.Itoa(h.Layer), h.Kind,
+				fmtFloat(h.Min + float64(b)*width), fmtFloat(h.Min + float64(b+1)*width),
+				strconv.Itoa(count)})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,76 @@
+package neuron
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Test binning values.
+func TestNewHistogram(t *testing.T) {
+	h := newHistogram([]float64{0.0, 0.1, 0.5, 0.9, 1.0}, 4)
+	want := []int{2, 0, 1, 2}
+	if h.Min != 0.0 || h.Max != 1.0 {
+		t.Errorf("Range is [%v, %v]; expected [0, 1]", h.Min, h.Max)
+	}
+	for b := range want {
+		if h.Counts[b] != want[b] {
+			t.Errorf("Counts are %v; expected %v", h.Counts, want)
+			break
+		}
+	}
+	if h := newHistogram([]float64{2.0, 2.0}, 3); h.Counts[0] != 2 {
+		t.Errorf("Constant values counts are %v; expected [2 0 0]", h.Counts)
+	}
+}
+
+// Test writing histograms periodically.
+func TestHistogramLogger(t *testing.T) {
+	rand.Seed(14)
+	n := MustNewMLP([]int{2, 3, 1}, NewSGD(0.1, 0.0, 0.0))
+	n.Start(true, 1)
+	defer n.Stop()
+	var csvBuf, jsonBuf bytes.Buffer
+	loggers := []*HistogramLogger{{W: &csvBuf, Every: 2, Bins: 5},
+		{W: &jsonBuf, Every: 2, Bins: 5, JSON: true}}
+	for ii := 0; ii < 4; ii++ {
+		n.MustForward([]float64{1.0, -1.0})
+		n.MustBackward([]float64{1.0})
+		for _, l := range loggers {
+			if err := l.Update(n); err != nil {
+				t.Fatalf("Update returned an error: %v", err)
+			}
+		}
+	}
+
+	// Two writes of 2 weight and 3 activation histograms.
+	rows, err := csv.NewReader(&csvBuf).ReadAll()
+	if err != nil {
+		t.Fatalf("Reading CSV failed: %v", err)
+	}
+	if len(rows) != 1+2*5*5 {
+		t.Errorf("Got %d CSV rows; expected %d", len(rows), 1+2*5*5)
+	}
+	if rows[1][0] != "2" || rows[len(rows)-1][0] != "4" {
+		t.Errorf("Steps are %s to %s; expected 2 to 4", rows[1][0], rows[len(rows)-1][0])
+	}
+
+	lines := strings.Split(strings.TrimSpace(jsonBuf.String()), "\n")
+	if len(lines) != 2*5 {
+		t.Fatalf("Got %d JSON lines; expected 10", len(lines))
+	}
+	var h Histogram
+	if err := json.Unmarshal([]byte(lines[0]), &h); err != nil {
+		t.Fatalf("Decoding JSON failed: %v", err)
+	}
+	total := 0
+	for _, c := range h.Counts {
+		total += c
+	}
+	if h.Kind != "weight" || h.Layer != 1 || total != 6 {
+		t.Errorf("First histogram is %+v; expected 6 layer 1 weights", h)
+	}
+}
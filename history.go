@@ -0,0 +1,88 @@
+package neuron
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// A History records the progress of a Trainer.Fit run, e.g. to plot learning
+// curves.
+type History struct {
+	// Mean training loss of every mini-batch.
+	Batches []Record
+	// Mean training loss of every epoch.
+	Epochs []Record
+	// Loss and accuracy of every validation, see Trainer.Validation.
+	Validations []Record
+}
+
+// A Record is one entry of a History.
+type Record struct {
+	Epoch int
+	// Mini-batch within the epoch, or -1 for epoch and validation records.
+	Batch int
+	// Step count of the network.
+	Step int
+	// Wall-clock time since the start of Fit.
+	Time time.Duration
+	// Learning rate, or 0 if the network's optimizer isn't an LrOptimizer.
+	LR       float64
+	Loss     float64
+	Accuracy float64
+	// Validation metrics by name, see Trainer.Metrics.
+	Metrics map[string]float64
+}
+
+// ExportCSV writes the history as CSV, with one row per record and columns
+//
+//	kind,epoch,batch,step,seconds,lr,loss,accuracy
+//
+// followed by a column for each validation metric, in sorted order. kind is
+// "batch", "epoch", or "validation". Accuracy and metrics are left empty for
+// training records.
+func (h *History) ExportCSV(w io.Writer) error {
+	var names []string
+	seen := make(map[string]bool)
+	for _, r := range h.Validations {
+		for name := range r.Metrics {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"kind", "epoch", "batch", "step", "seconds", "lr", "loss",
+		"accuracy"}, names...))
+	fmtFloat := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	for _, kind := range []struct {
+		name    string
+		records []Record
+	}{{"batch", h.Batches}, {"epoch", h.Epochs}, {"validation", h.Validations}} {
+		for _, r := range kind.records {
+			acc := ""
+			if kind.name == "validation" {
+				acc = fmtFloat(r.Accuracy)
+			}
+			row := []string{kind.name, strconv.Itoa(r.Epoch), strconv.Itoa(r.Batch),
+				strconv.Itoa(r.Step), fmtFloat(r.Time.Seconds()), fmtFloat(r.LR),
+				fmtFloat(r.Loss), acc}
+			for _, name := range names {
+				v, ok := r.Metrics[name]
+				if ok {
+					row = append(row, fmtFloat(v))
+				} else {
+					row = append(row, "")
+				}
+			}
+			cw.Write(row)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
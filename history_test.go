@@ -0,0 +1,50 @@
+package neuron
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math/rand"
+	"testing"
+)
+
+// Test the history recorded by Fit.
+func TestHistory(t *testing.T) {
+	rand.Seed(18)
+	n := MustNewMLP([]int{2, 4, 1}, NewSGD(0.05, 0.0, 0.0))
+	defer n.Stop()
+	loss, _ := GetLoss("mse")
+	tr := &Trainer{Net: n, Loss: loss, Data: linearData(10), BatchSize: 4,
+		Validation: linearData(5)}
+	hist, err := tr.Fit(2)
+	if err != nil {
+		t.Fatalf("Fit returned an error: %v", err)
+	}
+	if len(hist.Batches) != 6 || len(hist.Epochs) != 2 || len(hist.Validations) != 2 {
+		t.Fatalf("History has %d batches, %d epochs, and %d validations; expected 6, 2, 2",
+			len(hist.Batches), len(hist.Epochs), len(hist.Validations))
+	}
+	last := hist.Batches[5]
+	if last.Epoch != 1 || last.Batch != 2 || last.Step != 20 || last.LR != 0.05 {
+		t.Errorf("Last batch record is %+v", last)
+	}
+	if hist.Epochs[1].Time < hist.Epochs[0].Time {
+		t.Errorf("Epoch times %v, %v aren't increasing", hist.Epochs[0].Time,
+			hist.Epochs[1].Time)
+	}
+
+	var buf bytes.Buffer
+	if err := hist.ExportCSV(&buf); err != nil {
+		t.Fatalf("ExportCSV returned an error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Reading CSV failed: %v", err)
+	}
+	if len(rows) != 1+10 {
+		t.Fatalf("Got %d CSV rows; expected 11", len(rows))
+	}
+	if rows[1][0] != "batch" || rows[10][0] != "validation" || rows[10][7] == "" ||
+		rows[1][7] != "" {
+		t.Errorf("Unexpected CSV rows %v, %v", rows[1], rows[10])
+	}
+}
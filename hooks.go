@@ -0,0 +1,170 @@
+package neuron
+
+import (
+	"fmt"
+)
+
+// A ForwardInfo describes a unit's forward pass, see RegisterForwardHook.
+type ForwardInfo struct {
+	ID string
+	// Inputs to the unit's trainable connection weights, keyed by upstream
+	// unit ID.
+	Inputs map[string]float64
+	// Pre is the pre-activation, including the bias and any noise, and Act
+	// the activation.
+	Pre, Act float64
+}
+
+// A ForwardHook is called after a unit computes its activation.
+type ForwardHook func(info *ForwardInfo)
+
+// A BackwardInfo describes a unit's backward pass, see RegisterBackwardHook.
+type BackwardInfo struct {
+	ID       string
+	Pre, Act float64
+	// Grad is the gradient of the loss with respect to the activation. Hooks
+	// can change it, e.g. to add the gradient of an activation penalty, before
+	// it's back-propagated.
+	Grad float64
+}
+
+// A BackwardHook is called when a unit receives the gradient of its
+// activation, before back-propagating it.
+type BackwardHook func(info *BackwardInfo)
+
+// RegisterForwardHook adds a hook called on every forward pass of the unit,
+// e.g. to log activations. Hooks are called in the order they're registered,
+// from the unit's goroutine, so hooks of different units can run
+// concurrently. They aren't called by the dense engine. Hooks should only be
+// registered while the network is idle.
+func (u *Unit) RegisterForwardHook(h ForwardHook) {
+	u.fwdHooks = append(u.fwdHooks, h)
+}
+
+// RegisterBackwardHook adds a hook called on every backward pass of the unit.
+// Like forward hooks, they're called in order from the unit's goroutine.
+func (u *Unit) RegisterBackwardHook(h BackwardHook) {
+	u.bwdHooks = append(u.bwdHooks, h)
+}
+
+// ClearHooks removes the unit's forward and backward hooks.
+func (u *Unit) ClearHooks() {
+	u.fwdHooks, u.bwdHooks = nil, nil
+}
+
+// RegisterForwardHook registers a forward hook on every unit in a layer, see
+// Unit.RegisterForwardHook.
+func (n *Net) RegisterForwardHook(layer int, h ForwardHook) error {
+	if layer < 0 || layer >= len(n.Layers) {
+		return fmt.Errorf("layer %d out of range", layer)
+	}
+	for _, u := range n.Layers[layer] {
+		u.RegisterForwardHook(h)
+	}
+	return nil
+}
+
+// RegisterBackwardHook registers a backward hook on every unit in a layer, see
+// Unit.RegisterBackwardHook.
+func (n *Net) RegisterBackwardHook(layer int, h BackwardHook) error {
+	if layer < 0 || layer >= len(n.Layers) {
+		return fmt.Errorf("layer %d out of range", layer)
+	}
+	for _, u := range n.Layers[layer] {
+		u.RegisterBackwardHook(h)
+	}
+	return nil
+}
+
+// ClearHooks removes the hooks of every unit.
+func (n *Net) ClearHooks() {
+	for _, l := range n.Layers {
+		for _, u := range l {
+			u.ClearHooks()
+		}
+	}
+}
+
+// forwardHooks calls the unit's forward hooks.
+func (u *Unit) forwardHooks() {
+	if len(u.fwdHooks) == 0 {
+		return
+	}
+	info := ForwardInfo{
+		ID:     u.ID,
+		Inputs: make(map[string]float64, u.nin),
+		Pre:    u.pre,
+		Act:    u.act,
+	}
+	for k, p := range u.W.Params {
+		if isConn(k) && p.RequiresGrad {
+			info.Inputs[k] = p.value
+		}
+	}
+	for _, h := range u.fwdHooks {
+		h(&info)
+	}
+}
+
+// backwardHooks calls the unit's backward hooks, and returns the possibly
+// changed gradient.
+func (u *Unit) backwardHooks(grad float64) float64 {
+	if len(u.bwdHooks) == 0 {
+		return grad
+	}
+	info := BackwardInfo{ID: u.ID, Pre: u.pre, Act: u.act, Grad: grad}
+	for _, h := range u.bwdHooks {
+		h(&info)
+	}
+	return info.Grad
+}
+
+// Activations returns a snapshot of the activation of every unit in the most
+// recent forward pass, keyed by unit ID, e.g. to extract hidden layer
+// features. It should only be called while the network is idle, e.g. after
+// Forward returns. Use ForwardWithActivations to capture the activations of a
+// pass when other goroutines share the network. Activations aren't recorded
+// by the dense engine.
+func (n *Net) Activations() map[string]float64 {
+	acts := make(map[string]float64)
+	for _, l := range n.Layers {
+		for _, u := range l {
+			acts[u.ID] = u.act
+		}
+	}
+	return acts
+}
+
+// LayerActivations returns the activations of the units in a layer in the
+// most recent forward pass, in unit order. See Activations.
+func (n *Net) LayerActivations(layer int) ([]float64, error) {
+	if layer < 0 || layer >= len(n.Layers) {
+		return nil, fmt.Errorf("layer %d out of range", layer)
+	}
+	acts := make([]float64, len(n.Layers[layer]))
+	for jj, u := range n.Layers[layer] {
+		acts[jj] = u.act
+	}
+	return acts, nil
+}
+
+// InputGradients returns the gradient of the loss with respect to each input
+// of the most recent backward pass, e.g. for saliency maps or adversarial
+// examples. Like Activations, it should only be called while the network is
+// idle, e.g. after Backward returns. In sequence mode it's the gradient of the
+// first step's input. It returns an error after a batch, since the gradients
+// of each sample aren't kept. The gradients of categorical inputs, see
+// WithEmbedding, are 0.
+func (n *Net) InputGradients() ([]float64, error) {
+	if n.batch > 1 {
+		return nil, fmt.Errorf("input gradients after a batch of %d samples", n.batch)
+	}
+	grads := make([]float64, n.inputDim())
+	idx := n.inputIndex()
+	for jj, u := range n.Layers[0] {
+		if u.embed == nil {
+			grads[idx[jj]] = u.inGrad
+		}
+	}
+	return grads, nil
+}
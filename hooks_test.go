@@ -0,0 +1,137 @@
+package neuron
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// Test that hooks see each unit's pass, in both engines.
+func TestHooks(t *testing.T) {
+	arch := []int{2, 3, 1}
+	data := []float64{1.0, -0.5}
+	for _, opts := range [][]Option{nil, {WithLayerEngine()}} {
+		rand.Seed(6)
+		n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), opts...)
+		var mu sync.Mutex
+		fwd := make(map[string]ForwardInfo)
+		n.RegisterForwardHook(1, func(info *ForwardInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			fwd[info.ID] = *info
+		})
+		var grad float64
+		n.RegisterBackwardHook(2, func(info *BackwardInfo) {
+			grad = info.Grad
+			info.Grad = 0.0
+		})
+		if err := n.RegisterForwardHook(3, func(*ForwardInfo) {}); err == nil {
+			t.Errorf("RegisterForwardHook did not return an error")
+		}
+
+		n.Start(true, 0)
+		n.MustForward(data)
+		n.MustBackward([]float64{2.0})
+		n.Stop()
+
+		if len(fwd) != 3 {
+			t.Fatalf("Forward hooks called for %d units; expected 3", len(fwd))
+		}
+		for _, u := range n.Layers[1] {
+			info := fwd[u.ID]
+			pre := u.W.Params[biasID].Data
+			for ii, u1 := range n.Layers[0] {
+				if info.Inputs[u1.ID] != data[ii] {
+					t.Errorf("Input %s of unit %s is %v; expected %v", u1.ID, u.ID,
+						info.Inputs[u1.ID], data[ii])
+				}
+				pre += data[ii] * u.W.Params[u1.ID].Data
+			}
+			if !almostEqual(info.Pre, pre) || info.Act != new(Relu).Forward(info.Pre) {
+				t.Errorf("Unit %s pre-activation and activation are %v, %v; expected %v",
+					u.ID, info.Pre, info.Act, pre)
+			}
+		}
+		if grad != 2.0 {
+			t.Errorf("Backward hook got gradient %v; expected 2", grad)
+		}
+		// The hook zeroed the output gradient.
+		for uid, g := range n.Grads() {
+			for id, v := range g {
+				if v != 0.0 {
+					t.Errorf("Grad[%s][%s] is %v; expected 0", uid, id, v)
+				}
+			}
+		}
+	}
+}
+
+// Test capturing the activations of a pass.
+func TestActivations(t *testing.T) {
+	rand.Seed(7)
+	n := MustNewMLP([]int{2, 3, 2}, NewSGD(0.1, 0.0, 0.0))
+	n.Start(false, 0)
+	defer n.Stop()
+	data := []float64{0.5, -1.0}
+	output, acts, err := n.ForwardWithActivations(data)
+	if err != nil {
+		t.Fatalf("ForwardWithActivations returned an error: %v", err)
+	}
+	if len(acts) != 7 {
+		t.Errorf("Got %d activations; expected 7", len(acts))
+	}
+	for jj, u := range n.Layers[0] {
+		if acts[u.ID] != data[jj] {
+			t.Errorf("Input activation %s is %v; expected %v", u.ID, acts[u.ID], data[jj])
+		}
+	}
+	for jj, u := range n.Layers[2] {
+		if acts[u.ID] != output[jj] {
+			t.Errorf("Output activation %s is %v; expected %v", u.ID, acts[u.ID],
+				output[jj])
+		}
+	}
+	hidden, _ := n.LayerActivations(1)
+	for jj, u := range n.Layers[1] {
+		if hidden[jj] != acts[u.ID] {
+			t.Errorf("Hidden activation %d is %v; expected %v", jj, hidden[jj], acts[u.ID])
+		}
+	}
+	if _, err := n.LayerActivations(3); err == nil {
+		t.Errorf("LayerActivations did not return an error")
+	}
+}
+
+// Test input gradients against finite differences, in both engines.
+func TestInputGradients(t *testing.T) {
+	data := []float64{0.5, -1.0, 0.3}
+	for _, opts := range [][]Option{nil, {WithLayerEngine()}} {
+		rand.Seed(8)
+		opts = append(opts, WithInitializer(XavierNormal{}),
+			WithUnitKinds([]string{InputKind, TanhKind, OutputKind}))
+		n := MustNewMLP([]int{3, 4, 1}, NewSGD(0.1, 0.0, 0.0), opts...)
+		n.Start(true, 0)
+		// Loss is the output, so its gradient is 1.
+		n.MustForward(data)
+		n.MustBackward([]float64{1.0})
+		grads, err := n.InputGradients()
+		if err != nil {
+			t.Fatalf("InputGradients returned an error: %v", err)
+		}
+		n.Stop()
+
+		n.Start(false, 0)
+		const eps = 1.0e-05
+		for ii := range data {
+			x := append([]float64(nil), data...)
+			x[ii] = data[ii] + eps
+			plus := n.MustForward(x)[0]
+			x[ii] = data[ii] - eps
+			minus := n.MustForward(x)[0]
+			if want := (plus - minus) / (2 * eps); !almostEqualTol(grads[ii], want, 1.0e-06) {
+				t.Errorf("Input gradient %d is %v; expected %v", ii, grads[ii], want)
+			}
+		}
+		n.Stop()
+	}
+}
@@ -0,0 +1,202 @@
+package neuron
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// An Initializer draws the initial connection weights of a layer. fanIn is
+// the number of units feeding into the receiving unit, and fanOut the number
+// of units in the receiving layer.
+type Initializer interface {
+	Init(rng *rand.Rand, fanIn, fanOut int) float64
+}
+
+// InitializerFunc adapts a function to an Initializer.
+type InitializerFunc func(rng *rand.Rand, fanIn, fanOut int) float64
+
+// Init calls f.
+func (f InitializerFunc) Init(rng *rand.Rand, fanIn, fanOut int) float64 {
+	return f(rng, fanIn, fanOut)
+}
+
+// Uniform draws weights uniformly from [Min, Max). The default initializer
+// is Uniform{-0.01, 0.01}.
+type Uniform struct {
+	Min, Max float64
+}
+
+// Init draws a weight.
+func (i Uniform) Init(rng *rand.Rand, fanIn, fanOut int) float64 {
+	return i.Min + (i.Max-i.Min)*rng.Float64()
+}
+
+// Constant sets every weight to Value.
+type Constant struct {
+	Value float64
+}
+
+// Init returns Value.
+func (i Constant) Init(rng *rand.Rand, fanIn, fanOut int) float64 {
+	return i.Value
+}
+
+// XavierUniform draws weights uniformly from [-a, a) with
+// a = sqrt(6 / (fanIn + fanOut)), for sigmoid and tanh layers (Glorot and
+// Bengio, 2010).
+type XavierUniform struct{}
+
+// Init draws a weight.
+func (XavierUniform) Init(rng *rand.Rand, fanIn, fanOut int) float64 {
+	a := math.Sqrt(6.0 / float64(fanIn+fanOut))
+	return Uniform{-a, a}.Init(rng, fanIn, fanOut)
+}
+
+// XavierNormal draws weights from N(0, 2 / (fanIn + fanOut)).
+type XavierNormal struct{}
+
+// Init draws a weight.
+func (XavierNormal) Init(rng *rand.Rand, fanIn, fanOut int) float64 {
+	return math.Sqrt(2.0/float64(fanIn+fanOut)) * rng.NormFloat64()
+}
+
+// HeUniform draws weights uniformly from [-a, a) with a = sqrt(6 / fanIn), for
+// ReLU layers (He et al., 2015).
+type HeUniform struct{}
+
+// Init draws a weight.
+func (HeUniform) Init(rng *rand.Rand, fanIn, fanOut int) float64 {
+	a := math.Sqrt(6.0 / float64(fanIn))
+	return Uniform{-a, a}.Init(rng, fanIn, fanOut)
+}
+
+// HeNormal draws weights from N(0, 2 / fanIn).
+type HeNormal struct{}
+
+// Init draws a weight.
+func (HeNormal) Init(rng *rand.Rand, fanIn, fanOut int) float64 {
+	return math.Sqrt(2.0/float64(fanIn)) * rng.NormFloat64()
+}
+
+// WithInitializer sets the initializer of the connection weights of every
+// layer, instead of the default Uniform{-0.01, 0.01}.
+func WithInitializer(init Initializer) Option {
+	return func(c *netConfig) {
+		c.init = init
+	}
+}
+
+// WithLayerInitializer sets the initializer of the incoming connection
+// weights of a single layer, overriding WithInitializer.
+func WithLayerInitializer(layer int, init Initializer) Option {
+	return func(c *netConfig) {
+		if c.layerInits == nil {
+			c.layerInits = make(map[int]Initializer)
+		}
+		c.layerInits[layer] = init
+	}
+}
+
+// biasSetting is the bias of a layer's units, see WithBias and WithoutBias.
+type biasSetting struct {
+	off   bool
+	value float64
+}
+
+// WithBias sets the initial bias of the units of a layer, instead of the
+// default of the layer's unit kind, e.g. 0.1 for HiddenKind and 0 for
+// OutputKind. Units without a bias, like input units, get one.
+func WithBias(layer int, value float64) Option {
+	return func(c *netConfig) {
+		if c.biases == nil {
+			c.biases = make(map[int]biasSetting)
+		}
+		c.biases[layer] = biasSetting{value: value}
+	}
+}
+
+// WithoutBias removes the bias of the units of each of layers, e.g. for
+// layers followed by a normalization, or to constrain the network to pass
+// through the origin.
+func WithoutBias(layers ...int) Option {
+	return func(c *netConfig) {
+		if c.biases == nil {
+			c.biases = make(map[int]biasSetting)
+		}
+		for _, ii := range layers {
+			c.biases[ii] = biasSetting{off: true}
+		}
+	}
+}
+
+// checkInits checks the initializer and bias settings.
+func checkInits(c *netConfig, numLayers int) error {
+	for ii := range c.layerInits {
+		if ii < 1 || ii >= numLayers {
+			return fmt.Errorf("initializer layer %d out of range", ii)
+		}
+	}
+	for ii := range c.biases {
+		if ii < 1 || ii >= numLayers {
+			return fmt.Errorf("bias layer %d out of range", ii)
+		}
+	}
+	return nil
+}
+
+// layerInitializers returns the initializer of each layer, or nil for the
+// default.
+func (c *netConfig) layerInitializers(numLayers int) []Initializer {
+	inits := make([]Initializer, numLayers)
+	for ii := 1; ii < numLayers; ii++ {
+		inits[ii] = c.init
+		if init, ok := c.layerInits[ii]; ok {
+			inits[ii] = init
+		}
+	}
+	return inits
+}
+
+// initUnit draws the incoming connection weights of unit u in layer with the
+// layer's initializer, and sets its bias. Units in layers without an
+// initializer keep the default weights drawn by connect, and units in layers
+// without a bias setting keep the default bias of their kind.
+func (n *Net) initUnit(layer int, u *Unit) {
+	if b, ok := n.biases[layer]; ok {
+		if b.off {
+			u.RemoveBias()
+		} else {
+			u.SetBias(b.value)
+		}
+	}
+	init := n.inits[layer]
+	if init == nil {
+		return
+	}
+	fanIn, fanOut := 0, len(n.Layers[layer])
+	for k := range u.W.Params {
+		if isConn(k) {
+			fanIn++
+		}
+	}
+	for _, k := range weightKeys(u.W) {
+		u.W.Params[k].Data = init.Init(n.rand(), fanIn, fanOut)
+		if u.cell != nil {
+			// Gate weights are drawn like the connection weight.
+			for _, p := range u.cell.w[k] {
+				p.Data = init.Init(n.rand(), fanIn, fanOut)
+			}
+		}
+	}
+}
+
+// globalRand draws from the global math/rand source, so that rand.Seed makes
+// weight init reproducible.
+var globalRand = rand.New(globalSource{})
+
+// globalSource is a rand.Source backed by the global math/rand functions.
+type globalSource struct{}
+
+func (globalSource) Int63() int64    { return rand.Int63() }
+func (globalSource) Seed(seed int64) { rand.Seed(seed) }
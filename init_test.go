@@ -0,0 +1,127 @@
+package neuron
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Test that initializers draw weights with the expected scale.
+func TestInitializers(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const fanIn, fanOut, samples = 50, 20, 20000
+	for _, c := range []struct {
+		init Initializer
+		std  float64
+	}{
+		{XavierUniform{}, math.Sqrt(2.0 / (fanIn + fanOut))},
+		{XavierNormal{}, math.Sqrt(2.0 / (fanIn + fanOut))},
+		{HeUniform{}, math.Sqrt(2.0 / fanIn)},
+		{HeNormal{}, math.Sqrt(2.0 / fanIn)},
+		{Uniform{-1.0, 1.0}, 1.0 / math.Sqrt(3.0)},
+	} {
+		sum, sumSq := 0.0, 0.0
+		for ii := 0; ii < samples; ii++ {
+			w := c.init.Init(rng, fanIn, fanOut)
+			sum += w
+			sumSq += w * w
+		}
+		mean := sum / samples
+		std := math.Sqrt(sumSq/samples - mean*mean)
+		if math.Abs(mean) > 0.05*c.std || math.Abs(std-c.std) > 0.05*c.std {
+			t.Errorf("%T weights have mean %.4f and std %.4f; expected 0 and %.4f",
+				c.init, mean, std, c.std)
+		}
+	}
+	if w := (Constant{0.3}).Init(rng, fanIn, fanOut); w != 0.3 {
+		t.Errorf("Constant weight is %.2f; expected 0.3", w)
+	}
+}
+
+// Test per layer initializers in NewMLP.
+func TestWithInitializer(t *testing.T) {
+	arch := []int{3, 4, 2}
+	n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), WithInitializer(HeNormal{}),
+		WithLayerInitializer(2, Constant{0.5}), WithSkipConnections(0, 2))
+	for _, u := range n.Layers[2] {
+		if len(weightKeys(u.W)) != 7 {
+			t.Errorf("Unit %s has %d weights; expected 7", u.ID, len(weightKeys(u.W)))
+		}
+		for _, k := range weightKeys(u.W) {
+			if w := u.W.Params[k].Data; w != 0.5 {
+				t.Errorf("Weight %s/%s is %.4f; expected 0.5", u.ID, k, w)
+			}
+		}
+	}
+	big := false
+	for _, u := range n.Layers[1] {
+		for _, k := range weightKeys(u.W) {
+			if math.Abs(u.W.Params[k].Data) > 0.05 {
+				big = true
+			}
+		}
+	}
+	if !big {
+		t.Errorf("Layer 1 weights look like the default initializer")
+	}
+
+	// New units use the layer's initializer.
+	if err := n.ReplaceOutputLayer(3, nil); err != nil {
+		t.Fatal(err)
+	}
+	if w := n.Layers[2][2].W.Params[unitID(1, 0)].Data; w != 0.5 {
+		t.Errorf("Replaced output weight is %.4f; expected 0.5", w)
+	}
+
+	// Custom initializers see the fan-in and fan-out.
+	var gotIn, gotOut int
+	MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), WithLayerInitializer(1,
+		InitializerFunc(func(rng *rand.Rand, fanIn, fanOut int) float64 {
+			gotIn, gotOut = fanIn, fanOut
+			return 0.0
+		})))
+	if gotIn != 3 || gotOut != 4 {
+		t.Errorf("Fan-in and fan-out are %d and %d; expected 3 and 4", gotIn, gotOut)
+	}
+
+	if _, err := NewMLP(arch, NewSGD(0.1, 0.0, 0.0),
+		WithLayerInitializer(0, HeNormal{})); err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+}
+
+// Test bias-free layers and custom initial biases.
+func TestBias(t *testing.T) {
+	for _, layers := range []bool{false, true} {
+		opts := []Option{WithoutBias(1), WithBias(2, -0.5)}
+		if layers {
+			opts = append(opts, WithLayerEngine())
+		}
+		n := MustNewMLP([]int{2, 3, 1}, NewSGD(0.1, 0.0, 0.0), opts...)
+		for _, u := range n.Layers[1] {
+			if _, ok := u.W.Params[biasID]; ok {
+				t.Errorf("Unit %s has a bias", u.ID)
+			}
+		}
+		if b := n.Layers[2][0].W.Params[biasID]; b == nil || b.Data != -0.5 {
+			t.Errorf("Output bias is %v; expected -0.5", b)
+		}
+
+		// Without a bias, a zero input gives the output bias.
+		n.Start(true, 1)
+		out := n.MustForward([]float64{0.0, 0.0})
+		n.MustBackward([]float64{1.0})
+		n.Stop()
+		if out[0] != -0.5 {
+			t.Errorf("(layers=%v) Output for zero input is %.3f; expected -0.5", layers,
+				out[0])
+		}
+		if b := n.Layers[2][0].W.Params[biasID].Data; b >= -0.5 {
+			t.Errorf("(layers=%v) Output bias %.3f wasn't updated", layers, b)
+		}
+	}
+
+	if _, err := NewMLP([]int{2, 3, 1}, NewSGD(0.1, 0.0, 0.0), WithoutBias(3)); err == nil {
+		t.Errorf("Expected an error for an out of range bias layer")
+	}
+}
@@ -0,0 +1,326 @@
+package neuron
+
+import (
+	"runtime"
+)
+
+// WithLayerEngine runs each layer as a single goroutine instead of running a
+// goroutine per unit. A layer receives the activations of the layer below as
+// a single vector and computes the activations of all its units with one
+// matrix-vector product, so a pass takes a few channel operations per layer
+// instead of one per connection, which is much faster for wide layers. Units,
+// their weights, and the rest of the Net API are unchanged.
+//
+// The engine only applies to networks started with Start; StartSequence still
+// runs a goroutine per unit. Unlike units, layers are not supervised, and the
+// watchdog can't report which layer a pass is blocked on.
+func WithLayerEngine() Option {
+	return func(c *netConfig) {
+		c.layers = true
+	}
+}
+
+// A layerRunner runs the units of a single layer, see WithLayerEngine.
+type layerRunner struct {
+	units []*Unit
+	// IDs of the units in the layers below with connections into the layer,
+	// and the index of each ID. Usually these are just the units of the
+	// previous layer.
+	prev  []string
+	index map[string]int
+	// Connection weights of each unit, indexed like prev. Missing connections
+	// are nil.
+	params [][]*Param
+	// Activations from the connected layers below, in layer order, and to the
+	// connected layers above. Similarly, gradients from the layers above and to
+	// the layers below, where the gradients to the layers below are split
+	// into the segments of prev at offsets. The input and output layers have
+	// no channels on one side. Their units exchange signals with the network
+	// instead.
+	in, out   []chan []float64
+	inB, outB []chan []float64
+	offsets   []int
+	quit      chan struct{}
+	// Shared worker pool, see WithWorkers. nil if the layer runs its units
+	// itself.
+	pool *workerPool
+}
+
+// layerRunners builds a runner for each layer, connected by vector channels.
+func (n *Net) layerRunners() []*layerRunner {
+	runners := make([]*layerRunner, len(n.Layers))
+	for ii, l := range n.Layers {
+		r := &layerRunner{units: l, quit: n.quit}
+		if ii > 0 {
+			r.index = make(map[string]int)
+			for _, jj := range n.upstream(ii) {
+				below := runners[jj]
+				out, outB := make(chan []float64), make(chan []float64)
+				below.out, below.inB = append(below.out, out), append(below.inB, outB)
+				r.in, r.outB = append(r.in, out), append(r.outB, outB)
+				r.offsets = append(r.offsets, len(r.prev))
+				for _, u := range n.Layers[jj] {
+					r.index[u.ID] = len(r.prev)
+					r.prev = append(r.prev, u.ID)
+				}
+			}
+			r.params = make([][]*Param, len(l))
+			for jj, u := range l {
+				r.params[jj] = make([]*Param, len(r.prev))
+				for kk, id := range r.prev {
+					r.params[jj][kk] = u.W.Params[id]
+				}
+			}
+		}
+		runners[ii] = r
+	}
+	return runners
+}
+
+// runLayers starts a goroutine running each layer's loop, after calling init
+// for each unit.
+func (n *Net) runLayers(init func(u *Unit), updateFreq int) {
+	for _, l := range n.Layers {
+		for _, u := range l {
+			n.setup(u)
+			init(u)
+		}
+	}
+	runners := n.layerRunners()
+	if n.workers > 0 {
+		pool := n.startWorkers()
+		for _, r := range runners {
+			r.pool = pool
+		}
+	}
+	for _, r := range runners {
+		n.units.Add(1)
+		go func(r *layerRunner) {
+			defer n.units.Done()
+			r.start(updateFreq)
+		}(r)
+	}
+}
+
+// start starts an endless loop of forward and backward passes through the
+// layer, like Unit.start. Each unit signals done at the end of a pass.
+func (r *layerRunner) start(updateFreq int) {
+	for {
+		r.iterate(updateFreq)
+		for _, u := range r.units {
+			u.done()
+		}
+	}
+}
+
+// iterate runs a single forward/backward/step iteration over all units of
+// the layer, like Unit.iterate.
+func (r *layerRunner) iterate(updateFreq int) {
+	r.setTag(0)
+	r.forward()
+	train, batch := *r.units[0].train, *r.units[0].batch
+	for k := 1; k < batch; k++ {
+		if train {
+			for _, u := range r.units {
+				u.record()
+			}
+		}
+		r.setTag(k)
+		r.forward()
+	}
+	if !train {
+		return
+	}
+
+	for k := batch - 1; k >= 0; k-- {
+		if k < batch-1 {
+			for _, u := range r.units {
+				u.rewind()
+			}
+		}
+		r.setTag(k)
+		r.backward()
+		// See Unit.backwardBatch.
+		if k > 0 && len(r.outB) == 0 && !r.units[0].pipeline {
+			for _, u := range r.units {
+				u.done()
+			}
+		}
+	}
+	r.each(func(jj int) {
+		u := r.units[jj]
+		steps := u.steps
+		u.steps += batch
+		if updateFreq > 0 && u.steps/updateFreq > steps/updateFreq && !u.netStep {
+			u.step()
+			u.save()
+		}
+	})
+}
+
+// each calls f for the index of every unit in the layer, on the worker pool if
+// there is one, and waits for the calls to return.
+func (r *layerRunner) each(f func(jj int)) {
+	if r.pool == nil {
+		for jj := range r.units {
+			f(jj)
+		}
+		return
+	}
+	r.pool.run(len(r.units), f)
+}
+
+// setTag sets the sample of the batch each unit is working on.
+func (r *layerRunner) setTag(tag int) {
+	for _, u := range r.units {
+		u.tag = tag
+	}
+}
+
+// forward runs the forward pass of every unit in the layer.
+func (r *layerRunner) forward() {
+	var x []float64
+	if len(r.in) == 0 {
+		// Input units each receive a single input from the network.
+		x = make([]float64, len(r.units))
+		for jj, u := range r.units {
+			x[jj] = u.recvSample(u.input).value
+		}
+	} else if len(r.in) == 1 {
+		x = r.recv(r.in[0])
+	} else {
+		x = make([]float64, 0, len(r.prev))
+		for _, c := range r.in {
+			x = append(x, r.recv(c)...)
+		}
+	}
+
+	act := make([]float64, len(r.units))
+	r.each(func(jj int) {
+		u := r.units[jj]
+		u.W.ready = false
+		if len(r.in) == 0 {
+			act[jj] = u.activate(u.weighInput(x[jj]))
+		} else {
+			act[jj] = u.activate(r.dot(jj, x))
+		}
+	})
+
+	if len(r.out) == 0 {
+		for jj, u := range r.units {
+			for _, c := range u.output {
+				u.send(c, signal{id: u.ID, value: act[jj], tag: u.tag})
+			}
+		}
+		return
+	}
+	// The layers above only read the activations, so they can share them.
+	for _, c := range r.out {
+		r.send(c, act)
+	}
+}
+
+// dot computes the weighted input of unit jj from the activations x of the
+// layer below.
+func (r *layerRunner) dot(jj int, x []float64) float64 {
+	u := r.units[jj]
+	act := 0.0
+	if u.W.reparam() {
+		for kk, id := range r.prev {
+			act += u.W.forward(id, x[kk])
+		}
+		return act
+	}
+	// Same as Weight.forward, without the map lookups.
+	for kk, p := range r.params[jj] {
+		if p == nil || p.masked {
+			continue
+		}
+		if p.RequiresGrad {
+			p.value = x[kk]
+		}
+		p.pullTied()
+		act += p.Data * x[kk]
+	}
+	return act
+}
+
+// backward runs the backward pass of every unit in the layer.
+func (r *layerRunner) backward() {
+	var grad []float64
+	if len(r.inB) == 0 {
+		// Output units each receive a single gradient from the network.
+		grad = make([]float64, len(r.units))
+		for jj, u := range r.units {
+			grad[jj] = u.recvSample(u.inputB).value
+		}
+	} else {
+		// Gradients flow in the opposite order of activations.
+		grad = make([]float64, len(r.units))
+		for ii := len(r.inB) - 1; ii >= 0; ii-- {
+			for jj, g := range r.recv(r.inB[ii]) {
+				grad[jj] += g
+			}
+		}
+	}
+
+	var gradIn []float64
+	var rows [][]float64
+	if len(r.outB) > 0 {
+		gradIn = make([]float64, len(r.prev))
+		if r.pool != nil {
+			rows = make([][]float64, len(r.units))
+		}
+	}
+	r.each(func(jj int) {
+		// Units running on different workers accumulate their input gradients
+		// separately.
+		acc := gradIn
+		if rows != nil {
+			acc = make([]float64, len(r.prev))
+			rows[jj] = acc
+		}
+		r.units[jj].backpropTo(grad[jj], func(k string, gradi float64) {
+			if kk, ok := r.index[k]; ok {
+				acc[kk] += gradi
+			}
+		})
+	})
+
+	if len(r.outB) > 0 {
+		for _, row := range rows {
+			for kk, g := range row {
+				gradIn[kk] += g
+			}
+		}
+		for ii := len(r.outB) - 1; ii >= 0; ii-- {
+			end := len(gradIn)
+			if ii+1 < len(r.offsets) {
+				end = r.offsets[ii+1]
+			}
+			r.send(r.outB[ii], gradIn[r.offsets[ii]:end])
+		}
+	}
+}
+
+// recv receives a vector from c. If the network is stopped while waiting, the
+// layer's goroutine exits.
+func (r *layerRunner) recv(c chan []float64) []float64 {
+	select {
+	case x := <-c:
+		return x
+	case <-r.quit:
+		runtime.Goexit()
+	}
+	panic("unreachable")
+}
+
+// send sends a vector on c. If the network is stopped while waiting, the
+// layer's goroutine exits.
+func (r *layerRunner) send(c chan []float64, x []float64) {
+	select {
+	case c <- x:
+	case <-r.quit:
+		runtime.Goexit()
+	}
+}
@@ -0,0 +1,100 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test that the layer engine computes the same outputs and updates as the
+// unit engine.
+func TestLayerEngine(t *testing.T) {
+	arch := []int{2, 4, 3, 2}
+	data := [][]float64{{1.0, -1.0}, {0.5, 2.0}, {-1.5, 0.3}, {0.1, 0.1}}
+	grads := [][]float64{{1.0, 0.0}, {-0.5, 1.0}, {0.2, 0.3}, {1.0, -1.0}}
+	for _, opts := range [][]Option{nil, {WithSoftmaxOutput()}, {WithWeightNorm()},
+		{WithMixedLayer(1, HiddenKind, TanhKind)}, {WithPipelining()},
+		{WithWorkers(3)}, {WithWorkers(0), WithPipelining()}} {
+		rand.Seed(7)
+		n := MustNewMLP(arch, NewSGD(0.1, 0.9, 0.0), opts...)
+		rand.Seed(7)
+		nl := MustNewMLP(arch, NewSGD(0.1, 0.9, 0.0), append(opts, WithLayerEngine())...)
+		n.Start(true, len(data))
+		nl.Start(true, len(data))
+
+		check := func(got, want []float64) {
+			for ii := range want {
+				if !almostEqual(got[ii], want[ii]) {
+					t.Errorf("Layer engine output %d is %.6f; expected %.6f", ii,
+						got[ii], want[ii])
+				}
+			}
+		}
+		for epoch := 0; epoch < 2; epoch++ {
+			for ii := range data {
+				check(nl.MustForward(data[ii]), n.MustForward(data[ii]))
+				n.MustBackward(grads[ii])
+				nl.MustBackward(grads[ii])
+			}
+		}
+
+		// Batches.
+		out, err := nl.ForwardBatch(data)
+		if err != nil {
+			t.Fatalf("ForwardBatch failed: %v", err)
+		}
+		if err := nl.BackwardBatch(grads); err != nil {
+			t.Fatalf("BackwardBatch failed: %v", err)
+		}
+		for ii := range data {
+			check(out[ii], n.MustForward(data[ii]))
+			n.MustBackward(grads[ii])
+		}
+
+		theta, thetaL := n.Data(), nl.Data()
+		for uid, ud := range theta {
+			for id, v := range ud {
+				if !almostEqual(thetaL[uid][id], v) {
+					t.Errorf("Param %s/%s is %.6f; expected %.6f", uid, id,
+						thetaL[uid][id], v)
+				}
+			}
+		}
+
+		// Evaluation mode.
+		n.Eval()
+		nl.Eval()
+		for ii := range data {
+			check(nl.MustForward(data[ii]), n.MustForward(data[ii]))
+		}
+		n.Stop()
+		nl.Stop()
+	}
+}
+
+func BenchmarkLayerEngine(b *testing.B) {
+	Verbosity = 0
+	arch := []int{64, 128, 128, 1}
+	input := make([]float64, arch[0])
+	for ii := range input {
+		input[ii] = rand.Float64()
+	}
+	grad := []float64{1.0}
+	for _, layers := range []bool{false, true} {
+		var opts []Option
+		name := "Units"
+		if layers {
+			opts = append(opts, WithLayerEngine())
+			name = "Layers"
+		}
+		b.Run(name, func(b *testing.B) {
+			n := MustNewMLP(arch, NewSGD(0.0, 0.0, 0.0), opts...)
+			n.Start(true, 1)
+			defer n.Stop()
+			b.ResetTimer()
+			for ii := 0; ii < b.N; ii++ {
+				n.MustForward(input)
+				n.MustBackward(grad)
+			}
+		})
+	}
+}
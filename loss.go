@@ -3,12 +3,13 @@ package neuron
 import (
 	"fmt"
 	"math"
+	"sync"
 )
 
 // MarginLoss computes the maximum-margin SVM loss and its derivative.
-func MarginLoss(score float64, target int) (loss float64, grad float64) {
-	if !(target == 1 || target == -1) {
-		panic(fmt.Sprintf("Expected target +/- 1; got %d", target))
+func MarginLoss(score float64, target int) (loss float64, grad float64, err error) {
+	if err := checkBinary(target); err != nil {
+		return 0.0, 0.0, err
 	}
 
 	targetf := float64(target)
@@ -20,3 +21,489 @@ func MarginLoss(score float64, target int) (loss float64, grad float64) {
 	}
 	return
 }
+
+// MustMarginLoss is like MarginLoss but panics if the target isn't +/- 1.
+func MustMarginLoss(score float64, target int) (loss float64, grad float64) {
+	loss, grad, err := MarginLoss(score, target)
+	if err != nil {
+		panic(err.Error())
+	}
+	return loss, grad
+}
+
+// SquaredHingeLoss computes the squared hinge loss max(1 - score*target, 0)^2
+// for target +/- 1 and its derivative. Unlike MarginLoss, it's smooth at the
+// margin.
+func SquaredHingeLoss(score float64, target int) (loss float64, grad float64, err error) {
+	if err := checkBinary(target); err != nil {
+		return 0.0, 0.0, err
+	}
+
+	targetf := float64(target)
+	margin := math.Max(1.0-score*targetf, 0.0)
+	return margin * margin, -2.0 * targetf * margin, nil
+}
+
+// MustSquaredHingeLoss is like SquaredHingeLoss but panics if the target isn't
+// +/- 1.
+func MustSquaredHingeLoss(score float64, target int) (loss float64, grad float64) {
+	loss, grad, err := SquaredHingeLoss(score, target)
+	if err != nil {
+		panic(err.Error())
+	}
+	return loss, grad
+}
+
+// LogisticLoss computes the logistic loss log(1 + exp(-score*target)) for
+// target +/- 1 and its derivative.
+func LogisticLoss(score float64, target int) (loss float64, grad float64, err error) {
+	if err := checkBinary(target); err != nil {
+		return 0.0, 0.0, err
+	}
+
+	targetf := float64(target)
+	z := score * targetf
+	loss = softplus(-z)
+	grad = -targetf / (1.0 + math.Exp(z))
+	return
+}
+
+// MustLogisticLoss is like LogisticLoss but panics if the target isn't +/- 1.
+func MustLogisticLoss(score float64, target int) (loss float64, grad float64) {
+	loss, grad, err := LogisticLoss(score, target)
+	if err != nil {
+		panic(err.Error())
+	}
+	return loss, grad
+}
+
+// checkBinary checks for a binary target +/- 1.
+func checkBinary(target int) error {
+	if !(target == 1 || target == -1) {
+		return fmt.Errorf("expected target +/- 1; got %d", target)
+	}
+	return nil
+}
+
+// MSELoss computes the squared error loss (score - target)^2 and its
+// derivative.
+func MSELoss(score float64, target float64) (loss float64, grad float64) {
+	diff := score - target
+	return diff * diff, 2.0 * diff
+}
+
+// MAELoss computes the absolute error loss |score - target| and its
+// derivative.
+func MAELoss(score float64, target float64) (loss float64, grad float64) {
+	diff := score - target
+	switch {
+	case diff > 0:
+		grad = 1.0
+	case diff < 0:
+		grad = -1.0
+	}
+	return math.Abs(diff), grad
+}
+
+// MSELossVec computes the mean squared error over the outputs of a
+// multi-output network and its gradient.
+func MSELossVec(scores []float64, targets []float64) (loss float64, grad []float64,
+	err error) {
+	return meanLoss(MSELoss, scores, targets)
+}
+
+// MustMSELossVec is like MSELossVec but panics if the sizes of scores and
+// targets differ.
+func MustMSELossVec(scores []float64, targets []float64) (loss float64, grad []float64) {
+	loss, grad, err := MSELossVec(scores, targets)
+	if err != nil {
+		panic(err.Error())
+	}
+	return loss, grad
+}
+
+// MAELossVec computes the mean absolute error over the outputs of a
+// multi-output network and its gradient.
+func MAELossVec(scores []float64, targets []float64) (loss float64, grad []float64,
+	err error) {
+	return meanLoss(MAELoss, scores, targets)
+}
+
+// MustMAELossVec is like MAELossVec but panics if the sizes of scores and
+// targets differ.
+func MustMAELossVec(scores []float64, targets []float64) (loss float64, grad []float64) {
+	loss, grad, err := MAELossVec(scores, targets)
+	if err != nil {
+		panic(err.Error())
+	}
+	return loss, grad
+}
+
+// meanLoss averages a scalar loss over the outputs.
+func meanLoss(f func(float64, float64) (float64, float64), scores []float64,
+	targets []float64) (loss float64, grad []float64, err error) {
+	if len(scores) != len(targets) {
+		return 0.0, nil, fmt.Errorf("got %d scores for %d targets", len(scores), len(targets))
+	}
+	grad = make([]float64, len(scores))
+	if len(scores) == 0 {
+		return
+	}
+	scale := 1.0 / float64(len(scores))
+	for ii := range scores {
+		l, g := f(scores[ii], targets[ii])
+		loss += scale * l
+		grad[ii] = scale * g
+	}
+	return
+}
+
+// CrossEntropyLoss computes the multi-class cross-entropy loss of raw output
+// scores, -log(softmax(scores)[target]), and its gradient with respect to each
+// score. The gradient can be passed straight to Net.Backward. scores should be
+// the raw scores of a network without WithSoftmaxOutput.
+func CrossEntropyLoss(scores []float64, target int) (loss float64, grad []float64,
+	err error) {
+	if target < 0 || target >= len(scores) {
+		return 0.0, nil, fmt.Errorf("expected target in [0, %d); got %d", len(scores), target)
+	}
+
+	grad = Softmax(scores, 1.0)
+	// log p[target], computed from the scores for numerical stability.
+	max := math.Inf(-1)
+	for _, s := range scores {
+		max = math.Max(max, s)
+	}
+	sum := 0.0
+	for _, s := range scores {
+		sum += math.Exp(s - max)
+	}
+	loss = max + math.Log(sum) - scores[target]
+	grad[target] -= 1.0
+	return loss, grad, nil
+}
+
+// MustCrossEntropyLoss is like CrossEntropyLoss but panics if the target is out
+// of range.
+func MustCrossEntropyLoss(scores []float64, target int) (loss float64, grad []float64) {
+	loss, grad, err := CrossEntropyLoss(scores, target)
+	if err != nil {
+		panic(err.Error())
+	}
+	return loss, grad
+}
+
+// FocalLoss computes the binary focal loss of a score for target +/- 1, and
+// its derivative. The score is the logit of the positive class probability p.
+// The loss
+//
+//	-alpha_t * (1 - p_t)^gamma * log(p_t)
+//
+// where p_t is the probability of the target class, down-weights well
+// classified samples, so training focuses on hard samples. alpha is the weight
+// of the positive class, and 1 - alpha the weight of the negative class, to
+// balance skewed class priors. With gamma = 0 and alpha = 0.5 it's half the
+// logistic loss.
+func FocalLoss(score float64, target int, gamma float64, alpha float64) (loss float64,
+	grad float64, err error) {
+	if err := checkBinary(target); err != nil {
+		return 0.0, 0.0, err
+	}
+
+	targetf := float64(target)
+	alphat := alpha
+	if target == -1 {
+		alphat = 1.0 - alpha
+	}
+	z := score * targetf
+	pt := 1.0 / (1.0 + math.Exp(-z))
+	// log(p_t) = -log(1 + exp(-z)), computed stably.
+	logpt := -softplus(-z)
+	loss = -alphat * math.Pow(1.0-pt, gamma) * logpt
+	dz := alphat * (gamma*math.Pow(1.0-pt, gamma)*pt*logpt - math.Pow(1.0-pt, gamma+1.0))
+	grad = targetf * dz
+	return
+}
+
+// MustFocalLoss is like FocalLoss but panics if the target isn't +/- 1.
+func MustFocalLoss(score float64, target int, gamma float64, alpha float64) (loss float64,
+	grad float64) {
+	loss, grad, err := FocalLoss(score, target, gamma, alpha)
+	if err != nil {
+		panic(err.Error())
+	}
+	return loss, grad
+}
+
+// softplus computes log(1 + exp(x)) without overflow.
+func softplus(x float64) float64 {
+	if x > 0 {
+		return x + math.Log1p(math.Exp(-x))
+	}
+	return math.Log1p(math.Exp(x))
+}
+
+// A Loss is a loss function over the outputs of a network. Forward computes
+// the loss of the scores for a target, and Backward returns the gradient with
+// respect to each score from the last Forward, to pass to Net.Backward.
+//
+// Regression losses take one target per score. Classification losses take a
+// single target, the class label: +/- 1 for binary losses, or the class index
+// for multi-class losses.
+//
+// Forward returns an error if the target isn't valid for the loss.
+type Loss interface {
+	Forward(scores []float64, target []float64) (float64, error)
+	Backward() []float64
+}
+
+// scalarLoss adapts a loss over a single output with a scalar target.
+type scalarLoss struct {
+	f    func(score float64, target float64) (float64, float64, error)
+	grad []float64
+}
+
+func (l *scalarLoss) Forward(scores []float64, target []float64) (float64, error) {
+	if len(scores) != 1 || len(target) != 1 {
+		return 0.0, fmt.Errorf("expected 1 score and target; got %d and %d",
+			len(scores), len(target))
+	}
+	loss, grad, err := l.f(scores[0], target[0])
+	if err != nil {
+		return 0.0, err
+	}
+	l.grad = []float64{grad}
+	return loss, nil
+}
+
+func (l *scalarLoss) Backward() []float64 {
+	return l.grad
+}
+
+// vecLoss adapts a loss over a vector of outputs.
+type vecLoss struct {
+	f    func(scores []float64, target []float64) (float64, []float64, error)
+	grad []float64
+}
+
+func (l *vecLoss) Forward(scores []float64, target []float64) (float64, error) {
+	loss, grad, err := l.f(scores, target)
+	if err != nil {
+		return 0.0, err
+	}
+	l.grad = grad
+	return loss, nil
+}
+
+func (l *vecLoss) Backward() []float64 {
+	return l.grad
+}
+
+// classLabel converts a target to an integer class label.
+func classLabel(target []float64) (int, error) {
+	if len(target) != 1 {
+		return 0, fmt.Errorf("expected a single class target; got %d", len(target))
+	}
+	return int(target[0]), nil
+}
+
+var (
+	lossesMu sync.RWMutex
+	losses   = map[string]func() Loss{
+		"mse": func() Loss { return &vecLoss{f: MSELossVec} },
+		"mae": func() Loss { return &vecLoss{f: MAELossVec} },
+		"margin": func() Loss {
+			return &scalarLoss{f: func(score, target float64) (float64, float64, error) {
+				return MarginLoss(score, int(target))
+			}}
+		},
+		"squared_hinge": func() Loss {
+			return &scalarLoss{f: func(score, target float64) (float64, float64, error) {
+				return SquaredHingeLoss(score, int(target))
+			}}
+		},
+		"logistic": func() Loss {
+			return &scalarLoss{f: func(score, target float64) (float64, float64, error) {
+				return LogisticLoss(score, int(target))
+			}}
+		},
+		"focal": func() Loss {
+			return &scalarLoss{f: func(score, target float64) (float64, float64, error) {
+				return FocalLoss(score, int(target), 2.0, 0.25)
+			}}
+		},
+		"cross_entropy": func() Loss {
+			return &vecLoss{f: func(scores, target []float64) (float64, []float64, error) {
+				class, err := classLabel(target)
+				if err != nil {
+					return 0.0, nil, err
+				}
+				return CrossEntropyLoss(scores, class)
+			}}
+		},
+	}
+)
+
+// RegisterLoss registers a loss under a name so that it can be selected by
+// name, e.g. from a config file. newLoss should return a new Loss each time.
+// Registering an existing name replaces it.
+func RegisterLoss(name string, newLoss func() Loss) {
+	lossesMu.Lock()
+	defer lossesMu.Unlock()
+	losses[name] = newLoss
+}
+
+// GetLoss returns a new instance of the loss registered under name. The
+// built-in losses are "mse", "mae", "margin", "squared_hinge", "logistic",
+// "focal" (with gamma = 2 and alpha = 0.25), and "cross_entropy".
+func GetLoss(name string) (loss Loss, ok bool) {
+	lossesMu.RLock()
+	newLoss, ok := losses[name]
+	lossesMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return newLoss(), true
+}
+
+// WeightedLoss scales a classification or regression loss, and its gradient,
+// by a per-sample weight and, for classification losses, a per-class weight.
+// This is useful for imbalanced datasets, e.g. weighting each class by its
+// inverse frequency.
+type WeightedLoss struct {
+	Loss Loss
+	// ClassWeights, if not nil, holds the weight of each class, indexed by
+	// class label. Binary targets -1 and +1 use indices 0 and 1.
+	ClassWeights []float64
+	grad         []float64
+}
+
+// NewWeightedLoss creates a new weighted loss.
+func NewWeightedLoss(loss Loss, classWeights []float64) *WeightedLoss {
+	return &WeightedLoss{Loss: loss, ClassWeights: classWeights}
+}
+
+// Forward computes the weighted loss with sample weight 1.
+func (l *WeightedLoss) Forward(scores []float64, target []float64) (float64, error) {
+	return l.ForwardWeighted(scores, target, 1.0)
+}
+
+// ForwardWeighted computes the loss weighted by sampleWeight and the weight
+// of the target class.
+func (l *WeightedLoss) ForwardWeighted(scores []float64, target []float64,
+	sampleWeight float64) (float64, error) {
+	w := sampleWeight
+	if l.ClassWeights != nil {
+		class, err := classLabel(target)
+		if err != nil {
+			return 0.0, err
+		}
+		if class == -1 {
+			class = 0
+		}
+		if class < 0 || class >= len(l.ClassWeights) {
+			return 0.0, fmt.Errorf("no weight for class %d", class)
+		}
+		w *= l.ClassWeights[class]
+	}
+
+	loss, err := l.Loss.Forward(scores, target)
+	if err != nil {
+		return 0.0, err
+	}
+	grad := l.Loss.Backward()
+	l.grad = make([]float64, len(grad))
+	for ii, g := range grad {
+		l.grad[ii] = w * g
+	}
+	return w * loss, nil
+}
+
+// Backward returns the weighted gradient from the last forward.
+func (l *WeightedLoss) Backward() []float64 {
+	return l.grad
+}
+
+// TripletLoss computes the triplet margin loss
+//
+//	max(||a - p||^2 - ||a - n||^2 + margin, 0)
+//
+// for embeddings of an anchor a, a positive p of the same class, and a
+// negative n of a different class. It returns the loss and the gradient with
+// respect to each embedding.
+//
+// To train a network on triplets, run it in sequence mode so each branch is
+// a step of the same window:
+//
+//	n.StartSequence(true, 1)
+//	out, err := n.ForwardSequence([][]float64{anchor, pos, neg})
+//	...
+//	_, ga, gp, gn, err := TripletLoss(out[0], out[1], out[2], 1.0)
+//	...
+//	err = n.BackwardSequence([][]float64{ga, gp, gn})
+func TripletLoss(anchor, pos, neg []float64, margin float64) (loss float64,
+	gradAnchor, gradPos, gradNeg []float64, err error) {
+	if len(pos) != len(anchor) || len(neg) != len(anchor) {
+		return 0.0, nil, nil, nil, fmt.Errorf("embedding dims differ: %d, %d, %d",
+			len(anchor), len(pos), len(neg))
+	}
+
+	dim := len(anchor)
+	gradAnchor = make([]float64, dim)
+	gradPos = make([]float64, dim)
+	gradNeg = make([]float64, dim)
+	loss = margin + sqDist(anchor, pos) - sqDist(anchor, neg)
+	if loss <= 0 {
+		return 0.0, gradAnchor, gradPos, gradNeg, nil
+	}
+	for ii := range anchor {
+		gradAnchor[ii] = 2.0 * (neg[ii] - pos[ii])
+		gradPos[ii] = -2.0 * (anchor[ii] - pos[ii])
+		gradNeg[ii] = 2.0 * (anchor[ii] - neg[ii])
+	}
+	return
+}
+
+// ContrastiveLoss computes the contrastive loss for a pair of embeddings a and
+// b: ||a - b||^2 for similar pairs, and max(margin - ||a - b||, 0)^2 for
+// dissimilar pairs. It returns the loss and the gradient with respect to each
+// embedding. See TripletLoss for training a network on pairs.
+func ContrastiveLoss(a, b []float64, similar bool, margin float64) (loss float64,
+	gradA, gradB []float64, err error) {
+	if len(a) != len(b) {
+		return 0.0, nil, nil, fmt.Errorf("embedding dims differ: %d, %d", len(a), len(b))
+	}
+
+	gradA = make([]float64, len(a))
+	gradB = make([]float64, len(b))
+	dist2 := sqDist(a, b)
+	var scale float64
+	if similar {
+		loss = dist2
+		scale = 2.0
+	} else {
+		dist := math.Sqrt(dist2)
+		gap := margin - dist
+		if gap <= 0 || dist == 0 {
+			// At dist 0 the gradient direction is undefined.
+			return math.Max(gap, 0.0) * math.Max(gap, 0.0), gradA, gradB, nil
+		}
+		loss = gap * gap
+		scale = -2.0 * gap / dist
+	}
+	for ii := range a {
+		gradA[ii] = scale * (a[ii] - b[ii])
+		gradB[ii] = -gradA[ii]
+	}
+	return
+}
+
+// sqDist computes the squared euclidean distance between a and b.
+func sqDist(a, b []float64) float64 {
+	d := 0.0
+	for ii := range a {
+		diff := a[ii] - b[ii]
+		d += diff * diff
+	}
+	return d
+}
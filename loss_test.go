@@ -1,6 +1,7 @@
 package neuron
 
 import (
+	"math"
 	"testing"
 )
 
@@ -12,12 +13,325 @@ func TestMarginLoss(t *testing.T) {
 	gradWant := []float64{0.0, 1.0}
 
 	for ii := range scores {
-		loss, grad := MarginLoss(scores[ii], targets[ii])
+		loss, grad, _ := MarginLoss(scores[ii], targets[ii])
 		if loss != lossWant[ii] || grad != gradWant[ii] {
 			t.Errorf("(%d) Margin loss returned (%.3f, %.3f); expected (%.3f, %.3f)",
 				ii, loss, grad, lossWant[ii], gradWant[ii])
 		}
 	}
 
-	assertPanic(t, func() { MarginLoss(1.0, 99) })
+	if _, _, err := MarginLoss(1.0, 99); err == nil {
+		t.Errorf("MarginLoss did not return an error")
+	}
+}
+
+// Test that the Must variants of the losses match them, and panic on bad
+// targets.
+func TestMustLosses(t *testing.T) {
+	if loss, grad := MustMarginLoss(9.0, -1); loss != 10.0 || grad != 1.0 {
+		t.Errorf("MustMarginLoss returned (%.3f, %.3f); expected (10, 1)", loss, grad)
+	}
+	if loss, grad := MustMSELossVec([]float64{1.0, 3.0}, []float64{0.0, 3.0}); loss != 0.5 ||
+		grad[0] != 1.0 || grad[1] != 0.0 {
+		t.Errorf("MustMSELossVec returned (%.3f, %v); expected (0.5, [1 0])", loss, grad)
+	}
+	assertPanic(t, func() { MustMarginLoss(1.0, 99) })
+	assertPanic(t, func() { MustSquaredHingeLoss(1.0, 0) })
+	assertPanic(t, func() { MustLogisticLoss(1.0, 2) })
+	assertPanic(t, func() { MustMSELossVec([]float64{1.0}, nil) })
+	assertPanic(t, func() { MustMAELossVec([]float64{1.0}, nil) })
+	assertPanic(t, func() { MustCrossEntropyLoss([]float64{1.0, 2.0}, 2) })
+	assertPanic(t, func() { MustFocalLoss(1.0, 0, 2.0, 0.25) })
+}
+
+// Test squared hinge and logistic losses.
+func TestBinaryLosses(t *testing.T) {
+	scores := []float64{9.0, 9.0, 0.5}
+	targets := []int{1, -1, 1}
+	lossWant := []float64{0.0, 100.0, 0.25}
+	gradWant := []float64{0.0, 20.0, -1.0}
+	for ii := range scores {
+		loss, grad, _ := SquaredHingeLoss(scores[ii], targets[ii])
+		if loss != lossWant[ii] || grad != gradWant[ii] {
+			t.Errorf("(%d) Squared hinge loss returned (%.3f, %.3f); expected (%.3f, %.3f)",
+				ii, loss, grad, lossWant[ii], gradWant[ii])
+		}
+	}
+
+	loss, grad, _ := LogisticLoss(0.0, 1)
+	if !almostEqual(loss, math.Log(2.0)) || grad != -0.5 {
+		t.Errorf("Logistic loss returned (%.3f, %.3f); expected (%.3f, -0.5)", loss, grad,
+			math.Log(2.0))
+	}
+	loss, grad, _ = LogisticLoss(2.0, -1)
+	if !almostEqual(loss, math.Log(1.0+math.Exp(2.0))) ||
+		!almostEqual(grad, 1.0/(1.0+math.Exp(-2.0))) {
+		t.Errorf("Logistic loss returned (%.3f, %.3f)", loss, grad)
+	}
+	if loss, _, _ = LogisticLoss(-1000.0, 1); !almostEqual(loss, 1000.0) {
+		t.Errorf("Logistic loss is %.3f; expected 1000", loss)
+	}
+
+	if _, _, err := SquaredHingeLoss(1.0, 0); err == nil {
+		t.Errorf("SquaredHingeLoss did not return an error")
+	}
+	if _, _, err := LogisticLoss(1.0, 0); err == nil {
+		t.Errorf("LogisticLoss did not return an error")
+	}
+}
+
+// Test squared and absolute error losses.
+func TestRegressionLosses(t *testing.T) {
+	loss, grad := MSELoss(3.0, 1.0)
+	if loss != 4.0 || grad != 4.0 {
+		t.Errorf("MSE loss returned (%.3f, %.3f); expected (4, 4)", loss, grad)
+	}
+	loss, grad = MAELoss(-1.0, 1.0)
+	if loss != 2.0 || grad != -1.0 {
+		t.Errorf("MAE loss returned (%.3f, %.3f); expected (2, -1)", loss, grad)
+	}
+	loss, grad = MAELoss(1.0, 1.0)
+	if loss != 0.0 || grad != 0.0 {
+		t.Errorf("MAE loss returned (%.3f, %.3f); expected (0, 0)", loss, grad)
+	}
+
+	scores := []float64{1.0, 2.0}
+	targets := []float64{0.0, 4.0}
+	loss, grads, _ := MSELossVec(scores, targets)
+	if loss != 2.5 || grads[0] != 1.0 || grads[1] != -2.0 {
+		t.Errorf("MSE vector loss returned (%.3f, %v); expected (2.5, [1 -2])", loss, grads)
+	}
+	loss, grads, _ = MAELossVec(scores, targets)
+	if loss != 1.5 || grads[0] != 0.5 || grads[1] != -0.5 {
+		t.Errorf("MAE vector loss returned (%.3f, %v); expected (1.5, [0.5 -0.5])", loss, grads)
+	}
+
+	if _, _, err := MSELossVec(scores, targets[:1]); err == nil {
+		t.Errorf("MSELossVec did not return an error")
+	}
+}
+
+// Test multi-class cross-entropy loss.
+func TestCrossEntropyLoss(t *testing.T) {
+	scores := []float64{1.0, 2.0, 3.0}
+	loss, grad, _ := CrossEntropyLoss(scores, 1)
+	probs := Softmax(scores, 1.0)
+	if !almostEqual(loss, -math.Log(probs[1])) {
+		t.Errorf("Cross-entropy loss is %.4f; expected %.4f", loss, -math.Log(probs[1]))
+	}
+	for ii, p := range probs {
+		want := p
+		if ii == 1 {
+			want -= 1.0
+		}
+		if !almostEqual(grad[ii], want) {
+			t.Errorf("Cross-entropy grad %d is %.4f; expected %.4f", ii, grad[ii], want)
+		}
+	}
+
+	// Large scores don't overflow.
+	loss, _, _ = CrossEntropyLoss([]float64{1000.0, 0.0}, 0)
+	if math.IsNaN(loss) || math.Abs(loss) > 1.0e-06 {
+		t.Errorf("Cross-entropy loss is %.4f; expected 0", loss)
+	}
+
+	if _, _, err := CrossEntropyLoss(scores, 3); err == nil {
+		t.Errorf("CrossEntropyLoss did not return an error")
+	}
+}
+
+// Test selecting losses by name.
+func TestLossRegistry(t *testing.T) {
+	mse, ok := GetLoss("mse")
+	if !ok {
+		t.Fatalf("mse loss not registered")
+	}
+	if loss, _ := mse.Forward([]float64{1.0, 2.0}, []float64{0.0, 4.0}); loss != 2.5 {
+		t.Errorf("mse loss is %.3f; expected 2.5", loss)
+	}
+	if grad := mse.Backward(); grad[0] != 1.0 || grad[1] != -2.0 {
+		t.Errorf("mse grad is %v; expected [1 -2]", grad)
+	}
+
+	margin, _ := GetLoss("margin")
+	if loss, _ := margin.Forward([]float64{9.0}, []float64{-1}); loss != 10.0 {
+		t.Errorf("margin loss is %.3f; expected 10", loss)
+	}
+	if grad := margin.Backward(); grad[0] != 1.0 {
+		t.Errorf("margin grad is %v; expected [1]", grad)
+	}
+
+	ce, _ := GetLoss("cross_entropy")
+	scores := []float64{1.0, 2.0, 3.0}
+	want, wantGrad, _ := CrossEntropyLoss(scores, 2)
+	if loss, _ := ce.Forward(scores, []float64{2}); loss != want {
+		t.Errorf("cross_entropy loss is %.3f; expected %.3f", loss, want)
+	}
+	if grad := ce.Backward(); grad[2] != wantGrad[2] {
+		t.Errorf("cross_entropy grad is %v; expected %v", grad, wantGrad)
+	}
+
+	RegisterLoss("mae2", func() Loss { l, _ := GetLoss("mae"); return l })
+	if _, ok := GetLoss("mae2"); !ok {
+		t.Errorf("Registered loss not found")
+	}
+	if _, ok := GetLoss("foo"); ok {
+		t.Errorf("Got unregistered loss")
+	}
+}
+
+// Test focal loss.
+func TestFocalLoss(t *testing.T) {
+	// With gamma 0 and alpha 0.5 it's half the logistic loss.
+	for _, target := range []int{1, -1} {
+		loss, grad, _ := FocalLoss(0.7, target, 0.0, 0.5)
+		z := 0.7 * float64(target)
+		want := 0.5 * math.Log(1.0+math.Exp(-z))
+		if !almostEqual(loss, want) {
+			t.Errorf("Focal loss is %.4f; expected %.4f", loss, want)
+		}
+		wantGrad := -0.5 * float64(target) / (1.0 + math.Exp(z))
+		if !almostEqual(grad, wantGrad) {
+			t.Errorf("Focal grad is %.4f; expected %.4f", grad, wantGrad)
+		}
+	}
+
+	// Compare against central differences.
+	const eps = 1e-6
+	for _, score := range []float64{-3.0, -0.2, 0.5, 4.0} {
+		for _, target := range []int{1, -1} {
+			lp, _, _ := FocalLoss(score+eps, target, 2.0, 0.25)
+			lm, _, _ := FocalLoss(score-eps, target, 2.0, 0.25)
+			_, grad, _ := FocalLoss(score, target, 2.0, 0.25)
+			if want := (lp - lm) / (2 * eps); !almostEqualTol(grad, want, 1e-5) {
+				t.Errorf("Focal grad at (%.2f, %d) is %.6f; expected %.6f", score, target,
+					grad, want)
+			}
+		}
+	}
+
+	// Well classified samples are down-weighted.
+	easy, _, _ := FocalLoss(3.0, 1, 2.0, 0.5)
+	logistic, _, _ := FocalLoss(3.0, 1, 0.0, 0.5)
+	if easy >= 0.1*logistic {
+		t.Errorf("Focal loss %.4f not down-weighted from %.4f", easy, logistic)
+	}
+
+	// No overflow with large scores.
+	if loss, grad, _ := FocalLoss(-1000.0, 1, 2.0, 0.25); math.IsNaN(loss) || math.IsNaN(grad) {
+		t.Errorf("Focal loss overflowed: (%.4f, %.4f)", loss, grad)
+	}
+	if _, _, err := FocalLoss(1.0, 0, 2.0, 0.25); err == nil {
+		t.Errorf("FocalLoss did not return an error")
+	}
+}
+
+// Test sample and class weighted losses.
+func TestWeightedLoss(t *testing.T) {
+	ce, _ := GetLoss("cross_entropy")
+	wl := NewWeightedLoss(ce, []float64{1.0, 3.0, 0.5})
+	scores := []float64{1.0, 2.0, 3.0}
+	want, wantGrad, _ := CrossEntropyLoss(scores, 1)
+
+	loss, _ := wl.ForwardWeighted(scores, []float64{1}, 2.0)
+	if !almostEqual(loss, 6.0*want) {
+		t.Errorf("Weighted loss is %.4f; expected %.4f", loss, 6.0*want)
+	}
+	for ii, g := range wl.Backward() {
+		if !almostEqual(g, 6.0*wantGrad[ii]) {
+			t.Errorf("Weighted grad %d is %.4f; expected %.4f", ii, g, 6.0*wantGrad[ii])
+		}
+	}
+
+	// Binary targets use class indices 0 and 1.
+	margin, _ := GetLoss("margin")
+	wl = NewWeightedLoss(margin, []float64{0.25, 1.0})
+	if loss, _ := wl.Forward([]float64{9.0}, []float64{-1}); loss != 2.5 {
+		t.Errorf("Weighted margin loss is %.4f; expected 2.5", loss)
+	}
+	if g := wl.Backward(); g[0] != 0.25 {
+		t.Errorf("Weighted margin grad is %v; expected [0.25]", g)
+	}
+
+	// Regression losses only take a sample weight.
+	mse, _ := GetLoss("mse")
+	wl = NewWeightedLoss(mse, nil)
+	if loss, _ := wl.ForwardWeighted([]float64{1.0}, []float64{0.0}, 0.5); loss != 0.5 {
+		t.Errorf("Weighted mse loss is %.4f; expected 0.5", loss)
+	}
+
+	wl = NewWeightedLoss(ce, []float64{1.0})
+	if _, err := wl.Forward(scores, []float64{2}); err == nil {
+		t.Errorf("Forward did not return an error")
+	}
+}
+
+// Test triplet loss.
+func TestTripletLoss(t *testing.T) {
+	anchor := []float64{0.0, 0.0}
+	pos := []float64{1.0, 0.0}
+	neg := []float64{0.0, 1.5}
+
+	// 1 - 2.25 + 2 = 0.75
+	loss, ga, gp, gn, _ := TripletLoss(anchor, pos, neg, 2.0)
+	if !almostEqual(loss, 0.75) {
+		t.Errorf("Triplet loss is %.4f; expected 0.75", loss)
+	}
+	if ga[0] != -2.0 || ga[1] != 3.0 || gp[0] != 2.0 || gn[1] != -3.0 {
+		t.Errorf("Triplet grads are %v, %v, %v", ga, gp, gn)
+	}
+
+	loss, ga, _, _, _ = TripletLoss(anchor, pos, neg, 1.0)
+	if loss != 0.0 || ga[0] != 0.0 {
+		t.Errorf("Satisfied triplet has loss %.4f", loss)
+	}
+	if _, _, _, _, err := TripletLoss(anchor, pos[:1], neg, 1.0); err == nil {
+		t.Errorf("TripletLoss did not return an error")
+	}
+}
+
+// Test contrastive loss.
+func TestContrastiveLoss(t *testing.T) {
+	a := []float64{0.0, 0.0}
+	b := []float64{0.6, 0.8}
+
+	loss, ga, gb, _ := ContrastiveLoss(a, b, true, 2.0)
+	if !almostEqual(loss, 1.0) || !almostEqual(ga[0], -1.2) || !almostEqual(gb[0], 1.2) {
+		t.Errorf("Similar contrastive loss returned (%.4f, %v, %v)", loss, ga, gb)
+	}
+
+	// (2 - 1)^2, pushing the embeddings apart.
+	loss, ga, gb, _ = ContrastiveLoss(a, b, false, 2.0)
+	if !almostEqual(loss, 1.0) || !almostEqual(ga[1], 1.6) || !almostEqual(gb[1], -1.6) {
+		t.Errorf("Dissimilar contrastive loss returned (%.4f, %v, %v)", loss, ga, gb)
+	}
+
+	loss, ga, _, _ = ContrastiveLoss(a, b, false, 0.5)
+	if loss != 0.0 || ga[0] != 0.0 {
+		t.Errorf("Distant dissimilar pair has loss %.4f", loss)
+	}
+}
+
+// Test training a network on triplets in sequence mode.
+func TestTripletTraining(t *testing.T) {
+	arch := []int{2, 4, 2}
+	n := MustNewMLP(arch, NewSGD(0.05, 0.0, 0.0))
+	n.StartSequence(true, 1)
+
+	anchor := []float64{1.0, 0.0}
+	pos := []float64{0.9, 0.1}
+	neg := []float64{0.0, 1.0}
+	var first, loss float64
+	for ii := 0; ii < 50; ii++ {
+		out, _ := n.ForwardSequence([][]float64{anchor, pos, neg})
+		var ga, gp, gn []float64
+		loss, ga, gp, gn, _ = TripletLoss(out[0], out[1], out[2], 1.0)
+		n.BackwardSequence([][]float64{ga, gp, gn})
+		if ii == 0 {
+			first = loss
+		}
+	}
+	if loss >= first {
+		t.Errorf("Triplet loss didn't decrease: %.4f -> %.4f", first, loss)
+	}
 }
@@ -0,0 +1,83 @@
+package neuron
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// A Mask is a binary mask over the network's connections, keyed by the ID of
+// the receiving unit and then the ID of the sending unit. Connections with a
+// false mask value are pruned: they contribute nothing to either pass and
+// their weights are never updated.
+type Mask map[string]map[string]bool
+
+// GetMask returns the current mask over all connections.
+func (n *Net) GetMask() Mask {
+	m := make(Mask)
+	for _, l := range n.Layers {
+		for _, u := range l {
+			for _, k := range weightKeys(u.W) {
+				if m[u.ID] == nil {
+					m[u.ID] = make(map[string]bool)
+				}
+				m[u.ID][k] = !u.W.Params[k].masked
+			}
+		}
+	}
+	return m
+}
+
+// SetMask applies a connection mask. Connections missing from m are left
+// unchanged. Unmasked weights keep the value they had before they were
+// masked, so combined with SetData a lottery ticket rewind is just
+//
+//	n.SetData(init)
+//	n.SetMask(mask)
+//
+// SetMask should only be called while the network is idle, e.g. after
+// Backward returns.
+func (n *Net) SetMask(m Mask) {
+	for _, l := range n.Layers {
+		for _, u := range l {
+			for k, v := range m[u.ID] {
+				if p, ok := u.W.Params[k]; ok && isConn(k) {
+					p.masked = !v
+					if !v {
+						p.grad = 0.0
+					}
+				}
+			}
+		}
+	}
+}
+
+// Sparsity returns the fraction of connections that are masked.
+func (m Mask) Sparsity() float64 {
+	total, masked := 0, 0
+	for _, um := range m {
+		for _, v := range um {
+			total++
+			if !v {
+				masked++
+			}
+		}
+	}
+	if total == 0 {
+		return 0.0
+	}
+	return float64(masked) / float64(total)
+}
+
+// Save writes the mask as JSON.
+func (m Mask) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(m)
+}
+
+// LoadMask reads a mask written by Mask.Save.
+func LoadMask(r io.Reader) (Mask, error) {
+	var m Mask
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,57 @@
+package neuron
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// Test connection masks.
+func TestMask(t *testing.T) {
+	arch := []int{2, 2, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	n := MustNewMLP(arch, opt)
+
+	m := n.GetMask()
+	if len(m) != 3 || len(m["002_000000"]) != 2 || m.Sparsity() != 0.0 {
+		t.Fatalf("Invalid initial mask %v", m)
+	}
+
+	// Prune the connection 001_000000 -> 002_000000.
+	m["002_000000"]["001_000000"] = false
+	n.SetMask(m)
+	if s := n.GetMask().Sparsity(); !almostEqual(s, 1.0/6.0) {
+		t.Errorf("Sparsity is %.3f; expected %.3f", s, 1.0/6.0)
+	}
+
+	out := n.Layers[2][0].W.Params
+	masked := out["001_000000"].Data
+	data := []float64{1.0, 2.0}
+	h := n.Layers[1][1].W.Params
+	hidden := math.Max(h["000_000000"].Data*data[0]+h["000_000001"].Data*data[1]+
+		h[biasID].Data, 0.0)
+	outWant := out["001_000001"].Data*hidden + out[biasID].Data
+
+	n.Start(true, 1)
+	output := n.MustForward(data)
+	n.MustBackward([]float64{1.0})
+	if !almostEqual(output[0], outWant) {
+		t.Errorf("Masked output is %.6f; expected %.6f", output[0], outWant)
+	}
+	if out["001_000000"].Data != masked {
+		t.Errorf("Masked weight was updated")
+	}
+
+	// Round trip.
+	var buf bytes.Buffer
+	if err := m.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	m2, err := LoadMask(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m2["002_000000"]["001_000000"] || !m2["002_000000"]["001_000001"] {
+		t.Errorf("Loaded mask %v doesn't match saved", m2)
+	}
+}
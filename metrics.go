@@ -0,0 +1,255 @@
+package neuron
+
+import (
+	"math"
+	"sort"
+)
+
+// RegressionMetrics accumulates per-output regression errors over a sequence
+// of predictions.
+type RegressionMetrics struct {
+	absErr []float64
+	sqErr  []float64
+	count  int
+}
+
+// NewRegressionMetrics creates regression metrics for dim outputs.
+func NewRegressionMetrics(dim int) *RegressionMetrics {
+	return &RegressionMetrics{
+		absErr: make([]float64, dim),
+		sqErr:  make([]float64, dim),
+	}
+}
+
+// Update accumulates the errors of a single prediction.
+func (m *RegressionMetrics) Update(pred, target []float64) {
+	for ii := range m.absErr {
+		d := pred[ii] - target[ii]
+		m.absErr[ii] += math.Abs(d)
+		m.sqErr[ii] += d * d
+	}
+	m.count++
+}
+
+// MAE returns the mean absolute error of each output.
+func (m *RegressionMetrics) MAE() []float64 {
+	mae := make([]float64, len(m.absErr))
+	if m.count == 0 {
+		return mae
+	}
+	for ii, v := range m.absErr {
+		mae[ii] = v / float64(m.count)
+	}
+	return mae
+}
+
+// RMSE returns the root mean squared error of each output.
+func (m *RegressionMetrics) RMSE() []float64 {
+	rmse := make([]float64, len(m.sqErr))
+	if m.count == 0 {
+		return rmse
+	}
+	for ii, v := range m.sqErr {
+		rmse[ii] = math.Sqrt(v / float64(m.count))
+	}
+	return rmse
+}
+
+// Reset clears the accumulated errors.
+func (m *RegressionMetrics) Reset() {
+	for ii := range m.absErr {
+		m.absErr[ii] = 0.0
+		m.sqErr[ii] = 0.0
+	}
+	m.count = 0
+}
+
+// A Metric accumulates a score over a sequence of predictions, e.g. while
+// evaluating a network, see Trainer.Metrics. Compute returns the score of the
+// predictions since the last Reset.
+type Metric interface {
+	Name() string
+	Update(pred, target []float64)
+	Compute() float64
+	Reset()
+}
+
+// classCounts accumulates the counts of each (target, predicted) class pair
+// for the classification metrics. Targets need to be a single class label:
+// the class index for networks with several outputs, or +/- 1 for networks
+// with a single output, which are binary with +1 the positive class.
+type classCounts struct {
+	counts map[[2]int]int
+	binary bool
+}
+
+// update adds a prediction.
+func (c *classCounts) update(pred, target []float64) {
+	if c.counts == nil {
+		c.counts = make(map[[2]int]int)
+	}
+	c.binary = len(pred) == 1
+	c.counts[[2]int{int(target[0]), int(predictClass(pred))}]++
+}
+
+// reset clears the counts.
+func (c *classCounts) reset() {
+	c.counts = nil
+}
+
+// total returns the number of predictions.
+func (c *classCounts) total() int {
+	n := 0
+	for _, v := range c.counts {
+		n += v
+	}
+	return n
+}
+
+// classes returns the classes scored by precision and recall: the positive
+// class for binary predictions, and every class seen otherwise.
+func (c *classCounts) classes() []int {
+	if c.binary {
+		return []int{1}
+	}
+	return c.seen()
+}
+
+// seen returns every class seen as a target or prediction in sorted order.
+func (c *classCounts) seen() []int {
+	seen := make(map[int]bool)
+	var classes []int
+	for k := range c.counts {
+		for _, class := range k {
+			if !seen[class] {
+				seen[class] = true
+				classes = append(classes, class)
+			}
+		}
+	}
+	sort.Ints(classes)
+	return classes
+}
+
+// stats returns the true positives, false positives, and false negatives of
+// class.
+func (c *classCounts) stats(class int) (tp, fp, fn int) {
+	for k, v := range c.counts {
+		switch {
+		case k[0] == class && k[1] == class:
+			tp += v
+		case k[1] == class:
+			fp += v
+		case k[0] == class:
+			fn += v
+		}
+	}
+	return
+}
+
+// macro averages score over the classes.
+func (c *classCounts) macro(score func(tp, fp, fn int) float64) float64 {
+	classes := c.classes()
+	if len(classes) == 0 {
+		return 0.0
+	}
+	sum := 0.0
+	for _, class := range classes {
+		sum += score(c.stats(class))
+	}
+	return sum / float64(len(classes))
+}
+
+// ratio returns a / b, or 0 if b is 0.
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0.0
+	}
+	return float64(a) / float64(b)
+}
+
+func precision(tp, fp, fn int) float64 { return ratio(tp, tp+fp) }
+func recall(tp, fp, fn int) float64    { return ratio(tp, tp+fn) }
+func f1(tp, fp, fn int) float64        { return ratio(2*tp, 2*tp+fp+fn) }
+
+// Accuracy is the fraction of correctly classified predictions. Like the
+// other classification metrics, targets need to be a single class label: the
+// class index for networks with several outputs, or +/- 1 for networks with a
+// single output.
+type Accuracy struct {
+	c classCounts
+}
+
+// Name returns "accuracy".
+func (m *Accuracy) Name() string { return "accuracy" }
+
+// Update adds a prediction.
+func (m *Accuracy) Update(pred, target []float64) { m.c.update(pred, target) }
+
+// Compute returns the accuracy.
+func (m *Accuracy) Compute() float64 {
+	correct := 0
+	for k, v := range m.c.counts {
+		if k[0] == k[1] {
+			correct += v
+		}
+	}
+	return ratio(correct, m.c.total())
+}
+
+// Reset clears the predictions.
+func (m *Accuracy) Reset() { m.c.reset() }
+
+// Precision is the fraction of predictions of a class that are correct. For
+// binary networks with a single output it's the precision of the positive
+// class, and for multi-class networks the mean over classes (macro average).
+type Precision struct {
+	c classCounts
+}
+
+// Name returns "precision".
+func (m *Precision) Name() string { return "precision" }
+
+// Update adds a prediction.
+func (m *Precision) Update(pred, target []float64) { m.c.update(pred, target) }
+
+// Compute returns the precision.
+func (m *Precision) Compute() float64 { return m.c.macro(precision) }
+
+// Reset clears the predictions.
+func (m *Precision) Reset() { m.c.reset() }
+
+// Recall is the fraction of samples of a class that are predicted correctly,
+// averaged like Precision.
+type Recall struct {
+	c classCounts
+}
+
+// Name returns "recall".
+func (m *Recall) Name() string { return "recall" }
+
+// Update adds a prediction.
+func (m *Recall) Update(pred, target []float64) { m.c.update(pred, target) }
+
+// Compute returns the recall.
+func (m *Recall) Compute() float64 { return m.c.macro(recall) }
+
+// Reset clears the predictions.
+func (m *Recall) Reset() { m.c.reset() }
+
+// F1 is the harmonic mean of precision and recall, averaged like Precision.
+type F1 struct {
+	c classCounts
+}
+
+// Name returns "f1".
+func (m *F1) Name() string { return "f1" }
+
+// Update adds a prediction.
+func (m *F1) Update(pred, target []float64) { m.c.update(pred, target) }
+
+// Compute returns the F1 score.
+func (m *F1) Compute() float64 { return m.c.macro(f1) }
+
+// Reset clears the predictions.
+func (m *F1) Reset() { m.c.reset() }
@@ -0,0 +1,73 @@
+package neuron
+
+import (
+	"math"
+	"testing"
+)
+
+// Test per-output regression metrics.
+func TestRegressionMetrics(t *testing.T) {
+	m := NewRegressionMetrics(2)
+	m.Update([]float64{1.0, 0.0}, []float64{0.0, 0.0})
+	m.Update([]float64{-1.0, 2.0}, []float64{2.0, 0.0})
+
+	maeWant := []float64{2.0, 1.0}
+	rmseWant := []float64{math.Sqrt(5.0), math.Sqrt(2.0)}
+	mae, rmse := m.MAE(), m.RMSE()
+	for ii := range maeWant {
+		if !almostEqual(mae[ii], maeWant[ii]) || !almostEqual(rmse[ii], rmseWant[ii]) {
+			t.Errorf("(%d) Metrics are (%.3f, %.3f); expected (%.3f, %.3f)",
+				ii, mae[ii], rmse[ii], maeWant[ii], rmseWant[ii])
+		}
+	}
+
+	m.Reset()
+	if mae = m.MAE(); mae[0] != 0.0 {
+		t.Errorf("MAE after reset is %.3f; expected 0", mae[0])
+	}
+}
+
+// Test classification metrics for binary and multi-class predictions.
+func TestClassificationMetrics(t *testing.T) {
+	onehot := func(class int) []float64 {
+		v := make([]float64, 3)
+		v[class] = 1.0
+		return v
+	}
+	cases := []struct {
+		name         string
+		pred, target [][]float64
+		want         map[string]float64
+	}{
+		{
+			name:   "binary",
+			pred:   [][]float64{{0.8}, {-0.5}, {0.3}, {-0.9}, {0.6}},
+			target: [][]float64{{1.0}, {1.0}, {-1.0}, {-1.0}, {1.0}},
+			want: map[string]float64{"accuracy": 3.0 / 5.0, "precision": 2.0 / 3.0,
+				"recall": 2.0 / 3.0, "f1": 2.0 / 3.0},
+		},
+		{
+			name:   "multiclass",
+			pred:   [][]float64{onehot(0), onehot(2), onehot(2), onehot(1), onehot(2)},
+			target: [][]float64{{0.0}, {1.0}, {2.0}, {2.0}, {2.0}},
+			// Class 2 has precision 2/3 and recall 2/3.
+			want: map[string]float64{"accuracy": 3.0 / 5.0, "precision": 5.0 / 9.0,
+				"recall": 5.0 / 9.0, "f1": 5.0 / 9.0},
+		},
+	}
+	for _, tc := range cases {
+		metrics := []Metric{&Accuracy{}, &Precision{}, &Recall{}, &F1{}}
+		for _, m := range metrics {
+			for ii := range tc.pred {
+				m.Update(tc.pred[ii], tc.target[ii])
+			}
+			if got, want := m.Compute(), tc.want[m.Name()]; !almostEqual(got, want) {
+				t.Errorf("(%s) %s is %.3f; expected %.3f", tc.name, m.Name(), got, want)
+			}
+			m.Reset()
+			if got := m.Compute(); got != 0.0 {
+				t.Errorf("(%s) %s after reset is %.3f; expected 0", tc.name, m.Name(), got)
+			}
+		}
+	}
+}
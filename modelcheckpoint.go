@@ -0,0 +1,97 @@
+package neuron
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// A ModelCheckpoint is a Callback that saves the network trained by a
+// Trainer, with Net.Save, every Every steps, and at the end of every epoch in
+// which the loss on a validation dataset improves. Saved networks include the
+// optimizer state, and can be read back with LoadNet. Typical use is
+//
+//	c := &ModelCheckpoint{Path: "model-%06d.gob", Validation: val}
+//	t := &Trainer{..., Callbacks: []Callback{c}}
+//
+// Unlike a Checkpoint, only the network is saved, not the training state.
+type ModelCheckpoint struct {
+	// File the network is written to. If Path contains a formatting verb,
+	// it's formatted with the step count, e.g. "model-%06d.gob", so every
+	// checkpoint gets its own file. Otherwise each checkpoint replaces the
+	// last.
+	Path string
+	// Save every Every steps, i.e. back-propagated samples. Optional.
+	Every int
+	// Save at the end of each epoch in which the loss on Validation improves.
+	// Optional.
+	Validation Dataset
+	// Save with SaveJSON instead of Save.
+	JSON bool
+	// Step count at the last call to OnBatchEnd.
+	steps int
+	// Best validation loss so far.
+	best float64
+	seen bool
+}
+
+// OnBatchEnd saves the network each time its step count passes a multiple of
+// Every.
+func (c *ModelCheckpoint) OnBatchEnd(t *Trainer, batch int, loss float64) error {
+	if c.Every <= 0 {
+		return nil
+	}
+	steps := t.Net.Steps()
+	if steps/c.Every <= c.steps/c.Every {
+		c.steps = steps
+		return nil
+	}
+	c.steps = steps
+	return c.Save(t.Net)
+}
+
+// OnEpochEnd saves the network if the validation loss improved.
+func (c *ModelCheckpoint) OnEpochEnd(t *Trainer, epoch int, loss float64) error {
+	if c.Validation == nil {
+		return nil
+	}
+	e, err := t.Evaluate(c.Validation)
+	if err != nil {
+		return err
+	}
+	val := e.Loss
+	if c.seen && val >= c.best {
+		return nil
+	}
+	logf(1, "Validation loss improved to %.5e\n", val)
+	c.best, c.seen = val, true
+	return c.Save(t.Net)
+}
+
+// Best returns the best validation loss so far, or +Inf if there's none yet.
+func (c *ModelCheckpoint) Best() float64 {
+	if !c.seen {
+		return math.Inf(1)
+	}
+	return c.best
+}
+
+// Save writes the network right away, to the path for its current step
+// count. Like Checkpoint.Save, the file is replaced atomically.
+func (c *ModelCheckpoint) Save(n *Net) error {
+	path := c.Path
+	if strings.Contains(path, "%") {
+		path = fmt.Sprintf(path, n.Steps())
+	}
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		if c.JSON {
+			return n.SaveJSON(w)
+		}
+		return n.Save(w)
+	})
+	if err == nil {
+		logf(1, "Saved network at step %d to %s\n", n.Steps(), path)
+	}
+	return err
+}
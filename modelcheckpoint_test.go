@@ -0,0 +1,55 @@
+package neuron
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Test saving networks periodically and on validation improvements.
+func TestModelCheckpoint(t *testing.T) {
+	rand.Seed(16)
+	opt := NewSGD(0.05, 0.0, 0.0)
+	n := MustNewMLP([]int{2, 8, 1}, opt, WithInitializer(HeNormal{}))
+	defer n.Stop()
+	loss, _ := GetLoss("mse")
+	dir := t.TempDir()
+	c := &ModelCheckpoint{Path: filepath.Join(dir, "model-%03d.gob"), Every: 16,
+		Validation: linearData(10)}
+	if !math.IsInf(c.Best(), 1) {
+		t.Errorf("Best loss before training is %v; expected +Inf", c.Best())
+	}
+	tr := &Trainer{Net: n, Loss: loss, Data: linearData(24), BatchSize: 8,
+		Callbacks: []Callback{c}}
+	if _, err := tr.Fit(2); err != nil {
+		t.Fatalf("Fit returned an error: %v", err)
+	}
+
+	// Every 16 steps, and at step 24 after the first epoch, when the
+	// validation loss first improves.
+	for _, step := range []string{"016", "024", "032"} {
+		if _, err := os.Stat(filepath.Join(dir, "model-"+step+".gob")); err != nil {
+			t.Errorf("Missing checkpoint: %v", err)
+		}
+	}
+	if math.IsInf(c.Best(), 1) {
+		t.Errorf("Best validation loss not recorded")
+	}
+
+	// The last checkpoint has the final weights.
+	f, err := os.Open(filepath.Join(dir, "model-048.gob"))
+	if err != nil {
+		t.Fatalf("Missing final checkpoint: %v", err)
+	}
+	defer f.Close()
+	loaded, err := LoadNet(f, opt, WithInitializer(HeNormal{}))
+	if err != nil {
+		t.Fatalf("LoadNet returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Data(), n.Data()) {
+		t.Errorf("Loaded weights don't match the final weights")
+	}
+}
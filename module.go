@@ -0,0 +1,134 @@
+package neuron
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A Module is a trainable network that can be composed with others, see
+// Sequential. Like a Net, it's started in training or evaluation mode, and
+// every Forward in training mode needs a matching Backward. InputGradients
+// returns the gradient with respect to the inputs of the last backward pass,
+// which is what flows into the previous module. Weights are updated by each
+// module every updateFreq backward passes, so modules don't need an explicit
+// optimizer step, and Steps counts the backward passes.
+//
+// *Net and *Sequential are Modules.
+type Module interface {
+	Start(train bool, updateFreq int)
+	Stop()
+	Forward(data []float64) ([]float64, error)
+	Backward(grad []float64) error
+	InputGradients() ([]float64, error)
+	Steps() int
+}
+
+// Sequential chains modules, feeding the output of each into the next, e.g.
+// an encoder and a classifier head, so they can be trained end to end. The
+// backward pass runs the modules in reverse, passing the input gradients of
+// each module back as the output gradient of the previous one. Typical use is
+//
+//	s, err := NewSequential(encoder, head)
+//	s.Start(true, 1)
+//	output, err := s.Forward(x)
+//	loss.Forward(output, y)
+//	err = s.Backward(loss.Backward())
+//
+// Each module keeps its own optimizer and weights, and the modules run
+// concurrently with each other, like the units of a Net.
+type Sequential struct {
+	Modules []Module
+	train   bool
+}
+
+// NewSequential creates a sequential container. Nets are checked to have
+// matching output and input sizes.
+func NewSequential(modules ...Module) (*Sequential, error) {
+	if len(modules) == 0 {
+		return nil, errors.New("sequential needs at least one module")
+	}
+	for ii := 1; ii < len(modules); ii++ {
+		prev, ok1 := modules[ii-1].(*Net)
+		next, ok2 := modules[ii].(*Net)
+		if !ok1 || !ok2 {
+			continue
+		}
+		if out, in := prev.Arch[len(prev.Arch)-1], next.inputDim(); out != in {
+			return nil, fmt.Errorf("module %d has %d outputs but module %d has %d inputs",
+				ii-1, out, ii, in)
+		}
+	}
+	return &Sequential{Modules: modules}, nil
+}
+
+// Start starts every module.
+func (s *Sequential) Start(train bool, updateFreq int) {
+	s.train = train
+	for _, m := range s.Modules {
+		m.Start(train, updateFreq)
+	}
+}
+
+// Stop stops every module.
+func (s *Sequential) Stop() {
+	for _, m := range s.Modules {
+		m.Stop()
+	}
+}
+
+// Forward runs a forward pass through each module in order. If a module
+// returns an error in training mode, the passes already started are finished
+// with zero gradients before returning, so the modules are ready for the next
+// sample.
+func (s *Sequential) Forward(data []float64) ([]float64, error) {
+	output := data
+	// Output size of each module that ran its pass.
+	var sizes []int
+	for ii, m := range s.Modules {
+		out, err := m.Forward(output)
+		if err != nil {
+			if s.train {
+				// An output along with the error means the pass ran, e.g. an
+				// anomaly, and needs its backward pass too.
+				if out != nil {
+					sizes = append(sizes, len(out))
+				}
+				for jj := len(sizes) - 1; jj >= 0; jj-- {
+					s.Modules[jj].Backward(make([]float64, sizes[jj]))
+				}
+			}
+			return nil, fmt.Errorf("module %d: %w", ii, err)
+		}
+		output = out
+		sizes = append(sizes, len(out))
+	}
+	return output, nil
+}
+
+// Backward runs a backward pass through each module in reverse order.
+func (s *Sequential) Backward(grad []float64) error {
+	for ii := len(s.Modules) - 1; ii >= 0; ii-- {
+		m := s.Modules[ii]
+		if err := m.Backward(grad); err != nil {
+			return fmt.Errorf("module %d: %w", ii, err)
+		}
+		if ii == 0 {
+			break
+		}
+		var err error
+		if grad, err = m.InputGradients(); err != nil {
+			return fmt.Errorf("module %d: %w", ii, err)
+		}
+	}
+	return nil
+}
+
+// InputGradients returns the input gradients of the first module.
+func (s *Sequential) InputGradients() ([]float64, error) {
+	return s.Modules[0].InputGradients()
+}
+
+// Steps returns the number of backward passes of the last module.
+func (s *Sequential) Steps() int {
+	return s.Modules[len(s.Modules)-1].Steps()
+}
@@ -0,0 +1,66 @@
+package neuron
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// Test training two chained networks end to end.
+func TestSequential(t *testing.T) {
+	rand.Seed(18)
+	enc := MustNewMLP([]int{2, 8, 4}, NewSGD(0.01, 0.5, 0.0), WithInitializer(HeNormal{}))
+	head := MustNewMLP([]int{4, 4, 1}, NewSGD(0.01, 0.5, 0.0), WithInitializer(HeNormal{}))
+	if _, err := NewSequential(head, enc); err == nil {
+		t.Errorf("Expected an error for mismatched modules")
+	}
+	s, err := NewSequential(enc, head)
+	if err != nil {
+		t.Fatalf("NewSequential returned an error: %v", err)
+	}
+	encBefore := enc.Data()
+
+	loss, _ := GetLoss("mse")
+	ds := linearData(50)
+	s.Start(true, 1)
+	defer s.Stop()
+	epochLoss := func() float64 {
+		total := 0.0
+		for ii := 0; ii < ds.Len(); ii++ {
+			x, y := ds.Get(ii)
+			output, err := s.Forward(x)
+			if err != nil {
+				t.Fatalf("Forward returned an error: %v", err)
+			}
+			l, _ := loss.Forward(output, y)
+			total += l
+			if err := s.Backward(loss.Backward()); err != nil {
+				t.Fatalf("Backward returned an error: %v", err)
+			}
+		}
+		return total / float64(ds.Len())
+	}
+	first := epochLoss()
+	last := first
+	for ii := 0; ii < 30; ii++ {
+		last = epochLoss()
+	}
+	if last > 0.1*first {
+		t.Errorf("Loss went from %.3e to %.3e", first, last)
+	}
+	if reflect.DeepEqual(enc.Data(), encBefore) {
+		t.Errorf("Encoder weights weren't updated")
+	}
+	if s.Steps() != 31*50 || enc.Steps() != 31*50 {
+		t.Errorf("Took %d and %d steps; expected %d", s.Steps(), enc.Steps(), 31*50)
+	}
+	if g, err := s.InputGradients(); err != nil || len(g) != 2 {
+		t.Errorf("Input gradients are %v (%v); expected 2", g, err)
+	}
+
+	// A failed pass leaves the modules ready for the next sample.
+	if _, err := s.Forward([]float64{1.0}); err == nil {
+		t.Errorf("Expected an error for the wrong input size")
+	}
+	epochLoss()
+}
@@ -1,7 +1,12 @@
 package neuron
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math/rand"
+	"sync"
+	"time"
 )
 
 // A Net is a neural network consisting of a sequence of layers, each of which
@@ -11,83 +16,626 @@ type Net struct {
 	// Size of each layer
 	Arch []int
 	// Pointers to the units in each layer
-	Layers   [][](*Unit)
-	stepDone chan int
+	Layers [][](*Unit)
+	// Input scaling of the training data, saved with the network. Optional,
+	// see Scaler.
+	Scaler *Scaler
+	// If SymmetryFreq > 0, the per-layer weight symmetry is logged every
+	// SymmetryFreq calls to Backward.
+	SymmetryFreq int
+	steps        int
+	stepDone     chan int
+	train        bool
+	batch        int
+	pipeline     bool
+	// Run a goroutine per layer instead of per unit, see WithLayerEngine and
+	// WithWorkers.
+	layers  bool
+	workers int
+	// Batch the signals between layers, see WithSignalBatching.
+	batching bool
+	// Skip connections, see WithSkipConnections.
+	skips [][2]int
+	// Named input groups and output heads, see WithInputHeads and
+	// WithOutputHeads.
+	inHeads, outHeads []Head
+	// Categorical inputs, see WithEmbedding.
+	embeds []embedding
+	// Convolution layers, see WithConv1D.
+	convs map[int]conv1D
+	// Connection weight initializer of each layer, see WithInitializer, and
+	// bias settings, see WithBias.
+	inits  []Initializer
+	biases map[int]biasSetting
+	// Random source, see WithRand. src is its source if it was created by
+	// WithSeed, so that checkpoints can save its state.
+	rng *rand.Rand
+	src *RandSource
+	// Pass ticket, see acquire.
+	ticket  chan struct{}
+	pending bool
+	// Softmax output state, see WithSoftmaxOutput. The softmax is computed
+	// over each span of output units, see WithOutputHeads.
+	softmax      bool
+	softmaxSpans [][2]int
+	seq          bool
+	probs        [][]float64
+	// Number of steps of the last ForwardSequence, see BackwardSequence.
+	seqSteps int
+	// Learning rate schedule state, see Scheduled.
+	updateFreq int
+	windows    int
+	sched      []*schedState
+	// Learning rate state, see SetLR. opt is the optimizer passed to NewMLP,
+	// and lrOpt a clone of it that tracks the rate for GetLR. Layers in
+	// ownOpts have their own optimizer, see WithLayerOptimizer.
+	opt       Optimizer
+	lrOpt     Optimizer
+	lrMu      sync.Mutex
+	lr        float64
+	lrPending bool
+	lrScales  map[int]float64
+	ownOpts   map[int]bool
+	// Global gradient norm clipping, see WithGradClipNorm.
+	clipNorm float64
+	// Unit loop state, see Stop.
+	quit  chan struct{}
+	units sync.WaitGroup
+	// Watchdog state, see WithWatchdog.
+	watchdog time.Duration
+	stuck    string
+	// Anomaly detection state, see WithAnomalyDetection.
+	anomaly *anomalyState
+}
+
+// unitID formats the ID of unit jj in layer ii.
+func unitID(ii, jj int) string {
+	return fmt.Sprintf("%03d_%06d", ii, jj)
+}
+
+// An Option configures optional settings of a network.
+type Option func(*netConfig)
+
+// netConfig holds the optional network settings.
+type netConfig struct {
+	kinds       []string
+	mixed       map[int][]string
+	activs      []Activation
+	weightNorm  bool
+	quantBits   int
+	bayes       *bayesConfig
+	softmax     bool
+	clipValue   float64
+	clipNorm    float64
+	layerOpts   map[int]Optimizer
+	lrScales    map[int]float64
+	watchdog    time.Duration
+	pipeline    bool
+	layers      bool
+	workers     int
+	batching    bool
+	skips       [][2]int
+	ties        [][2]int
+	autoTies    bool
+	connProb    float64
+	fanIn       int
+	actNoise    map[int]float64
+	weightNoise map[int]float64
+	inHeads     []Head
+	outHeads    []Head
+	embeds      []embedding
+	convs       map[int]conv1D
+	init        Initializer
+	layerInits  map[int]Initializer
+	biases      map[int]biasSetting
+	rng         *rand.Rand
+	src         *RandSource
+	anomaly     bool
+}
+
+// WithUnitKinds sets the unit kind of each layer by registered name. By
+// default the first layer is InputKind, the last is OutputKind, and the rest
+// are HiddenKind.
+func WithUnitKinds(kinds []string) Option {
+	return func(c *netConfig) {
+		c.kinds = kinds
+	}
+}
+
+// WithMixedLayer mixes several unit kinds within a single layer. Units are
+// assigned kinds by cycling through kinds, so e.g.
+//
+//	WithMixedLayer(1, HiddenKind, TanhKind)
+//
+// alternates between the two. It overrides WithUnitKinds for that layer.
+func WithMixedLayer(layer int, kinds ...string) Option {
+	return func(c *netConfig) {
+		if c.mixed == nil {
+			c.mixed = make(map[int][]string)
+		}
+		c.mixed[layer] = kinds
+	}
+}
+
+// WithActivations sets the activation of each layer after the input layer,
+// overriding the default activation of the layer's unit kind. activs needs one
+// entry per layer, e.g.
+//
+//	WithActivations([]Activation{new(Tanh), new(Tanh), new(Identity)})
+//
+// for a network with two hidden layers. A nil entry keeps the default. Each
+// unit gets its own copy of the activation.
+func WithActivations(activs []Activation) Option {
+	return func(c *netConfig) {
+		c.activs = activs
+	}
+}
+
+// WithLayerOptimizer sets the optimizer of a single layer, instead of the
+// optimizer passed to NewMLP. Each unit in the layer gets its own clone, whose
+// learning rate isn't changed by Net.SetLR.
+func WithLayerOptimizer(layer int, opt Optimizer) Option {
+	return func(c *netConfig) {
+		if c.layerOpts == nil {
+			c.layerOpts = make(map[int]Optimizer)
+		}
+		c.layerOpts[layer] = opt
+	}
+}
+
+// WithLayerLRScale scales the learning rate of a single layer's optimizers,
+// e.g. to train the output layer with a smaller learning rate than the hidden
+// layers. The scale also applies to learning rates set with Net.SetLR. It
+// needs an LrOptimizer.
+func WithLayerLRScale(layer int, scale float64) Option {
+	return func(c *netConfig) {
+		if c.lrScales == nil {
+			c.lrScales = make(map[int]float64)
+		}
+		c.lrScales[layer] = scale
+	}
+}
+
+// unitOptimizer creates a new optimizer for a unit in the given layer.
+func (c *netConfig) unitOptimizer(opt Optimizer, layer int) Optimizer {
+	if lopt, ok := c.layerOpts[layer]; ok {
+		opt = lopt
+	}
+	opt = opt.New()
+	if scale, ok := c.lrScales[layer]; ok {
+		lr := opt.(LrOptimizer)
+		lr.SetLearningRate(scale * baseLR(lr))
+	}
+	return opt
+}
+
+// checkLayerOpts checks the per-layer optimizer settings.
+func checkLayerOpts(c *netConfig, opt Optimizer, numLayers int) error {
+	for ii := range c.layerOpts {
+		if ii < 0 || ii >= numLayers {
+			return fmt.Errorf("optimizer layer %d out of range", ii)
+		}
+	}
+	for ii := range c.lrScales {
+		if ii < 0 || ii >= numLayers {
+			return fmt.Errorf("learning rate scale layer %d out of range", ii)
+		}
+		lopt, ok := c.layerOpts[ii]
+		if !ok {
+			lopt = opt
+		}
+		if _, ok := lopt.(LrOptimizer); !ok {
+			return fmt.Errorf("learning rate scale for layer %d needs an LrOptimizer; got %T",
+				ii, lopt)
+		}
+	}
+	return nil
+}
+
+// WithWeightNorm enables weight normalization for all units after the input
+// layer. Each unit's connection weights are reparameterized as a trainable
+// gain times a normalized direction, which helps stabilize training of deeper
+// networks.
+func WithWeightNorm() Option {
+	return func(c *netConfig) {
+		c.weightNorm = true
+	}
 }
 
 // NewMLP constructs a new fully-connected network with the given architecture.
-func NewMLP(arch []int, opt Optimizer) *Net {
+// It returns an error if the architecture or options are invalid.
+func NewMLP(arch []int, opt Optimizer, opts ...Option) (*Net, error) {
 	// Check for valid architecture
 	numLayers := len(arch)
 	if numLayers < 3 {
-		// TODO: These should probably be errors, not panics. Also, add error
-		// handling elsewhere as needed.
-		panic(fmt.Sprintf("MLP architectures need >= 2 layers; got %d",
-			numLayers))
+		return nil, fmt.Errorf("MLP architectures need >= 2 layers; got %d",
+			numLayers)
 	}
 	for _, sz := range arch {
 		if sz < 1 {
-			panic(fmt.Sprintf("Each layer >= 1 unit; got %d", sz))
+			return nil, fmt.Errorf("each layer needs >= 1 unit; got %d", sz)
 		}
 	}
 
+	var c netConfig
+	for _, o := range opts {
+		o(&c)
+	}
+	kinds, err := layerKinds(numLayers, c.kinds, c.mixed)
+	if err != nil {
+		return nil, err
+	}
+	if c.activs != nil && len(c.activs) != numLayers-1 {
+		return nil, fmt.Errorf("need one activation per layer after the input (%d); got %d",
+			numLayers-1, len(c.activs))
+	}
+	if c.quantBits != 0 && c.quantBits < 2 {
+		return nil, fmt.Errorf("quantization needs >= 2 bits; got %d", c.quantBits)
+	}
+	if err := checkLayerOpts(&c, opt, numLayers); err != nil {
+		return nil, err
+	}
+	if err := checkBayes(&c); err != nil {
+		return nil, err
+	}
+	if err := checkSkips(&c, numLayers); err != nil {
+		return nil, err
+	}
+	if err := checkSparse(&c); err != nil {
+		return nil, err
+	}
+	if err := checkNoise(&c, numLayers); err != nil {
+		return nil, err
+	}
+	if err := checkEmbeddings(&c, arch); err != nil {
+		return nil, err
+	}
+	if err := checkHeads(&c, arch); err != nil {
+		return nil, err
+	}
+	if err := checkInits(&c, numLayers); err != nil {
+		return nil, err
+	}
+	if err := checkTies(&c, arch); err != nil {
+		return nil, err
+	}
+	if err := checkConvs(&c, arch); err != nil {
+		return nil, err
+	}
+	spans := headSpans(&c, arch[numLayers-1])
+
 	n := Net{
-		Arch:     make([]int, len(arch)),
-		Layers:   make([][](*Unit), numLayers),
-		stepDone: make(chan int),
+		Arch:         make([]int, len(arch)),
+		Layers:       make([][](*Unit), numLayers),
+		stepDone:     make(chan int),
+		ticket:       make(chan struct{}, 1),
+		softmax:      len(spans) > 0,
+		softmaxSpans: spans,
+		inHeads:      c.inHeads,
+		outHeads:     c.outHeads,
+		inits:        c.layerInitializers(numLayers),
+		biases:       c.biases,
+		rng:          c.rng,
+		src:          c.src,
+		opt:          opt,
+		lrOpt:        opt.New(),
+		clipNorm:     c.clipNorm,
+		lrScales:     c.lrScales,
+		ownOpts:      make(map[int]bool),
+		watchdog:     c.watchdog,
+		pipeline:     c.pipeline,
+		layers:       c.layers,
+		workers:      c.workers,
+		batching:     c.batching,
+	}
+
+	if c.anomaly {
+		n.anomaly = new(anomalyState)
+	}
+	for ii := range c.layerOpts {
+		n.ownOpts[ii] = true
 	}
 
 	logf(1, "Building a %d layer network.\n  Arch=%v\n", numLayers, arch)
 	copy(n.Arch, arch)
 
 	// Make layers.
-	const idFormStr = "%03d_%06d"
 	var id string
 	var u *Unit
 	for ii := 0; ii < numLayers; ii++ {
 		l := make([]*Unit, arch[ii])
 		for jj := 0; jj < arch[ii]; jj++ {
-			id = fmt.Sprintf(idFormStr, ii, jj)
+			id = unitID(ii, jj)
+			// Need a new opt for each unit so that each gets their own buffer data.
+			u = kinds[ii][jj%len(kinds[ii])](id, c.unitOptimizer(opt, ii))
+			u.stepDone = n.stepDone
+			u.clipValue = c.clipValue
+			u.netStep = c.clipNorm > 0
+			if ii > 0 && c.activs != nil && c.activs[ii-1] != nil {
+				u.setActivation(cloneActivation(c.activs[ii-1]))
+			}
 			switch ii {
 			case 0:
-				// Need a new opt for each unit so that each gets their own buffer data.
-				u = newInputUnit(id, opt.New(), n.stepDone)
+				u.feedIn()
 			case numLayers - 1:
-				u = newOutputUnit(id, opt.New(), n.stepDone)
-			default:
-				u = newHiddenUnit(id, opt.New(), n.stepDone)
+				u.feedOut()
 			}
 			l[jj] = u
 		}
 		n.Layers[ii] = l
 	}
 
-	// Connect all the layers in a fully-connected pattern.
+	n.sched = schedStates(n.Layers)
+
+	// Connect all the layers in a fully-connected pattern, unless they're
+	// sparse or convolutions.
 	for ii := 0; ii < numLayers-1; ii++ {
+		if cv, ok := c.convs[ii+1]; ok {
+			cv.connect(n.Layers[ii], n.Layers[ii+1], n.rand())
+			continue
+		}
+		if c.sparse() {
+			c.connectSparse(n.Layers[ii], n.Layers[ii+1], n.rand())
+			continue
+		}
 		for _, u1 := range n.Layers[ii] {
 			for _, u2 := range n.Layers[ii+1] {
-				u1.connect(u2)
+				u1.connect(u2, n.rand())
+			}
+		}
+	}
+	n.connectSkips(c.skips)
+	for ii, l := range n.Layers {
+		for _, u := range l {
+			n.initUnit(ii, u)
+		}
+	}
+	for _, t := range c.ties {
+		n.tieWeights(t[0], t[1])
+	}
+	n.shareKernels(c.convs)
+	n.embedInputs(c.embeds)
+	for _, l := range n.Layers {
+		for _, u := range l {
+			n.seedUnit(u)
+		}
+	}
+
+	if c.weightNorm {
+		for _, l := range n.Layers[1:] {
+			for _, u := range l {
+				u.W.normalize()
 			}
 		}
 	}
-	return &n
+	if c.bayes != nil {
+		n.bayesNet(c.bayes)
+	}
+	if c.quantBits > 0 {
+		n.quantizeNet(c.quantBits)
+	}
+	n.noiseNet(&c)
+	return &n, nil
 }
 
-// Forward pass through the network. The input is a single data sample.
-func (n *Net) Forward(data []float64) (output []float64) {
-	inDim := len(data)
-	if inDim != n.Arch[0] {
-		panic(fmt.Sprintf("Input dim (%d) not equal to number of input units (%d)",
-			inDim, n.Arch[0]))
+// MustNewMLP is like NewMLP but panics if the architecture or options are
+// invalid.
+func MustNewMLP(arch []int, opt Optimizer, opts ...Option) *Net {
+	n, err := NewMLP(arch, opt, opts...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return n
+}
+
+// layerKinds looks up the unit kinds for each layer. Layers with more than
+// one kind cycle through them.
+func layerKinds(numLayers int, names []string,
+	mixed map[int][]string) ([][]UnitKind, error) {
+	if names == nil {
+		names = make([]string, numLayers)
+		names[0] = InputKind
+		names[numLayers-1] = OutputKind
+		for ii := 1; ii < numLayers-1; ii++ {
+			names[ii] = HiddenKind
+		}
+	}
+	if len(names) != numLayers {
+		return nil, fmt.Errorf("got %d unit kinds for %d layers", len(names),
+			numLayers)
+	}
+
+	layerNames := make([][]string, numLayers)
+	for ii, name := range names {
+		layerNames[ii] = []string{name}
+	}
+	for ii, m := range mixed {
+		if ii < 0 || ii >= numLayers {
+			return nil, fmt.Errorf("mixed layer %d out of range", ii)
+		}
+		if len(m) == 0 {
+			return nil, fmt.Errorf("mixed layer %d has no unit kinds", ii)
+		}
+		layerNames[ii] = m
+	}
+
+	kinds := make([][]UnitKind, numLayers)
+	for ii, lnames := range layerNames {
+		kinds[ii] = make([]UnitKind, len(lnames))
+		for jj, name := range lnames {
+			kind, ok := LookupUnitKind(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown unit kind %q", name)
+			}
+			kinds[ii][jj] = kind
+		}
+	}
+	return kinds, nil
+}
+
+// Forward pass through the network. The input is a single data sample. It
+// returns an error if the input dimension doesn't match the network.
+//
+// Forward is safe to call from multiple goroutines: passes are run one at a
+// time, and in training mode each Forward and the matching Backward are run
+// together. So a goroutine mustn't call Forward twice without a Backward in
+// between, or it blocks forever (see WithWatchdog).
+func (n *Net) Forward(data []float64) ([]float64, error) {
+	output, _, err := n.forwardCapture(data, false)
+	return output, err
+}
+
+// ForwardWithActivations is like Forward, but also returns the activation of
+// every unit in the pass, keyed by unit ID, e.g. to extract hidden layer
+// features. See Activations.
+func (n *Net) ForwardWithActivations(data []float64) (output []float64,
+	acts map[string]float64, err error) {
+	return n.forwardCapture(data, true)
+}
+
+// forwardCapture runs a forward pass, capturing the unit activations before
+// another pass can start if capture is set.
+func (n *Net) forwardCapture(data []float64, capture bool) (output []float64,
+	acts map[string]float64, err error) {
+	if err := n.checkInput(data); err != nil {
+		return nil, nil, err
 	}
 
 	logf(2, "MLP Forward\n")
+	done, stop := n.watch()
+	defer stop()
+	if !n.acquire(done) {
+		return nil, nil, n.deadlock("forward")
+	}
+	output, ok := n.forward(data, done)
+	if ok && capture {
+		acts = n.Activations()
+	}
+	n.endForward(ok)
+	if !ok {
+		return nil, nil, n.deadlock("forward")
+	}
+	return output, acts, n.anomalyErr()
+}
+
+// MustForward is like Forward but panics on error.
+func (n *Net) MustForward(data []float64) []float64 {
+	output, err := n.Forward(data)
+	if err != nil {
+		panic(err.Error())
+	}
+	return output
+}
 
-	// Feed in.
-	for ii, v := range data {
-		n.Layers[0][ii].input <- signal{id: inputID, value: v}
+// checkInput checks the dimension of an input sample.
+func (n *Net) checkInput(data []float64) error {
+	if dim := n.inputDim(); len(data) != dim {
+		return fmt.Errorf("input dim (%d) not equal to number of inputs (%d)",
+			len(data), dim)
 	}
+	return n.checkCategories(data)
+}
+
+// checkGrad checks the dimension of an output gradient.
+func (n *Net) checkGrad(grad []float64) error {
+	outDim := n.Arch[len(n.Arch)-1]
+	if len(grad) != outDim {
+		return fmt.Errorf("grad dim (%d) not equal to number of output units (%d)",
+			len(grad), outDim)
+	}
+	return nil
+}
+
+// ForwardTimeout is like Forward, but returns an error if the pass doesn't
+// complete within d, e.g. due to a misconfigured graph, instead of blocking
+// indefinitely. See ForwardContext.
+func (n *Net) ForwardTimeout(data []float64, d time.Duration) ([]float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	output, err := n.ForwardContext(ctx, data)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("forward pass did not complete within %v: %w", d,
+			ctx.Err())
+	}
+	return output, err
+}
+
+// ForwardContext is like Forward, but returns an error if the context is
+// cancelled or its deadline passes before the pass completes. If the context
+// is already done, the pass isn't started. Otherwise the aborted pass can't be
+// completed, so the network is stopped (see Stop) and has to be started again
+// before it's reused.
+func (n *Net) ForwardContext(ctx context.Context, data []float64) ([]float64, error) {
+	if err := n.checkInput(data); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	logf(2, "MLP Forward (context)\n")
+	if !n.acquire(ctx.Done()) {
+		return nil, fmt.Errorf("forward pass not started: %w", ctx.Err())
+	}
+	output, ok := n.forward(data, ctx.Done())
+	n.endForward(ok)
+	if !ok {
+		n.Stop()
+		return nil, fmt.Errorf("forward pass aborted: %w", ctx.Err())
+	}
+	return output, n.anomalyErr()
+}
 
+// forward feeds a data sample in and collects the output. It gives up if done
+// is closed before the pass completes, in which case ok is false and n.stuck
+// describes what the pass was waiting for. A nil done blocks until the pass
+// completes.
+func (n *Net) forward(data []float64, done <-chan struct{}) (output []float64, ok bool) {
+	n.applyLR()
+	// Units read the batch size mid-pass, so it's only written when it changes
+	// to keep misuse, e.g. a Forward without a Backward, race free.
+	if n.batch != 1 {
+		n.batch = 1
+	}
+	if output, ok = n.feedForward(data, 0, done); !ok {
+		return nil, false
+	}
+
+	// In evaluation mode units are done after the forward pass. Sequence
+	// units are only done after a backward step.
+	if !n.train && !n.seq && !n.syncDone(done) {
+		return nil, false
+	}
+	return output, true
+}
+
+// feedForward feeds a single data sample in and collects the output, like
+// forward, without waiting for the units to finish the pass. tag is the index
+// of the sample within a batch.
+func (n *Net) feedForward(data []float64, tag int, done <-chan struct{}) (output []float64, ok bool) {
+	if !n.feedInput(data, tag, done) {
+		return nil, false
+	}
+	return n.collectOutput(done)
+}
+
+// feedInput feeds a single data sample into the input layer.
+func (n *Net) feedInput(data []float64, tag int, done <-chan struct{}) (ok bool) {
+	for ii, v := range n.expandInput(data) {
+		select {
+		case n.Layers[0][ii].input <- signal{id: inputID, value: v, tag: tag}:
+		case <-done:
+			n.stuck = "sending input to " + n.Layers[0][ii].ID
+			return false
+		}
+	}
+	return true
+}
+
+// collectOutput collects the output of the next sample from the output
+// layer.
+func (n *Net) collectOutput(done <-chan struct{}) (output []float64, ok bool) {
 	numLayers := len(n.Arch)
 	outDim := n.Arch[numLayers-1]
 	output = make([]float64, outDim)
@@ -95,53 +643,314 @@ func (n *Net) Forward(data []float64) (output []float64) {
 	// Feed out.
 	var s signal
 	for ii := 0; ii < outDim; ii++ {
-		s = <-n.Layers[numLayers-1][ii].output[outputID]
+		select {
+		case s = <-n.Layers[numLayers-1][ii].output[outputID]:
+		case <-done:
+			n.stuck = "receiving output from " + n.Layers[numLayers-1][ii].ID
+			return nil, false
+		}
 		output[ii] = s.value
 	}
-	return
+	if n.softmax {
+		output = n.forwardSoftmax(output)
+	}
+	return output, true
 }
 
 // Backward pass a loss gradient through the network. Input grad should be a
-// gradient with respect to each of the network outputs.
-func (n *Net) Backward(grad []float64) {
-	outDim := n.Arch[len(n.Arch)-1]
-	gradDim := len(grad)
-	if gradDim != outDim {
-		panic(fmt.Sprintf("Grad dim (%d) not equal to number of output units (%d)",
-			gradDim, outDim))
+// gradient with respect to each of the network outputs. It returns an error
+// if the gradient dimension doesn't match the network.
+func (n *Net) Backward(grad []float64) error {
+	if err := n.checkGrad(grad); err != nil {
+		return err
 	}
 
 	logf(2, "MLP Backward\n")
+	done, stop := n.watch()
+	defer stop()
+	err := n.backward(grad, done)
+	if errors.Is(err, errAborted) {
+		return n.deadlock("backward")
+	}
+	if err == nil {
+		n.endBackward()
+		err = n.anomalyErr()
+	}
+	return err
+}
+
+// MustBackward is like Backward but panics on error.
+func (n *Net) MustBackward(grad []float64) {
+	if err := n.Backward(grad); err != nil {
+		panic(err.Error())
+	}
+}
+
+// BackwardContext is like Backward, but returns an error if the context is
+// cancelled or its deadline passes before the pass completes, e.g. when
+// called without a matching forward pass. Like ForwardContext, the network is
+// then stopped. Gradients of the aborted pass may have been partially
+// accumulated.
+func (n *Net) BackwardContext(ctx context.Context, grad []float64) error {
+	if err := n.checkGrad(grad); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	logf(2, "MLP Backward (context)\n")
+	err := n.backward(grad, ctx.Done())
+	if errors.Is(err, errAborted) {
+		n.Stop()
+		return fmt.Errorf("backward pass aborted: %w", ctx.Err())
+	}
+	if err == nil {
+		n.endBackward()
+		err = n.anomalyErr()
+	}
+	return err
+}
+
+// errAborted is returned by backward when done is closed before the pass
+// completes.
+var errAborted = errors.New("pass aborted")
+
+// backward feeds a loss gradient in and waits for the pass to complete. It
+// gives up with errAborted if done is closed first.
+func (n *Net) backward(grad []float64, done <-chan struct{}) error {
+	if n.batch > 1 {
+		return fmt.Errorf("backward after a batch of %d samples; use BackwardBatch",
+			n.batch)
+	}
+	if n.softmax {
+		var err error
+		if grad, err = n.backwardSoftmax(grad); err != nil {
+			return err
+		}
+	}
 
-	// Feed in (backward).
+	if !n.feedGrad(grad, 0, done) || !n.syncDone(done) {
+		return errAborted
+	}
+	n.stepped(1)
+	return nil
+}
+
+// stepped counts samples that completed a backward pass, updating the weight
+// update and symmetry logging state.
+func (n *Net) stepped(samples int) {
+	steps := n.steps
+	n.steps += samples
+	if n.updateFreq > 0 && n.steps/n.updateFreq > steps/n.updateFreq {
+		n.updated()
+	}
+	if n.SymmetryFreq > 0 && n.steps/n.SymmetryFreq > steps/n.SymmetryFreq {
+		n.logSymmetry()
+	}
+}
+
+// Steps returns the number of samples back-propagated through the network
+// since it was created.
+func (n *Net) Steps() int {
+	return n.steps
+}
+
+// setSteps sets the number of samples back-propagated, e.g. when resuming
+// training, so that weight updates fall on the same steps.
+func (n *Net) setSteps(steps int) {
+	n.steps = steps
+	for _, l := range n.Layers {
+		for _, u := range l {
+			u.steps = steps
+		}
+	}
+}
+
+// feedGrad feeds a gradient into the output layer. tag is the index of the
+// sample within a batch. It gives up if done is closed first, in which case
+// ok is false and n.stuck describes what the pass was waiting for. Once the
+// gradient is fed in, the units should be synced to wait for them to finish
+// backward and step, to avoid a race.
+func (n *Net) feedGrad(grad []float64, tag int, done <-chan struct{}) (ok bool) {
 	numLayers := len(n.Arch)
 	for ii, v := range grad {
-		n.Layers[numLayers-1][ii].inputB <- signal{id: inputID, value: v}
+		select {
+		case n.Layers[numLayers-1][ii].inputB <- signal{id: inputID, value: v, tag: tag}:
+		case <-done:
+			n.stuck = "sending gradient to " + n.Layers[numLayers-1][ii].ID
+			return false
+		}
 	}
+	return true
+}
 
-	// Wait for all units to finish backward and step to avoid a race.
-	n.sync()
+// updated is called after each weight update.
+func (n *Net) updated() {
+	if n.clipNorm > 0 {
+		n.clipStep()
+	} else if n.convs != nil {
+		n.stepShared()
+	}
+	for _, s := range n.sched {
+		s.advance()
+	}
 }
 
 // sync waits for all units to complete their forward/backward/step sequence.
 func (n *Net) sync() {
+	n.syncDone(nil)
+}
+
+// syncDone is like sync, but gives up if done is closed first, in which case
+// ok is false.
+func (n *Net) syncDone(done <-chan struct{}) (ok bool) {
 	totalUnits := 0
 	for _, v := range n.Arch {
 		totalUnits += v
 	}
+	return n.syncUnits(totalUnits, done)
+}
+
+// syncUnits waits for count units to signal done, giving up if done is closed
+// first.
+func (n *Net) syncUnits(totalUnits int, done <-chan struct{}) (ok bool) {
 	for ii := 0; ii < totalUnits; ii++ {
-		<-n.stepDone
+		select {
+		case <-n.stepDone:
+		case <-done:
+			n.stuck = fmt.Sprintf("waiting for %d of %d units to finish the pass",
+				totalUnits-ii, totalUnits)
+			return false
+		}
 	}
+	return true
+}
+
+// Restarts returns the total number of times units have been restarted after
+// a failure. It should only be called while the network is idle, e.g. after
+// Backward returns.
+func (n *Net) Restarts() int {
+	restarts := 0
+	for _, l := range n.Layers {
+		for _, u := range l {
+			restarts += u.restarts
+		}
+	}
+	return restarts
 }
 
 // Start running each unit's forward/backward/step loop concurrently. Neuron
 // weights and biases are updated every updateFreq iterations. By setting
 // updateFreq > 1, we can simulate mini-batch optimization.
+//
+// In training mode, every Forward must be followed by a Backward. In
+// evaluation mode units only run the forward pass. The mode can be switched
+// later with Train and Eval.
+//
+// Units are supervised: if a unit panics, e.g. in a custom activation, it is
+// restored from the weights saved at its last update and the current pass is
+// completed with zero signals, so that the network keeps running. Layers run
+// with WithLayerEngine are not supervised.
 func (n *Net) Start(train bool, updateFreq int) {
+	n.train = train
+	n.updateFreq = updateFreq
+	init := func(u *Unit) {
+		u.save()
+		logf(2, "Start %s\n", u.ID)
+	}
+	if n.layers {
+		n.runLayers(init, updateFreq)
+		return
+	}
+	n.run(init, func(u *Unit) {
+		u.start(updateFreq)
+	})
+}
+
+// Train switches the running network to training mode, so that every
+// Forward has to be followed by a Backward. Like Eval, it waits for any pass
+// in progress and takes effect from the next pass, so it mustn't be called
+// between a Forward and its Backward.
+func (n *Net) Train() {
+	n.acquire(nil)
+	n.train = true
+	n.release()
+}
+
+// Eval switches the running network to evaluation mode, so that units only
+// run the forward pass, e.g. to serve a trained network without restarting
+// its goroutines. Accumulated gradients are kept for when training resumes.
+func (n *Net) Eval() {
+	n.acquire(nil)
+	n.train = false
+	n.release()
+}
+
+// Training reports whether the network is in training mode.
+func (n *Net) Training() bool {
+	return n.train
+}
+
+// run starts a goroutine running loop for each unit, after calling init for
+// each unit.
+func (n *Net) run(init, loop func(u *Unit)) {
+	if n.batching {
+		n.connectBuses()
+	}
+	for _, l := range n.Layers {
+		for _, u := range l {
+			n.setup(u)
+			init(u)
+			n.units.Add(1)
+			go func(u *Unit) {
+				defer n.units.Done()
+				loop(u)
+			}(u)
+		}
+	}
+}
+
+// setup shares the network's run state with a unit about to be started.
+func (n *Net) setup(u *Unit) {
+	if n.quit == nil {
+		n.quit = make(chan struct{})
+	}
+	u.quit = n.quit
+	u.train = &n.train
+	u.W.train = u.train
+	u.batch = &n.batch
+	u.pipeline = n.pipeline
+	u.watch = n.watchdog > 0
+	u.anomaly = n.anomaly
+}
+
+// Stop terminates every unit's loop and waits for the unit goroutines to
+// exit, so that a network that's no longer needed doesn't leak them. Units
+// blocked mid-pass, e.g. after a ForwardTimeout, are stopped too. The
+// network keeps its weights and can be started again. Stop does nothing if
+// the network isn't running.
+func (n *Net) Stop() {
+	if n.quit == nil {
+		return
+	}
+	close(n.quit)
+	n.units.Wait()
+	n.quit = nil
+	// A pass waiting for its backward pass can't be completed.
+	n.endBackward()
 	for _, l := range n.Layers {
 		for _, u := range l {
-			go u.start(train, updateFreq)
-			logf(2, "Start %s\n", u.ID)
+			u.quit = nil
+			u.waitOp = waitNone
+			u.tag = 0
+			u.early = u.early[:0]
+			u.seq = false
+			u.hist = u.hist[:0]
 		}
 	}
+	n.seq = false
+	n.probs = n.probs[:0]
+	logf(2, "Stopped\n")
 }
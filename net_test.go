@@ -1,9 +1,14 @@
 package neuron
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"runtime"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Test construction of a new MLP network
@@ -12,7 +17,7 @@ func TestNewMLP(t *testing.T) {
 
 	arch := []int{2, 4, 4, 1}
 	opt := NewSGD(1.0, 0.0, 0.0)
-	n := NewMLP(arch, opt)
+	n := MustNewMLP(arch, opt)
 	for ii, sz := range arch {
 		if n.Arch[ii] != sz {
 			t.Errorf("Layer %d size is %d; expected %d", ii, n.Arch[ii], sz)
@@ -21,9 +26,14 @@ func TestNewMLP(t *testing.T) {
 
 	// Check that invalid architectures are checked.
 	arch = []int{2, 4}
-	assertPanic(t, func() { NewMLP(arch, opt) })
+	if _, err := NewMLP(arch, opt); err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
 	arch = []int{2, 4, -1}
-	assertPanic(t, func() { NewMLP(arch, opt) })
+	if _, err := NewMLP(arch, opt); err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+	assertPanic(t, func() { MustNewMLP(arch, opt) })
 }
 
 // Test full forward/backward/step loop for the entire MLP.
@@ -35,11 +45,11 @@ func TestMLP(t *testing.T) {
 
 	arch := []int{2, 3, 2, 1}
 	opt := NewSGD(1.0, 0.9, 1.0e-04)
-	n := NewMLP(arch, opt)
+	n := MustNewMLP(arch, opt)
 
 	n.Start(true, 1)
-	output := n.Forward([]float64{1.123, -2.234})
-	n.Backward([]float64{1.0})
+	output := n.MustForward([]float64{1.123, -2.234})
+	n.MustBackward([]float64{1.0})
 
 	const outWant = 8.4846442116e-05
 	if !almostEqual(output[0], outWant) {
@@ -54,10 +64,15 @@ func TestMLP(t *testing.T) {
 	}
 
 	// Check that invalid args are checked.
-	assertPanic(t, func() { n.Forward([]float64{1.123}) })
-	// TODO: If backward is called without a forward it blocks because each unit
-	// is blocked on forward. This should be tracked and handled inside net?
-	assertPanic(t, func() { n.Backward([]float64{1.123, -2.234}) })
+	if _, err := n.Forward([]float64{1.123}); err == nil {
+		t.Errorf("Forward did not return an error")
+	}
+	assertPanic(t, func() { n.MustForward([]float64{1.123}) })
+	// Backward without a forward would block since each unit is waiting on
+	// forward, but a bad grad dim is caught first. See TestWatchdog.
+	if err := n.Backward([]float64{1.123, -2.234}); err == nil {
+		t.Errorf("Backward did not return an error")
+	}
 }
 
 // Benchmark a full forward/backward/step loop.
@@ -75,7 +90,7 @@ func BenchmarkMLP(b *testing.B) {
 	arch := []int{inDim, 128, 128, outDim}
 	// lr set to 0 so we don't acually update the weights
 	opt := NewSGD(0.0, 0.0, 0.0)
-	n := NewMLP(arch, opt)
+	n := MustNewMLP(arch, opt)
 
 	input := make([]float64, inDim)
 	for ii := 0; ii < inDim; ii++ {
@@ -86,7 +101,496 @@ func BenchmarkMLP(b *testing.B) {
 	n.Start(true, 1)
 
 	for ii := 0; ii < b.N; ii++ {
-		n.Forward(input)
-		n.Backward(grad)
+		n.MustForward(input)
+		n.MustBackward(grad)
+	}
+}
+
+// Test building a network from custom unit kinds.
+func TestUnitKinds(t *testing.T) {
+	RegisterUnitKind("reversal", func(id string, opt Optimizer) *Unit {
+		u := NewUnit(id, &GradReversal{Lambda: 1.0}, opt)
+		u.SetBias(0.0)
+		return u
+	})
+
+	arch := []int{2, 3, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	kinds := []string{InputKind, "reversal", OutputKind}
+	n := MustNewMLP(arch, opt, WithUnitKinds(kinds))
+	for _, u := range n.Layers[1] {
+		if _, ok := u.Activation().(*GradReversal); !ok {
+			t.Errorf("Unit %s has activation %T; expected *GradReversal", u.ID,
+				u.Activation())
+		}
+	}
+
+	n.Start(true, 1)
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{1.0})
+
+	// Built-in sigmoid and tanh hidden units.
+	kinds = []string{InputKind, SigmoidKind, TanhKind, OutputKind}
+	n = MustNewMLP([]int{2, 3, 3, 1}, opt, WithUnitKinds(kinds))
+	if _, ok := n.Layers[1][0].Activation().(*Sigmoid); !ok {
+		t.Errorf("Expected *Sigmoid activation; got %T", n.Layers[1][0].Activation())
+	}
+	if _, ok := n.Layers[2][0].Activation().(*Tanh); !ok {
+		t.Errorf("Expected *Tanh activation; got %T", n.Layers[2][0].Activation())
+	}
+
+	// Check that invalid kinds are checked.
+	if _, err := NewMLP(arch, opt, WithUnitKinds([]string{"foo"})); err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+	kinds = []string{InputKind, "foo", OutputKind}
+	if _, err := NewMLP(arch, opt, WithUnitKinds(kinds)); err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+}
+
+// flakyActivation is an identity activation that panics on its first forward
+// pass.
+type flakyActivation struct {
+	calls int
+}
+
+func (a *flakyActivation) Forward(value float64) float64 {
+	a.calls++
+	if a.calls == 1 {
+		panic("flaky activation")
+	}
+	return value
+}
+
+func (a *flakyActivation) Backward(grad float64) float64 {
+	return grad
+}
+
+// Test that failed units are restarted without wedging the network.
+func TestUnitRestart(t *testing.T) {
+	RegisterUnitKind("flaky", func(id string, opt Optimizer) *Unit {
+		return NewUnit(id, new(flakyActivation), opt)
+	})
+
+	arch := []int{2, 2, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	kinds := []string{InputKind, "flaky", OutputKind}
+	n := MustNewMLP(arch, opt, WithUnitKinds(kinds))
+	weights := n.Layers[1][0].W.Params["000_000000"].Data
+
+	n.Start(true, 1)
+	output := n.MustForward([]float64{1.0, 1.0})
+	n.MustBackward([]float64{1.0})
+	// Failed units emit zero, leaving only the output bias.
+	if output[0] != 0.0 {
+		t.Errorf("Output is %.4f; expected 0", output[0])
+	}
+	if r := n.Restarts(); r != 2 {
+		t.Errorf("Got %d restarts; expected 2", r)
+	}
+	// Failed units receive no update.
+	if w := n.Layers[1][0].W.Params["000_000000"].Data; w != weights {
+		t.Errorf("Failed unit weight is %.4f; expected %.4f", w, weights)
+	}
+
+	// The network keeps running.
+	n.MustForward([]float64{1.0, 1.0})
+	n.MustBackward([]float64{1.0})
+	if r := n.Restarts(); r != 2 {
+		t.Errorf("Got %d restarts; expected 2", r)
+	}
+}
+
+// failingActivation is an identity activation that panics on its forwardAt-th
+// forward pass and its backwardAt-th backward pass.
+type failingActivation struct {
+	forwardAt, backwardAt int
+	forwards, backwards   int
+}
+
+func (a *failingActivation) Forward(value float64) float64 {
+	a.forwards++
+	if a.forwards == a.forwardAt {
+		panic("failing activation forward")
+	}
+	return value
+}
+
+func (a *failingActivation) Backward(grad float64) float64 {
+	a.backwards++
+	if a.backwards == a.backwardAt {
+		panic("failing activation backward")
+	}
+	return grad
+}
+
+// Test that units failing mid-batch are restarted without wedging the
+// network, with and without pipelining.
+func TestUnitRestartBatch(t *testing.T) {
+	// Fail on the second sample of the first batch. Backward passes run from
+	// the last sample.
+	RegisterUnitKind("failForward", func(id string, opt Optimizer) *Unit {
+		return NewUnit(id, &failingActivation{forwardAt: 2}, opt)
+	})
+	RegisterUnitKind("failBackward", func(id string, opt Optimizer) *Unit {
+		return NewUnit(id, &failingActivation{backwardAt: 2}, opt)
+	})
+	batch := [][]float64{{1.0, 1.0}, {0.5, -1.0}, {-1.0, 2.0}}
+	grads := [][]float64{{1.0}, {1.0}, {1.0}}
+	for _, kind := range []string{"failForward", "failBackward"} {
+		for _, pipeline := range []bool{false, true} {
+			opts := []Option{WithUnitKinds([]string{InputKind, kind, OutputKind}),
+				WithWatchdog(time.Second)}
+			if pipeline {
+				opts = append(opts, WithPipelining())
+			}
+			n := MustNewMLP([]int{2, 3, 1}, NewSGD(0.1, 0.0, 0.0), opts...)
+			bias := n.Layers[2][0].W.Params[biasID].Data
+			n.Start(true, 3)
+			for ii := 0; ii < 2; ii++ {
+				out, err := n.ForwardBatch(batch)
+				if err != nil {
+					t.Fatalf("(%s, pipeline=%v) ForwardBatch %d failed: %v", kind, pipeline,
+						ii, err)
+				}
+				if err := n.BackwardBatch(grads); err != nil {
+					t.Fatalf("(%s, pipeline=%v) BackwardBatch %d failed: %v", kind, pipeline,
+						ii, err)
+				}
+				// Units failing in the forward pass emit zero for the rest of
+				// the batch.
+				if ii == 0 && kind == "failForward" && out[2][0] != bias {
+					t.Errorf("(%s, pipeline=%v) Output is %.4f; expected %.4f", kind,
+						pipeline, out[2][0], bias)
+				}
+			}
+			if _, err := n.ForwardTimeout(batch[0], time.Second); err != nil {
+				t.Fatalf("(%s, pipeline=%v) Forward failed: %v", kind, pipeline, err)
+			}
+			n.MustBackward(grads[0])
+			if r := n.Restarts(); r != 3 {
+				t.Errorf("(%s, pipeline=%v) Got %d restarts; expected 3", kind, pipeline, r)
+			}
+			for _, u := range n.Layers[1] {
+				if u.steps != n.steps {
+					t.Errorf("(%s, pipeline=%v) Unit %s is at step %d; expected %d", kind,
+						pipeline, u.ID, u.steps, n.steps)
+				}
+			}
+			n.Stop()
+		}
+	}
+}
+
+// Test forward passes with a deadline.
+func TestForwardTimeout(t *testing.T) {
+	arch := []int{2, 3, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	n := MustNewMLP(arch, opt)
+	n.Start(false, 0)
+
+	if _, err := n.ForwardTimeout([]float64{1.0, 1.0}, time.Second); err != nil {
+		t.Errorf("ForwardTimeout failed: %v", err)
+	}
+	if _, err := n.ForwardTimeout([]float64{1.0}, time.Second); err == nil {
+		t.Errorf("Expected error for invalid input dim")
+	}
+
+	// Misconfigured unit waiting on an input that never arrives.
+	n = MustNewMLP(arch, opt)
+	n.Layers[2][0].nin++
+	n.Start(false, 0)
+	_, err := n.ForwardTimeout([]float64{1.0, 1.0}, 10*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ForwardTimeout returned %v; expected deadline exceeded", err)
+	}
+}
+
+// Test mixing unit kinds within a layer.
+func TestMixedLayer(t *testing.T) {
+	RegisterUnitKind("reversal", func(id string, opt Optimizer) *Unit {
+		u := NewUnit(id, &GradReversal{Lambda: 1.0}, opt)
+		u.SetBias(0.0)
+		return u
+	})
+
+	arch := []int{2, 4, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	n := MustNewMLP(arch, opt, WithMixedLayer(1, HiddenKind, "reversal"))
+	for jj, u := range n.Layers[1] {
+		_, isRelu := u.Activation().(*Relu)
+		_, isRev := u.Activation().(*GradReversal)
+		if (jj%2 == 0 && !isRelu) || (jj%2 == 1 && !isRev) {
+			t.Errorf("Unit %s has activation %T", u.ID, u.Activation())
+		}
+	}
+
+	n.Start(true, 1)
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{1.0})
+
+	// Check that invalid mixed layers are checked.
+	if _, err := NewMLP(arch, opt, WithMixedLayer(3, HiddenKind)); err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+	if _, err := NewMLP(arch, opt, WithMixedLayer(1)); err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+	if _, err := NewMLP(arch, opt, WithMixedLayer(1, "foo")); err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+}
+
+// Test choosing the activation of each layer.
+func TestWithActivations(t *testing.T) {
+	arch := []int{2, 3, 3, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	activs := []Activation{new(Tanh), nil, &GradReversal{Lambda: 2.0}}
+	n := MustNewMLP(arch, opt, WithActivations(activs))
+	for _, u := range n.Layers[1] {
+		if _, ok := u.Activation().(*Tanh); !ok {
+			t.Errorf("Unit %s has activation %T; expected *Tanh", u.ID, u.Activation())
+		}
+	}
+	for _, u := range n.Layers[2] {
+		if _, ok := u.Activation().(*Relu); !ok {
+			t.Errorf("Unit %s has activation %T; expected *Relu", u.ID, u.Activation())
+		}
+	}
+	rev, ok := n.Layers[3][0].Activation().(*GradReversal)
+	if !ok || rev.Lambda != 2.0 {
+		t.Errorf("Output has activation %#v; expected GradReversal{2}",
+			n.Layers[3][0].Activation())
+	}
+	// Units don't share activation state.
+	if n.Layers[1][0].Activation() == n.Layers[1][1].Activation() {
+		t.Errorf("Units share an activation")
+	}
+
+	n.Start(true, 1)
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{1.0})
+
+	if _, err := NewMLP(arch, opt, WithActivations(activs[:2])); err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+}
+
+// Test per-layer optimizers and learning rate scales.
+func TestLayerOptimizers(t *testing.T) {
+	arch := []int{2, 3, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	n := MustNewMLP(arch, opt, WithLayerOptimizer(1, NewAdagrad(0.5, 0.0, 0.0)),
+		WithLayerLRScale(2, 0.1))
+	for _, u := range n.Layers[1] {
+		if lr := u.opt.(*Adagrad).Lr; lr != 0.5 {
+			t.Errorf("Unit %s has lr %.4f; expected 0.5", u.ID, lr)
+		}
+	}
+	out := n.Layers[2][0]
+	if lr := out.opt.(*SGD).Lr; !almostEqual(lr, 0.1) {
+		t.Errorf("Output lr is %.4f; expected 0.1", lr)
+	}
+	if opt.Lr != 1.0 {
+		t.Errorf("Prototype optimizer was modified")
+	}
+
+	// The scale also applies to SetLR.
+	n.Start(true, 1)
+	n.SetLR(2.0)
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{0.0})
+	if lr := out.opt.(*SGD).Lr; !almostEqual(lr, 0.2) {
+		t.Errorf("Output lr is %.4f; expected 0.2", lr)
+	}
+	// Layers with their own optimizer keep their rate.
+	for _, u := range n.Layers[1] {
+		if lr := u.opt.(*Adagrad).Lr; lr != 0.5 {
+			t.Errorf("Unit %s has lr %.4f after SetLR; expected 0.5", u.ID, lr)
+		}
+	}
+	n.Stop()
+
+	// Check that invalid settings are checked.
+	if _, err := NewMLP(arch, opt, WithLayerOptimizer(3, opt)); err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+	if _, err := NewMLP(arch, opt, WithLayerLRScale(-1, 0.1)); err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+	_, err := NewMLP(arch, opt, WithLayerOptimizer(1, new(fixedOptimizer)),
+		WithLayerLRScale(1, 0.1))
+	if err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+}
+
+// fixedOptimizer is an Optimizer without an adjustable learning rate.
+type fixedOptimizer struct{}
+
+func (opt *fixedOptimizer) Step(id string, p *Param) {}
+
+func (opt *fixedOptimizer) New() Optimizer { return opt }
+
+// Test stopping the unit goroutines.
+func TestStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	arch := []int{2, 3, 1}
+	opt := NewSGD(0.1, 0.0, 0.0)
+	n := MustNewMLP(arch, opt)
+	n.Stop()
+
+	n.Start(true, 1)
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{1.0})
+	n.Stop()
+	if g := runtime.NumGoroutine(); g > before {
+		t.Errorf("%d goroutines running after Stop; expected %d", g, before)
+	}
+
+	// Stop mid-pass after a timeout.
+	n2 := MustNewMLP(arch, opt)
+	n2.Layers[2][0].nin++
+	n2.Start(true, 1)
+	n2.ForwardTimeout([]float64{1.0, -1.0}, 10*time.Millisecond)
+	n2.Stop()
+
+	// Restart in sequence mode.
+	n.Start(true, 1)
+	want := n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{0.0})
+	n.Stop()
+	n.StartSequence(false, 0)
+	got, _ := n.ForwardSequence([][]float64{{1.0, -1.0}})
+	n.Stop()
+	if g := runtime.NumGoroutine(); g > before {
+		t.Errorf("%d goroutines running after Stop; expected %d", g, before)
+	}
+	if !almostEqual(got[0][0], want[0]) {
+		t.Errorf("Output after restart is %.6f; expected %.6f", got[0][0], want[0])
+	}
+}
+
+// Test forward and backward passes that abort with their context.
+func TestContextPasses(t *testing.T) {
+	arch := []int{2, 3, 1}
+	opt := NewSGD(0.1, 0.0, 0.0)
+	n := MustNewMLP(arch, opt)
+	n.Start(true, 1)
+
+	ctx := context.Background()
+	if _, err := n.ForwardContext(ctx, []float64{1.0, -1.0}); err != nil {
+		t.Errorf("ForwardContext failed: %v", err)
+	}
+	if err := n.BackwardContext(ctx, []float64{1.0}); err != nil {
+		t.Errorf("BackwardContext failed: %v", err)
+	}
+	if err := n.BackwardContext(ctx, []float64{1.0, 1.0}); err == nil {
+		t.Errorf("Expected error for invalid grad dim")
+	}
+
+	// Backward without a matching forward blocks until the deadline.
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err := n.BackwardContext(tctx, []float64{1.0})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("BackwardContext returned %v; expected deadline exceeded", err)
+	}
+
+	// The network is stopped and can be started again. Passes with a done
+	// context aren't started.
+	n.Start(true, 1)
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := n.ForwardContext(cctx, []float64{1.0, -1.0}); !errors.Is(err, context.Canceled) {
+		t.Errorf("ForwardContext returned %v; expected canceled", err)
+	}
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{1.0})
+	n.Stop()
+}
+
+// Test switching a running network between training and evaluation mode.
+func TestTrainEval(t *testing.T) {
+	arch := []int{2, 3, 1}
+	n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0))
+	n.Start(true, 1)
+	defer n.Stop()
+	data := []float64{1.0, -1.0}
+	n.MustForward(data)
+	n.MustBackward([]float64{1.0})
+
+	// Forward passes alone don't block in evaluation mode, and don't change
+	// the weights.
+	n.Eval()
+	if n.Training() {
+		t.Errorf("Net is training after Eval")
+	}
+	want := n.MustForward(data)
+	for ii := 0; ii < 3; ii++ {
+		if got := n.MustForward(data); !almostEqual(got[0], want[0]) {
+			t.Errorf("Eval output is %.6f; expected %.6f", got[0], want[0])
+		}
+	}
+
+	// Training resumes with backward passes and weight updates.
+	n.Train()
+	n.MustForward(data)
+	n.MustBackward([]float64{1.0})
+	n.Eval()
+	if got := n.MustForward(data); almostEqual(got[0], want[0]) {
+		t.Errorf("Output unchanged after training step")
+	}
+}
+
+// Test passes from multiple goroutines.
+func TestConcurrentPasses(t *testing.T) {
+	arch := []int{2, 4, 1}
+	n := MustNewMLP(arch, NewSGD(0.01, 0.0, 0.0))
+	n.Start(false, 0)
+	defer n.Stop()
+
+	inputs := make([][]float64, 8)
+	want := make([]float64, len(inputs))
+	for ii := range inputs {
+		inputs[ii] = []float64{float64(ii), -1.0}
+		want[ii] = n.MustForward(inputs[ii])[0]
+	}
+	var wg sync.WaitGroup
+	for ii := range inputs {
+		wg.Add(1)
+		go func(ii int) {
+			defer wg.Done()
+			for jj := 0; jj < 20; jj++ {
+				got, err := n.Forward(inputs[ii])
+				if jj%2 == 1 {
+					got, err = n.Predict(inputs[ii])
+				}
+				if err != nil || !almostEqual(got[0], want[ii]) {
+					t.Errorf("Output for input %d is %v (%v); expected %.6f", ii, got,
+						err, want[ii])
+					return
+				}
+			}
+		}(ii)
+	}
+	wg.Wait()
+
+	// Forward/Backward pairs from several goroutines are run together.
+	n.Train()
+	for ii := 0; ii < 4; ii++ {
+		wg.Add(1)
+		go func(ii int) {
+			defer wg.Done()
+			for jj := 0; jj < 10; jj++ {
+				n.MustForward(inputs[ii])
+				n.MustBackward([]float64{1.0})
+			}
+		}(ii)
+	}
+	wg.Wait()
+	if n.steps != 40 {
+		t.Errorf("Ran %d backward passes; expected 40", n.steps)
 	}
 }
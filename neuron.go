@@ -7,7 +7,12 @@
 package neuron
 
 import (
+	"math"
 	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
 )
 
 // A Unit is a single neuron unit with weights, a bias, and input/output
@@ -28,11 +33,89 @@ type Unit struct {
 	outputB map[string](chan signal)
 	// Channel to keep track of when the update is done.
 	stepDone chan int
+	// Shared training mode and batch size, see Net.Train and Net.ForwardBatch.
+	// Only read once a pass has started.
+	train *bool
+	batch *int
+	// Pipelining state, see WithPipelining. tag is the index of the current
+	// sample within the batch, and early holds signals of later samples that
+	// arrived first.
+	pipeline bool
+	tag      int
+	early    []signal
+	// Signal batching state, see WithSignalBatching. busIndex is the unit's
+	// index on outBus.
+	inBus, outBus *bus
+	busIndex      int
+	// Closed to stop the unit's loop, see Net.Stop.
+	quit chan struct{}
+	// Loop state, used to recover from a failed iteration.
+	steps      int
+	phase      int
+	received   int
+	sent       bool
+	checkpoint map[string]float64
+	restarts   int
+	// Sequence state, see startSequence.
+	seq   bool
+	pre   float64
+	act   float64
+	hprev float64
+	carry float64
+	hist  []stepState
+	// Gates and cell state of gated units, see GRUKind and LSTMKind.
+	cell *gatedCell
+	// Embedding of categorical inputs, see WithEmbedding.
+	embed *embedTable
+	// Query and key weights of attention units, see AttentionKind.
+	attn *attention
+	// Hooks, see RegisterForwardHook and RegisterBackwardHook.
+	fwdHooks []ForwardHook
+	bwdHooks []BackwardHook
+	// Shared anomaly detection state, see WithAnomalyDetection.
+	anomaly *anomalyState
+	// Gradient of the network input, see Net.InputGradients.
+	inGrad float64
+	// Pre-activation noise, see WithActivationNoise.
+	noise float64
+	// Gradient clipping state, see WithGradClipValue and WithGradClipNorm.
+	clipValue float64
+	netStep   bool
+	// Watchdog state, see WithWatchdog.
+	watch     bool
+	waitMu    sync.Mutex
+	waitOp    int
+	waitCh    chan signal
+	waitSince time.Time
 }
 
 // A Weight represents a neuron's weight map.
 type Weight struct {
 	Params map[string]*Param
+	// Per-pass state for reparameterized weights, see prepare.
+	ready bool
+	// Weight normalization state, see normalize.
+	norm  bool
+	scale float64
+	r     float64
+	dw    map[string]float64
+	// Fake quantization state, see quantize.
+	qbits int
+	qstep float64
+	// Bayes by backprop state, see bayesian.
+	bayes    bool
+	eps      map[string]float64
+	priorVar float64
+	klWeight float64
+	// Weight noise state, see WithWeightNoise. train is the unit's shared
+	// training mode.
+	noise float64
+	noisy map[string]float64
+	train *bool
+	// The unit's random source for noise, see WithRand, and its source. Nil
+	// uses the global source.
+	rng *rand.Rand
+	src *RandSource
 }
 
 func (w *Weight) init(id string, data float64, requiresGrad bool) {
@@ -44,24 +127,117 @@ func (w *Weight) init(id string, data float64, requiresGrad bool) {
 
 func (w *Weight) forward(id string, value float64) float64 {
 	p, ok := w.Params[id]
-	if !ok {
+	if !ok || p.masked {
 		return 0.0
 	}
 	if p.RequiresGrad {
 		p.value = value
 	}
+	p.pullTied()
+	if w.reparam() && isConn(id) {
+		w.prepare()
+		return w.effective(id, p) * value
+	}
 	return p.Data * value
 }
 
 func (w *Weight) backward(id string, grad float64) float64 {
 	p, ok := w.Params[id]
-	if !ok {
+	if !ok || p.masked || id == gainID || isLogVar(id) || isActivParam(id) {
 		return 0.0
 	}
-	if p.RequiresGrad {
-		p.grad += grad * p.value
+	if !w.reparam() || !isConn(id) {
+		if p.trainable() {
+			p.grad += grad * p.value
+		}
+		return p.Data * grad
+	}
+
+	if p.trainable() {
+		// Quantized weights use a straight-through gradient estimate.
+		g := grad * p.value
+		if w.bayes {
+			w.backwardBayes(id, g)
+		}
+		if w.norm {
+			// Connection grads are finished in finishBackward.
+			w.dw[id] += g
+		} else {
+			p.grad += g
+		}
+	}
+	return w.effective(id, p) * grad
+}
+
+// reparam checks whether the connection weights are reparameterized.
+func (w *Weight) reparam() bool {
+	return w.norm || w.qbits > 0 || w.bayes || w.noise > 0
+}
+
+// prepare computes the per-pass state of reparameterized connection weights.
+// It's called once the pass has started.
+func (w *Weight) prepare() {
+	if w.ready {
+		return
+	}
+	if w.bayes {
+		w.prepareBayes()
+	}
+	if w.norm {
+		w.prepareNorm()
+	}
+	if w.noise > 0 {
+		w.prepareNoise()
+	}
+	if w.qbits > 0 {
+		w.prepareQuant()
+	}
+	w.ready = true
+}
+
+// effective returns the effective value of connection weight p after weight
+// sampling, weight normalization, weight noise, and fake quantization.
+func (w *Weight) effective(id string, p *Param) float64 {
+	v := w.real(id, p)
+	if w.qbits > 0 {
+		v = fakeQuant(v, w.qstep, w.qbits)
+	}
+	return v
+}
+
+// real returns the full precision effective value of connection weight p.
+func (w *Weight) real(id string, p *Param) float64 {
+	v := p.Data
+	if w.bayes {
+		v = w.sample(id, p)
+	}
+	if w.norm {
+		v *= w.scale
+	}
+	if w.noise > 0 {
+		v += w.noisy[id]
+	}
+	return v
+}
+
+// finishBackward completes the gradients of reparameterized weights once all
+// connection gradients for a sample have been accumulated.
+func (w *Weight) finishBackward() {
+	if w.norm {
+		w.finishNorm()
+	}
+	if w.bayes {
+		w.finishBayes()
 	}
-	return p.Data * grad
+}
+
+// grads returns a copy of the accumulated gradients.
+func (w *Weight) grads() map[string]float64 {
+	g := make(map[string]float64, len(w.Params))
+	for k, p := range w.Params {
+		g[k] = p.grad
+	}
+	return g
 }
 
 // NewWeight creates a new weight map.
@@ -78,12 +254,37 @@ type Param struct {
 	RequiresGrad bool
 	value        float64
 	grad         float64
+	// Masked parameters are treated as zero and never updated.
+	masked bool
+	// Frozen parameters keep their value, see Unit.Freeze.
+	frozen bool
+	// Tied weights, see WithTiedWeights. A tied copy points to its owner, and
+	// the owner to its copies.
+	tied *Param
+	ties []*Param
+}
+
+// AddGrad accumulates a gradient for p, e.g. in the Backward pass of a
+// ParamActivation.
+func (p *Param) AddGrad(grad float64) {
+	if p.trainable() && !p.masked {
+		p.grad += grad
+	}
+}
+
+// trainable checks whether p accumulates gradients.
+func (p *Param) trainable() bool {
+	return p.RequiresGrad && !p.frozen
 }
 
 // signals are used to communicate between neuron Units.
 type signal struct {
 	id    string
 	value float64
+	// Index of the sample within a batch, see WithPipelining.
+	tag int
+	// Activations of a whole layer, see WithSignalBatching.
+	vec []float64
 }
 
 // special IDs for input and output channels and bias parameters.
@@ -91,52 +292,198 @@ const (
 	inputID  = "_INPUT"
 	outputID = "_OUTPUT"
 	biasID   = "_BIAS"
+	gainID   = "_GAIN"
+	recurID  = "_RECUR"
+)
+
+// isConn checks whether a parameter ID refers to a connection weight.
+func isConn(id string) bool {
+	return id != biasID && id != inputID && id != gainID && id != recurID &&
+		!isLogVar(id) && !isActivParam(id) && !isGate(id) && !isEmbed(id) &&
+		!isAttn(id)
+}
+
+// activPrefix prefixes the IDs of activation parameters.
+const activPrefix = "_ACTIV_"
+
+// isActivParam checks whether a parameter ID refers to an activation
+// parameter.
+func isActivParam(id string) bool {
+	return strings.HasPrefix(id, activPrefix)
+}
+
+// A UnitKind constructs a new unit of a particular kind, e.g. with a custom
+// activation or bias initialization. The network takes care of connecting the
+// unit and feeding data in and out.
+//
+// Custom kinds build on NewUnit, so their units always compute the weighted
+// sum of their inputs plus bias, followed by the activation. They can only
+// customize the activation, which can be any Activation or ParamActivation
+// with its own forward and backward pass, the bias and recurrent weight (see
+// SetBias and SetRecurrent), and hooks. Units with other forward and backward
+// logic, like the gated and attention kinds, can't be defined outside the
+// package.
+type UnitKind func(id string, opt Optimizer) *Unit
+
+// Names of the built-in unit kinds.
+const (
+	InputKind  = "input"
+	HiddenKind = "hidden"
+	OutputKind = "output"
+	// RecurrentKind is a hidden unit whose output feeds back into its own
+	// input on the next step of a sequence.
+	RecurrentKind = "recurrent"
+	// SigmoidKind and TanhKind are hidden units with sigmoid and tanh
+	// activations.
+	SigmoidKind = "sigmoid"
+	TanhKind    = "tanh"
+	// GRUKind and LSTMKind are gated recurrent units, a GRU and an LSTM cell,
+	// whose gates control how much of their state carries over from one
+	// step of a sequence to the next.
+	GRUKind  = "gru"
+	LSTMKind = "lstm"
+	// AttentionKind is a hidden unit that sums its weighted inputs with
+	// softmax attention weights, computed from learned query and key weights
+	// on its inputs, instead of plainly. It doesn't support Dense.
+	AttentionKind = "attention"
+)
+
+var (
+	unitKindsMu sync.RWMutex
+	unitKinds   = map[string]UnitKind{
+		InputKind:     newInputUnit,
+		HiddenKind:    newHiddenUnit,
+		OutputKind:    newOutputUnit,
+		RecurrentKind: newRecurrentUnit,
+		SigmoidKind:   newSigmoidUnit,
+		TanhKind:      newTanhUnit,
+		GRUKind:       newGRUUnit,
+		LSTMKind:      newLSTMUnit,
+		AttentionKind: newAttentionUnit,
+	}
 )
 
-func newInputUnit(id string, opt Optimizer, stepDone chan int) *Unit {
+// RegisterUnitKind registers a unit kind under a name so that it can be used
+// when constructing networks. Registering an existing name replaces it.
+func RegisterUnitKind(name string, kind UnitKind) {
+	unitKindsMu.Lock()
+	defer unitKindsMu.Unlock()
+	unitKinds[name] = kind
+}
+
+// LookupUnitKind returns the unit kind registered under name.
+func LookupUnitKind(name string) (kind UnitKind, ok bool) {
+	unitKindsMu.RLock()
+	defer unitKindsMu.RUnlock()
+	kind, ok = unitKinds[name]
+	return
+}
+
+func newInputUnit(id string, opt Optimizer) *Unit {
 	activ := new(Identity)
-	u := newUnit(id, activ, opt, stepDone)
-	u.feedIn()
-	return u
+	return NewUnit(id, activ, opt)
 }
 
-func newHiddenUnit(id string, opt Optimizer, stepDone chan int) *Unit {
+func newHiddenUnit(id string, opt Optimizer) *Unit {
 	activ := new(Relu)
-	u := newUnit(id, activ, opt, stepDone)
-	u.W.init(biasID, 0.1, true)
+	u := NewUnit(id, activ, opt)
+	u.SetBias(0.1)
+	return u
+}
+
+func newSigmoidUnit(id string, opt Optimizer) *Unit {
+	activ := new(Sigmoid)
+	u := NewUnit(id, activ, opt)
+	u.SetBias(0.0)
+	return u
+}
+
+func newTanhUnit(id string, opt Optimizer) *Unit {
+	activ := new(Tanh)
+	u := NewUnit(id, activ, opt)
+	u.SetBias(0.0)
 	return u
 }
 
-func newOutputUnit(id string, opt Optimizer, stepDone chan int) *Unit {
+func newRecurrentUnit(id string, opt Optimizer) *Unit {
+	u := newHiddenUnit(id, opt)
+	u.SetRecurrent(0.5)
+	return u
+}
+
+func newOutputUnit(id string, opt Optimizer) *Unit {
 	activ := new(Identity)
-	u := newUnit(id, activ, opt, stepDone)
-	u.W.init(biasID, 0.0, true)
-	u.feedOut()
+	u := NewUnit(id, activ, opt)
+	u.SetBias(0.0)
 	return u
 }
 
-// Create a new Unit with a given string id and layer type.
-func newUnit(id string, activ Activation, opt Optimizer, stepDone chan int) *Unit {
+// NewUnit creates a new, unconnected Unit with a given string id, activation,
+// and optimizer. It's the building block for custom unit kinds.
+func NewUnit(id string, activ Activation, opt Optimizer) *Unit {
 	u := Unit{
-		ID:       id,
-		W:        NewWeight(),
-		activ:    activ,
-		opt:      opt,
-		input:    make(chan signal),
-		output:   make(map[string](chan signal)),
-		inputB:   make(chan signal),
-		outputB:  make(map[string](chan signal)),
-		stepDone: stepDone,
+		ID:      id,
+		W:       NewWeight(),
+		opt:     opt,
+		input:   make(chan signal),
+		output:  make(map[string](chan signal)),
+		inputB:  make(chan signal),
+		outputB: make(map[string](chan signal)),
 	}
+	u.setActivation(activ)
 
 	logf(2, "New unit %s\n", id)
 	return &u
 }
 
+// setActivation replaces the unit's activation, along with any activation
+// parameters.
+func (u *Unit) setActivation(activ Activation) {
+	for k := range u.W.Params {
+		if isActivParam(k) {
+			delete(u.W.Params, k)
+		}
+	}
+	u.activ = activ
+	if pa, ok := activ.(ParamActivation); ok {
+		for k, p := range pa.Params() {
+			u.W.Params[activPrefix+k] = p
+		}
+	}
+}
+
+// SetBias adds a trainable bias to the unit with the given initial value.
+func (u *Unit) SetBias(value float64) {
+	u.W.init(biasID, value, true)
+}
+
+// RemoveBias removes the unit's bias, if any.
+func (u *Unit) RemoveBias() {
+	delete(u.W.Params, biasID)
+}
+
+// SetRecurrent adds a trainable recurrent weight to the unit with the given
+// initial value. When processing sequences, the unit's previous output is fed
+// back into its input through this weight.
+func (u *Unit) SetRecurrent(value float64) {
+	u.W.init(recurID, value, true)
+}
+
+// Activation returns the unit's activation function.
+func (u *Unit) Activation() Activation {
+	return u.activ
+}
+
 // Connect two units together in series: u1 -> u2.
-func (u *Unit) connect(u2 *Unit) {
+func (u *Unit) connect(u2 *Unit, rng *rand.Rand) {
 	u.output[u2.ID] = u2.input
-	u2.W.init(u.ID, randUnif(-0.01, 0.01), true)
+	u2.W.init(u.ID, randUnif(rng, -0.01, 0.01), true)
+	if u2.cell != nil {
+		u2.cell.connect(u2, u.ID, rng)
+	}
+	if u2.attn != nil {
+		u2.attn.connect(u2, u.ID, rng)
+	}
 	u2.outputB[u.ID] = u.inputB
 	u2.nin++
 	logf(2, "Connect: %s -> %s\n", u.ID, u2.ID)
@@ -153,9 +500,9 @@ func (u *Unit) feedOut() {
 	u.output[outputID] = make(chan signal)
 }
 
-// Initialize a weight value by sampling randomly from [-0.01, 0.01).
-func randUnif(a, b float64) float64 {
-	w := rand.Float64()
+// Initialize a weight value by sampling randomly from [a, b).
+func randUnif(rng *rand.Rand, a, b float64) float64 {
+	w := rng.Float64()
 	w = a + (b-a)*w
 	return w
 }
@@ -163,21 +510,85 @@ func randUnif(a, b float64) float64 {
 // Forward pass through the unit. Collects input from all incoming units and
 // fires an activation.
 func (u *Unit) forward() {
-	var s signal
+	u.received, u.sent = 0, false
+	u.W.ready = false
 	// Accumulate weighted inputs from input connections.
 	// NOTE: assuming only one received activation per input unit.
-	act := u.W.forward(biasID, 1.0)
-	for ii := 0; ii < u.nin; ii++ {
-		s = <-u.input
-		act += u.W.forward(s.id, s.value)
+	act := 0.0
+	for ii := 0; ii < u.inputs(); ii++ {
+		act += u.receive(u.recvSample(u.input))
 	}
+	u.fire(act)
+}
 
-	// Fire activation
-	act = u.activ.Forward(act)
-	s = signal{id: u.ID, value: act}
-	for k := range u.output {
-		u.output[k] <- s
+// inputs returns the number of signals the unit receives on a forward pass.
+func (u *Unit) inputs() int {
+	if u.inBus != nil {
+		return 1
 	}
+	return u.nin
+}
+
+// gradInputs returns the number of signals the unit receives on a backward
+// pass.
+func (u *Unit) gradInputs() int {
+	if u.outBus != nil {
+		return 1
+	}
+	return len(u.output)
+}
+
+// receive weights a single input signal.
+func (u *Unit) receive(s signal) float64 {
+	u.received++
+	if s.vec != nil {
+		return u.inBus.weigh(u, s.vec)
+	}
+	if s.id == inputID {
+		return u.weighInput(s.value)
+	}
+	return u.W.forward(s.id, s.value)
+}
+
+// fire activates the unit and sends the activation downstream.
+func (u *Unit) fire(act float64) {
+	s := signal{id: u.ID, value: u.activate(act), tag: u.tag}
+	if u.outBus != nil {
+		u.outBus.fire(u, s.value)
+	} else {
+		for k := range u.output {
+			u.send(u.output[k], s)
+		}
+	}
+	u.sent = true
+}
+
+// activate adds the bias and recurrent input to the accumulated weighted
+// inputs, and returns the activation, or the output of the cell of gated
+// units. Attention units replace the accumulated weighted inputs with their
+// attention-weighted sum.
+func (u *Unit) activate(act float64) float64 {
+	// Parameters are only read once the pass has started so that they can be
+	// safely modified between passes.
+	if u.attn != nil {
+		act = u.attn.forward(u)
+	}
+	act += u.W.forward(biasID, 1.0)
+	if u.cell != nil {
+		act = u.cell.forward(u, act+u.preNoise())
+	} else {
+		act += u.W.forward(recurID, u.hprev)
+		act += u.preNoise()
+		u.pre = act
+		act = u.activ.Forward(act)
+	}
+	u.act = act
+	u.checkAnomaly("forward", act)
+	if u.seq {
+		u.hprev = act
+	}
+	u.forwardHooks()
+	return act
 }
 
 // Backward pass through the unit. Waits for gradients from all downstream
@@ -185,42 +596,301 @@ func (u *Unit) forward() {
 func (u *Unit) backward() {
 	var s signal
 	// Accumulate grads from all output connections.
+	u.received, u.sent = 0, false
 	grad := 0.0
-	for ii := 0; ii < len(u.output); ii++ {
-		s = <-u.inputB
+	for ii := 0; ii < u.gradInputs(); ii++ {
+		s = u.recvSample(u.inputB)
+		u.received++
 		grad += s.value
 	}
+	u.backprop(grad)
+}
+
+// backprop back-propagates the accumulated output gradient through the
+// activation and weights, and sends the input gradients upstream.
+func (u *Unit) backprop(grad float64) {
+	if u.inBus != nil {
+		grads := make([]float64, len(u.inBus.up))
+		u.backpropTo(grad, func(k string, gradi float64) {
+			if ii, ok := u.inBus.index[k]; ok {
+				grads[ii] = gradi
+			}
+		})
+		u.inBus.backprop(u, grads)
+		return
+	}
+	u.backpropTo(grad, func(k string, gradi float64) {
+		if c, ok := u.outputB[k]; ok {
+			u.send(c, signal{id: u.ID, value: gradi, tag: u.tag})
+		}
+	})
+}
 
+// backpropTo is like backprop, but passes the gradient for each input k to
+// emit instead of sending it.
+func (u *Unit) backpropTo(grad float64, emit func(k string, gradi float64)) {
+	clipper, clip := unwrapOpt(u.opt).(sampleClipper)
+	var prev map[string]float64
+	if clip {
+		prev = u.W.grads()
+	}
 	// Backprop.
-	grad = u.activ.Backward(grad)
-	for k := range u.W.Params {
-		gradi := u.W.backward(k, grad)
-		if c, ok := u.outputB[k]; ok {
-			c <- signal{id: u.ID, value: gradi}
+	grad = u.activ.Backward(u.backwardHooks(grad + u.carry))
+	u.checkAnomaly("backward", grad)
+	if u.cell != nil {
+		u.cell.backprop(u, grad, emit)
+	} else {
+		if u.attn != nil {
+			u.attn.backprop(u, grad, emit)
+		}
+		for k := range u.W.Params {
+			if u.attn != nil && (isConn(k) || isAttn(k)) {
+				// Back-propagated through the attention.
+				continue
+			}
+			gradi := u.W.backward(k, grad)
+			if k == recurID {
+				// Carried back to the previous step of the sequence.
+				if u.seq {
+					u.carry = gradi
+				}
+			} else {
+				if k == inputID {
+					u.inGrad = gradi
+				}
+				emit(k, gradi)
+			}
 		}
 	}
+	u.W.finishBackward()
+	u.sent = true
+	if clip {
+		clipper.clipSample(u.W, prev)
+	}
 }
 
 // Update the weights and bias by taking a gradient descent step.
 func (u *Unit) step() {
-	for k, p := range u.W.Params {
-		u.opt.Step(k, p)
+	keys := u.W.stepKeys()
+	for _, k := range keys {
+		p := u.W.Params[k]
+		if p.tied != nil {
+			// Updated by its owner.
+			continue
+		}
+		p.mergeTies()
+		if !p.masked && !p.frozen {
+			if u.clipValue > 0 {
+				p.grad = math.Max(math.Min(p.grad, u.clipValue), -u.clipValue)
+			}
+			u.opt.Step(k, p)
+		}
+	}
+}
+
+// recv receives a signal from c. If the unit is stopped while waiting, its
+// goroutine exits.
+func (u *Unit) recv(c chan signal) signal {
+	u.waiting(waitRecv, c)
+	select {
+	case s := <-c:
+		u.waiting(waitNone, nil)
+		return s
+	case <-u.quit:
+		runtime.Goexit()
+	}
+	panic("unreachable")
+}
+
+// recvSample receives the signal of the current sample from c. When samples
+// are pipelined, signals of later samples can arrive first. These are held
+// back until the unit gets to their sample. A unit only receives on one
+// channel at a time, so held back signals always belong to that channel.
+func (u *Unit) recvSample(c chan signal) signal {
+	for ii, s := range u.early {
+		if s.tag == u.tag {
+			u.early = append(u.early[:ii], u.early[ii+1:]...)
+			return s
+		}
+	}
+	for {
+		s := u.recv(c)
+		if s.tag == u.tag {
+			return s
+		}
+		u.early = append(u.early, s)
+	}
+}
+
+// send sends a signal on c. If the unit is stopped while waiting, its
+// goroutine exits.
+func (u *Unit) send(c chan signal, s signal) {
+	u.waiting(waitSend, c)
+	select {
+	case c <- s:
+		u.waiting(waitNone, nil)
+	case <-u.quit:
+		runtime.Goexit()
+	}
+}
+
+// done signals that the unit has completed a step. If the unit is stopped
+// while waiting, its goroutine exits.
+func (u *Unit) done() {
+	u.waiting(waitDone, nil)
+	select {
+	case u.stepDone <- 1:
+		u.waiting(waitNone, nil)
+	case <-u.quit:
+		runtime.Goexit()
 	}
 }
 
+// Phases of a unit's forward/backward/step iteration.
+const (
+	phaseForward = iota
+	phaseBackward
+	phaseStep
+)
+
 // Start starts an endless loop of forward and backward passes with periodic
 // gradient updates.
-func (u *Unit) start(train bool, updateFreq int) {
-	step := 1
+func (u *Unit) start(updateFreq int) {
 	for {
-		u.forward()
+		u.iterate(updateFreq)
+		u.done()
+	}
+}
+
+// iterate runs a single forward/backward/step iteration, or only a forward
+// pass in evaluation mode. If the iteration panics, the unit is restarted
+// from its last checkpoint.
+func (u *Unit) iterate(updateFreq int) {
+	defer func() {
+		if r := recover(); r != nil {
+			u.restart(r)
+		}
+	}()
+
+	u.phase = phaseForward
+	u.tag = 0
+	u.forward()
+	train, batch := *u.train, *u.batch
+	for k := 1; k < batch; k++ {
 		if train {
+			u.record()
+		}
+		u.tag = k
+		u.forward()
+	}
+	if train {
+		u.phase = phaseBackward
+		if batch > 1 {
+			u.backwardBatch(batch)
+		} else {
 			u.backward()
-			if updateFreq > 0 && step%updateFreq == 0 {
-				u.step()
+		}
+		u.phase = phaseStep
+		// Only training passes count towards updateFreq.
+		steps := u.steps
+		u.steps += batch
+		if updateFreq > 0 && u.steps/updateFreq > steps/updateFreq && !u.netStep {
+			u.step()
+			u.save()
+		}
+	}
+}
+
+// save checkpoints the unit's weights.
+func (u *Unit) save() {
+	if u.checkpoint == nil {
+		u.checkpoint = make(map[string]float64, len(u.W.Params))
+	}
+	for k, p := range u.W.Params {
+		u.checkpoint[k] = p.Data
+	}
+}
+
+// restart restores the unit's weights from its last checkpoint after a failed
+// iteration, and completes the iteration by exchanging zero signals with its
+// neighbors for every sample of the batch still in flight, so that the rest
+// of the network doesn't wedge. The unit's pass state is then reset for the
+// next iteration, which still counts towards updateFreq.
+func (u *Unit) restart(r interface{}) {
+	train, batch := *u.train, *u.batch
+	u.restarts++
+	logf(1, "Unit %s failed: %v\n  Restarting from checkpoint.\n", u.ID, r)
+	for k, p := range u.W.Params {
+		if v, ok := u.checkpoint[k]; ok {
+			p.Data = v
+		}
+		p.grad = 0.0
+	}
+
+	if u.phase == phaseForward {
+		for {
+			u.skipForward()
+			if u.tag >= batch-1 {
+				break
 			}
+			u.tag++
+			u.received, u.sent = 0, false
+		}
+		if train {
+			u.phase = phaseBackward
+			u.tag = batch - 1
+			u.received, u.sent = 0, false
+		}
+	}
+	if u.phase == phaseBackward {
+		for {
+			u.skipBackward()
+			if u.tag <= 0 {
+				break
+			}
+			// See backwardBatch.
+			if len(u.outputB) == 0 && !u.pipeline {
+				u.done()
+			}
+			u.tag--
+			u.received, u.sent = 0, false
+		}
+		u.steps += batch
+	}
+
+	u.tag, u.received, u.sent = 0, 0, false
+	u.early = u.early[:0]
+	u.hist = u.hist[:0]
+}
+
+// skipForward completes the forward pass of the current sample after a
+// failure, receiving the remaining inputs and sending a zero activation.
+func (u *Unit) skipForward() {
+	for ; u.received < u.inputs(); u.received++ {
+		u.recvSample(u.input)
+	}
+	if !u.sent && u.outBus != nil {
+		u.outBus.fire(u, 0.0)
+	} else if !u.sent {
+		for _, c := range u.output {
+			u.send(c, signal{id: u.ID, value: 0.0, tag: u.tag})
+		}
+	}
+	u.sent = true
+}
+
+// skipBackward completes the backward pass of the current sample after a
+// failure, receiving the remaining gradients and sending zero gradients.
+func (u *Unit) skipBackward() {
+	for ; u.received < u.gradInputs(); u.received++ {
+		u.recvSample(u.inputB)
+	}
+	if !u.sent && u.inBus != nil {
+		u.inBus.backprop(u, make([]float64, len(u.inBus.up)))
+	} else if !u.sent {
+		for _, c := range u.outputB {
+			u.send(c, signal{id: u.ID, value: 0.0, tag: u.tag})
 		}
-		step++
-		u.stepDone <- 1
 	}
+	u.sent = true
 }
@@ -0,0 +1,85 @@
+package neuron
+
+import (
+	"fmt"
+)
+
+// WithActivationNoise adds Gaussian noise with standard deviation std to the
+// pre-activations of every unit in layer during training, as a regularizer.
+// The noise is additive, so gradients flow through it unchanged. No noise is
+// added in eval mode, or by the dense engine. It can be passed once per layer.
+func WithActivationNoise(layer int, std float64) Option {
+	return func(c *netConfig) {
+		if c.actNoise == nil {
+			c.actNoise = make(map[int]float64)
+		}
+		c.actNoise[layer] = std
+	}
+}
+
+// WithWeightNoise adds Gaussian noise with standard deviation std to the
+// incoming connection weights of every unit in layer during training, with
+// new noise sampled for every pass. Like WithActivationNoise, the noise is
+// additive and disabled in eval mode. Noisy weights are reparameterized, so
+// they aren't supported by the dense engine.
+func WithWeightNoise(layer int, std float64) Option {
+	return func(c *netConfig) {
+		if c.weightNoise == nil {
+			c.weightNoise = make(map[int]float64)
+		}
+		c.weightNoise[layer] = std
+	}
+}
+
+// checkNoise checks the noise injection settings.
+func checkNoise(c *netConfig, numLayers int) error {
+	for _, noise := range []map[int]float64{c.actNoise, c.weightNoise} {
+		for ii, std := range noise {
+			if ii < 1 || ii >= numLayers {
+				return fmt.Errorf("noise layer %d out of range", ii)
+			}
+			if std < 0 {
+				return fmt.Errorf("noise std needs to be >= 0; got %g", std)
+			}
+		}
+	}
+	return nil
+}
+
+// noiseNet sets the noise levels of each layer.
+func (n *Net) noiseNet(c *netConfig) {
+	for ii, std := range c.actNoise {
+		for _, u := range n.Layers[ii] {
+			u.noise = std
+		}
+	}
+	for ii, std := range c.weightNoise {
+		for _, u := range n.Layers[ii] {
+			u.W.noise = std
+		}
+	}
+}
+
+// preNoise samples the pre-activation noise for this pass.
+func (u *Unit) preNoise() float64 {
+	if u.noise == 0 || !*u.train {
+		return 0.0
+	}
+	return u.noise * u.W.normFloat64()
+}
+
+// prepareNoise samples the weight noise for this pass, or clears it in eval
+// mode.
+func (w *Weight) prepareNoise() {
+	if w.noisy == nil {
+		w.noisy = make(map[string]float64)
+	}
+	train := w.train != nil && *w.train
+	for _, k := range weightKeys(w) {
+		if train {
+			w.noisy[k] = w.noise * w.normFloat64()
+		} else {
+			w.noisy[k] = 0.0
+		}
+	}
+}
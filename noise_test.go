@@ -0,0 +1,61 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestNoiseErrors(t *testing.T) {
+	arch := []int{2, 3, 1}
+	for _, opts := range [][]Option{{WithActivationNoise(0, 0.1)},
+		{WithActivationNoise(3, 0.1)}, {WithWeightNoise(1, -0.1)}} {
+		if _, err := NewMLP(arch, NewSGD(0.1, 0.0, 0.0), opts...); err == nil {
+			t.Errorf("NewMLP did not return an error")
+		}
+	}
+	n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), WithWeightNoise(1, 0.1))
+	if _, err := NewDense(n); err == nil {
+		t.Errorf("NewDense did not return an error")
+	}
+}
+
+// Test that noise is only injected during training.
+func TestNoise(t *testing.T) {
+	arch := []int{2, 4, 2}
+	data := []float64{1.0, -1.0}
+	for _, opts := range [][]Option{{WithActivationNoise(1, 0.5)},
+		{WithWeightNoise(1, 0.5), WithActivationNoise(2, 0.5)}, {WithWeightNoise(2, 0.5)},
+		{WithWeightNoise(1, 0.5), WithLayerEngine()}} {
+		rand.Seed(4)
+		clean := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0))
+		rand.Seed(4)
+		n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), opts...)
+		clean.Start(false, 0)
+		n.Start(true, 0)
+		want := clean.MustForward(data)
+
+		noisy := false
+		for ii := 0; ii < 3; ii++ {
+			got := n.MustForward(data)
+			n.MustBackward([]float64{0.0, 0.0})
+			for jj := range want {
+				if !almostEqual(got[jj], want[jj]) {
+					noisy = true
+				}
+			}
+		}
+		if !noisy {
+			t.Errorf("Training outputs are the same as without noise")
+		}
+
+		n.Eval()
+		got := n.MustForward(data)
+		for jj := range want {
+			if !almostEqual(got[jj], want[jj]) {
+				t.Errorf("Eval output %d is %.6f; expected %.6f", jj, got[jj], want[jj])
+			}
+		}
+		n.Stop()
+		clean.Stop()
+	}
+}
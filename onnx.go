@@ -0,0 +1,278 @@
+package neuron
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ONNX versions written by ExportONNX.
+const (
+	onnxIRVersion = 8
+	onnxOpset     = 13
+)
+
+// ONNX enum values.
+const (
+	onnxFloat     = 1 // TensorProto.FLOAT
+	onnxAttrFloat = 1 // AttributeProto.FLOAT
+	onnxAttrInt   = 2 // AttributeProto.INT
+)
+
+// ExportONNX writes the network as an ONNX model, so that it can be served by
+// standard runtimes. Each layer becomes a Gemm node, with weights and biases
+// stored as float32 initializers named "layerN.weight" and "layerN.bias",
+// followed by the layer's activation. The model has a single input "input"
+// of shape [N, Arch[0]] and a single output "output" of shape
+// [N, Arch[len(Arch)-1]], including the softmax with WithSoftmaxOutput.
+//
+// Only plain fully-connected MLPs are supported: the units of a layer need
+// the same activation, one of Relu, LeakyRelu, PRelu, Elu, Sigmoid, Tanh, or
+// Identity, weights can't be reparameterized, units can't be gated or
+// attention units, and there can't be skip connections. Input units must
+// pass their input through unchanged. Pruned connections are exported as zero
+// weights, and recurrent weights are dropped since the model only computes
+// single passes.
+// ExportONNX reads the weights directly, so it should only be called while
+// the network is idle.
+func (n *Net) ExportONNX(w io.Writer) error {
+	if len(n.skips) > 0 {
+		return fmt.Errorf("ONNX export doesn't support skip connections")
+	}
+	for _, u := range n.Layers[0] {
+		_, ident := u.activ.(*Identity)
+		if !ident || u.embed != nil || paramValue(u.W.Params[inputID]) != 1.0 ||
+			paramValue(u.W.Params[biasID]) != 0.0 {
+			return fmt.Errorf("ONNX export needs identity input units; got %s", u.ID)
+		}
+	}
+
+	var graph protoBuf
+	graph.string(2, "go-neuron")
+	x := "input"
+	for ii := 1; ii < len(n.Layers); ii++ {
+		l, prev := n.Layers[ii], n.Layers[ii-1]
+		weight := make([]float64, 0, len(l)*len(prev))
+		bias := make([]float64, len(l))
+		for jj, u := range l {
+			if u.W.reparam() {
+				return fmt.Errorf("ONNX export doesn't support reparameterized weights; got %s",
+					u.ID)
+			}
+			if u.cell != nil {
+				return fmt.Errorf("ONNX export doesn't support gated units; got %s", u.ID)
+			}
+			if u.attn != nil {
+				return fmt.Errorf("ONNX export doesn't support attention units; got %s", u.ID)
+			}
+			for _, u2 := range prev {
+				weight = append(weight, paramValue(u.W.Params[u2.ID]))
+			}
+			bias[jj] = paramValue(u.W.Params[biasID])
+		}
+		wname := fmt.Sprintf("layer%d.weight", ii)
+		bname := fmt.Sprintf("layer%d.bias", ii)
+		graph.message(5, onnxTensor(wname, []int{len(l), len(prev)}, weight))
+		graph.message(5, onnxTensor(bname, []int{len(l)}, bias))
+
+		z := fmt.Sprintf("layer%d.pre", ii)
+		gemm := onnxNode(fmt.Sprintf("layer%d.gemm", ii), "Gemm",
+			[]string{x, wname, bname}, z)
+		gemm.message(5, onnxIntAttr("transB", 1))
+		graph.message(1, gemm)
+
+		act, err := n.onnxActivation(ii, z, &graph)
+		if err != nil {
+			return err
+		}
+		x = act
+	}
+	if n.softmax {
+		if len(n.softmaxSpans) != 1 || n.softmaxSpans[0] != [2]int{0, n.Arch[len(n.Arch)-1]} {
+			return fmt.Errorf("ONNX export doesn't support softmax output heads")
+		}
+		sm := onnxNode("softmax", "Softmax", []string{x}, "softmax")
+		sm.message(5, onnxIntAttr("axis", 1))
+		graph.message(1, sm)
+		x = "softmax"
+	}
+	// Rename the last value to "output".
+	graph.message(1, onnxNode("output", "Identity", []string{x}, "output"))
+
+	graph.message(11, onnxValueInfo("input", n.Arch[0]))
+	graph.message(12, onnxValueInfo("output", n.Arch[len(n.Arch)-1]))
+
+	var model, opset protoBuf
+	model.varint(1, onnxIRVersion)
+	model.string(2, "go-neuron")
+	opset.string(1, "")
+	opset.varint(2, onnxOpset)
+	model.message(8, &opset)
+	model.message(7, &graph)
+	_, err := w.Write(model.b)
+	return err
+}
+
+// onnxActivation adds the activation node of layer ii, with input z, to graph
+// and returns the name of its output.
+func (n *Net) onnxActivation(ii int, z string, graph *protoBuf) (string, error) {
+	l := n.Layers[ii]
+	name := activationName(l[0].activ)
+	for _, u := range l[1:] {
+		if activationName(u.activ) != name {
+			return "", fmt.Errorf("ONNX export needs one activation per layer; layer %d has %s and %s",
+				ii, name, activationName(u.activ))
+		}
+	}
+
+	out := fmt.Sprintf("layer%d.out", ii)
+	node := onnxNode(fmt.Sprintf("layer%d.activation", ii), name, []string{z}, out)
+	switch a := l[0].activ.(type) {
+	case *Identity:
+		return z, nil
+	case *Relu, *Sigmoid, *Tanh:
+	case *LeakyRelu, *Elu:
+		alpha := activAlpha(a)
+		for _, u := range l {
+			if activAlpha(u.activ) != alpha {
+				return "", fmt.Errorf("ONNX export needs one %s slope per layer; layer %d has several",
+					name, ii)
+			}
+		}
+		node.message(5, onnxFloatAttr("alpha", alpha))
+	case *PRelu:
+		slope := make([]float64, len(l))
+		for jj, u := range l {
+			slope[jj] = u.activ.(*PRelu).Alpha.Data
+		}
+		sname := fmt.Sprintf("layer%d.slope", ii)
+		graph.message(5, onnxTensor(sname, []int{len(l)}, slope))
+		node = onnxNode(fmt.Sprintf("layer%d.activation", ii), name, []string{z, sname}, out)
+	default:
+		return "", fmt.Errorf("ONNX export doesn't support %s activations", name)
+	}
+	graph.message(1, node)
+	return out, nil
+}
+
+// activAlpha returns the negative slope of a LeakyRelu or Elu.
+func activAlpha(a Activation) float64 {
+	switch a := a.(type) {
+	case *LeakyRelu:
+		return a.Slope
+	case *Elu:
+		return a.Alpha
+	}
+	return 0.0
+}
+
+// onnxNode builds a NodeProto.
+func onnxNode(name, op string, inputs []string, output string) *protoBuf {
+	var node protoBuf
+	for _, in := range inputs {
+		node.string(1, in)
+	}
+	node.string(2, output)
+	node.string(3, name)
+	node.string(4, op)
+	return &node
+}
+
+// onnxIntAttr builds an integer AttributeProto.
+func onnxIntAttr(name string, v int) *protoBuf {
+	var attr protoBuf
+	attr.string(1, name)
+	attr.varint(3, uint64(v))
+	attr.varint(20, onnxAttrInt)
+	return &attr
+}
+
+// onnxFloatAttr builds a float AttributeProto.
+func onnxFloatAttr(name string, v float64) *protoBuf {
+	var attr protoBuf
+	attr.string(1, name)
+	attr.fixed32(2, math.Float32bits(float32(v)))
+	attr.varint(20, onnxAttrFloat)
+	return &attr
+}
+
+// onnxTensor builds a float32 TensorProto.
+func onnxTensor(name string, dims []int, values []float64) *protoBuf {
+	var t protoBuf
+	for _, d := range dims {
+		t.varint(1, uint64(d))
+	}
+	t.varint(2, onnxFloat)
+	t.string(8, name)
+	raw := make([]byte, 4*len(values))
+	for ii, v := range values {
+		binary.LittleEndian.PutUint32(raw[4*ii:], math.Float32bits(float32(v)))
+	}
+	t.bytes(9, raw)
+	return &t
+}
+
+// onnxValueInfo builds the ValueInfoProto of a float32 [N, dim] tensor.
+func onnxValueInfo(name string, dim int) *protoBuf {
+	var batch, size, shape, tensor, typ, info protoBuf
+	batch.string(2, "N")
+	size.varint(1, uint64(dim))
+	shape.message(1, &batch)
+	shape.message(1, &size)
+	tensor.varint(1, onnxFloat)
+	tensor.message(2, &shape)
+	typ.message(1, &tensor)
+	info.string(1, name)
+	info.message(2, &typ)
+	return &info
+}
+
+// A protoBuf is a minimal protocol buffer encoder, enough to write ONNX
+// models without depending on a protobuf library.
+type protoBuf struct {
+	b []byte
+}
+
+// Protocol buffer wire types.
+const (
+	wireVarint  = 0
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func (p *protoBuf) uvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	p.b = append(p.b, buf[:n]...)
+}
+
+func (p *protoBuf) tag(field, wire int) {
+	p.uvarint(uint64(field<<3 | wire))
+}
+
+func (p *protoBuf) varint(field int, v uint64) {
+	p.tag(field, wireVarint)
+	p.uvarint(v)
+}
+
+func (p *protoBuf) fixed32(field int, v uint32) {
+	p.tag(field, wireFixed32)
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	p.b = append(p.b, buf[:]...)
+}
+
+func (p *protoBuf) bytes(field int, b []byte) {
+	p.tag(field, wireBytes)
+	p.uvarint(uint64(len(b)))
+	p.b = append(p.b, b...)
+}
+
+func (p *protoBuf) string(field int, s string) {
+	p.bytes(field, []byte(s))
+}
+
+func (p *protoBuf) message(field int, m *protoBuf) {
+	p.bytes(field, m.b)
+}
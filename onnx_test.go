@@ -0,0 +1,109 @@
+package neuron
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// A protoField is a single decoded protocol buffer field.
+type protoField struct {
+	num int
+	v   uint64
+	b   []byte
+}
+
+// decodeProto decodes the fields of a protocol buffer message, supporting the
+// wire types written by protoBuf.
+func decodeProto(t *testing.T, b []byte) []protoField {
+	var fields []protoField
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		b = b[n:]
+		f := protoField{num: int(tag >> 3)}
+		switch tag & 7 {
+		case wireVarint:
+			f.v, n = binary.Uvarint(b)
+			b = b[n:]
+		case wireFixed32:
+			f.v = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			f.b = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			t.Fatalf("Unexpected wire type %d", tag&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// protoGet returns the fields numbered num.
+func protoGet(fields []protoField, num int) []protoField {
+	var got []protoField
+	for _, f := range fields {
+		if f.num == num {
+			got = append(got, f)
+		}
+	}
+	return got
+}
+
+func TestExportONNX(t *testing.T) {
+	arch := []int{3, 4, 2}
+	n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), WithSoftmaxOutput())
+	n.Layers[1][2].W.Params["000_000001"].Data = 0.25
+	var buf bytes.Buffer
+	if err := n.ExportONNX(&buf); err != nil {
+		t.Fatalf("ExportONNX failed: %v", err)
+	}
+
+	model := decodeProto(t, buf.Bytes())
+	if v := protoGet(model, 1)[0].v; v != onnxIRVersion {
+		t.Errorf("IR version is %d; expected %d", v, onnxIRVersion)
+	}
+	graph := decodeProto(t, protoGet(model, 7)[0].b)
+	var ops []string
+	for _, node := range protoGet(graph, 1) {
+		ops = append(ops, string(protoGet(decodeProto(t, node.b), 4)[0].b))
+	}
+	want := []string{"Gemm", "Relu", "Gemm", "Softmax", "Identity"}
+	if len(ops) != len(want) {
+		t.Fatalf("Got ops %v; expected %v", ops, want)
+	}
+	for ii := range want {
+		if ops[ii] != want[ii] {
+			t.Errorf("Got ops %v; expected %v", ops, want)
+			break
+		}
+	}
+
+	inits := protoGet(graph, 5)
+	if len(inits) != 4 {
+		t.Fatalf("Got %d initializers; expected 4", len(inits))
+	}
+	weight := decodeProto(t, inits[0].b)
+	if name := string(protoGet(weight, 8)[0].b); name != "layer1.weight" {
+		t.Errorf("First initializer is %s; expected layer1.weight", name)
+	}
+	dims := protoGet(weight, 1)
+	if len(dims) != 2 || dims[0].v != 4 || dims[1].v != 3 {
+		t.Errorf("Weight dims are %v; expected [4 3]", dims)
+	}
+	raw := protoGet(weight, 9)[0].b
+	if w := math.Float32frombits(binary.LittleEndian.Uint32(raw[4*(2*3+1):])); w != 0.25 {
+		t.Errorf("Weight [2, 1] is %.4f; expected 0.25", w)
+	}
+
+	// Unsupported networks.
+	for _, opts := range [][]Option{{WithWeightNorm()},
+		{WithMixedLayer(1, HiddenKind, TanhKind)}} {
+		n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), opts...)
+		if err := n.ExportONNX(&buf); err == nil {
+			t.Errorf("ExportONNX did not return an error")
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package neuron
 
+import (
+	"math"
+	"math/rand"
+)
+
 // An Optimizer performs gradient based parameter updates
 type Optimizer interface {
 	Step(id string, p *Param)
@@ -58,3 +63,165 @@ func NewSGD(lr float64, momentum float64, weightDecay float64) *SGD {
 		buf:         make(map[string]float64),
 	}
 }
+
+// RMSProp Optimizer with weight decay. Each step is scaled by a running
+// average of the squared gradient, with decay rate Alpha.
+type RMSProp struct {
+	Lr          float64
+	Alpha       float64
+	Eps         float64
+	WeightDecay float64
+	buf         map[string]float64
+}
+
+// Step takes an RMSProp optimization step on one scalar parameter.
+func (opt *RMSProp) Step(id string, p *Param) {
+	if !p.RequiresGrad {
+		return
+	}
+
+	grad := p.grad
+	if opt.WeightDecay > 0 {
+		grad += opt.WeightDecay * p.Data
+	}
+
+	v := opt.Alpha*opt.buf[id] + (1.0-opt.Alpha)*grad*grad
+	opt.buf[id] = v
+	p.Data -= opt.Lr * grad / (math.Sqrt(v) + opt.Eps)
+	p.grad = 0.0
+}
+
+// New initializes a new RMSProp optimizer with the same parameters.
+func (opt *RMSProp) New() Optimizer {
+	return NewRMSProp(opt.Lr, opt.Alpha, opt.Eps, opt.WeightDecay)
+}
+
+// NewRMSProp creates a new RMSProp optimizer.
+func NewRMSProp(lr float64, alpha float64, eps float64, weightDecay float64) *RMSProp {
+	return &RMSProp{
+		Lr:          lr,
+		Alpha:       alpha,
+		Eps:         eps,
+		WeightDecay: weightDecay,
+		buf:         make(map[string]float64),
+	}
+}
+
+// Adagrad Optimizer with weight decay. Each step is scaled by the sum of all
+// past squared gradients.
+type Adagrad struct {
+	Lr          float64
+	Eps         float64
+	WeightDecay float64
+	buf         map[string]float64
+}
+
+// Step takes an Adagrad optimization step on one scalar parameter.
+func (opt *Adagrad) Step(id string, p *Param) {
+	if !p.RequiresGrad {
+		return
+	}
+
+	grad := p.grad
+	if opt.WeightDecay > 0 {
+		grad += opt.WeightDecay * p.Data
+	}
+
+	v := opt.buf[id] + grad*grad
+	opt.buf[id] = v
+	p.Data -= opt.Lr * grad / (math.Sqrt(v) + opt.Eps)
+	p.grad = 0.0
+}
+
+// New initializes a new Adagrad optimizer with the same parameters.
+func (opt *Adagrad) New() Optimizer {
+	return NewAdagrad(opt.Lr, opt.Eps, opt.WeightDecay)
+}
+
+// NewAdagrad creates a new Adagrad optimizer.
+func NewAdagrad(lr float64, eps float64, weightDecay float64) *Adagrad {
+	return &Adagrad{
+		Lr:          lr,
+		Eps:         eps,
+		WeightDecay: weightDecay,
+		buf:         make(map[string]float64),
+	}
+}
+
+// A sampleClipper is an Optimizer that clips the gradient contributed by each
+// sample before it's accumulated. prev holds the accumulated gradients from
+// before the current sample.
+type sampleClipper interface {
+	clipSample(w *Weight, prev map[string]float64)
+}
+
+// DPSGD is a differentially private SGD optimizer. Each unit's per-sample
+// gradient is clipped to norm at most ClipNorm before it's accumulated, and
+// Gaussian noise with standard deviation NoiseMultiplier * ClipNorm is added
+// to the accumulated gradient at each step.
+//
+// Clipping is done per unit, so the norm of the full per-sample gradient is
+// at most ClipNorm * sqrt(U), where U is the number of units with trainable
+// parameters. The effective noise multiplier to pass to DPEpsilon is therefore
+// NoiseMultiplier / sqrt(U).
+type DPSGD struct {
+	Lr              float64
+	ClipNorm        float64
+	NoiseMultiplier float64
+	rng             *rand.Rand
+}
+
+// Step takes a noisy SGD optimization step on one scalar parameter.
+func (opt *DPSGD) Step(id string, p *Param) {
+	if !p.RequiresGrad {
+		return
+	}
+
+	var noise float64
+	if opt.rng != nil {
+		noise = opt.rng.NormFloat64()
+	} else {
+		noise = rand.NormFloat64()
+	}
+	grad := p.grad + opt.NoiseMultiplier*opt.ClipNorm*noise
+	p.Data -= opt.Lr * grad
+	p.grad = 0.0
+}
+
+// clipSample clips the gradient contributed by the current sample to norm at
+// most ClipNorm.
+func (opt *DPSGD) clipSample(w *Weight, prev map[string]float64) {
+	norm := 0.0
+	for k, p := range w.Params {
+		d := p.grad - prev[k]
+		norm += d * d
+	}
+	norm = math.Sqrt(norm)
+	if norm <= opt.ClipNorm {
+		return
+	}
+
+	scale := opt.ClipNorm / norm
+	for k, p := range w.Params {
+		p.grad = prev[k] + scale*(p.grad-prev[k])
+	}
+}
+
+// setRand draws the noise from rng instead of the global source.
+func (opt *DPSGD) setRand(rng *rand.Rand) {
+	opt.rng = rng
+}
+
+// New initializes a new DPSGD optimizer with the same parameters.
+func (opt *DPSGD) New() Optimizer {
+	return NewDPSGD(opt.Lr, opt.ClipNorm, opt.NoiseMultiplier)
+}
+
+// NewDPSGD creates a new DPSGD optimizer.
+func NewDPSGD(lr float64, clipNorm float64, noiseMultiplier float64) *DPSGD {
+	return &DPSGD{
+		Lr:              lr,
+		ClipNorm:        clipNorm,
+		NoiseMultiplier: noiseMultiplier,
+	}
+}
@@ -0,0 +1,146 @@
+package neuron
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// A StatefulOptimizer is an Optimizer with internal state, e.g. momentum
+// buffers, that can be exported and restored to resume training exactly.
+// State is keyed by parameter ID, with one or more values per parameter.
+type StatefulOptimizer interface {
+	Optimizer
+	State() map[string][]float64
+	SetState(state map[string][]float64)
+}
+
+// OptimizerState holds the optimizer state of a network, keyed by unit ID and
+// then parameter ID.
+type OptimizerState map[string]map[string][]float64
+
+// Optimizer state key for the update counter of Scheduled optimizers.
+const updatesID = "_UPDATES"
+
+// bufState exports a single value per parameter state buffer.
+func bufState(buf map[string]float64) map[string][]float64 {
+	state := make(map[string][]float64, len(buf))
+	for k, v := range buf {
+		state[k] = []float64{v}
+	}
+	return state
+}
+
+// setBufState restores a buffer exported by bufState.
+func setBufState(buf map[string]float64, state map[string][]float64) {
+	for k := range buf {
+		delete(buf, k)
+	}
+	for k, v := range state {
+		if len(v) > 0 {
+			buf[k] = v[0]
+		}
+	}
+}
+
+// State returns a copy of the SGD momentum buffers.
+func (opt *SGD) State() map[string][]float64 { return bufState(opt.buf) }
+
+// SetState restores the SGD momentum buffers.
+func (opt *SGD) SetState(state map[string][]float64) { setBufState(opt.buf, state) }
+
+// State returns a copy of the RMSProp squared gradient averages.
+func (opt *RMSProp) State() map[string][]float64 { return bufState(opt.buf) }
+
+// SetState restores the RMSProp squared gradient averages.
+func (opt *RMSProp) SetState(state map[string][]float64) { setBufState(opt.buf, state) }
+
+// State returns a copy of the Adagrad squared gradient sums.
+func (opt *Adagrad) State() map[string][]float64 { return bufState(opt.buf) }
+
+// SetState restores the Adagrad squared gradient sums.
+func (opt *Adagrad) SetState(state map[string][]float64) { setBufState(opt.buf, state) }
+
+// State returns the state of the wrapped optimizer, if any, along with the
+// schedule's update counter.
+func (opt *Scheduled) State() map[string][]float64 {
+	state := make(map[string][]float64)
+	if s, ok := opt.Opt.(StatefulOptimizer); ok {
+		state = s.State()
+	}
+	state[updatesID] = []float64{float64(opt.Updates())}
+	return state
+}
+
+// SetState restores the wrapped optimizer state and the update counter.
+func (opt *Scheduled) SetState(state map[string][]float64) {
+	inner := make(map[string][]float64, len(state))
+	for k, v := range state {
+		if k == updatesID {
+			if len(v) > 0 {
+				atomic.StoreInt64(&opt.state.updates, int64(v[0]))
+			}
+			continue
+		}
+		inner[k] = v
+	}
+	if s, ok := opt.Opt.(StatefulOptimizer); ok {
+		s.SetState(inner)
+	}
+}
+
+// OptimizerState returns a copy of the optimizer state of every unit with a
+// StatefulOptimizer. It should only be called while the network is idle,
+// e.g. after Backward returns.
+func (n *Net) OptimizerState() OptimizerState {
+	s := make(OptimizerState)
+	for _, l := range n.Layers {
+		for _, u := range l {
+			if opt, ok := u.opt.(StatefulOptimizer); ok {
+				s[u.ID] = opt.State()
+			}
+		}
+	}
+	return s
+}
+
+// SetOptimizerState restores optimizer state saved with OptimizerState. It
+// returns an error if s has state for a unit that doesn't exist or doesn't
+// have a StatefulOptimizer. Units missing from s are left unchanged. It
+// should only be called while the network is idle.
+func (n *Net) SetOptimizerState(s OptimizerState) error {
+	units := make(map[string]*Unit)
+	for _, l := range n.Layers {
+		for _, u := range l {
+			units[u.ID] = u
+		}
+	}
+	for id := range s {
+		u, ok := units[id]
+		if !ok {
+			return fmt.Errorf("optimizer state for unknown unit %s", id)
+		}
+		if _, ok := u.opt.(StatefulOptimizer); !ok {
+			return fmt.Errorf("unit %s optimizer %T has no state", id, u.opt)
+		}
+	}
+	for id, state := range s {
+		units[id].opt.(StatefulOptimizer).SetState(state)
+	}
+	return nil
+}
+
+// Save writes the optimizer state as JSON.
+func (s OptimizerState) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+// LoadOptimizerState reads optimizer state written by OptimizerState.Save.
+func LoadOptimizerState(r io.Reader) (OptimizerState, error) {
+	var s OptimizerState
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
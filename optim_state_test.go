@@ -0,0 +1,77 @@
+package neuron
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// Test that restoring parameters and optimizer state resumes training
+// exactly.
+func TestOptimizerState(t *testing.T) {
+	arch := []int{2, 3, 1}
+	train := func(n *Net, steps int) {
+		for ii := 0; ii < steps; ii++ {
+			x := []float64{rand.NormFloat64(), rand.NormFloat64()}
+			out := n.MustForward(x)
+			n.MustBackward([]float64{out[0] - x[0]})
+		}
+	}
+
+	rand.Seed(3)
+	n := MustNewMLP(arch, NewSGD(0.1, 0.9, 0.0))
+	n.Start(true, 1)
+	train(n, 5)
+	params := n.Data()
+	var buf bytes.Buffer
+	if err := n.OptimizerState().Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	rand.Seed(4)
+	train(n, 5)
+	want := n.Data()
+
+	// Resume from the checkpoint in a fresh network.
+	state, err := LoadOptimizerState(&buf)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	n2 := MustNewMLP(arch, NewSGD(0.1, 0.9, 0.0))
+	n2.SetData(params)
+	if err := n2.SetOptimizerState(state); err != nil {
+		t.Fatalf("SetOptimizerState failed: %v", err)
+	}
+	n2.Start(true, 1)
+	rand.Seed(4)
+	train(n2, 5)
+	got := n2.Data()
+	for uid, ps := range want {
+		for id, v := range ps {
+			if got[uid][id] != v {
+				t.Errorf("Param %s/%s is %.6f; expected %.6f", uid, id, got[uid][id], v)
+			}
+		}
+	}
+
+	// Check that invalid state is checked.
+	if err := n2.SetOptimizerState(OptimizerState{"foo": nil}); err == nil {
+		t.Errorf("Expected error for unknown unit")
+	}
+}
+
+// Test exporting the state of a scheduled optimizer.
+func TestScheduledState(t *testing.T) {
+	opt := NewScheduled(NewSGD(1.0, 0.9, 0.0), StepLR{StepSize: 1, Gamma: 0.5}, 1)
+	opt.state.advance()
+	opt.Opt.(*SGD).buf["a"] = 2.0
+
+	state := opt.State()
+	opt2 := NewScheduled(NewSGD(1.0, 0.9, 0.0), StepLR{StepSize: 1, Gamma: 0.5}, 1)
+	opt2.SetState(state)
+	if opt2.Updates() != 1 || opt2.Opt.(*SGD).buf["a"] != 2.0 {
+		t.Errorf("Scheduled state not restored")
+	}
+	if _, ok := opt2.Opt.(*SGD).buf[updatesID]; ok {
+		t.Errorf("Update counter leaked into the SGD state")
+	}
+}
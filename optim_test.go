@@ -1,6 +1,7 @@
 package neuron
 
 import (
+	"math"
 	"testing"
 )
 
@@ -37,3 +38,91 @@ func TestSGD(t *testing.T) {
 		t.Errorf("Incorrect SGD step")
 	}
 }
+
+// Test RMSProp steps.
+func TestRMSProp(t *testing.T) {
+	const id = "000"
+	p := &Param{
+		Data:         1.0,
+		RequiresGrad: true,
+		grad:         2.0,
+	}
+	opt := NewRMSProp(0.1, 0.75, 0.0, 0.0)
+
+	// v = 0.25 * 4 = 1
+	opt.Step(id, p)
+	if !almostEqual(opt.buf[id], 1.0) || !almostEqual(p.Data, 0.8) {
+		t.Errorf("Incorrect RMSProp step")
+	}
+
+	// v = 0.75 * 1 + 0.25 * 16 = 4.75
+	p.grad = -4.0
+	opt.Step(id, p)
+	if !almostEqual(opt.buf[id], 4.75) || !almostEqual(p.Data, 0.8+0.4/math.Sqrt(4.75)) {
+		t.Errorf("Incorrect RMSProp step")
+	}
+	if p.grad != 0.0 {
+		t.Errorf("RMSProp didn't reset grad")
+	}
+}
+
+// Test Adagrad steps.
+func TestAdagrad(t *testing.T) {
+	const id = "000"
+	p := &Param{
+		Data:         1.0,
+		RequiresGrad: true,
+		grad:         3.0,
+	}
+	opt := NewAdagrad(0.1, 0.0, 0.0)
+
+	opt.Step(id, p)
+	if !almostEqual(opt.buf[id], 9.0) || !almostEqual(p.Data, 0.9) {
+		t.Errorf("Incorrect Adagrad step")
+	}
+
+	// v = 9 + 16 = 25
+	p.grad = 4.0
+	opt.Step(id, p)
+	if !almostEqual(opt.buf[id], 25.0) || !almostEqual(p.Data, 0.82) {
+		t.Errorf("Incorrect Adagrad step")
+	}
+}
+
+// Test DP-SGD per-sample clipping and noisy steps.
+func TestDPSGD(t *testing.T) {
+	opt := NewDPSGD(0.1, 1.0, 0.0)
+	w := NewWeight()
+	w.init("a", 1.0, true)
+	w.init("b", 1.0, true)
+
+	// First sample has norm 5, clipped to 1.
+	prev := w.grads()
+	w.Params["a"].grad = 3.0
+	w.Params["b"].grad = 4.0
+	opt.clipSample(w, prev)
+	if !almostEqual(w.Params["a"].grad, 0.6) || !almostEqual(w.Params["b"].grad, 0.8) {
+		t.Errorf("Incorrect DPSGD clipping")
+	}
+
+	// Second sample has norm 0.5, not clipped.
+	prev = w.grads()
+	w.Params["a"].grad += 0.5
+	opt.clipSample(w, prev)
+	if !almostEqual(w.Params["a"].grad, 1.1) || !almostEqual(w.Params["b"].grad, 0.8) {
+		t.Errorf("Incorrect DPSGD clipping")
+	}
+
+	// No noise, so a plain SGD step.
+	opt.Step("a", w.Params["a"])
+	if !almostEqual(w.Params["a"].Data, 0.89) || w.Params["a"].grad != 0.0 {
+		t.Errorf("Incorrect DPSGD step")
+	}
+
+	// Noisy step.
+	opt.NoiseMultiplier = 1.0
+	opt.Step("b", w.Params["b"])
+	if almostEqual(w.Params["b"].Data, 0.92) {
+		t.Errorf("DPSGD step is not noisy")
+	}
+}
@@ -0,0 +1,160 @@
+package neuron
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// paramsMagic identifies the binary parameter format written by SaveParams.
+const paramsMagic = "GNPV"
+
+// Flags stored in the parameter format header.
+const (
+	paramsVersion = 1
+	flagDelta     = 1 << 0
+)
+
+// maxIDLen bounds the length of the unit and parameter IDs read by
+// LoadParams, so that corrupt files can't make it allocate huge strings.
+const maxIDLen = 1 << 16
+
+// SaveParams writes parameter values in a compact gzip-compressed binary
+// format. If base is not nil, values are delta encoded against it: each value
+// is stored as the XOR of its bits with the bits of the matching base value.
+// Parameters that change little between checkpoints share most of their
+// bits, so delta encoded checkpoints compress to a fraction of the size. The
+// same base must be passed to LoadParams.
+//
+// Only parameter values are saved, e.g. to keep a compact history of
+// snapshots of a long training run. SaveParams is separate from Net.Save and
+// Checkpoint, which save the full network and optimizer state without
+// compression. The values can be set on a network built with the same
+// options with SetData.
+func SaveParams(w io.Writer, v ParamVector, base ParamVector) error {
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+
+	var flags byte
+	if base != nil {
+		flags |= flagDelta
+	}
+	bw.WriteString(paramsMagic)
+	bw.WriteByte(paramsVersion)
+	bw.WriteByte(flags)
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	putUvarint := func(x uint64) {
+		n := binary.PutUvarint(buf, x)
+		bw.Write(buf[:n])
+	}
+	putString := func(s string) {
+		putUvarint(uint64(len(s)))
+		bw.WriteString(s)
+	}
+
+	uids := make([]string, 0, len(v))
+	for uid := range v {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	putUvarint(uint64(len(uids)))
+	for _, uid := range uids {
+		putString(uid)
+		ids := make([]string, 0, len(v[uid]))
+		for id := range v[uid] {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		putUvarint(uint64(len(ids)))
+		for _, id := range ids {
+			putString(id)
+			bits := math.Float64bits(v[uid][id]) ^ math.Float64bits(base[uid][id])
+			binary.BigEndian.PutUint64(buf, bits)
+			bw.Write(buf[:8])
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// LoadParams reads parameter values written by SaveParams. base must be the
+// same base passed to SaveParams for delta encoded values.
+func LoadParams(r io.Reader, base ParamVector) (ParamVector, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	br := bufio.NewReader(zr)
+
+	header := make([]byte, len(paramsMagic)+2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(paramsMagic)]) != paramsMagic {
+		return nil, errors.New("not a parameter file")
+	}
+	if header[len(paramsMagic)] != paramsVersion {
+		return nil, fmt.Errorf("unsupported parameter file version %d",
+			header[len(paramsMagic)])
+	}
+	delta := header[len(paramsMagic)+1]&flagDelta != 0
+	if delta && base == nil {
+		return nil, errors.New("delta encoded parameters need a base")
+	}
+
+	readString := func() (string, error) {
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return "", err
+		}
+		if n > maxIDLen {
+			return "", fmt.Errorf("ID length %d too long", n)
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(br, b)
+		return string(b), err
+	}
+
+	nunits, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	v := make(ParamVector)
+	buf := make([]byte, 8)
+	for ii := uint64(0); ii < nunits; ii++ {
+		uid, err := readString()
+		if err != nil {
+			return nil, err
+		}
+		nparams, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		v[uid] = make(map[string]float64)
+		for jj := uint64(0); jj < nparams; jj++ {
+			id, err := readString()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := io.ReadFull(br, buf); err != nil {
+				return nil, err
+			}
+			bits := binary.BigEndian.Uint64(buf)
+			if delta {
+				bits ^= math.Float64bits(base[uid][id])
+			}
+			v[uid][id] = math.Float64frombits(bits)
+		}
+	}
+	return v, nil
+}
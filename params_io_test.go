@@ -0,0 +1,77 @@
+package neuron
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Test saving and loading compressed parameter checkpoints.
+func TestSaveParams(t *testing.T) {
+	rand.Seed(12)
+
+	arch := []int{16, 32, 32, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	n := MustNewMLP(arch, opt)
+	base := n.Data()
+
+	// Small update to every parameter.
+	n.forEachParam(func(u *Unit, id string, p *Param) {
+		p.Data += 1.0e-06 * rand.NormFloat64()
+	})
+	v := n.Data()
+
+	var full, delta bytes.Buffer
+	if err := SaveParams(&full, v, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveParams(&delta, v, base); err != nil {
+		t.Fatal(err)
+	}
+	if delta.Len() >= full.Len() {
+		t.Errorf("Delta checkpoint is %d bytes; full is %d", delta.Len(), full.Len())
+	}
+
+	for _, c := range []struct {
+		buf  *bytes.Buffer
+		base ParamVector
+	}{{&full, nil}, {&delta, base}} {
+		loaded, err := LoadParams(c.buf, c.base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for uid, params := range v {
+			for id, want := range params {
+				if loaded[uid][id] != want {
+					t.Errorf("Loaded [%s][%s] is %v; expected %v", uid, id,
+						loaded[uid][id], want)
+				}
+			}
+		}
+	}
+
+	// Delta checkpoints need a base.
+	delta.Reset()
+	SaveParams(&delta, v, base)
+	if _, err := LoadParams(&delta, nil); err == nil {
+		t.Errorf("Expected error loading delta checkpoint without a base")
+	}
+	if _, err := LoadParams(bytes.NewBufferString("foo"), nil); err == nil {
+		t.Errorf("Expected error loading invalid checkpoint")
+	}
+
+	// A corrupt ID length.
+	var corrupt bytes.Buffer
+	zw := gzip.NewWriter(&corrupt)
+	zw.Write([]byte{'G', 'N', 'P', 'V', paramsVersion, 0})
+	buf := make([]byte, binary.MaxVarintLen64)
+	zw.Write(buf[:binary.PutUvarint(buf, 1)])
+	zw.Write(buf[:binary.PutUvarint(buf, math.MaxUint64)])
+	zw.Close()
+	if _, err := LoadParams(&corrupt, nil); err == nil {
+		t.Errorf("Expected error loading checkpoint with a corrupt ID length")
+	}
+}
@@ -0,0 +1,42 @@
+package neuron
+
+// Passes are serialized with a ticket, so that Forward, Predict, and the
+// other passes can be called from multiple goroutines, e.g. HTTP handlers
+// serving predictions, without the signals of concurrent passes mixing. In
+// training mode a forward pass keeps the ticket until its matching backward
+// pass completes, so each goroutine's Forward/Backward pair runs as a unit.
+
+// acquire waits for any other pass to complete and takes the ticket. It gives
+// up if done is closed first, in which case ok is false.
+func (n *Net) acquire(done <-chan struct{}) (ok bool) {
+	select {
+	case n.ticket <- struct{}{}:
+		return true
+	case <-done:
+		n.stuck = "waiting for another pass to complete"
+		return false
+	}
+}
+
+// release returns the ticket.
+func (n *Net) release() {
+	<-n.ticket
+}
+
+// endForward completes a forward pass. In training mode a successful pass
+// keeps the ticket until the matching backward pass.
+func (n *Net) endForward(ok bool) {
+	if ok && n.train {
+		n.pending = true
+		return
+	}
+	n.release()
+}
+
+// endBackward completes the backward pass matching the last forward pass.
+func (n *Net) endBackward() {
+	if n.pending {
+		n.pending = false
+		n.release()
+	}
+}
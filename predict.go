@@ -0,0 +1,98 @@
+package neuron
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Predict runs an inference-only forward pass through a network started with
+// Start, in either mode. Units skip the backward pass and are done as soon as
+// the forward pass completes, so no Backward is needed to unblock them, and
+// the weights and accumulated gradients are left untouched. Like Forward,
+// Predict is safe to call from multiple goroutines, and in training mode it
+// waits for any Forward/Backward pair in progress.
+func (n *Net) Predict(data []float64) ([]float64, error) {
+	if err := n.checkInput(data); err != nil {
+		return nil, err
+	}
+	if n.seq {
+		return nil, errors.New("predict needs a network started with Start; " +
+			"use ForwardSequence in sequence mode")
+	}
+
+	logf(2, "MLP Predict\n")
+	done, stop := n.watch()
+	defer stop()
+	if !n.acquire(done) {
+		return nil, n.deadlock("forward")
+	}
+	// Units read the mode once the pass has started, and the pass is synced
+	// before the mode is restored.
+	train := n.train
+	n.train = false
+	output, ok := n.forward(data, done)
+	n.train = train
+	n.release()
+	if !ok {
+		return nil, n.deadlock("forward")
+	}
+	return output, n.anomalyErr()
+}
+
+// Softmax converts network output scores to class probabilities with the
+// given temperature. Temperatures above 1 flatten the distribution, below 1
+// sharpen it. temperature must be > 0.
+func Softmax(scores []float64, temperature float64) []float64 {
+	probs := make([]float64, len(scores))
+	if len(scores) == 0 {
+		return probs
+	}
+
+	// Subtract the max for numerical stability.
+	max := math.Inf(-1)
+	for _, s := range scores {
+		max = math.Max(max, s)
+	}
+	sum := 0.0
+	for ii, s := range scores {
+		probs[ii] = math.Exp((s - max) / temperature)
+		sum += probs[ii]
+	}
+	for ii := range probs {
+		probs[ii] /= sum
+	}
+	return probs
+}
+
+// Argmax returns the index of the largest score, or -1 if scores is empty.
+// Ties go to the lowest index.
+func Argmax(scores []float64) int {
+	best := -1
+	for ii, s := range scores {
+		if best < 0 || s > scores[best] {
+			best = ii
+		}
+	}
+	return best
+}
+
+// TopK returns the indices of the k largest scores in descending order. Ties
+// go to the lowest index. All indices are returned if there are fewer than k
+// scores, and none if k is negative.
+func TopK(scores []float64, k int) []int {
+	if k < 0 {
+		k = 0
+	}
+	idx := make([]int, len(scores))
+	for ii := range idx {
+		idx[ii] = ii
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return scores[idx[i]] > scores[idx[j]]
+	})
+	if k < len(idx) {
+		idx = idx[:k]
+	}
+	return idx
+}
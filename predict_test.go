@@ -0,0 +1,100 @@
+package neuron
+
+import (
+	"math"
+	"testing"
+)
+
+// Test softmax with temperature.
+func TestSoftmax(t *testing.T) {
+	scores := []float64{1.0, 2.0, 3.0}
+	probs := Softmax(scores, 1.0)
+	z := math.Exp(1.0) + math.Exp(2.0) + math.Exp(3.0)
+	for ii, s := range scores {
+		if !almostEqual(probs[ii], math.Exp(s)/z) {
+			t.Errorf("Softmax[%d] is %.4f; expected %.4f", ii, probs[ii],
+				math.Exp(s)/z)
+		}
+	}
+
+	// Higher temperature flattens the distribution.
+	hot := Softmax(scores, 10.0)
+	if hot[2] >= probs[2] || hot[0] <= probs[0] {
+		t.Errorf("Temperature didn't flatten softmax")
+	}
+
+	// Large scores don't overflow.
+	probs = Softmax([]float64{1000.0, 1000.0}, 1.0)
+	if !almostEqual(probs[0], 0.5) {
+		t.Errorf("Softmax overflowed: %v", probs)
+	}
+}
+
+// Test argmax and top-k.
+func TestTopK(t *testing.T) {
+	scores := []float64{0.1, 0.7, 0.2, 0.7}
+	if a := Argmax(scores); a != 1 {
+		t.Errorf("Argmax is %d; expected 1", a)
+	}
+	if a := Argmax(nil); a != -1 {
+		t.Errorf("Argmax of empty is %d; expected -1", a)
+	}
+
+	top := TopK(scores, 3)
+	want := []int{1, 3, 2}
+	for ii := range want {
+		if top[ii] != want[ii] {
+			t.Errorf("TopK is %v; expected %v", top, want)
+			break
+		}
+	}
+	if top = TopK(scores, 10); len(top) != 4 {
+		t.Errorf("TopK returned %d indices; expected 4", len(top))
+	}
+	if top = TopK(scores, -1); len(top) != 0 {
+		t.Errorf("TopK with negative k returned %d indices; expected 0", len(top))
+	}
+}
+
+// Test inference-only passes on a network running in training mode.
+func TestNetPredict(t *testing.T) {
+	n := MustNewMLP([]int{2, 3, 1}, NewSGD(0.1, 0.0, 0.0))
+	n.Start(true, 2)
+	defer n.Stop()
+	data := []float64{1.0, -1.0}
+
+	want := n.MustForward(data)
+	n.MustBackward([]float64{1.0})
+	grads := n.Grads()
+	for ii := 0; ii < 3; ii++ {
+		got, err := n.Predict(data)
+		if err != nil {
+			t.Fatalf("Predict failed: %v", err)
+		}
+		if !almostEqual(got[0], want[0]) {
+			t.Errorf("Predict output is %.6f; expected %.6f", got[0], want[0])
+		}
+	}
+	if !n.Training() {
+		t.Errorf("Predict didn't restore training mode")
+	}
+	for uid, ug := range n.Grads() {
+		for id, g := range ug {
+			if g != grads[uid][id] {
+				t.Errorf("Grad %s/%s changed by Predict", uid, id)
+			}
+		}
+	}
+
+	// Predict passes don't count towards the update frequency.
+	weight := n.Layers[2][0].W.Params["001_000000"].Data
+	n.MustForward(data)
+	n.MustBackward([]float64{1.0})
+	if n.Layers[2][0].W.Params["001_000000"].Data == weight {
+		t.Errorf("Weights not updated after 2 training passes")
+	}
+
+	if _, err := n.Predict([]float64{1.0}); err == nil {
+		t.Errorf("Predict did not return an error")
+	}
+}
@@ -0,0 +1,77 @@
+package neuron
+
+import (
+	"math"
+)
+
+// maxRDPOrder is the largest Renyi divergence order tried by DPEpsilon.
+const maxRDPOrder = 256
+
+// DPEpsilon computes the (epsilon, delta) differential privacy guarantee for a
+// DPSGD training run of steps updates, each computed on a random subsample of
+// the data with sampling rate q (i.e. updateFreq / dataset size). It uses the
+// Renyi DP accountant for the sampled Gaussian mechanism (Mironov et al.,
+// 2019), minimizing over integer orders. order is the Renyi order achieving
+// the returned epsilon.
+func DPEpsilon(q, noiseMultiplier float64, steps int, delta float64) (eps float64, order int) {
+	if q <= 0 || steps <= 0 {
+		return 0.0, 0
+	}
+	if noiseMultiplier <= 0 {
+		return math.Inf(1), 0
+	}
+
+	eps = math.Inf(1)
+	for alpha := 2; alpha <= maxRDPOrder; alpha++ {
+		rdp := float64(steps) * sampledGaussianRDP(q, noiseMultiplier, alpha)
+		e := rdp + math.Log(1/delta)/float64(alpha-1)
+		if e < eps {
+			eps, order = e, alpha
+		}
+	}
+	return
+}
+
+// sampledGaussianRDP computes the Renyi DP of a single step of the sampled
+// Gaussian mechanism at an integer order alpha, using the binomial expansion
+//
+//	A = sum_k C(alpha, k) (1-q)^(alpha-k) q^k exp((k^2 - k) / (2 sigma^2))
+//
+// and returning log(A) / (alpha - 1).
+func sampledGaussianRDP(q, sigma float64, alpha int) float64 {
+	if q >= 1 {
+		// No subsampling, plain Gaussian mechanism.
+		return float64(alpha) / (2 * sigma * sigma)
+	}
+	terms := make([]float64, alpha+1)
+	for k := 0; k <= alpha; k++ {
+		kf := float64(k)
+		terms[k] = logBinom(alpha, k) + kf*math.Log(q) +
+			float64(alpha-k)*math.Log1p(-q) + (kf*kf-kf)/(2*sigma*sigma)
+	}
+	return logSumExp(terms) / float64(alpha-1)
+}
+
+// logBinom computes log(n choose k).
+func logBinom(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// logSumExp computes log(sum(exp(x))) stably.
+func logSumExp(x []float64) float64 {
+	m := math.Inf(-1)
+	for _, v := range x {
+		m = math.Max(m, v)
+	}
+	if math.IsInf(m, -1) {
+		return m
+	}
+	s := 0.0
+	for _, v := range x {
+		s += math.Exp(v - m)
+	}
+	return m + math.Log(s)
+}
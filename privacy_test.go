@@ -0,0 +1,34 @@
+package neuron
+
+import (
+	"math"
+	"testing"
+)
+
+// Test the DP-SGD privacy accountant.
+func TestDPEpsilon(t *testing.T) {
+	// Without subsampling, eps = min_a a/2 + log(1/delta)/(a-1).
+	const delta = 1.0e-05
+	eps, order := DPEpsilon(1.0, 1.0, 1, delta)
+	epsWant := 3.0 + math.Log(1/delta)/5.0
+	if order != 6 || !almostEqual(eps, epsWant) {
+		t.Errorf("DPEpsilon returned (%.4f, %d); expected (%.4f, 6)", eps, order,
+			epsWant)
+	}
+
+	// Subsampling amplifies privacy, more steps and less noise consume more.
+	base, _ := DPEpsilon(0.01, 1.0, 1000, delta)
+	if e, _ := DPEpsilon(0.1, 1.0, 1000, delta); e <= base {
+		t.Errorf("Larger sampling rate gave eps %.4f <= %.4f", e, base)
+	}
+	if e, _ := DPEpsilon(0.01, 1.0, 2000, delta); e <= base {
+		t.Errorf("More steps gave eps %.4f <= %.4f", e, base)
+	}
+	if e, _ := DPEpsilon(0.01, 0.5, 1000, delta); e <= base {
+		t.Errorf("Less noise gave eps %.4f <= %.4f", e, base)
+	}
+
+	if e, _ := DPEpsilon(0.01, 0.0, 1000, delta); !math.IsInf(e, 1) {
+		t.Errorf("No noise gave eps %.4f; expected +Inf", e)
+	}
+}
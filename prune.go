@@ -0,0 +1,196 @@
+package neuron
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// PruneUnit removes a hidden unit and all of its connections, e.g. for
+// pruning schedules that drop the units with the smallest weights. Unlike
+// masking (see SetMask), the unit's goroutine and channels are removed too,
+// so the network gets cheaper to run. The layer must keep at least one unit,
+// and every unit downstream at least one input.
+//
+// Like AddUnit, PruneUnit can be called while the network is running, but not
+// between a Forward and its Backward.
+func (n *Net) PruneUnit(id string) error {
+	ii, jj, ok := n.findUnit(id)
+	if !ok {
+		return fmt.Errorf("unknown unit %s", id)
+	}
+	if ii < 1 || ii >= len(n.Layers)-1 {
+		return fmt.Errorf("can only prune hidden units; got %s", id)
+	}
+	if len(n.Layers[ii]) == 1 {
+		return fmt.Errorf("can't prune the last unit of layer %d", ii)
+	}
+	if _, ok := n.convs[ii]; ok {
+		return fmt.Errorf("can't prune %s of convolution layer %d", id, ii)
+	}
+	u := n.Layers[ii][jj]
+	for _, u2 := range n.downstream(u) {
+		if u2.nin == 1 {
+			return fmt.Errorf("pruning %s would leave %s without inputs", id, u2.ID)
+		}
+		if u2.cell != nil {
+			return fmt.Errorf("can't prune %s, an input of gated unit %s", id, u2.ID)
+		}
+		if u2.attn != nil {
+			return fmt.Errorf("can't prune %s, an input of attention unit %s", id, u2.ID)
+		}
+		if n.inConv(u2.ID) {
+			return fmt.Errorf("can't prune %s, an input of convolution unit %s", id, u2.ID)
+		}
+	}
+
+	n.modify(func() {
+		logf(1, "Pruning unit %s\n", id)
+		for _, l := range n.Layers[:ii] {
+			for _, u1 := range l {
+				if _, ok := u1.output[id]; ok {
+					u1.disconnect(u)
+				}
+			}
+		}
+		for _, u2 := range n.downstream(u) {
+			u.disconnect(u2)
+		}
+		l := n.Layers[ii]
+		n.Layers[ii] = append(l[:jj:jj], l[jj+1:]...)
+		n.Arch[ii]--
+		n.sched = schedStates(n.Layers)
+	})
+	return nil
+}
+
+// PruneConnection removes the connection from unit from to unit to, along
+// with its weight and channels. The receiving unit must keep at least one
+// input. Like PruneUnit, it can be called while the network is running.
+func (n *Net) PruneConnection(from, to string) error {
+	ii, jj, ok := n.findUnit(from)
+	if !ok {
+		return fmt.Errorf("unknown unit %s", from)
+	}
+	u1 := n.Layers[ii][jj]
+	ii, jj, ok = n.findUnit(to)
+	if !ok {
+		return fmt.Errorf("unknown unit %s", to)
+	}
+	u2 := n.Layers[ii][jj]
+	if _, ok := u1.output[to]; !ok {
+		return fmt.Errorf("no connection %s -> %s", from, to)
+	}
+	if u2.nin == 1 {
+		return fmt.Errorf("pruning %s -> %s would leave %s without inputs", from, to, to)
+	}
+	if u2.cell != nil {
+		return fmt.Errorf("can't prune an input of gated unit %s", to)
+	}
+	if u2.attn != nil {
+		return fmt.Errorf("can't prune an input of attention unit %s", to)
+	}
+	if _, ok := n.convs[ii]; ok {
+		return fmt.Errorf("can't prune an input of convolution unit %s", to)
+	}
+	n.modify(func() {
+		logf(2, "Pruning connection %s -> %s\n", from, to)
+		u1.disconnect(u2)
+	})
+	return nil
+}
+
+// disconnect removes the connection from u to u2, undoing connect.
+func (u *Unit) disconnect(u2 *Unit) {
+	delete(u.output, u2.ID)
+	delete(u2.outputB, u.ID)
+	delete(u2.W.Params, u.ID)
+	delete(u2.W.Params, logVarID(u.ID))
+	delete(u2.W.dw, u.ID)
+	delete(u2.checkpoint, u.ID)
+	u2.nin--
+}
+
+// findUnit returns the layer and index of the unit with the given ID.
+func (n *Net) findUnit(id string) (layer, index int, ok bool) {
+	for ii, l := range n.Layers {
+		for jj, u := range l {
+			if u.ID == id {
+				return ii, jj, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// downstream returns the units u sends its output to.
+func (n *Net) downstream(u *Unit) []*Unit {
+	var units []*Unit
+	for _, l := range n.Layers {
+		for _, u2 := range l {
+			if _, ok := u.output[u2.ID]; ok {
+				units = append(units, u2)
+			}
+		}
+	}
+	return units
+}
+
+// Prune masks the connection weights with the smallest magnitudes across the
+// whole network, so that at least a fraction sparsity of all connections are
+// masked, and returns the achieved sparsity. Masked weights are treated as
+// zero and never updated (see SetMask), so pruning can be undone, or the
+// masked connections removed for good with PruneConnection. Already masked
+// connections count towards the target and stay masked. Prune should only be
+// called while the network is idle, e.g. after Backward returns.
+func (n *Net) Prune(sparsity float64) (float64, error) {
+	if sparsity < 0 || sparsity >= 1 {
+		return 0, fmt.Errorf("sparsity needs 0 <= s < 1; got %g", sparsity)
+	}
+	var units []*Unit
+	for _, l := range n.Layers {
+		units = append(units, l...)
+	}
+	pruneMagnitude(units, sparsity)
+	return n.GetMask().Sparsity(), nil
+}
+
+// PruneLayers is like Prune, but prunes each layer's incoming connections to
+// the target sparsity separately, so that no layer is pruned away entirely.
+func (n *Net) PruneLayers(sparsity float64) (float64, error) {
+	if sparsity < 0 || sparsity >= 1 {
+		return 0, fmt.Errorf("sparsity needs 0 <= s < 1; got %g", sparsity)
+	}
+	for _, l := range n.Layers[1:] {
+		pruneMagnitude(l, sparsity)
+	}
+	return n.GetMask().Sparsity(), nil
+}
+
+// pruneMagnitude masks the smallest magnitude connection weights of units, so
+// that at least a fraction sparsity of them are masked.
+func pruneMagnitude(units []*Unit, sparsity float64) {
+	type conn struct {
+		p   *Param
+		mag float64
+	}
+	var conns []conn
+	for _, u := range units {
+		for _, k := range weightKeys(u.W) {
+			p := u.W.Params[k]
+			mag := math.Abs(p.Data)
+			if p.masked {
+				mag = -1.0
+			}
+			conns = append(conns, conn{p, mag})
+		}
+	}
+	sort.SliceStable(conns, func(ii, jj int) bool {
+		return conns[ii].mag < conns[jj].mag
+	})
+	k := int(math.Ceil(sparsity * float64(len(conns))))
+	for _, c := range conns[:k] {
+		c.p.masked = true
+		c.p.grad = 0.0
+	}
+}
@@ -0,0 +1,144 @@
+package neuron
+
+import (
+	"math"
+	"testing"
+)
+
+// Test pruning units and connections from a running network.
+func TestPruneUnit(t *testing.T) {
+	arch := []int{2, 4, 3, 2}
+	data := []float64{1.0, -1.0}
+	grad := []float64{1.0, -1.0}
+	for _, opts := range [][]Option{nil, {WithLayerEngine()}, {WithSignalBatching()},
+		{WithSkipConnections(1, 3), WithWeightNorm()}} {
+		n := MustNewMLP(arch, NewSGD(0.5, 0.0, 0.0), opts...)
+		n.Start(true, 1)
+		n.MustForward(data)
+		n.MustBackward(grad)
+
+		if err := n.PruneUnit(unitID(1, 1)); err != nil {
+			t.Fatalf("PruneUnit failed: %v", err)
+		}
+		if err := n.PruneConnection(unitID(1, 0), unitID(2, 2)); err != nil {
+			t.Fatalf("PruneConnection failed: %v", err)
+		}
+		if n.Arch[1] != 3 || len(n.Layers[1]) != 3 {
+			t.Errorf("Arch is %v after pruning", n.Arch)
+		}
+		for ii := 0; ii < 2; ii++ {
+			n.MustForward(data)
+			n.MustBackward(grad)
+		}
+		n.Stop()
+		if err := n.Validate(); err != nil {
+			t.Errorf("Validate failed: %v", err)
+		}
+		if _, ok := n.Layers[2][2].W.Params[unitID(1, 0)]; ok {
+			t.Errorf("Pruned connection still has a weight")
+		}
+
+		// Pruned networks match the dense engine.
+		if n.Layers[1][0].W.reparam() {
+			continue
+		}
+		d, _ := NewDense(n)
+		want, _ := d.Forward(data)
+		n.Start(false, 0)
+		got := n.MustForward(data)
+		n.Stop()
+		for ii := range want {
+			if !almostEqualTol(got[ii], want[ii], 1.0e-06) {
+				t.Errorf("Output %d is %.6f; expected %.6f", ii, got[ii], want[ii])
+			}
+		}
+	}
+
+	n := MustNewMLP([]int{2, 1, 2}, NewSGD(0.5, 0.0, 0.0))
+	for _, id := range []string{unitID(0, 0), unitID(1, 0), unitID(2, 0), "x"} {
+		if err := n.PruneUnit(id); err == nil {
+			t.Errorf("PruneUnit(%s) did not return an error", id)
+		}
+	}
+	for _, c := range [][2]string{{unitID(1, 0), unitID(2, 0)}, {unitID(0, 0), unitID(2, 0)},
+		{"x", unitID(2, 0)}} {
+		if err := n.PruneConnection(c[0], c[1]); err == nil {
+			t.Errorf("PruneConnection(%s, %s) did not return an error", c[0], c[1])
+		}
+	}
+}
+
+// Test magnitude pruning to sparsity targets.
+func TestPrune(t *testing.T) {
+	arch := []int{4, 5, 3}
+	n := MustNewMLP(arch, NewSGD(0.5, 0.0, 0.0))
+	got, err := n.Prune(0.5)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if got < 0.5 || got > 0.55 {
+		t.Errorf("Sparsity is %.3f; expected 0.5", got)
+	}
+	// Every masked weight is smaller than every unmasked weight.
+	maxMasked, minKept := 0.0, math.Inf(1)
+	for _, l := range n.Layers {
+		for _, u := range l {
+			for _, k := range weightKeys(u.W) {
+				p := u.W.Params[k]
+				if p.masked {
+					maxMasked = math.Max(maxMasked, math.Abs(p.Data))
+				} else {
+					minKept = math.Min(minKept, math.Abs(p.Data))
+				}
+			}
+		}
+	}
+	if maxMasked > minKept {
+		t.Errorf("Masked a weight of magnitude %.4f but kept one of %.4f", maxMasked,
+			minKept)
+	}
+
+	// Masked weights stay masked, and aren't updated.
+	if got, _ := n.Prune(0.2); got < 0.5 {
+		t.Errorf("Sparsity dropped to %.3f", got)
+	}
+	before := n.Data()
+	n.Start(true, 1)
+	n.MustForward([]float64{1.0, -1.0, 0.5, 2.0})
+	n.MustBackward([]float64{1.0, 1.0, -1.0})
+	n.Stop()
+	after := n.Data()
+	for uid, ud := range before {
+		for id, v := range ud {
+			if m := n.GetMask()[uid]; m != nil && isConn(id) && !m[id] && after[uid][id] != v {
+				t.Errorf("Masked weight %s/%s changed", uid, id)
+			}
+		}
+	}
+
+	// Per layer pruning hits the target in every layer.
+	n = MustNewMLP(arch, NewSGD(0.5, 0.0, 0.0))
+	if _, err := n.PruneLayers(0.6); err != nil {
+		t.Fatalf("PruneLayers failed: %v", err)
+	}
+	for ii, l := range n.Layers[1:] {
+		total, masked := 0, 0
+		for _, u := range l {
+			for _, k := range weightKeys(u.W) {
+				total++
+				if u.W.Params[k].masked {
+					masked++
+				}
+			}
+		}
+		if s := float64(masked) / float64(total); s < 0.6 || s > 0.7 {
+			t.Errorf("Layer %d sparsity is %.3f; expected 0.6", ii+1, s)
+		}
+	}
+
+	for _, s := range []float64{-0.1, 1.0} {
+		if _, err := n.Prune(s); err == nil {
+			t.Errorf("Prune did not return an error")
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package neuron
+
+import (
+	"math"
+)
+
+// quantize enables fake quantization of the connection weights to bits bits.
+// Weights are quantized symmetrically per unit, with a step size set by the
+// largest effective weight magnitude.
+func (w *Weight) quantize(bits int) {
+	w.qbits = bits
+}
+
+// prepareQuant computes the weight quantization step size for this pass.
+func (w *Weight) prepareQuant() {
+	max := 0.0
+	for k, p := range w.Params {
+		if !isConn(k) || p.masked {
+			continue
+		}
+		max = math.Max(max, math.Abs(w.real(k, p)))
+	}
+	w.qstep = max / quantLevels(w.qbits)
+}
+
+// quantLevels returns the number of positive levels in a symmetric signed
+// grid with the given bits.
+func quantLevels(bits int) float64 {
+	return float64(int(1)<<(bits-1) - 1)
+}
+
+// fakeQuant rounds v to the nearest point of a symmetric grid with the given
+// step size, clipping to the grid range.
+func fakeQuant(v, step float64, bits int) float64 {
+	if step <= 0 {
+		return v
+	}
+	levels := quantLevels(bits)
+	q := math.Round(v / step)
+	q = math.Max(math.Min(q, levels), -levels)
+	return q * step
+}
+
+// A quantAct wraps an activation to fake-quantize its output. The grid covers
+// the largest output magnitude seen so far. Gradients pass straight through.
+type quantAct struct {
+	Activation
+	bits int
+	max  float64
+}
+
+// Forward quantized activation
+func (a *quantAct) Forward(value float64) float64 {
+	value = a.Activation.Forward(value)
+	a.max = math.Max(a.max, math.Abs(value))
+	return fakeQuant(value, a.max/quantLevels(a.bits), a.bits)
+}
+
+// Params returns the parameters of the wrapped activation, if any.
+func (a *quantAct) Params() map[string]*Param {
+	if pa, ok := a.Activation.(ParamActivation); ok {
+		return pa.Params()
+	}
+	return nil
+}
+
+// clone creates a copy of the quantized activation without shared state.
+func (a *quantAct) clone() Activation {
+	return &quantAct{
+		Activation: cloneActivation(a.Activation),
+		bits:       a.bits,
+		max:        a.max,
+	}
+}
+
+// WithQuantization enables quantization-aware training with the given bit
+// width. Forward passes use fake-quantized connection weights and hidden unit
+// activations, while gradients (estimated straight-through) update the full
+// precision Params, so that the trained network loses little accuracy when
+// deployed with quantized weights.
+func WithQuantization(bits int) Option {
+	return func(c *netConfig) {
+		c.quantBits = bits
+	}
+}
+
+// quantizeNet enables fake quantization for all units after the input layer.
+func (n *Net) quantizeNet(bits int) {
+	for ii, l := range n.Layers {
+		if ii == 0 {
+			continue
+		}
+		for _, u := range l {
+			u.W.quantize(bits)
+			if ii < len(n.Layers)-1 {
+				u.setActivation(&quantAct{Activation: u.activ, bits: bits})
+			}
+		}
+	}
+}
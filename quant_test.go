@@ -0,0 +1,53 @@
+package neuron
+
+import (
+	"testing"
+)
+
+// Test fake quantization.
+func TestFakeQuant(t *testing.T) {
+	const bits = 3
+	step := 0.7 / quantLevels(bits)
+	for _, c := range [][2]float64{{0.3, step}, {-0.7, -0.7}, {2.0, 0.7}, {0.0, 0.0}} {
+		if q := fakeQuant(c[0], step, bits); !almostEqualTol(q, c[1], 1.0e-06) {
+			t.Errorf("fakeQuant(%.3f) is %.4f; expected %.4f", c[0], q, c[1])
+		}
+	}
+}
+
+// Test quantization-aware training on a tiny network.
+func TestQuantization(t *testing.T) {
+	arch := []int{2, 2, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	n := MustNewMLP(arch, opt, WithQuantization(3))
+
+	set := func(u *Unit, w ...float64) {
+		for ii, v := range w[:len(w)-1] {
+			u.W.Params[unitID(0, ii)].Data = v
+		}
+		u.W.Params[biasID].Data = w[len(w)-1]
+	}
+	set(n.Layers[1][0], 0.3, -0.7, 0.1)
+	set(n.Layers[1][1], 0.5, 0.05, 0.1)
+	out := n.Layers[2][0].W.Params
+	out["001_000000"].Data = 1.0
+	out["001_000001"].Data = -0.2
+	out[biasID].Data = 0.0
+
+	// Hidden activations are (0, 0.6), and the quantized output weights are
+	// (1, -1/3).
+	n.Start(true, 1)
+	output := n.MustForward([]float64{1.0, 1.0})
+	n.MustBackward([]float64{1.0})
+	if !almostEqual(output[0], -0.2) {
+		t.Errorf("Quantized output is %.6f; expected -0.2", output[0])
+	}
+	// Full precision weights are updated.
+	if w := out["001_000001"].Data; !almostEqual(w, -0.8) {
+		t.Errorf("Updated weight is %.6f; expected -0.8", w)
+	}
+
+	if _, err := NewMLP(arch, opt, WithQuantization(1)); err == nil {
+		t.Errorf("NewMLP did not return an error")
+	}
+}
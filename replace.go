@@ -0,0 +1,95 @@
+package neuron
+
+import (
+	"fmt"
+)
+
+// ReplaceOutputLayer replaces the output layer with newSize new units with
+// activation activ, or the identity if activ is nil, for transfer learning.
+// The new units are fully connected to the last hidden layer, and to the
+// layers with skip connections into the output layer, with weights drawn by
+// the output layer's initializer. The rest of the network keeps its
+// weights, so it can be frozen (see Freeze) to only train the new output
+// layer. The new units keep the optimizer, weight normalization, and
+// quantization of the old ones, and a softmax added with WithSoftmaxOutput
+// applies to the new layer. Output heads are reset to a single head, dropping
+// any head softmax, and a convolution output layer, see WithConv1D, is
+// replaced by a dense one.
+//
+// Like AddUnit, ReplaceOutputLayer can be called while the network is
+// running: it waits for the pass in progress, and restarts the network with
+// the new layer.
+func (n *Net) ReplaceOutputLayer(newSize int, activ Activation) error {
+	if newSize < 1 {
+		return fmt.Errorf("output layer needs >= 1 unit; got %d", newSize)
+	}
+	last := len(n.Layers) - 1
+	old := n.Layers[last]
+	if old[0].W.bayes {
+		return fmt.Errorf("can't replace a Bayesian output layer")
+	}
+	if activ == nil {
+		activ = new(Identity)
+	}
+	n.modify(func() {
+		n.replaceOutput(newSize, activ)
+	})
+	return nil
+}
+
+// replaceOutput replaces the output layer, see ReplaceOutputLayer. It must be
+// called while the network is stopped.
+func (n *Net) replaceOutput(newSize int, activ Activation) {
+	last := len(n.Layers) - 1
+	old := n.Layers[last]
+	logf(1, "Replacing output layer of %d units with %d units\n", len(old), newSize)
+
+	// Detach the old units.
+	ups := n.upstream(last)
+	for _, jj := range ups {
+		for _, u := range n.Layers[jj] {
+			for _, u2 := range old {
+				delete(u.output, u2.ID)
+			}
+		}
+	}
+
+	l := make([]*Unit, newSize)
+	for jj := range l {
+		u := NewUnit(unitID(last, jj), cloneActivation(activ), old[0].opt.New())
+		u.SetBias(0.0)
+		u.stepDone = n.stepDone
+		u.clipValue, u.netStep = old[0].clipValue, n.clipNorm > 0
+		u.steps = n.steps
+		u.feedOut()
+		for _, ii := range ups {
+			for _, u1 := range n.Layers[ii] {
+				u1.connect(u, n.rand())
+			}
+		}
+		l[jj] = u
+	}
+	n.Layers[last] = l
+	delete(n.convs, last)
+	for _, u := range l {
+		n.initUnit(last, u)
+		n.seedUnit(u)
+		if old[0].W.norm {
+			u.W.normalize()
+		}
+		if old[0].W.qbits > 0 {
+			u.W.quantize(old[0].W.qbits)
+		}
+	}
+	// Only a softmax over the whole layer carries over, since the heads are
+	// reset.
+	full := len(n.softmaxSpans) == 1 && n.softmaxSpans[0] == [2]int{0, len(old)}
+	n.softmax, n.softmaxSpans = full, nil
+	if full {
+		n.softmaxSpans = [][2]int{{0, newSize}}
+	}
+	n.outHeads = []Head{{Name: OutputHead, Size: newSize}}
+
+	n.Arch[last] = newSize
+	n.sched = schedStates(n.Layers)
+}
@@ -0,0 +1,95 @@
+package neuron
+
+import (
+	"testing"
+	"time"
+)
+
+// Test transfer learning by replacing the output layer and fine-tuning it.
+func TestReplaceOutputLayer(t *testing.T) {
+	arch := []int{2, 4, 3, 2}
+	data := []float64{1.0, -1.0}
+	for _, opts := range [][]Option{nil, {WithSoftmaxOutput()}, {WithLayerEngine()},
+		{WithSkipConnections(1, 3), WithWeightNorm()}} {
+		n := MustNewMLP(arch, NewSGD(0.5, 0.0, 0.0), opts...)
+		n.Start(true, 1)
+		n.MustForward(data)
+		n.MustBackward([]float64{1.0, -1.0})
+		n.Stop()
+
+		if err := n.ReplaceOutputLayer(3, new(Tanh)); err != nil {
+			t.Fatalf("ReplaceOutputLayer failed: %v", err)
+		}
+		if err := n.Validate(); err != nil {
+			t.Fatalf("Validate failed: %v", err)
+		}
+		if n.Arch[3] != 3 || len(n.Layers[3]) != 3 {
+			t.Fatalf("Arch is %v after replacing the output layer", n.Arch)
+		}
+		for ii := 0; ii < 3; ii++ {
+			if err := n.Freeze(ii); err != nil {
+				t.Fatalf("Freeze failed: %v", err)
+			}
+		}
+		before := n.Data()
+
+		n.Start(true, 1)
+		out := n.MustForward(data)
+		if len(out) != 3 {
+			t.Errorf("Got %d outputs; expected 3", len(out))
+		}
+		n.MustBackward([]float64{1.0, 0.5, -1.0})
+		n.Stop()
+		if n.softmax {
+			sum := 0.0
+			for _, p := range out {
+				sum += p
+			}
+			if !almostEqual(sum, 1.0) {
+				t.Errorf("Probabilities sum to %.4f; expected 1", sum)
+			}
+		}
+
+		after := n.Data()
+		for _, u := range n.Layers[1] {
+			for id, v := range before[u.ID] {
+				if !almostEqual(after[u.ID][id], v) {
+					t.Errorf("Frozen param %s/%s changed", u.ID, id)
+				}
+			}
+		}
+		u := n.Layers[3][0]
+		if almostEqual(after[u.ID][biasID], before[u.ID][biasID]) {
+			t.Errorf("New output unit %s didn't train", u.ID)
+		}
+	}
+
+	n := MustNewMLP(arch, NewSGD(0.5, 0.0, 0.0))
+	if err := n.ReplaceOutputLayer(0, nil); err == nil {
+		t.Errorf("ReplaceOutputLayer did not return an error")
+	}
+}
+
+// Test replacing the output layer of a running network.
+func TestReplaceOutputLayerRunning(t *testing.T) {
+	data := []float64{1.0, -1.0}
+	n := MustNewMLP([]int{2, 4, 2}, NewSGD(0.5, 0.0, 0.0))
+	n.Start(true, 1)
+	defer n.Stop()
+	n.MustForward(data)
+	n.MustBackward([]float64{1.0, -1.0})
+	if err := n.ReplaceOutputLayer(3, nil); err != nil {
+		t.Fatalf("ReplaceOutputLayer failed: %v", err)
+	}
+	out, err := n.ForwardTimeout(data, time.Second)
+	if err != nil {
+		t.Fatalf("Forward after replacing the output layer failed: %v", err)
+	}
+	if len(out) != 3 {
+		t.Errorf("Got %d outputs; expected 3", len(out))
+	}
+	n.MustBackward([]float64{1.0, 0.5, -1.0})
+	if err := n.Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package neuron
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// A RandSource is a rand.Source64 whose state can be captured and restored
+// exactly, e.g. to save in a checkpoint alongside the weights so that resuming
+// training reproduces the same weight init, noise, and data shuffling. It's
+// safe for concurrent use.
+type RandSource struct {
+	mu    sync.Mutex
+	seed  int64
+	draws uint64
+	src   rand.Source64
+}
+
+// A RandState is a serializable snapshot of a RandSource.
+type RandState struct {
+	Seed  int64
+	Draws uint64
+}
+
+// NewRandSource creates a new RandSource with the given seed.
+func NewRandSource(seed int64) *RandSource {
+	return &RandSource{
+		seed: seed,
+		src:  rand.NewSource(seed).(rand.Source64),
+	}
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer.
+func (s *RandSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.draws++
+	return s.src.Int63()
+}
+
+// Uint64 returns a pseudo-random 64-bit integer.
+func (s *RandSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.draws++
+	return s.src.Uint64()
+}
+
+// Seed reseeds the source.
+func (s *RandSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seed = seed
+	s.draws = 0
+	s.src.Seed(seed)
+}
+
+// State returns a snapshot of the source's state.
+func (s *RandSource) State() RandState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return RandState{Seed: s.seed, Draws: s.draws}
+}
+
+// Restore restores the source to a snapshot by reseeding and replaying the
+// recorded number of draws.
+func (s *RandSource) Restore(st RandState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seed = st.Seed
+	s.src.Seed(st.Seed)
+	// Every draw advances the underlying generator by exactly one step.
+	for s.draws = 0; s.draws < st.Draws; s.draws++ {
+		s.src.Uint64()
+	}
+}
+
+// WithRand draws the network's random numbers from rng instead of the global
+// math/rand source, so that networks built concurrently are reproducible. rng
+// draws the initial weights and sparse connections, and seeds a separate
+// source for each unit's activation, weight, Bayesian, and DPSGD noise, so
+// that units running concurrently don't share a source. rng mustn't be used
+// concurrently elsewhere while the network is built or modified.
+func WithRand(rng *rand.Rand) Option {
+	return func(c *netConfig) {
+		c.rng, c.src = rng, nil
+	}
+}
+
+// WithSeed is like WithRand with a new RandSource seeded with seed. Each
+// network built with the option gets its own source, whose state is saved
+// by a Checkpoint along with the units' sources.
+func WithSeed(seed int64) Option {
+	return func(c *netConfig) {
+		c.src = NewRandSource(seed)
+		c.rng = rand.New(c.src)
+	}
+}
+
+// rand returns the network's random source, or the global source if it has
+// none.
+func (n *Net) rand() *rand.Rand {
+	if n.rng == nil {
+		return globalRand
+	}
+	return n.rng
+}
+
+// A randOptimizer is an Optimizer that draws random numbers, e.g. DPSGD.
+type randOptimizer interface {
+	setRand(rng *rand.Rand)
+}
+
+// seedUnit gives unit u its own random source seeded from the network's, if
+// the network has one. Otherwise u keeps using the global source.
+func (n *Net) seedUnit(u *Unit) {
+	if n.rng == nil {
+		return
+	}
+	u.W.src = NewRandSource(n.rng.Int63())
+	u.W.rng = rand.New(u.W.src)
+	if ro, ok := unwrapOpt(u.opt).(randOptimizer); ok {
+		ro.setRand(u.W.rng)
+	}
+}
+
+// randState returns the state of the network's random source, if it was
+// created by WithSeed, and of each unit's source.
+func (n *Net) randState() (*RandState, map[string]RandState) {
+	var net *RandState
+	if n.src != nil {
+		st := n.src.State()
+		net = &st
+	}
+	var units map[string]RandState
+	for _, l := range n.Layers {
+		for _, u := range l {
+			if u.W.src == nil {
+				continue
+			}
+			if units == nil {
+				units = make(map[string]RandState)
+			}
+			units[u.ID] = u.W.src.State()
+		}
+	}
+	return net, units
+}
+
+// setRandState restores the states returned by randState. Sources the
+// network doesn't have are skipped.
+func (n *Net) setRandState(net *RandState, units map[string]RandState) {
+	if n.src != nil && net != nil {
+		n.src.Restore(*net)
+	}
+	for _, l := range n.Layers {
+		for _, u := range l {
+			if st, ok := units[u.ID]; ok && u.W.src != nil {
+				u.W.src.Restore(st)
+			}
+		}
+	}
+}
+
+// normFloat64 draws a standard normal sample from the unit's random source.
+func (w *Weight) normFloat64() float64 {
+	if w.rng == nil {
+		return rand.NormFloat64()
+	}
+	return w.rng.NormFloat64()
+}
+
+// stepKeys returns the parameter IDs in the order the optimizer steps them.
+// Seeded units step in sorted order so that optimizer noise is reproducible.
+func (w *Weight) stepKeys() []string {
+	keys := make([]string, 0, len(w.Params))
+	for k := range w.Params {
+		keys = append(keys, k)
+	}
+	if w.rng != nil {
+		sort.Strings(keys)
+	}
+	return keys
+}
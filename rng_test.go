@@ -0,0 +1,71 @@
+package neuron
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Test capturing and restoring random state.
+func TestRandSource(t *testing.T) {
+	src := NewRandSource(12)
+	r := rand.New(src)
+	r.NormFloat64()
+	r.Intn(10)
+	r.Perm(5)
+
+	st := src.State()
+	want := []float64{r.Float64(), r.NormFloat64(), float64(r.Intn(100))}
+
+	// Restore into a fresh source.
+	src2 := NewRandSource(0)
+	src2.Restore(st)
+	r2 := rand.New(src2)
+	got := []float64{r2.Float64(), r2.NormFloat64(), float64(r2.Intn(100))}
+	for ii := range want {
+		if got[ii] != want[ii] {
+			t.Errorf("Restored draw %d is %v; expected %v", ii, got[ii], want[ii])
+		}
+	}
+	if src2.State() != src.State() {
+		t.Errorf("Restored state %+v; expected %+v", src2.State(), src.State())
+	}
+}
+
+// Test that networks with the same seed built concurrently are identical,
+// including their noise.
+func TestWithSeed(t *testing.T) {
+	arch := []int{3, 6, 2}
+	opts := []Option{WithSeed(5), WithConnectionProb(0.5), WithActivationNoise(1, 0.5),
+		WithWeightNoise(2, 0.5)}
+	var nets [2]*Net
+	var wg sync.WaitGroup
+	for ii := range nets {
+		wg.Add(1)
+		go func(ii int) {
+			defer wg.Done()
+			nets[ii] = MustNewMLP(arch, NewDPSGD(0.1, 1.0, 1.0), opts...)
+		}(ii)
+	}
+	wg.Wait()
+	if !reflect.DeepEqual(nets[0].Data(), nets[1].Data()) {
+		t.Fatalf("Weights with the same seed differ")
+	}
+
+	data := []float64{1.0, -1.0, 0.5}
+	for _, n := range nets {
+		n.Start(true, 1)
+		defer n.Stop()
+		n.MustForward(data)
+		n.MustBackward([]float64{0.0, 0.0})
+	}
+	if !reflect.DeepEqual(nets[0].Data(), nets[1].Data()) {
+		t.Errorf("Noisy updates with the same seed differ")
+	}
+
+	n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), WithSeed(6))
+	if reflect.DeepEqual(nets[0].Data(), n.Data()) {
+		t.Errorf("Weights with different seeds are the same")
+	}
+}
@@ -0,0 +1,127 @@
+package neuron
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// netVersion is the version of the format written by Net.Save.
+const netVersion = 1
+
+// netState is the serializable state of a network, see Net.Save.
+type netState struct {
+	Version int
+	Arch    []int
+	Params  ParamVector
+	Mask    Mask
+	// Only saved for units with a StatefulOptimizer.
+	Optimizer OptimizerState `json:",omitempty"`
+	Scaler    *Scaler        `json:",omitempty"`
+}
+
+// state captures the network's state.
+func (n *Net) state() *netState {
+	return &netState{
+		Version:   netVersion,
+		Arch:      n.Arch,
+		Params:    n.Data(),
+		Mask:      n.GetMask(),
+		Optimizer: n.OptimizerState(),
+		Scaler:    n.Scaler,
+	}
+}
+
+// Save writes the network's architecture, trainable parameters (weights,
+// biases, and activation parameters), connection mask, optimizer state, and
+// input Scaler in gob format, to be read back with LoadNet. Parameters are
+// saved at full precision, so a loaded network computes exactly the same
+// outputs. Save should only be called while the network is idle, e.g. after
+// Backward returns.
+func (n *Net) Save(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(n.state())
+}
+
+// SaveJSON is like Save but writes JSON, which is slower and larger but
+// portable, e.g. to inspect or convert a trained network with other tools.
+func (n *Net) SaveJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(n.state())
+}
+
+// LoadNet reads a network written by Net.Save. Unit kinds, activations, and
+// the other options that determine the network's structure aren't saved, so
+// the network is constructed with NewMLP(arch, opt, opts...) using the saved
+// architecture, and opts should match the options of the saved network. It
+// returns an error if the saved parameters don't match the new network. The
+// optimizer state is restored for units whose optimizer is a
+// StatefulOptimizer.
+func LoadNet(r io.Reader, opt Optimizer, opts ...Option) (*Net, error) {
+	var s netState
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	return s.restore(opt, opts)
+}
+
+// LoadNetJSON is like LoadNet for networks written by Net.SaveJSON.
+func LoadNetJSON(r io.Reader, opt Optimizer, opts ...Option) (*Net, error) {
+	var s netState
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	return s.restore(opt, opts)
+}
+
+// restore constructs a network from a saved state.
+func (s *netState) restore(opt Optimizer, opts []Option) (*Net, error) {
+	if s.Version != netVersion {
+		return nil, fmt.Errorf("unsupported network file version %d", s.Version)
+	}
+	n, err := NewMLP(s.Arch, opt, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.setState(s); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// setState restores a saved state into a network with the same architecture.
+func (n *Net) setState(s *netState) error {
+	if len(s.Arch) != len(n.Arch) {
+		return fmt.Errorf("saved network has %d layers; got %d", len(s.Arch),
+			len(n.Arch))
+	}
+	for ii, sz := range s.Arch {
+		if sz != n.Arch[ii] {
+			return fmt.Errorf("saved network layer %d has %d units; got %d", ii,
+				sz, n.Arch[ii])
+		}
+	}
+
+	params := n.Data()
+	for uid, ud := range s.Params {
+		for id := range ud {
+			if _, ok := params[uid][id]; !ok {
+				return fmt.Errorf("saved parameter %s/%s not in network", uid, id)
+			}
+		}
+	}
+	n.SetData(s.Params)
+	n.SetMask(s.Mask)
+	if s.Scaler != nil {
+		n.Scaler = s.Scaler
+	}
+
+	state := make(OptimizerState)
+	for _, l := range n.Layers {
+		for _, u := range l {
+			if _, ok := u.opt.(StatefulOptimizer); ok && s.Optimizer[u.ID] != nil {
+				state[u.ID] = s.Optimizer[u.ID]
+			}
+		}
+	}
+	return n.SetOptimizerState(state)
+}
@@ -0,0 +1,83 @@
+package neuron
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// Test that saved networks round trip in both formats.
+func TestSaveNet(t *testing.T) {
+	rand.Seed(12)
+	arch := []int{3, 4, 2}
+	n := MustNewMLP(arch, NewSGD(0.1, 0.9, 0.0))
+	n.Start(true, 1)
+	data := []float64{1.0, -0.5, 0.25}
+	for ii := 0; ii < 3; ii++ {
+		n.MustForward(data)
+		n.MustBackward([]float64{1.0, -1.0})
+	}
+	n.Stop()
+	n.SetMask(Mask{"001_000000": {"000_000001": false}})
+
+	formats := []struct {
+		name string
+		save func(w io.Writer) error
+		load func(r io.Reader, opt Optimizer, opts ...Option) (*Net, error)
+	}{{"gob", n.Save, LoadNet}, {"JSON", n.SaveJSON, LoadNetJSON}}
+	for _, f := range formats {
+		var buf bytes.Buffer
+		if err := f.save(&buf); err != nil {
+			t.Fatalf("Saving %s failed: %v", f.name, err)
+		}
+		loaded, err := f.load(&buf, NewSGD(0.1, 0.9, 0.0))
+		if err != nil {
+			t.Fatalf("Loading %s failed: %v", f.name, err)
+		}
+
+		theta, thetaL := n.Data(), loaded.Data()
+		for uid, ud := range theta {
+			for id, v := range ud {
+				if thetaL[uid][id] != v {
+					t.Errorf("%s param %s/%s is %.6f; expected %.6f", f.name, uid, id,
+						thetaL[uid][id], v)
+				}
+			}
+		}
+		n.Start(false, 1)
+		loaded.Start(false, 1)
+		want, got := n.MustForward(data), loaded.MustForward(data)
+		n.Stop()
+		loaded.Stop()
+		for ii := range want {
+			if !almostEqual(got[ii], want[ii]) {
+				t.Errorf("%s output %d is %.6f; expected %.6f", f.name, ii, got[ii],
+					want[ii])
+			}
+		}
+		if m := loaded.GetMask(); m["001_000000"]["000_000001"] {
+			t.Errorf("%s mask was not restored", f.name)
+		}
+		state, stateL := n.OptimizerState(), loaded.OptimizerState()
+		for uid, us := range state {
+			for id, v := range us {
+				if stateL[uid][id][0] != v[0] {
+					t.Errorf("%s optimizer state %s/%s is %.6f; expected %.6f", f.name,
+						uid, id, stateL[uid][id][0], v[0])
+				}
+			}
+		}
+	}
+
+	// Parameters that don't exist in the new network.
+	nw := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), WithWeightNorm())
+	var buf bytes.Buffer
+	nw.Save(&buf)
+	if _, err := LoadNet(&buf, NewSGD(0.1, 0.0, 0.0)); err == nil {
+		t.Errorf("LoadNet did not return an error")
+	}
+	if _, err := LoadNetJSON(bytes.NewBufferString("{}"), NewSGD(0.1, 0.0, 0.0)); err == nil {
+		t.Errorf("LoadNetJSON did not return an error")
+	}
+}
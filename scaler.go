@@ -0,0 +1,167 @@
+package neuron
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Scaler kinds.
+const (
+	// Scale each feature to [0, 1] by its min and max.
+	MinMaxScaling = "minmax"
+	// Standardize each feature to zero mean and unit variance (z-scores).
+	ZScoreScaling = "zscore"
+)
+
+// A Scaler scales each input feature by statistics fit to the training data,
+//
+//	x' = (x - Offset) / Scale
+//
+// where Offset and Scale are the min and range, or the mean and standard
+// deviation. Features are scaled independently, and constant features are
+// only shifted. Scaling matters, since the small initial weights make
+// networks with inputs far from unit scale train very slowly, if at all.
+//
+// A network's Scaler field is saved with it by Save, so a loaded network
+// comes with the scaling of its training data. The network doesn't apply it
+// though: inputs need to be scaled with Transform before passing them, or
+// datasets wrapped with Dataset.
+type Scaler struct {
+	// MinMaxScaling or ZScoreScaling.
+	Kind string
+	// Per-feature statistics, set by Fit.
+	Offset []float64
+	Scale  []float64
+}
+
+// NewMinMaxScaler creates a scaler to [0, 1].
+func NewMinMaxScaler() *Scaler {
+	return &Scaler{Kind: MinMaxScaling}
+}
+
+// NewZScoreScaler creates a scaler to zero mean and unit variance.
+func NewZScoreScaler() *Scaler {
+	return &Scaler{Kind: ZScoreScaling}
+}
+
+// Fit computes the per-feature statistics of samples xs.
+func (s *Scaler) Fit(xs [][]float64) error {
+	if len(xs) == 0 {
+		return errors.New("scaler needs at least one sample")
+	}
+	dim := len(xs[0])
+	for ii, x := range xs {
+		if len(x) != dim {
+			return fmt.Errorf("sample %d has %d features; expected %d", ii, len(x), dim)
+		}
+	}
+	offset, scale := make([]float64, dim), make([]float64, dim)
+	switch s.Kind {
+	case MinMaxScaling:
+		for jj := range offset {
+			min, max := math.Inf(1), math.Inf(-1)
+			for _, x := range xs {
+				min, max = math.Min(min, x[jj]), math.Max(max, x[jj])
+			}
+			offset[jj], scale[jj] = min, max-min
+		}
+	case ZScoreScaling:
+		for jj := range offset {
+			mean, sq := 0.0, 0.0
+			for _, x := range xs {
+				mean += x[jj]
+			}
+			mean /= float64(len(xs))
+			for _, x := range xs {
+				sq += (x[jj] - mean) * (x[jj] - mean)
+			}
+			offset[jj], scale[jj] = mean, math.Sqrt(sq/float64(len(xs)))
+		}
+	default:
+		return fmt.Errorf("unknown scaler kind %q", s.Kind)
+	}
+	for jj, v := range scale {
+		if v == 0.0 {
+			scale[jj] = 1.0
+		}
+	}
+	s.Offset, s.Scale = offset, scale
+	return nil
+}
+
+// FitDataset computes the per-feature statistics of the inputs of a dataset.
+func (s *Scaler) FitDataset(ds Dataset) error {
+	xs := make([][]float64, ds.Len())
+	for ii := range xs {
+		xs[ii], _ = ds.Get(ii)
+	}
+	return s.Fit(xs)
+}
+
+// Transform returns the scaled copy of x.
+func (s *Scaler) Transform(x []float64) ([]float64, error) {
+	if err := s.check(x); err != nil {
+		return nil, err
+	}
+	return s.transform(x), nil
+}
+
+// transform returns the scaled copy of x, which must have been checked.
+func (s *Scaler) transform(x []float64) []float64 {
+	out := make([]float64, len(x))
+	for ii, v := range x {
+		out[ii] = (v - s.Offset[ii]) / s.Scale[ii]
+	}
+	return out
+}
+
+// InverseTransform returns the unscaled copy of x, undoing Transform.
+func (s *Scaler) InverseTransform(x []float64) ([]float64, error) {
+	if err := s.check(x); err != nil {
+		return nil, err
+	}
+	out := make([]float64, len(x))
+	for ii, v := range x {
+		out[ii] = v*s.Scale[ii] + s.Offset[ii]
+	}
+	return out, nil
+}
+
+// check checks that the scaler is fit to samples the size of x.
+func (s *Scaler) check(x []float64) error {
+	if s.Offset == nil {
+		return errors.New("scaler isn't fit")
+	}
+	if len(x) != len(s.Offset) {
+		return fmt.Errorf("scaler expected %d features; got %d", len(s.Offset), len(x))
+	}
+	return nil
+}
+
+// Dataset wraps ds to scale its inputs on the fly. It returns an error unless
+// the scaler is fit to samples the size of every input of ds.
+func (s *Scaler) Dataset(ds Dataset) (Dataset, error) {
+	for ii := 0; ii < ds.Len(); ii++ {
+		x, _ := ds.Get(ii)
+		if err := s.check(x); err != nil {
+			return nil, fmt.Errorf("sample %d: %v", ii, err)
+		}
+	}
+	return &scaledDataset{ds: ds, s: s}, nil
+}
+
+// scaledDataset scales the inputs of a dataset, see Scaler.Dataset.
+type scaledDataset struct {
+	ds Dataset
+	s  *Scaler
+}
+
+func (d *scaledDataset) Len() int {
+	return d.ds.Len()
+}
+
+func (d *scaledDataset) Get(i int) (x, y []float64) {
+	x, y = d.ds.Get(i)
+	return d.s.transform(x), y
+}
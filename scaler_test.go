@@ -0,0 +1,81 @@
+package neuron
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/clane9/go-neuron/data"
+)
+
+// Test min-max and z-score scaling.
+func TestScaler(t *testing.T) {
+	xs := [][]float64{{1.0, 10.0, 5.0}, {3.0, 20.0, 5.0}, {5.0, 60.0, 5.0}}
+	s := NewMinMaxScaler()
+	if err := s.Fit(xs); err != nil {
+		t.Fatalf("Fit returned an error: %v", err)
+	}
+	got, _ := s.Transform(xs[1])
+	if want := []float64{0.5, 0.2, 0.0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Min-max scaled %v to %v; expected %v", xs[1], got, want)
+	}
+	inv, _ := s.InverseTransform(got)
+	if !reflect.DeepEqual(inv, xs[1]) {
+		t.Errorf("Inverse of %v is %v; expected %v", got, inv, xs[1])
+	}
+
+	s = NewZScoreScaler()
+	ds := &data.VectorDataset{Inputs: xs, Targets: [][]float64{{0}, {1}, {2}}}
+	if err := s.FitDataset(ds); err != nil {
+		t.Fatalf("FitDataset returned an error: %v", err)
+	}
+	scaled, err := s.Dataset(ds)
+	if err != nil {
+		t.Fatalf("Dataset returned an error: %v", err)
+	}
+	mean, sq := 0.0, 0.0
+	for ii := 0; ii < scaled.Len(); ii++ {
+		x, _ := scaled.Get(ii)
+		mean += x[0] / 3.0
+		sq += x[0] * x[0] / 3.0
+		if x[2] != 0.0 {
+			t.Errorf("Constant feature scaled to %.3f; expected 0", x[2])
+		}
+	}
+	if math.Abs(mean) > 1.0e-12 || !almostEqual(math.Sqrt(sq), 1.0) {
+		t.Errorf("Z-scores have mean %.3f and std %.3f; expected 0 and 1", mean, math.Sqrt(sq))
+	}
+
+	if _, err := s.Transform([]float64{1.0}); err == nil {
+		t.Errorf("Expected an error for the wrong number of features")
+	}
+	ragged := &data.VectorDataset{Inputs: [][]float64{xs[0], {1.0}}, Targets: [][]float64{{0}, {1}}}
+	if _, err := s.Dataset(ragged); err == nil {
+		t.Errorf("Expected an error for a dataset with the wrong number of features")
+	}
+	if err := s.Fit([][]float64{{1.0}, {1.0, 2.0}}); err == nil {
+		t.Errorf("Expected an error for ragged samples")
+	}
+	if _, err := NewMinMaxScaler().Transform(xs[0]); err == nil {
+		t.Errorf("Expected an error for an unfit scaler")
+	}
+}
+
+// Test that a network's scaler is saved with it.
+func TestSaveScaler(t *testing.T) {
+	n := MustNewMLP([]int{3, 2, 1}, NewSGD(0.1, 0.0, 0.0))
+	n.Scaler = NewZScoreScaler()
+	n.Scaler.Fit([][]float64{{1.0, 2.0, 3.0}, {3.0, 2.0, 1.0}})
+	var buf bytes.Buffer
+	if err := n.SaveJSON(&buf); err != nil {
+		t.Fatalf("Saving failed: %v", err)
+	}
+	loaded, err := LoadNetJSON(&buf, NewSGD(0.1, 0.0, 0.0))
+	if err != nil {
+		t.Fatalf("Loading failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Scaler, n.Scaler) {
+		t.Errorf("Loaded scaler %+v; expected %+v", loaded.Scaler, n.Scaler)
+	}
+}
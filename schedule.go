@@ -0,0 +1,259 @@
+package neuron
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// An LrOptimizer is an Optimizer with an adjustable learning rate.
+type LrOptimizer interface {
+	Optimizer
+	LearningRate() float64
+	SetLearningRate(lr float64)
+}
+
+// LearningRate returns the SGD learning rate.
+func (opt *SGD) LearningRate() float64 { return opt.Lr }
+
+// SetLearningRate sets the SGD learning rate.
+func (opt *SGD) SetLearningRate(lr float64) { opt.Lr = lr }
+
+// LearningRate returns the RMSProp learning rate.
+func (opt *RMSProp) LearningRate() float64 { return opt.Lr }
+
+// SetLearningRate sets the RMSProp learning rate.
+func (opt *RMSProp) SetLearningRate(lr float64) { opt.Lr = lr }
+
+// LearningRate returns the Adagrad learning rate.
+func (opt *Adagrad) LearningRate() float64 { return opt.Lr }
+
+// SetLearningRate sets the Adagrad learning rate.
+func (opt *Adagrad) SetLearningRate(lr float64) { opt.Lr = lr }
+
+// LearningRate returns the DPSGD learning rate.
+func (opt *DPSGD) LearningRate() float64 { return opt.Lr }
+
+// SetLearningRate sets the DPSGD learning rate.
+func (opt *DPSGD) SetLearningRate(lr float64) { opt.Lr = lr }
+
+// A Scheduler computes the learning rate at epoch t of a schedule, given the
+// base learning rate.
+type Scheduler interface {
+	LR(base float64, t int) float64
+}
+
+// StepLR decays the learning rate by Gamma every StepSize epochs. A StepSize
+// below 1, e.g. of the zero value, never decays.
+type StepLR struct {
+	StepSize int
+	Gamma    float64
+}
+
+// LR computes the learning rate at epoch t.
+func (s StepLR) LR(base float64, t int) float64 {
+	if s.StepSize < 1 {
+		return base
+	}
+	return base * math.Pow(s.Gamma, float64(t/s.StepSize))
+}
+
+// ExponentialLR decays the learning rate by Gamma every epoch.
+type ExponentialLR struct {
+	Gamma float64
+}
+
+// LR computes the learning rate at epoch t.
+func (s ExponentialLR) LR(base float64, t int) float64 {
+	return base * math.Pow(s.Gamma, float64(t))
+}
+
+// CosineAnnealing anneals the learning rate from its base value down to MinLr
+// over TMax epochs, following half a cosine period. It stays at MinLr after.
+type CosineAnnealing struct {
+	TMax  int
+	MinLr float64
+}
+
+// LR computes the learning rate at epoch t.
+func (s CosineAnnealing) LR(base float64, t int) float64 {
+	if t >= s.TMax {
+		return s.MinLr
+	}
+	frac := float64(t) / float64(s.TMax)
+	return s.MinLr + 0.5*(base-s.MinLr)*(1.0+math.Cos(math.Pi*frac))
+}
+
+// schedState is the update counter shared by all clones of a Scheduled
+// optimizer.
+type schedState struct {
+	updates int64
+}
+
+// Scheduled wraps an optimizer to adjust its learning rate following a
+// schedule. The schedule advances one epoch every Every weight updates.
+//
+// Each unit gets its own clone of the optimizer, but all clones share one
+// update counter, which is advanced by the Net that the optimizer was passed
+// to. They therefore all follow the same schedule.
+type Scheduled struct {
+	Opt   LrOptimizer
+	Sched Scheduler
+	Every int
+	base  float64
+	state *schedState
+}
+
+// NewScheduled creates a new scheduled optimizer. The base learning rate is
+// the current learning rate of opt.
+func NewScheduled(opt LrOptimizer, sched Scheduler, every int) *Scheduled {
+	if every < 1 {
+		every = 1
+	}
+	return &Scheduled{
+		Opt:   opt,
+		Sched: sched,
+		Every: every,
+		base:  opt.LearningRate(),
+		state: new(schedState),
+	}
+}
+
+// Step sets the scheduled learning rate and takes a step with the wrapped
+// optimizer.
+func (opt *Scheduled) Step(id string, p *Param) {
+	opt.Opt.SetLearningRate(opt.LearningRate())
+	opt.Opt.Step(id, p)
+}
+
+// New initializes a new scheduled optimizer with the same parameters, sharing
+// the same update counter.
+func (opt *Scheduled) New() Optimizer {
+	return &Scheduled{
+		Opt:   opt.Opt.New().(LrOptimizer),
+		Sched: opt.Sched,
+		Every: opt.Every,
+		base:  opt.base,
+		state: opt.state,
+	}
+}
+
+// fresh returns a clone of the scheduled optimizer with its own update
+// counter, starting from the current count, e.g. for another network.
+func (opt *Scheduled) fresh() *Scheduled {
+	s := opt.New().(*Scheduled)
+	s.state = &schedState{updates: atomic.LoadInt64(&opt.state.updates)}
+	return s
+}
+
+// LearningRate returns the current scheduled learning rate.
+func (opt *Scheduled) LearningRate() float64 {
+	t := int(atomic.LoadInt64(&opt.state.updates)) / opt.Every
+	return opt.Sched.LR(opt.base, t)
+}
+
+// SetLearningRate sets the base learning rate of the schedule.
+func (opt *Scheduled) SetLearningRate(lr float64) {
+	opt.base = lr
+}
+
+// Updates returns the number of weight updates counted so far.
+func (opt *Scheduled) Updates() int {
+	return int(atomic.LoadInt64(&opt.state.updates))
+}
+
+// unwrapOpt returns the optimizer wrapped by opt if it's Scheduled, or else
+// opt, so that the wrapped optimizer's per-sample clipping and random source
+// still apply, see sampleClipper and randOptimizer.
+func unwrapOpt(opt Optimizer) Optimizer {
+	for {
+		s, ok := opt.(*Scheduled)
+		if !ok {
+			return opt
+		}
+		opt = s.Opt
+	}
+}
+
+// baseLR returns the learning rate of opt, or the base rate of its schedule.
+func baseLR(opt LrOptimizer) float64 {
+	if s, ok := opt.(*Scheduled); ok {
+		return s.base
+	}
+	return opt.LearningRate()
+}
+
+// schedStates returns the distinct update counters of the units' scheduled
+// optimizers.
+func schedStates(layers [][]*Unit) []*schedState {
+	var states []*schedState
+	seen := make(map[*schedState]bool)
+	for _, l := range layers {
+		for _, u := range l {
+			if s, ok := u.opt.(*Scheduled); ok && !seen[s.state] {
+				seen[s.state] = true
+				states = append(states, s.state)
+			}
+		}
+	}
+	return states
+}
+
+// advance counts a weight update.
+func (s *schedState) advance() {
+	atomic.AddInt64(&s.updates, 1)
+}
+
+// SetLR sets the learning rate of the units' optimizers, scaled by any
+// WithLayerLRScale. It's safe to call at any time, including from another
+// goroutine while the network is running: the new rate takes effect from the
+// next forward pass, when all units are idle. For Scheduled optimizers SetLR
+// sets the base learning rate of the schedule. Layers with their own
+// optimizer, see WithLayerOptimizer, and units whose optimizer isn't an
+// LrOptimizer are unchanged. The optimizer passed to NewMLP is left as is, so
+// networks built from it later start from its original rate.
+func (n *Net) SetLR(lr float64) {
+	n.lrMu.Lock()
+	defer n.lrMu.Unlock()
+	if opt, ok := n.lrOpt.(LrOptimizer); ok {
+		opt.SetLearningRate(lr)
+	}
+	n.lr = lr
+	n.lrPending = true
+}
+
+// GetLR returns the current learning rate of the network's optimizer, or 0 if
+// it isn't an LrOptimizer. Layers with their own optimizer, see
+// WithLayerOptimizer, and learning rate scales aren't taken into account.
+func (n *Net) GetLR() float64 {
+	n.lrMu.Lock()
+	defer n.lrMu.Unlock()
+	if opt, ok := n.lrOpt.(LrOptimizer); ok {
+		return opt.LearningRate()
+	}
+	return 0.0
+}
+
+// applyLR propagates a learning rate set with SetLR to the units. It must be
+// called while the units are idle.
+func (n *Net) applyLR() {
+	n.lrMu.Lock()
+	defer n.lrMu.Unlock()
+	if !n.lrPending {
+		return
+	}
+	n.lrPending = false
+	for ii, l := range n.Layers {
+		if n.ownOpts[ii] {
+			continue
+		}
+		scale, ok := n.lrScales[ii]
+		if !ok {
+			scale = 1.0
+		}
+		for _, u := range l {
+			if opt, ok := u.opt.(LrOptimizer); ok {
+				opt.SetLearningRate(scale * n.lr)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package neuron
+
+import (
+	"math"
+	"testing"
+)
+
+// Test learning rate schedules.
+func TestSchedulers(t *testing.T) {
+	step := StepLR{StepSize: 2, Gamma: 0.5}
+	for ii, want := range []float64{1.0, 1.0, 0.5, 0.5, 0.25} {
+		if lr := step.LR(1.0, ii); !almostEqual(lr, want) {
+			t.Errorf("StepLR at %d is %.4f; expected %.4f", ii, lr, want)
+		}
+	}
+	if lr := (StepLR{}).LR(1.0, 5); lr != 1.0 {
+		t.Errorf("Zero StepLR at 5 is %.4f; expected 1", lr)
+	}
+
+	exp := ExponentialLR{Gamma: 0.9}
+	if lr := exp.LR(2.0, 3); !almostEqual(lr, 2.0*math.Pow(0.9, 3)) {
+		t.Errorf("ExponentialLR is %.4f", lr)
+	}
+
+	cos := CosineAnnealing{TMax: 10, MinLr: 0.1}
+	if lr := cos.LR(1.0, 0); !almostEqual(lr, 1.0) {
+		t.Errorf("CosineAnnealing start is %.4f; expected 1", lr)
+	}
+	if lr := cos.LR(1.0, 5); !almostEqual(lr, 0.55) {
+		t.Errorf("CosineAnnealing midpoint is %.4f; expected 0.55", lr)
+	}
+	if lr := cos.LR(1.0, 20); !almostEqual(lr, 0.1) {
+		t.Errorf("CosineAnnealing end is %.4f; expected 0.1", lr)
+	}
+}
+
+// Test that all unit clones of a scheduled optimizer follow the schedule.
+func TestScheduled(t *testing.T) {
+	arch := []int{2, 3, 1}
+	opt := NewScheduled(NewSGD(1.0, 0.0, 0.0), StepLR{StepSize: 1, Gamma: 0.5}, 2)
+	n := MustNewMLP(arch, opt)
+	n.Start(true, 2)
+
+	for ii := 0; ii < 8; ii++ {
+		n.MustForward([]float64{1.0, -1.0})
+		n.MustBackward([]float64{0.0})
+	}
+	// 4 updates, so 2 epochs.
+	if u := opt.Updates(); u != 4 {
+		t.Errorf("Got %d updates; expected 4", u)
+	}
+	for _, l := range n.Layers {
+		for _, u := range l {
+			if lr := u.opt.(*Scheduled).LearningRate(); !almostEqual(lr, 0.25) {
+				t.Errorf("Unit %s learning rate is %.4f; expected 0.25", u.ID, lr)
+			}
+		}
+	}
+}
+
+// Test that a scheduled DPSGD still clips each sample's gradient and draws
+// its noise from the unit's random source.
+func TestScheduledDPSGD(t *testing.T) {
+	const clip = 1.0e-03
+	opt := NewScheduled(NewDPSGD(0.1, clip, 0.0), StepLR{StepSize: 1, Gamma: 0.5}, 1)
+	n := MustNewMLP([]int{2, 3, 1}, opt, WithSeed(5))
+	n.Start(true, 0)
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{100.0})
+	n.Stop()
+	for _, l := range n.Layers {
+		for _, u := range l {
+			norm := 0.0
+			for _, p := range u.W.Params {
+				norm += p.grad * p.grad
+			}
+			if norm = math.Sqrt(norm); norm > clip*(1+1.0e-09) {
+				t.Errorf("Unit %s gradient norm is %g; expected <= %g", u.ID, norm, clip)
+			}
+			if u.opt.(*Scheduled).Opt.(*DPSGD).rng == nil {
+				t.Errorf("Unit %s DPSGD doesn't use the unit's random source", u.ID)
+			}
+		}
+	}
+}
+
+// Test setting the learning rate of a running network.
+func TestSetLR(t *testing.T) {
+	arch := []int{2, 3, 1}
+	proto := NewSGD(1.0, 0.0, 0.0)
+	n := MustNewMLP(arch, proto)
+	n.Start(true, 1)
+	if lr := n.GetLR(); lr != 1.0 {
+		t.Errorf("Learning rate is %.4f; expected 1", lr)
+	}
+
+	// Set from another goroutine while the network is running.
+	done := make(chan struct{})
+	go func() {
+		n.SetLR(0.1)
+		close(done)
+	}()
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{1.0})
+	<-done
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{1.0})
+
+	if lr := n.GetLR(); lr != 0.1 {
+		t.Errorf("Learning rate is %.4f; expected 0.1", lr)
+	}
+	for _, l := range n.Layers {
+		for _, u := range l {
+			if lr := u.opt.(*SGD).Lr; lr != 0.1 {
+				t.Errorf("Unit %s learning rate is %.4f; expected 0.1", u.ID, lr)
+			}
+		}
+	}
+	// The prototype optimizer, and twins built from it, keep their rate.
+	n.Stop()
+	if proto.Lr != 1.0 {
+		t.Errorf("Prototype learning rate is %.4f; expected 1", proto.Lr)
+	}
+	tw, err := NewTwin(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lr := tw.GetLR(); lr != 1.0 {
+		t.Errorf("Twin learning rate is %.4f; expected 1", lr)
+	}
+
+	// Scheduled optimizers report the scheduled rate.
+	opt := NewScheduled(NewSGD(1.0, 0.0, 0.0), ExponentialLR{Gamma: 0.5}, 1)
+	n = MustNewMLP(arch, opt)
+	n.Start(true, 1)
+	n.SetLR(2.0)
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{1.0})
+	if lr := n.GetLR(); lr != 1.0 {
+		t.Errorf("Scheduled learning rate is %.4f; expected 1", lr)
+	}
+}
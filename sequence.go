@@ -0,0 +1,271 @@
+package neuron
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A stepState records the state of a unit at one forward step of a sequence,
+// so that it can be restored for the matching backward step.
+type stepState struct {
+	values map[string]float64
+	eps    map[string]float64
+	pre    float64
+	act    float64
+	cell   cellStep
+}
+
+// record saves the unit's state after a forward step.
+func (u *Unit) record() {
+	st := stepState{
+		values: make(map[string]float64, len(u.W.Params)),
+		pre:    u.pre,
+		act:    u.act,
+	}
+	for k, p := range u.W.Params {
+		st.values[k] = p.value
+	}
+	if u.W.bayes {
+		st.eps = make(map[string]float64, len(u.W.eps))
+		for k, v := range u.W.eps {
+			st.eps[k] = v
+		}
+	}
+	if u.cell != nil {
+		st.cell = u.cell.save()
+	}
+	u.hist = append(u.hist, st)
+}
+
+// rewind restores the unit's state at the most recent recorded forward step
+// and drops it from the history.
+func (u *Unit) rewind() {
+	st := u.hist[len(u.hist)-1]
+	u.hist = u.hist[:len(u.hist)-1]
+	for k, v := range st.values {
+		u.W.Params[k].value = v
+	}
+	for k, v := range st.eps {
+		u.W.eps[k] = v
+	}
+	u.pre, u.act = st.pre, st.act
+	if u.cell != nil {
+		u.cell.cellStep = st.cell
+	}
+	// Re-run the activation to restore its cached state.
+	u.activ.Forward(st.pre)
+}
+
+// startSequence starts an endless loop for processing sequences. In training
+// mode every forward step is recorded, and every backward step
+// back-propagates through the most recently recorded step. A window of
+// forward steps followed by the same number of backward steps is therefore
+// one pass of backpropagation through time. The unit signals stepDone after
+// each backward step, and weights are updated every updateFreq windows.
+func (u *Unit) startSequence(updateFreq int) {
+	u.seq = true
+	windows := 0
+	for {
+		u.waiting(waitIdle, nil)
+		select {
+		case s := <-u.input:
+			u.waiting(waitNone, nil)
+			train := *u.train
+			u.received, u.sent = 0, false
+			u.W.ready = false
+			act := u.receive(s)
+			for ii := 1; ii < u.inputs(); ii++ {
+				act += u.receive(u.recv(u.input))
+			}
+			u.fire(act)
+			if train {
+				u.carry = 0.0
+				u.record()
+			}
+
+		case s := <-u.inputB:
+			u.waiting(waitNone, nil)
+			u.rewind()
+			u.received, u.sent = 1, false
+			grad := s.value
+			for ii := 1; ii < u.gradInputs(); ii++ {
+				grad += u.recv(u.inputB).value
+				u.received++
+			}
+			u.backprop(grad)
+			if len(u.hist) == 0 {
+				windows++
+				if updateFreq > 0 && windows%updateFreq == 0 && !u.netStep {
+					u.step()
+				}
+			}
+			u.done()
+
+		case <-u.quit:
+			return
+		}
+	}
+}
+
+// StartSequence starts running each unit's loop for processing sequences
+// instead of independent samples. Sequences are fed in one step at a time
+// with ForwardSequence, and the state of recurrent units carries over from
+// one step to the next, until ResetState is called.
+//
+// In training mode, each ForwardSequence must be followed by a
+// BackwardSequence with one gradient per step, which runs backpropagation
+// through time over the window. Weights are updated every updateFreq windows.
+// Units started with StartSequence are not supervised.
+func (n *Net) StartSequence(train bool, updateFreq int) {
+	n.seq = true
+	n.train = train
+	n.updateFreq = updateFreq
+	n.seqSteps = 0
+	n.run(func(u *Unit) {
+		logf(2, "Start sequence %s\n", u.ID)
+	}, func(u *Unit) {
+		u.startSequence(updateFreq)
+	})
+}
+
+// ForwardSequence feeds a window of sequence steps through the network,
+// returning the output at each step. The network must have been started with
+// StartSequence.
+func (n *Net) ForwardSequence(seq [][]float64) ([][]float64, error) {
+	if !n.seq {
+		return nil, errors.New("ForwardSequence needs a network started with StartSequence")
+	}
+	for _, data := range seq {
+		if err := n.checkInput(data); err != nil {
+			return nil, err
+		}
+	}
+
+	done, stop := n.watch()
+	defer stop()
+	if !n.acquire(done) {
+		return nil, n.deadlock("forward")
+	}
+	outputs := make([][]float64, len(seq))
+	for t, data := range seq {
+		var ok bool
+		outputs[t], ok = n.forward(data, done)
+		if !ok {
+			n.endForward(false)
+			return outputs[:t], n.deadlock("forward")
+		}
+	}
+	n.endForward(true)
+	n.seqSteps = len(seq)
+	return outputs, n.anomalyErr()
+}
+
+// BackwardSequence back-propagates a loss gradient for each step of the last
+// window through time, starting from the last step. Gradients are not
+// propagated past the start of the window, i.e. backpropagation through time
+// is truncated to the window. It returns an error unless there's one gradient
+// per step of the window.
+func (n *Net) BackwardSequence(grads [][]float64) error {
+	if !n.seq {
+		return errors.New("BackwardSequence needs a network started with StartSequence")
+	}
+	if len(grads) != n.seqSteps {
+		return fmt.Errorf("got %d grads for a window of %d steps", len(grads),
+			n.seqSteps)
+	}
+	for _, grad := range grads {
+		if err := n.checkGrad(grad); err != nil {
+			return err
+		}
+	}
+	if n.softmax && len(n.probs) < len(grads) {
+		return fmt.Errorf("softmax backward for %d steps after %d forward steps",
+			len(grads), len(n.probs))
+	}
+
+	logf(2, "MLP Backward sequence\n")
+
+	// Each step needs to complete before the next so that gradients from
+	// different steps don't mix.
+	for t := len(grads) - 1; t >= 0; t-- {
+		grad := grads[t]
+		if n.softmax {
+			var err error
+			if grad, err = n.backwardSoftmax(grad); err != nil {
+				return err
+			}
+		}
+		done, stop := n.watch()
+		ok := n.feedGrad(grad, 0, done) && n.syncDone(done)
+		stop()
+		if !ok {
+			return n.deadlock("backward")
+		}
+	}
+
+	n.seqSteps = 0
+	n.windows++
+	if n.updateFreq > 0 && n.windows%n.updateFreq == 0 {
+		n.updated()
+	}
+	n.endBackward()
+	return n.anomalyErr()
+}
+
+// TrainSequence runs truncated backpropagation through time over a sequence.
+// The sequence is split into windows of at most window steps. Each window is
+// fed forward, lossGrad is called to compute the loss gradient for each step
+// t of the full sequence, and the gradients are back-propagated through the
+// window. The network must have been started with StartSequence in training
+// mode. TrainSequence returns the outputs at each step.
+func (n *Net) TrainSequence(seq [][]float64, window int,
+	lossGrad func(t int, output []float64) []float64) ([][]float64, error) {
+	if !n.seq || !n.train {
+		return nil, errors.New("TrainSequence needs a network started with StartSequence in training mode")
+	}
+	if window < 1 {
+		return nil, fmt.Errorf("BPTT window needs >= 1 step; got %d", window)
+	}
+
+	outputs := make([][]float64, 0, len(seq))
+	for start := 0; start < len(seq); start += window {
+		end := start + window
+		if end > len(seq) {
+			end = len(seq)
+		}
+		out, err := n.ForwardSequence(seq[start:end])
+		// The window still needs its backward pass after an anomaly.
+		var anomaly *AnomalyError
+		if err != nil && !errors.As(err, &anomaly) {
+			return outputs, err
+		}
+		grads := make([][]float64, len(out))
+		for ii, o := range out {
+			grads[ii] = lossGrad(start+ii, o)
+		}
+		if berr := n.BackwardSequence(grads); berr != nil {
+			return outputs, berr
+		}
+		outputs = append(outputs, out...)
+		if err != nil {
+			return outputs, err
+		}
+	}
+	return outputs, nil
+}
+
+// ResetState clears the recurrent state of all units, e.g. before starting a
+// new sequence. It should only be called between windows.
+func (n *Net) ResetState() {
+	n.probs = n.probs[:0]
+	for _, l := range n.Layers {
+		for _, u := range l {
+			u.hprev = 0.0
+			u.carry = 0.0
+			u.hist = u.hist[:0]
+			if u.cell != nil {
+				u.cell.reset()
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test backpropagation through time against central differences.
+func TestSequenceGrads(t *testing.T) {
+	rand.Seed(12)
+
+	arch := []int{1, 2, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	kinds := []string{InputKind, RecurrentKind, OutputKind}
+	n := MustNewMLP(arch, opt, WithUnitKinds(kinds))
+	n.StartSequence(true, 0)
+
+	// Loss is the sum of the outputs over the sequence.
+	seq := [][]float64{{1.0}, {-0.5}, {2.0}}
+	ones := [][]float64{{1.0}, {1.0}, {1.0}}
+	zeros := [][]float64{{0.0}, {0.0}, {0.0}}
+	n.ForwardSequence(seq)
+	n.BackwardSequence(ones)
+	grads := n.Grads()
+	theta := n.Data()
+
+	const eps = 1.0e-06
+	loss := func() float64 {
+		n.ResetState()
+		out, _ := n.ForwardSequence(seq)
+		n.BackwardSequence(zeros)
+		return out[0][0] + out[1][0] + out[2][0]
+	}
+	for uid, g := range grads {
+		for id := range g {
+			v := theta[uid][id]
+			n.SetData(ParamVector{uid: {id: v + eps}})
+			lossPlus := loss()
+			n.SetData(ParamVector{uid: {id: v - eps}})
+			lossMinus := loss()
+			n.SetData(ParamVector{uid: {id: v}})
+
+			numeric := (lossPlus - lossMinus) / (2 * eps)
+			if !almostEqualTol(g[id], numeric, 1.0e-04) {
+				t.Errorf("Grad[%s][%s] is %.6e; expected %.6e", uid, id, g[id],
+					numeric)
+			}
+		}
+	}
+}
+
+// Test that recurrent state carries over between windows.
+func TestTrainSequence(t *testing.T) {
+	rand.Seed(12)
+
+	arch := []int{1, 3, 1}
+	kinds := []string{InputKind, RecurrentKind, OutputKind}
+	seq := [][]float64{{1.0}, {-0.5}, {2.0}, {1.0}, {1.5}}
+
+	// No updates, so outputs should match regardless of the window.
+	opt := NewSGD(0.0, 0.0, 0.0)
+	n := MustNewMLP(arch, opt, WithUnitKinds(kinds))
+	n.StartSequence(true, 1)
+	lossGrad := func(t int, output []float64) []float64 { return output }
+	outputs, _ := n.TrainSequence(seq, 2, lossGrad)
+	n.ResetState()
+	outputsFull, _ := n.TrainSequence(seq, len(seq), lossGrad)
+
+	if len(outputs) != len(seq) {
+		t.Fatalf("Got %d outputs; expected %d", len(outputs), len(seq))
+	}
+	for ii := range outputs {
+		if !almostEqual(outputs[ii][0], outputsFull[ii][0]) {
+			t.Errorf("Step %d output is %.6e; expected %.6e", ii, outputs[ii][0],
+				outputsFull[ii][0])
+		}
+	}
+	// The recurrent state should make the output depend on history.
+	if almostEqual(outputs[0][0], outputs[3][0]) {
+		t.Errorf("Outputs don't depend on history")
+	}
+
+	if _, err := n.TrainSequence(seq, 0, lossGrad); err == nil {
+		t.Errorf("TrainSequence did not return an error")
+	}
+
+	// One gradient per step of the window.
+	n.ForwardSequence(seq[:2])
+	for _, grads := range [][][]float64{{{1.0}, {1.0}, {1.0}}, {{1.0}}} {
+		if err := n.BackwardSequence(grads); err == nil {
+			t.Errorf("BackwardSequence with %d grads after 2 steps didn't fail", len(grads))
+		}
+	}
+	if err := n.BackwardSequence([][]float64{{1.0}, {1.0}}); err != nil {
+		t.Errorf("BackwardSequence returned an error: %v", err)
+	}
+	if err := n.BackwardSequence([][]float64{{1.0}}); err == nil {
+		t.Error("BackwardSequence without a window didn't fail")
+	}
+	n.Stop()
+
+	// Sequences need StartSequence.
+	n.Start(true, 0)
+	defer n.Stop()
+	if _, err := n.ForwardSequence(seq[:2]); err == nil {
+		t.Error("ForwardSequence without StartSequence didn't fail")
+	}
+	if err := n.BackwardSequence(nil); err == nil {
+		t.Error("BackwardSequence without StartSequence didn't fail")
+	}
+	if _, err := n.TrainSequence(seq, 2, lossGrad); err == nil {
+		t.Error("TrainSequence without StartSequence didn't fail")
+	}
+}
@@ -0,0 +1,81 @@
+package neuron
+
+import (
+	"fmt"
+)
+
+// WithSkipConnections connects every unit in layer from directly to every
+// unit in layer to, skipping the layers in between, e.g.
+//
+//	WithSkipConnections(1, 3)
+//
+// feeds the first hidden layer into the third. Skip connections have their
+// own trainable weights, initialized like the connections between adjacent
+// layers, and gradients flow back along them too, so that deep networks
+// don't suffer from vanishing gradients. It can be passed more than once.
+func WithSkipConnections(from, to int) Option {
+	return func(c *netConfig) {
+		c.skips = append(c.skips, [2]int{from, to})
+	}
+}
+
+// checkSkips checks the skip connection settings.
+func checkSkips(c *netConfig, numLayers int) error {
+	seen := make(map[[2]int]bool)
+	for _, s := range c.skips {
+		from, to := s[0], s[1]
+		if from < 0 || to >= numLayers || to <= from+1 {
+			return fmt.Errorf("skip connection %d -> %d needs 0 <= from < to - 1 < %d",
+				from, to, numLayers-1)
+		}
+		if seen[s] {
+			return fmt.Errorf("duplicate skip connection %d -> %d", from, to)
+		}
+		seen[s] = true
+	}
+	return nil
+}
+
+// connectSkips adds the skip connections.
+func (n *Net) connectSkips(skips [][2]int) {
+	for _, s := range skips {
+		for _, u1 := range n.Layers[s[0]] {
+			for _, u2 := range n.Layers[s[1]] {
+				u1.connect(u2, n.rand())
+			}
+		}
+	}
+	n.skips = append(n.skips, skips...)
+}
+
+// hasSkip checks whether layer is either end of a skip connection.
+func (n *Net) hasSkip(layer int) bool {
+	for _, s := range n.skips {
+		if s[0] == layer || s[1] == layer {
+			return true
+		}
+	}
+	return false
+}
+
+// upstream returns the indices of the layers with connections into layer ii,
+// in increasing order.
+func (n *Net) upstream(ii int) []int {
+	ids := make(map[string]bool, len(n.Layers[ii]))
+	for _, u := range n.Layers[ii] {
+		ids[u.ID] = true
+	}
+	var up []int
+	for jj := 0; jj < ii; jj++ {
+	search:
+		for _, u := range n.Layers[jj] {
+			for k := range u.output {
+				if ids[k] {
+					up = append(up, jj)
+					break search
+				}
+			}
+		}
+	}
+	return up
+}
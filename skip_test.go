@@ -0,0 +1,118 @@
+package neuron
+
+import (
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestSkipConnectionErrors(t *testing.T) {
+	arch := []int{2, 3, 3, 1}
+	for _, opts := range [][]Option{
+		{WithSkipConnections(0, 1)}, {WithSkipConnections(2, 1)},
+		{WithSkipConnections(-1, 2)}, {WithSkipConnections(1, 4)},
+		{WithSkipConnections(0, 2), WithSkipConnections(0, 2)}} {
+		if _, err := NewMLP(arch, NewSGD(0.1, 0.0, 0.0), opts...); err == nil {
+			t.Errorf("NewMLP did not return an error")
+		}
+	}
+
+	n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), WithSkipConnections(0, 2))
+	if err := n.WidenLayer(2, 4); err == nil {
+		t.Errorf("WidenLayer did not return an error")
+	}
+	if err := n.ExportONNX(io.Discard); err == nil {
+		t.Errorf("ExportONNX did not return an error")
+	}
+}
+
+// Test that skip connections are wired into each engine, and that their
+// gradients match the dense engine and finite differences.
+func TestSkipConnections(t *testing.T) {
+	arch := []int{3, 4, 3, 2}
+	skips := []Option{WithSkipConnections(0, 2), WithSkipConnections(1, 3),
+		WithSkipConnections(0, 3)}
+	data := []float64{0.5, -1.0, 2.0}
+	grad := []float64{1.0, -0.5}
+	for _, opts := range [][]Option{nil, {WithLayerEngine()}, {WithSignalBatching()},
+		{WithWorkers(2)}, {WithLayerEngine(), WithPipelining()}} {
+		rand.Seed(3)
+		n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), append(opts, skips...)...)
+		if err := n.Validate(); err != nil {
+			t.Errorf("Validate failed: %v", err)
+		}
+		if _, ok := n.Layers[3][0].W.Params[unitID(0, 1)]; !ok {
+			t.Fatalf("Unit %s has no skip connection from %s", n.Layers[3][0].ID,
+				unitID(0, 1))
+		}
+		// Random weights, so that no unit is dead.
+		theta := n.Data()
+		for _, ud := range theta {
+			for id := range ud {
+				if isConn(id) {
+					ud[id] = rand.NormFloat64()
+				}
+			}
+		}
+		n.SetData(theta)
+
+		n.Start(true, 0)
+		got := n.MustForward(data)
+		n.MustBackward(grad)
+		n.Stop()
+		gotGrads := n.Grads()
+		n.zeroGrad()
+
+		d, err := NewDense(n)
+		if err != nil {
+			t.Fatalf("NewDense failed: %v", err)
+		}
+		want, _ := d.Forward(data)
+		d.Backward(grad)
+		for ii := range want {
+			if !almostEqualTol(got[ii], want[ii], 1.0e-06) {
+				t.Errorf("Output %d is %.6f; expected %.6f", ii, got[ii], want[ii])
+			}
+		}
+		wantGrads := n.Grads()
+		for uid, ug := range wantGrads {
+			for id, g := range ug {
+				if !almostEqualTol(gotGrads[uid][id], g, 1.0e-06) {
+					t.Errorf("Grad %s/%s is %.6f; expected %.6f", uid, id,
+						gotGrads[uid][id], g)
+				}
+			}
+		}
+	}
+
+	// Finite differences of the dense engine, along the skip connections.
+	rand.Seed(3)
+	n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), skips...)
+	d, _ := NewDense(n)
+	d.Forward(data)
+	d.Backward(grad)
+	loss := func() float64 {
+		out, _ := d.Forward(data)
+		l := 0.0
+		for ii, v := range out {
+			l += grad[ii] * v
+		}
+		return l
+	}
+	const eps = 1.0e-06
+	for _, c := range []struct{ layer, unit, from, idx int }{
+		{2, 0, 0, 2}, {3, 1, 1, 3}, {3, 0, 0, 0}} {
+		u := n.Layers[c.layer][c.unit]
+		id := unitID(c.from, c.idx)
+		p := u.W.Params[id]
+		v := p.Data
+		p.Data = v + eps
+		lp := loss()
+		p.Data = v - eps
+		lm := loss()
+		p.Data = v
+		if want := (lp - lm) / (2 * eps); !almostEqualTol(p.grad, want, 1.0e-04) {
+			t.Errorf("Grad %s/%s is %.6f; expected %.6f", u.ID, id, p.grad, want)
+		}
+	}
+}
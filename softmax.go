@@ -0,0 +1,72 @@
+package neuron
+
+import (
+	"errors"
+)
+
+// WithSoftmaxOutput adds a softmax over the output layer, for multi-class
+// classification. Forward returns class probabilities instead of raw scores,
+// and Backward takes the loss gradient with respect to the probabilities,
+// back-propagating it through the softmax. E.g. for the cross-entropy loss
+// -log(p[y]) the gradient is -1/p[y] for the target class y, and 0 otherwise.
+func WithSoftmaxOutput() Option {
+	return func(c *netConfig) {
+		c.softmax = true
+	}
+}
+
+// forwardSoftmax computes the output probabilities and saves them for the
+// backward pass. In sequence mode the probabilities of every step in the
+// window are kept, to be used by the matching backward steps, and similarly
+// for every sample of a batch.
+func (n *Net) forwardSoftmax(scores []float64) []float64 {
+	probs := softmaxSpans(scores, n.softmaxSpans)
+	if !n.seq && n.batch <= 1 {
+		n.probs = n.probs[:0]
+	}
+	n.probs = append(n.probs, probs)
+	out := make([]float64, len(probs))
+	copy(out, probs)
+	return out
+}
+
+// backwardSoftmax converts a gradient with respect to the most recent output
+// probabilities to a gradient with respect to the scores.
+func (n *Net) backwardSoftmax(grad []float64) ([]float64, error) {
+	if len(n.probs) == 0 {
+		return nil, errors.New("softmax backward without a forward pass")
+	}
+	probs := n.probs[len(n.probs)-1]
+	n.probs = n.probs[:len(n.probs)-1]
+	return softmaxJVP(grad, probs, n.softmaxSpans), nil
+}
+
+// softmaxSpans applies a softmax to each span [start, end) of scores, leaving
+// the other scores unchanged.
+func softmaxSpans(scores []float64, spans [][2]int) []float64 {
+	probs := make([]float64, len(scores))
+	copy(probs, scores)
+	for _, s := range spans {
+		copy(probs[s[0]:s[1]], Softmax(scores[s[0]:s[1]], 1.0))
+	}
+	return probs
+}
+
+// softmaxJVP back-propagates grad through softmaxSpans, given its output
+// probs. Within each span
+//
+//	dscore[i] = p[i] * (grad[i] - sum_j grad[j] p[j])
+func softmaxJVP(grad, probs []float64, spans [][2]int) []float64 {
+	dscore := make([]float64, len(grad))
+	copy(dscore, grad)
+	for _, s := range spans {
+		dot := 0.0
+		for ii := s[0]; ii < s[1]; ii++ {
+			dot += grad[ii] * probs[ii]
+		}
+		for ii := s[0]; ii < s[1]; ii++ {
+			dscore[ii] = probs[ii] * (grad[ii] - dot)
+		}
+	}
+	return dscore
+}
@@ -0,0 +1,68 @@
+package neuron
+
+import (
+	"testing"
+)
+
+// Test the softmax output layer.
+func TestSoftmaxOutput(t *testing.T) {
+	arch := []int{2, 4, 3}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	n := MustNewMLP(arch, opt, WithSoftmaxOutput())
+	n.Start(true, 0)
+
+	probs := n.MustForward([]float64{1.0, -1.0})
+	sum := 0.0
+	for _, p := range probs {
+		if p <= 0 || p >= 1 {
+			t.Errorf("Invalid probability %.4f", p)
+		}
+		sum += p
+	}
+	if !almostEqual(sum, 1.0) {
+		t.Errorf("Probabilities sum to %.4f; expected 1", sum)
+	}
+
+	// Cross-entropy gradient through the softmax is p - y.
+	const target = 1
+	grad := make([]float64, 3)
+	grad[target] = -1.0 / probs[target]
+	n.MustBackward(grad)
+	g := n.Grads()
+	for ii, u := range n.Layers[2] {
+		want := probs[ii]
+		if ii == target {
+			want -= 1.0
+		}
+		if got := g[u.ID][biasID]; !almostEqual(got, want) {
+			t.Errorf("Output %d bias grad is %.4f; expected %.4f", ii, got, want)
+		}
+	}
+}
+
+// Test the softmax output layer in sequence mode.
+func TestSoftmaxOutputSequence(t *testing.T) {
+	arch := []int{1, 2, 2}
+	opt := NewSGD(0.0, 0.0, 0.0)
+	kinds := []string{InputKind, RecurrentKind, OutputKind}
+	n := MustNewMLP(arch, opt, WithUnitKinds(kinds), WithSoftmaxOutput())
+	n.StartSequence(true, 0)
+
+	out, _ := n.ForwardSequence([][]float64{{1.0}, {-1.0}, {0.5}})
+	grads := make([][]float64, len(out))
+	for ii, p := range out {
+		// Gradient of p[0], so p[0] * (1 - p[0]) for the first output bias.
+		grads[ii] = []float64{1.0, 0.0}
+		if !almostEqual(p[0]+p[1], 1.0) {
+			t.Errorf("Step %d probabilities sum to %.4f", ii, p[0]+p[1])
+		}
+	}
+	n.BackwardSequence(grads)
+	want := 0.0
+	for _, p := range out {
+		want += p[0] * (1.0 - p[0])
+	}
+	if got := n.Grads()[n.Layers[2][0].ID][biasID]; !almostEqual(got, want) {
+		t.Errorf("Output bias grad is %.4f; expected %.4f", got, want)
+	}
+}
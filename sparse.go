@@ -0,0 +1,100 @@
+package neuron
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// WithConnectionProb connects each unit to each unit in the previous layer
+// with probability p, instead of to all of them. Sparse layers need far fewer
+// weights and channels, so wider networks fit in the same memory and passes
+// exchange fewer signals. Every unit keeps at least one input and one output,
+// so no unit is cut off from the network. Skip connections are still fully
+// connected.
+func WithConnectionProb(p float64) Option {
+	return func(c *netConfig) {
+		c.connProb = p
+	}
+}
+
+// WithFanIn connects each unit to k random units in the previous layer,
+// instead of to all of them. Layers with k or fewer units are fully connected.
+// Like WithConnectionProb, every unit keeps at least one output, so a unit
+// left without outputs is connected to a random unit in the next layer, in
+// addition to its k inputs.
+func WithFanIn(k int) Option {
+	return func(c *netConfig) {
+		c.fanIn = k
+	}
+}
+
+// checkSparse checks the sparse connectivity settings.
+func checkSparse(c *netConfig) error {
+	if c.connProb != 0 && (c.connProb < 0 || c.connProb > 1) {
+		return fmt.Errorf("connection probability needs 0 < p <= 1; got %g", c.connProb)
+	}
+	if c.fanIn < 0 {
+		return fmt.Errorf("fan-in needs >= 1 connection; got %d", c.fanIn)
+	}
+	if c.connProb != 0 && c.fanIn != 0 {
+		return fmt.Errorf("can't set both a connection probability and a fan-in")
+	}
+	return nil
+}
+
+// sparse checks whether the layers are sparsely connected.
+func (c *netConfig) sparse() bool {
+	return c.connProb != 0 || c.fanIn != 0
+}
+
+// connectSparse connects each unit in down to a random subset of up, picked
+// by connection probability or fan-in.
+func (c *netConfig) connectSparse(up, down []*Unit, rng *rand.Rand) {
+	conns := make([][]int, len(down))
+	hasOutput := make([]bool, len(up))
+	for jj := range down {
+		var idx []int
+		if c.fanIn > 0 && c.fanIn < len(up) {
+			idx = rng.Perm(len(up))[:c.fanIn]
+			sort.Ints(idx)
+		} else if c.fanIn > 0 {
+			for ii := range up {
+				idx = append(idx, ii)
+			}
+		} else {
+			for ii := range up {
+				if rng.Float64() < c.connProb {
+					idx = append(idx, ii)
+				}
+			}
+			if len(idx) == 0 {
+				idx = []int{rng.Intn(len(up))}
+			}
+		}
+		for _, ii := range idx {
+			hasOutput[ii] = true
+		}
+		conns[jj] = idx
+	}
+	for ii, ok := range hasOutput {
+		if !ok {
+			jj := rng.Intn(len(down))
+			conns[jj] = append(conns[jj], ii)
+			sort.Ints(conns[jj])
+		}
+	}
+
+	// Connect in the same order as full connectivity, upstream units first.
+	byUp := make([][]*Unit, len(up))
+	for jj, idx := range conns {
+		for _, ii := range idx {
+			byUp[ii] = append(byUp[ii], down[jj])
+		}
+	}
+	for ii, u1 := range up {
+		for _, u2 := range byUp[ii] {
+			u1.connect(u2, rng)
+		}
+	}
+}
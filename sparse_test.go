@@ -0,0 +1,92 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSparseErrors(t *testing.T) {
+	arch := []int{2, 3, 1}
+	for _, opts := range [][]Option{{WithConnectionProb(-0.5)},
+		{WithConnectionProb(1.5)}, {WithFanIn(-1)},
+		{WithConnectionProb(0.5), WithFanIn(2)}} {
+		if _, err := NewMLP(arch, NewSGD(0.1, 0.0, 0.0), opts...); err == nil {
+			t.Errorf("NewMLP did not return an error")
+		}
+	}
+}
+
+// Test the sparse wiring, and that sparse networks compute the same outputs
+// and gradients with each engine.
+func TestSparse(t *testing.T) {
+	arch := []int{6, 10, 8, 3}
+	rand.Seed(5)
+	for _, opts := range [][]Option{{WithFanIn(2)}, {WithConnectionProb(0.3)},
+		{WithFanIn(3), WithLayerEngine()}, {WithConnectionProb(0.2), WithSignalBatching()},
+		{WithFanIn(20)}} {
+		n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), opts...)
+		if err := n.Validate(); err != nil {
+			t.Fatalf("Validate failed: %v", err)
+		}
+		var c netConfig
+		for _, o := range opts {
+			o(&c)
+		}
+		for ii, l := range n.Layers[:len(n.Layers)-1] {
+			for _, u := range l {
+				if len(u.output) == 0 {
+					t.Errorf("Unit %s has no outputs", u.ID)
+				}
+			}
+			for _, u := range n.Layers[ii+1] {
+				// Fan-in is at least the limit, plus any units without outputs.
+				if c.fanIn > 0 && u.nin < c.fanIn && u.nin < len(l) {
+					t.Errorf("Unit %s has fan-in %d; expected >= %d", u.ID, u.nin,
+						c.fanIn)
+				}
+				if u.nin < 1 {
+					t.Errorf("Unit %s has no inputs", u.ID)
+				}
+			}
+		}
+
+		data := []float64{1.0, -1.0, 0.5, 2.0, -0.3, 0.8}
+		grad := []float64{1.0, 0.5, -1.0}
+		n.Start(true, 0)
+		got := n.MustForward(data)
+		n.MustBackward(grad)
+		n.Stop()
+		gotGrads := n.Grads()
+		n.zeroGrad()
+
+		d, err := NewDense(n)
+		if err != nil {
+			t.Fatalf("NewDense failed: %v", err)
+		}
+		want, _ := d.Forward(data)
+		d.Backward(grad)
+		for ii := range want {
+			if !almostEqualTol(got[ii], want[ii], 1.0e-06) {
+				t.Errorf("Output %d is %.6f; expected %.6f", ii, got[ii], want[ii])
+			}
+		}
+		for uid, ug := range n.Grads() {
+			for id, g := range ug {
+				if !almostEqualTol(gotGrads[uid][id], g, 1.0e-06) {
+					t.Errorf("Grad %s/%s is %.6f; expected %.6f", uid, id,
+						gotGrads[uid][id], g)
+				}
+			}
+		}
+	}
+
+	// The connection probability sets the expected density.
+	n := MustNewMLP([]int{40, 40, 1}, NewSGD(0.1, 0.0, 0.0), WithConnectionProb(0.25))
+	conns := 0
+	for _, u := range n.Layers[1] {
+		conns += u.nin
+	}
+	if density := float64(conns) / (40 * 40); density < 0.2 || density > 0.3 {
+		t.Errorf("Connection density is %.3f; expected about 0.25", density)
+	}
+}
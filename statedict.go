@@ -0,0 +1,148 @@
+package neuron
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LoadStateDict sets the weights and biases from an externally trained MLP,
+// read as a JSON object mapping parameter names to arrays, e.g. a PyTorch
+// state dict saved with
+//
+//	json.dump({k: v.tolist() for k, v in model.state_dict().items()}, f)
+//
+// Names must end in ".weight", for a matrix of shape [out, in] like
+// torch.nn.Linear, or ".bias", for a vector of length out. Weights are matched
+// to layers in the natural order of their names, so "fc2" comes before "fc10"
+// and "net.2" before "net.10", and with a bias of the same prefix. Units with a
+// bias that have no bias in the state dict get a zero bias. The shapes must
+// match the architecture, the network's weights can't be reparameterized, and
+// it can't have skip connections. LoadStateDict should only be called while the
+// network is idle.
+func (n *Net) LoadStateDict(r io.Reader) error {
+	if len(n.skips) > 0 {
+		return fmt.Errorf("can't load a state dict into a network with skip connections")
+	}
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return err
+	}
+	weights := make(map[string][][]float64)
+	biases := make(map[string][]float64)
+	for k, v := range raw {
+		var err error
+		switch {
+		case strings.HasSuffix(k, ".weight"):
+			var w [][]float64
+			err = json.Unmarshal(v, &w)
+			weights[strings.TrimSuffix(k, ".weight")] = w
+		case strings.HasSuffix(k, ".bias"):
+			var b []float64
+			err = json.Unmarshal(v, &b)
+			biases[strings.TrimSuffix(k, ".bias")] = b
+		default:
+			return fmt.Errorf("unsupported state dict entry %q", k)
+		}
+		if err != nil {
+			return fmt.Errorf("state dict entry %q: %v", k, err)
+		}
+	}
+
+	prefixes := make([]string, 0, len(weights))
+	for k := range weights {
+		prefixes = append(prefixes, k)
+	}
+	sort.Slice(prefixes, func(ii, jj int) bool {
+		return naturalLess(prefixes[ii], prefixes[jj])
+	})
+	if len(prefixes) != len(n.Layers)-1 {
+		return fmt.Errorf("state dict has %d weight matrices for %d layers",
+			len(prefixes), len(n.Layers)-1)
+	}
+	for k := range biases {
+		if _, ok := weights[k]; !ok {
+			return fmt.Errorf("state dict bias %q has no weight", k)
+		}
+	}
+
+	// Check everything before changing any weights.
+	for ii, k := range prefixes {
+		l, prev := n.Layers[ii+1], n.Layers[ii]
+		w, b := weights[k], biases[k]
+		if len(w) != len(l) {
+			return fmt.Errorf("%s.weight has %d rows; layer %d has %d units", k,
+				len(w), ii+1, len(l))
+		}
+		for _, row := range w {
+			if len(row) != len(prev) {
+				return fmt.Errorf("%s.weight has %d columns; layer %d has %d units", k,
+					len(row), ii, len(prev))
+			}
+		}
+		if b != nil && len(b) != len(l) {
+			return fmt.Errorf("%s.bias has %d entries; layer %d has %d units", k,
+				len(b), ii+1, len(l))
+		}
+		for _, u := range l {
+			if u.W.reparam() {
+				return fmt.Errorf("unit %s has reparameterized weights", u.ID)
+			}
+			if _, ok := u.W.Params[biasID]; b != nil && !ok {
+				return fmt.Errorf("%s.bias given but unit %s has no bias", k, u.ID)
+			}
+		}
+	}
+
+	for ii, k := range prefixes {
+		l, prev := n.Layers[ii+1], n.Layers[ii]
+		w, b := weights[k], biases[k]
+		for jj, u := range l {
+			for kk, u2 := range prev {
+				if p, ok := u.W.Params[u2.ID]; ok {
+					p.Data = w[jj][kk]
+				}
+			}
+			if p, ok := u.W.Params[biasID]; ok {
+				p.Data = 0.0
+				if b != nil {
+					p.Data = b[jj]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// naturalLess compares strings with runs of digits compared as numbers.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != "" && db != "" {
+			na, _ := strconv.Atoi(da)
+			nb, _ := strconv.Atoi(db)
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// digitPrefix returns the leading run of digits in s.
+func digitPrefix(s string) string {
+	ii := 0
+	for ii < len(s) && s[ii] >= '0' && s[ii] <= '9' {
+		ii++
+	}
+	return s[:ii]
+}
@@ -0,0 +1,55 @@
+package neuron
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadStateDict(t *testing.T) {
+	n := MustNewMLP([]int{2, 3, 1}, NewSGD(0.1, 0.0, 0.0))
+	sd := `{
+		"net.10.weight": [[1.0, -1.0, 0.5]],
+		"net.2.weight": [[0.1, 0.2], [0.3, 0.4], [0.5, 0.6]],
+		"net.2.bias": [1.0, 2.0, 3.0]
+	}`
+	if err := n.LoadStateDict(strings.NewReader(sd)); err != nil {
+		t.Fatalf("LoadStateDict failed: %v", err)
+	}
+	if w := n.Layers[1][1].W.Params["000_000000"].Data; w != 0.3 {
+		t.Errorf("Weight is %.4f; expected 0.3", w)
+	}
+	if b := n.Layers[1][2].W.Params[biasID].Data; b != 3.0 {
+		t.Errorf("Bias is %.4f; expected 3", b)
+	}
+	if b := n.Layers[2][0].W.Params[biasID].Data; b != 0.0 {
+		t.Errorf("Missing bias is %.4f; expected 0", b)
+	}
+
+	// relu([0.1+0.2+1, 0.3+0.4+2, 0.5+0.6+3]) . [1, -1, 0.5]
+	n.Start(false, 1)
+	defer n.Stop()
+	if out := n.MustForward([]float64{1.0, 1.0}); !almostEqual(out[0], 1.3-2.7+2.05) {
+		t.Errorf("Output is %.4f; expected %.4f", out[0], 1.3-2.7+2.05)
+	}
+
+	for _, sd := range []string{
+		`{"fc1.weight": [[0.1, 0.2], [0.3, 0.4], [0.5, 0.6]]}`,
+		`{"fc1.weight": [[0.1, 0.2]], "fc2.weight": [[1.0]]}`,
+		`{"fc1.weight": [[0.1], [0.3], [0.5]], "fc2.weight": [[1.0, 1.0, 1.0]]}`,
+		`{"fc1.weight": [[0.1, 0.2], [0.3, 0.4], [0.5, 0.6]], "fc2.weight": [[1.0, 1.0, 1.0]], "fc2.bias": [1.0, 2.0]}`,
+		`{"fc1.weight": [[0.1, 0.2], [0.3, 0.4], [0.5, 0.6]], "fc2.weight": [[1.0, 1.0, 1.0]], "bn.running_mean": [0.0]}`,
+	} {
+		if err := n.LoadStateDict(strings.NewReader(sd)); err == nil {
+			t.Errorf("LoadStateDict did not return an error for %s", sd)
+		}
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	for _, c := range [][2]string{{"fc2", "fc10"}, {"net.2", "net.10"}, {"a", "b"},
+		{"layer1", "layer1x"}} {
+		if !naturalLess(c[0], c[1]) || naturalLess(c[1], c[0]) {
+			t.Errorf("Expected %s < %s", c[0], c[1])
+		}
+	}
+}
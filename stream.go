@@ -0,0 +1,77 @@
+package neuron
+
+import (
+	"context"
+)
+
+// A Sample is a single input and target, e.g. streamed to Trainer.FitStream.
+type Sample struct {
+	X, Y []float64
+}
+
+// FitStream trains the network online on samples as they arrive, e.g. from a
+// sensor or socket, until samples is closed, and returns the training
+// history. Each sample is forward and back-propagated as soon as it's
+// received, and the weights are updated every BatchSize samples by the
+// network's updateFreq, so with BatchSize 1 this is plain online SGD.
+//
+// Every BatchSize samples make a batch for the callbacks and history, with a
+// smaller final batch if the stream ends mid-batch. The whole stream is a
+// single epoch, ending when samples is closed, and validation with
+// ValidateEvery 0 runs only then. Data, Shuffle, and Workers are unused.
+//
+// If ctx is done before samples is closed, FitStream returns the history so
+// far with ctx.Err(), without ending the epoch. Like Fit, the network is
+// restarted in training mode, and left running.
+func (t *Trainer) FitStream(ctx context.Context, samples <-chan Sample) (*History, error) {
+	batchSize, err := t.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { t.hist = nil }()
+	hist := t.hist
+
+	count, batch, batchLen := 0, 0, 0
+	batchLoss, epochLoss := 0.0, 0.0
+	steps := t.Net.Steps()
+	for {
+		var s Sample
+		var ok bool
+		select {
+		case s, ok = <-samples:
+		case <-ctx.Done():
+			return hist, ctx.Err()
+		}
+		if !ok {
+			break
+		}
+		loss, err := t.step(Batch{Indices: []int{count},
+			Inputs: [][]float64{s.X}, Targets: [][]float64{s.Y}})
+		if err != nil {
+			return hist, err
+		}
+		count++
+		batchLen++
+		batchLoss += loss
+		epochLoss += loss
+		if batchLen == batchSize {
+			if err := t.endBatch(batch, batchLoss/float64(batchLen), steps); err != nil {
+				return hist, stopErr(err)
+			}
+			batch, batchLen, batchLoss = batch+1, 0, 0.0
+			steps = t.Net.Steps()
+		}
+	}
+	if count == 0 {
+		return hist, nil
+	}
+	if batchLen > 0 {
+		if err := t.endBatch(batch, batchLoss/float64(batchLen), steps); err != nil {
+			return hist, stopErr(err)
+		}
+	}
+	if err := t.endEpoch(0, epochLoss/float64(count)); err != nil {
+		return hist, stopErr(err)
+	}
+	return hist, nil
+}
@@ -0,0 +1,74 @@
+package neuron
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+// Test online training on a stream of samples.
+func TestFitStream(t *testing.T) {
+	rand.Seed(16)
+	n := MustNewMLP([]int{2, 8, 1}, NewSGD(0.02, 0.0, 0.0), WithInitializer(HeNormal{}))
+	defer n.Stop()
+	loss, _ := GetLoss("mse")
+	tr := &Trainer{Net: n, Loss: loss, BatchSize: 1}
+
+	ds := linearData(1002)
+	samples := make(chan Sample)
+	go func() {
+		defer close(samples)
+		for ii := 0; ii < ds.Len(); ii++ {
+			x, y := ds.Get(ii)
+			samples <- Sample{X: x, Y: y}
+		}
+	}()
+	hist, err := tr.FitStream(context.Background(), samples)
+	if err != nil {
+		t.Fatalf("FitStream returned an error: %v", err)
+	}
+	if len(hist.Batches) != 1002 || len(hist.Epochs) != 1 || n.Steps() != 1002 {
+		t.Fatalf("Got %d batches, %d epochs, and %d steps; expected 1002, 1, and 1002",
+			len(hist.Batches), len(hist.Epochs), n.Steps())
+	}
+	mean := func(records []Record) float64 {
+		sum := 0.0
+		for _, r := range records {
+			sum += r.Loss
+		}
+		return sum / float64(len(records))
+	}
+	if first, last := mean(hist.Batches[:100]), mean(hist.Batches[902:]); last > 0.1*first {
+		t.Errorf("Loss went from %.3e to %.3e", first, last)
+	}
+
+	// A partial final batch.
+	tr.BatchSize = 4
+	samples = make(chan Sample, 6)
+	for ii := 0; ii < 6; ii++ {
+		x, y := ds.Get(ii)
+		samples <- Sample{X: x, Y: y}
+	}
+	close(samples)
+	if hist, err = tr.FitStream(context.Background(), samples); err != nil {
+		t.Fatalf("FitStream returned an error: %v", err)
+	}
+	if len(hist.Batches) != 2 || hist.Batches[1].Step != 1008 {
+		t.Errorf("Got batches %+v; expected 2 ending at step 1008", hist.Batches)
+	}
+
+	// Cancelling an open stream.
+	ctx, cancel := context.WithCancel(context.Background())
+	samples = make(chan Sample)
+	go func() {
+		x, y := ds.Get(0)
+		samples <- Sample{X: x, Y: y}
+		cancel()
+	}()
+	if hist, err = tr.FitStream(ctx, samples); err != context.Canceled {
+		t.Errorf("FitStream returned %v; expected %v", err, context.Canceled)
+	}
+	if len(hist.Epochs) != 0 {
+		t.Errorf("Got %d epochs after cancelling; expected 0", len(hist.Epochs))
+	}
+}
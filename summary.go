@@ -0,0 +1,90 @@
+package neuron
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Summary returns a table of the network's layers, with the number of units,
+// activation, and number of parameters of each layer, followed by the total
+// number of parameters, e.g.
+//
+//	Layer  Units  Activation  Params
+//	================================
+//	0          2  Identity         2
+//	1          4  Relu            12
+//	2          1  Identity         5
+//	================================
+//	Total params: 19
+//	Trainable params: 17
+//	Non-trainable params: 2
+//
+// Layers with mixed activations list each of them. Parameters that aren't
+// updated by the optimizer, i.e. input weights, pruned connections, and frozen
+// units, are non-trainable.
+func (n *Net) Summary() string {
+	rows := [][]string{{"Layer", "Units", "Activation", "Params"}}
+	trainable, fixed := 0, 0
+	for ii, l := range n.Layers {
+		var activs []string
+		seen := make(map[string]bool)
+		count := 0
+		for _, u := range l {
+			name := activationName(u.activ)
+			if !seen[name] {
+				seen[name] = true
+				activs = append(activs, name)
+			}
+			for _, p := range u.W.Params {
+				if p.trainable() && !p.masked {
+					trainable++
+				} else {
+					fixed++
+				}
+				count++
+			}
+		}
+		rows = append(rows, []string{fmt.Sprint(ii), fmt.Sprint(len(l)),
+			strings.Join(activs, "/"), fmt.Sprint(count)})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for jj, col := range row {
+			if len(col) > widths[jj] {
+				widths[jj] = len(col)
+			}
+		}
+	}
+	total := 2 * (len(widths) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	rule := strings.Repeat("=", total) + "\n"
+
+	var b strings.Builder
+	for ii, row := range rows {
+		for jj, col := range row {
+			if jj > 0 {
+				b.WriteString("  ")
+			}
+			// Numbers are right aligned, except the layer index.
+			if ii > 0 && (jj == 1 || jj == 3) {
+				fmt.Fprintf(&b, "%*s", widths[jj], col)
+			} else if jj == len(row)-1 {
+				b.WriteString(col)
+			} else {
+				fmt.Fprintf(&b, "%-*s", widths[jj], col)
+			}
+		}
+		b.WriteString("\n")
+		if ii == 0 {
+			b.WriteString(rule)
+		}
+	}
+	b.WriteString(rule)
+	fmt.Fprintf(&b, "Total params: %d\n", trainable+fixed)
+	fmt.Fprintf(&b, "Trainable params: %d\n", trainable)
+	fmt.Fprintf(&b, "Non-trainable params: %d\n", fixed)
+	return b.String()
+}
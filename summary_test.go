@@ -0,0 +1,39 @@
+package neuron
+
+import (
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	n := MustNewMLP([]int{2, 4, 1}, NewSGD(0.1, 0.0, 0.0))
+	want := `Layer  Units  Activation  Params
+================================
+0          2  Identity         2
+1          4  Relu            12
+2          1  Identity         5
+================================
+Total params: 19
+Trainable params: 17
+Non-trainable params: 2
+`
+	if got := n.Summary(); got != want {
+		t.Errorf("Summary is\n%s\nexpected\n%s", got, want)
+	}
+
+	n = MustNewMLP([]int{2, 4, 1}, NewSGD(0.1, 0.0, 0.0),
+		WithMixedLayer(1, HiddenKind, TanhKind))
+	n.SetMask(Mask{"002_000000": {"001_000000": false}})
+	want = `Layer  Units  Activation  Params
+================================
+0          2  Identity         2
+1          4  Relu/Tanh       12
+2          1  Identity         5
+================================
+Total params: 19
+Trainable params: 16
+Non-trainable params: 3
+`
+	if got := n.Summary(); got != want {
+		t.Errorf("Summary is\n%s\nexpected\n%s", got, want)
+	}
+}
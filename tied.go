@@ -0,0 +1,139 @@
+package neuron
+
+import (
+	"errors"
+	"fmt"
+)
+
+// WithTiedWeights ties the incoming connection weights of layer to to the
+// transpose of the incoming weights of layer from, so the weight from unit i
+// of layer to - 1 to unit j of layer to is the weight from unit j of layer
+// from - 1 to unit i of layer from. The layers need transposed shapes, and to
+// needs to come after from, e.g. the mirrored encoder and decoder layers of an
+// autoencoder, see NewAutoencoder. It can be passed more than once.
+//
+// Tied weights are shared parameters owned by layer from. The gradients of both
+// uses are summed into the owner's gradient before each update, and the tied
+// copies pick up the owner's value in the next forward pass. Both copies show
+// up in Net.Data, and should be set to the same values. Tied weights don't
+// support sparse connections or pipelining.
+func WithTiedWeights(from, to int) Option {
+	return func(c *netConfig) {
+		c.ties = append(c.ties, [2]int{from, to})
+	}
+}
+
+// WithTiedAutoencoder ties every pair of mirrored layers of a symmetric
+// architecture, like {8, 4, 2, 4, 8}: the weights into the last layer are tied
+// to the weights into the first hidden layer, and so on inwards. It's the
+// option used by NewAutoencoder, and should be passed to LoadNet to load a
+// tied autoencoder.
+func WithTiedAutoencoder() Option {
+	return func(c *netConfig) {
+		c.autoTies = true
+	}
+}
+
+// NewAutoencoder creates a network that encodes its input with the encoder
+// layers arch, and decodes it back, mirroring them, e.g. {8, 4, 2} gives
+// {8, 4, 2, 4, 8}. The code layer arch[len(arch)-1] is a hidden layer, and
+// the output layer has the default identity activation, to be trained with a
+// reconstruction loss, e.g. "mse", against the input. If tied is true, the
+// decoder weights are tied to the transposed encoder weights, see
+// WithTiedAutoencoder.
+func NewAutoencoder(arch []int, opt Optimizer, tied bool, opts ...Option) (*Net, error) {
+	if len(arch) < 2 {
+		return nil, fmt.Errorf("autoencoders need >= 1 encoder layer; got %d", len(arch)-1)
+	}
+	full := append([]int(nil), arch...)
+	for ii := len(arch) - 2; ii >= 0; ii-- {
+		full = append(full, arch[ii])
+	}
+	if tied {
+		opts = append(opts, WithTiedAutoencoder())
+	}
+	return NewMLP(full, opt, opts...)
+}
+
+// checkTies checks the tied weight settings, and adds the ties of
+// WithTiedAutoencoder.
+func checkTies(c *netConfig, arch []int) error {
+	numLayers := len(arch)
+	if c.autoTies {
+		if numLayers%2 == 0 {
+			return fmt.Errorf("tied autoencoders need an odd number of layers; got %d",
+				numLayers)
+		}
+		last := numLayers - 1
+		for ii := 1; ii <= last/2; ii++ {
+			c.ties = append(c.ties, [2]int{ii, last - ii + 1})
+		}
+	}
+	if len(c.ties) == 0 {
+		return nil
+	}
+	if c.sparse() || c.pipeline {
+		return errors.New("tied weights don't support sparse connections or pipelining")
+	}
+	seen := make(map[int]bool)
+	for _, t := range c.ties {
+		from, to := t[0], t[1]
+		if from < 1 || to <= from || to >= numLayers {
+			return fmt.Errorf("tied weights %d -> %d need 1 <= from < to < %d", from,
+				to, numLayers)
+		}
+		if arch[to] != arch[from-1] || arch[to-1] != arch[from] {
+			return fmt.Errorf("tied weights %d -> %d need transposed shapes; got %dx%d and %dx%d",
+				from, to, arch[from], arch[from-1], arch[to], arch[to-1])
+		}
+		if seen[from] || seen[to] {
+			return fmt.Errorf("layers %d and %d are already tied", from, to)
+		}
+		seen[from], seen[to] = true, true
+	}
+	return nil
+}
+
+// tieWeights ties the incoming weights of layer to to layer from, see
+// WithTiedWeights.
+func (n *Net) tieWeights(from, to int) {
+	for jj, u := range n.Layers[to] {
+		for ii, u2 := range n.Layers[to-1] {
+			owner := n.Layers[from][ii].W.Params[n.Layers[from-1][jj].ID]
+			p := u.W.Params[u2.ID]
+			p.Data, p.tied = owner.Data, owner
+			owner.ties = append(owner.ties, p)
+		}
+	}
+}
+
+// mergeTies adds the gradients of the tied copies of p into p before it's
+// updated.
+func (p *Param) mergeTies() {
+	for _, t := range p.ties {
+		p.grad += t.grad
+		t.grad = 0.0
+	}
+}
+
+// pullTied copies the value of the owner of a tied copy p. It's called by the
+// unit using p once its inputs have arrived, since the owner updates its
+// value upstream before sending its next activation.
+func (p *Param) pullTied() {
+	if p.tied != nil {
+		p.Data = p.tied.Data
+	}
+}
+
+// tiedLayer checks whether any parameter of a unit of layer l is tied to or
+// from another parameter, by WithTiedWeights, a convolution, or a twin.
+func tiedLayer(l []*Unit) bool {
+	for _, u := range l {
+		for _, p := range u.W.Params {
+			if p.tied != nil || len(p.ties) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test that tied weights share their value and sum their gradients.
+func TestTiedWeights(t *testing.T) {
+	data := []float64{0.5, -1.0, 0.3, 0.8}
+	for _, layers := range []bool{false, true} {
+		newNet := func() *Net {
+			rand.Seed(19)
+			opts := []Option{WithInitializer(XavierNormal{})}
+			if layers {
+				opts = append(opts, WithLayerEngine())
+			}
+			return mustTiedAutoencoder([]int{4, 3, 2}, NewSGD(0.1, 0.0, 0.0), opts...)
+		}
+		n := newNet()
+		if got := n.Arch; len(got) != 5 || got[3] != 3 || got[4] != 4 {
+			t.Fatalf("Autoencoder architecture is %v; expected [4 3 2 3 4]", got)
+		}
+		// Weight from unit 1 of layer 3 to unit 2 of layer 4 is the weight from
+		// unit 2 of layer 0 to unit 1 of layer 1.
+		owner := n.Layers[1][1].W.Params[n.Layers[0][2].ID]
+		tied := n.Layers[4][2].W.Params[n.Layers[3][1].ID]
+		if owner.Data != tied.Data {
+			t.Fatalf("Tied weights are %v and %v", owner.Data, tied.Data)
+		}
+
+		// Gradients of both uses, without updating.
+		n.Start(true, 0)
+		n.MustForward(data)
+		n.MustBackward([]float64{1.0, -1.0, 0.5, 0.0})
+		n.Stop()
+		grad := owner.grad + tied.grad
+		before := owner.Data
+
+		// The update uses the summed gradient, and the copy follows.
+		n = newNet()
+		owner = n.Layers[1][1].W.Params[n.Layers[0][2].ID]
+		tied = n.Layers[4][2].W.Params[n.Layers[3][1].ID]
+		n.Start(true, 1)
+		n.MustForward(data)
+		n.MustBackward([]float64{1.0, -1.0, 0.5, 0.0})
+		// The copy picks up the new value in the next pass.
+		n.MustForward(data)
+		n.MustBackward([]float64{0.0, 0.0, 0.0, 0.0})
+		n.Stop()
+		if want := before - 0.1*grad; !almostEqual(owner.Data, want) || tied.Data != owner.Data {
+			t.Errorf("(layers=%v) Tied weights updated to %v and %v; expected %v", layers,
+				owner.Data, tied.Data, want)
+		}
+	}
+
+	bad := [][]Option{
+		{WithTiedWeights(1, 2)},
+		{WithTiedWeights(2, 1)},
+		{WithTiedWeights(1, 4), WithTiedWeights(1, 3)},
+		{WithTiedWeights(1, 4), WithConnectionProb(0.5)},
+	}
+	for ii, opts := range bad {
+		if _, err := NewMLP([]int{4, 3, 2, 3, 4}, NewSGD(0.1, 0.0, 0.0), opts...); err == nil {
+			t.Errorf("(%d) Expected an error for invalid tied weights", ii)
+		}
+	}
+	if _, err := NewMLP([]int{4, 3, 2, 4}, NewSGD(0.1, 0.0, 0.0), WithTiedAutoencoder()); err == nil {
+		t.Errorf("Expected an error for an asymmetric autoencoder")
+	}
+}
+
+// mustTiedAutoencoder creates a tied autoencoder, and panics on error.
+func mustTiedAutoencoder(arch []int, opt Optimizer, opts ...Option) *Net {
+	n, err := NewAutoencoder(arch, opt, true, opts...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return n
+}
+
+// Test training a tied autoencoder on low-rank data.
+func TestAutoencoder(t *testing.T) {
+	rand.Seed(20)
+	// A linear code layer, since the data is linear.
+	n, err := NewAutoencoder([]int{6, 4, 2}, NewSGD(0.01, 0.9, 0.0), true,
+		WithInitializer(XavierNormal{}),
+		WithActivations([]Activation{new(Tanh), new(Identity), new(Tanh), nil}))
+	if err != nil {
+		t.Fatalf("NewAutoencoder returned an error: %v", err)
+	}
+	defer n.Stop()
+	loss, _ := GetLoss("mse")
+	samples := make([][]float64, 100)
+	for ii := range samples {
+		a, b := rand.NormFloat64(), rand.NormFloat64()
+		samples[ii] = []float64{a, b, a + b, a - b, 0.5 * a, -b}
+	}
+	n.Start(true, 1)
+	epoch := func() float64 {
+		total := 0.0
+		for _, x := range samples {
+			l, _ := loss.Forward(n.MustForward(x), x)
+			n.MustBackward(loss.Backward())
+			total += l
+		}
+		return total / float64(len(samples))
+	}
+	first := epoch()
+	last := first
+	for ii := 0; ii < 100; ii++ {
+		last = epoch()
+	}
+	if last > 0.2*first {
+		t.Errorf("Reconstruction loss went from %.3e to %.3e", first, last)
+	}
+}
+
+// Test the gradients of a tied autoencoder against finite differences.
+func TestTiedGradCheck(t *testing.T) {
+	rand.Seed(21)
+	n, err := NewAutoencoder([]int{3, 2}, NewSGD(0.1, 0.0, 0.0), true,
+		WithInitializer(XavierNormal{}),
+		WithActivations([]Activation{new(Tanh), nil}))
+	if err != nil {
+		t.Fatalf("NewAutoencoder returned an error: %v", err)
+	}
+	loss, _ := GetLoss("mse")
+	data := []float64{0.5, -1.0, 2.0}
+	n.Start(true, 0)
+	defer n.Stop()
+	if _, maxErr, err := GradCheck(n, data, data, loss, 1.0e-05); err != nil || maxErr > 1.0e-06 {
+		t.Errorf("Max relative error is %g (%v); expected < 1e-6", maxErr, err)
+	}
+}
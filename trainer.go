@@ -0,0 +1,358 @@
+package neuron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ErrStopTraining can be returned by a Callback to stop training early.
+var ErrStopTraining = errors.New("stop training")
+
+// A Callback is notified of a Trainer's progress. OnBatchEnd is called after
+// every mini-batch with its mean loss, and OnEpochEnd after every epoch with
+// its mean loss. batch counts from 0 within each epoch, and epoch from 0
+// within each call to Fit. If a callback returns ErrStopTraining, Fit stops
+// and returns nil. Any other error aborts Fit.
+type Callback interface {
+	OnBatchEnd(t *Trainer, batch int, loss float64) error
+	OnEpochEnd(t *Trainer, epoch int, loss float64) error
+}
+
+// CallbackFuncs adapts functions to a ValidationCallback. Nil functions are
+// skipped.
+type CallbackFuncs struct {
+	BatchEnd   func(t *Trainer, batch int, loss float64) error
+	EpochEnd   func(t *Trainer, epoch int, loss float64) error
+	Validation func(t *Trainer, e Evaluation) error
+}
+
+// OnBatchEnd calls BatchEnd.
+func (c CallbackFuncs) OnBatchEnd(t *Trainer, batch int, loss float64) error {
+	if c.BatchEnd == nil {
+		return nil
+	}
+	return c.BatchEnd(t, batch, loss)
+}
+
+// OnEpochEnd calls EpochEnd.
+func (c CallbackFuncs) OnEpochEnd(t *Trainer, epoch int, loss float64) error {
+	if c.EpochEnd == nil {
+		return nil
+	}
+	return c.EpochEnd(t, epoch, loss)
+}
+
+// OnValidation calls Validation.
+func (c CallbackFuncs) OnValidation(t *Trainer, e Evaluation) error {
+	if c.Validation == nil {
+		return nil
+	}
+	return c.Validation(t, e)
+}
+
+// A Trainer trains a network on a dataset for a number of epochs. Typical use
+// is
+//
+//	t := &Trainer{Net: n, Loss: loss, Data: ds, BatchSize: 32, Shuffle: true}
+//	hist, err := t.Fit(10)
+//
+// Mini-batches use the network's updateFreq: Fit starts the network in
+// training mode with updateFreq BatchSize, and feeds the samples through one
+// at a time, so gradients accumulate over each batch. If the dataset size
+// isn't a multiple of BatchSize, the last batch of an epoch is smaller, and
+// its gradients carry over into the first update of the next epoch.
+type Trainer struct {
+	Net  *Net
+	Loss Loss
+	Data Dataset
+	// Number of samples per weight update. Defaults to 1.
+	BatchSize int
+	// Shuffle the samples every epoch, drawing from Rand, or from the global
+	// source if Rand is nil.
+	Shuffle bool
+	Rand    *rand.Rand
+	// Number of goroutines loading batches ahead of training, see DataLoader.
+	// Defaults to 1.
+	Workers int
+	// Augmentation of the training samples, see DataLoader.Transform. The
+	// validation samples aren't augmented.
+	Transform Transform
+	// Callbacks are called in order.
+	Callbacks []Callback
+	// Validation dataset, evaluated every ValidateEvery steps, i.e.
+	// back-propagated samples, or at the end of every epoch if ValidateEvery
+	// is 0. Optional.
+	Validation    Dataset
+	ValidateEvery int
+	// Report the classification accuracy of each evaluation. Targets need to
+	// be a single class label: the class index for networks with several
+	// outputs, or +/- 1 for networks with a single output.
+	Accuracy bool
+	// Metrics computed by each evaluation, e.g. &Precision{}. Optional.
+	Metrics []Metric
+	// Result of the most recent validation.
+	LastValidation Evaluation
+	// Fit state.
+	hist  *History
+	start time.Time
+	epoch int
+}
+
+// An Evaluation holds the results of evaluating a network on a dataset, see
+// Trainer.Evaluate.
+type Evaluation struct {
+	// Step count of the network.
+	Step int
+	// Mean loss.
+	Loss float64
+	// Classification accuracy, if Trainer.Accuracy is set.
+	Accuracy float64
+	// Score of each of Trainer.Metrics by name.
+	Metrics map[string]float64
+}
+
+// A ValidationCallback is a Callback that's also notified of each validation
+// run by a Trainer.
+type ValidationCallback interface {
+	Callback
+	OnValidation(t *Trainer, e Evaluation) error
+}
+
+// Fit trains the network for epochs passes over the dataset, and returns the
+// training history. The network is stopped if it's running, and left running
+// in training mode when Fit returns. If training is aborted, the history up
+// to that point is returned along with the error.
+func (t *Trainer) Fit(epochs int) (*History, error) {
+	if t.Data.Len() == 0 {
+		return nil, errors.New("empty dataset")
+	}
+	batchSize, err := t.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { t.hist = nil }()
+	hist := t.hist
+	loader := &DataLoader{Data: t.Data, BatchSize: batchSize, Shuffle: t.Shuffle,
+		Rand: t.Rand, Workers: t.Workers, Transform: t.Transform}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for t.epoch = 0; t.epoch < epochs; t.epoch++ {
+		epoch := t.epoch
+		epochLoss := 0.0
+		batch := 0
+		for b := range loader.Batches(ctx) {
+			steps := t.Net.Steps()
+			loss, err := t.step(b)
+			if err != nil {
+				return hist, err
+			}
+			epochLoss += loss * float64(b.Len())
+			if err := t.endBatch(batch, loss, steps); err != nil {
+				return hist, stopErr(err)
+			}
+			batch++
+		}
+		if err := t.endEpoch(epoch, epochLoss/float64(t.Data.Len())); err != nil {
+			return hist, stopErr(err)
+		}
+	}
+	return hist, nil
+}
+
+// begin checks the training settings, restarts the network in training mode,
+// and starts a new history. It returns the batch size.
+func (t *Trainer) begin() (int, error) {
+	batchSize := t.BatchSize
+	if batchSize == 0 {
+		batchSize = 1
+	}
+	if batchSize < 0 {
+		return 0, fmt.Errorf("batch size needs to be >= 1; got %d", batchSize)
+	}
+	if t.ValidateEvery < 0 {
+		return 0, fmt.Errorf("validation needs ValidateEvery >= 0; got %d",
+			t.ValidateEvery)
+	}
+	t.Net.Stop()
+	t.Net.Start(true, batchSize)
+	t.hist, t.start, t.epoch = new(History), time.Now(), 0
+	return batchSize, nil
+}
+
+// endBatch records a finished batch, notifies the callbacks, and validates if
+// the step count passed a multiple of ValidateEvery since steps.
+func (t *Trainer) endBatch(batch int, loss float64, steps int) error {
+	t.hist.Batches = append(t.hist.Batches, t.record(batch, loss))
+	if err := t.notify(func(c Callback) error {
+		return c.OnBatchEnd(t, batch, loss)
+	}); err != nil {
+		return err
+	}
+	if every := t.ValidateEvery; every > 0 && t.Net.Steps()/every > steps/every {
+		return t.validate()
+	}
+	return nil
+}
+
+// endEpoch records a finished epoch, validates if ValidateEvery is 0, and
+// notifies the callbacks.
+func (t *Trainer) endEpoch(epoch int, loss float64) error {
+	logf(1, "Epoch %d: loss=%.5e\n", epoch, loss)
+	t.hist.Epochs = append(t.hist.Epochs, t.record(-1, loss))
+	if t.ValidateEvery == 0 {
+		if err := t.validate(); err != nil {
+			return err
+		}
+	}
+	return t.notify(func(c Callback) error {
+		return c.OnEpochEnd(t, epoch, loss)
+	})
+}
+
+// record creates a history record of the current training state.
+func (t *Trainer) record(batch int, loss float64) Record {
+	return Record{
+		Epoch: t.epoch,
+		Batch: batch,
+		Step:  t.Net.Steps(),
+		Time:  time.Since(t.start),
+		LR:    t.Net.GetLR(),
+		Loss:  loss,
+	}
+}
+
+// Evaluate returns the mean loss of the network over a dataset, and the
+// accuracy and metrics if t.Accuracy and t.Metrics are set. The samples are run
+// with Predict, in evaluation mode, so the weights and accumulated gradients
+// are left untouched, and it can be called from a callback mid-training. The
+// network must be running, e.g. started by Fit.
+func (t *Trainer) Evaluate(ds Dataset) (Evaluation, error) {
+	e := Evaluation{Step: t.Net.Steps()}
+	if ds.Len() == 0 {
+		return e, errors.New("empty dataset")
+	}
+	for _, m := range t.Metrics {
+		m.Reset()
+	}
+	correct := 0
+	for ii := 0; ii < ds.Len(); ii++ {
+		x, y := ds.Get(ii)
+		output, err := t.Net.Predict(x)
+		if err != nil {
+			return e, err
+		}
+		loss, err := t.Loss.Forward(output, y)
+		if err != nil {
+			return e, fmt.Errorf("sample %d: %w", ii, err)
+		}
+		e.Loss += loss
+		if t.Accuracy && len(y) > 0 && predictClass(output) == y[0] {
+			correct++
+		}
+		for _, m := range t.Metrics {
+			m.Update(output, y)
+		}
+	}
+	if len(t.Metrics) > 0 {
+		e.Metrics = make(map[string]float64, len(t.Metrics))
+		for _, m := range t.Metrics {
+			e.Metrics[m.Name()] = m.Compute()
+		}
+	}
+	e.Loss /= float64(ds.Len())
+	e.Accuracy = float64(correct) / float64(ds.Len())
+	return e, nil
+}
+
+// predictClass returns the class label predicted by output, the index of the
+// largest output, or the sign of a single output.
+func predictClass(output []float64) float64 {
+	if len(output) == 1 {
+		if output[0] >= 0 {
+			return 1.0
+		}
+		return -1.0
+	}
+	return float64(Argmax(output))
+}
+
+// validate evaluates the network on the validation dataset, if any, and
+// notifies the callbacks.
+func (t *Trainer) validate() error {
+	if t.Validation == nil {
+		return nil
+	}
+	e, err := t.Evaluate(t.Validation)
+	if err != nil {
+		return err
+	}
+	t.LastValidation = e
+	if t.hist != nil {
+		r := t.record(-1, e.Loss)
+		r.Accuracy, r.Metrics = e.Accuracy, e.Metrics
+		t.hist.Validations = append(t.hist.Validations, r)
+	}
+	if t.Accuracy {
+		logf(1, "Validation at step %d: loss=%.5e accuracy=%.3f\n", e.Step, e.Loss,
+			e.Accuracy)
+	} else {
+		logf(1, "Validation at step %d: loss=%.5e\n", e.Step, e.Loss)
+	}
+	return t.notify(func(c Callback) error {
+		if vc, ok := c.(ValidationCallback); ok {
+			return vc.OnValidation(t, e)
+		}
+		return nil
+	})
+}
+
+// step trains on one mini-batch of samples, and returns its mean loss.
+func (t *Trainer) step(b Batch) (float64, error) {
+	total := 0.0
+	for jj, ii := range b.Indices {
+		x, y := b.Inputs[jj], b.Targets[jj]
+		output, err := t.Net.Forward(x)
+		if err != nil {
+			if output != nil {
+				// An anomaly still needs the backward pass, see
+				// WithAnomalyDetection.
+				t.Net.Backward(make([]float64, len(output)))
+			}
+			return 0.0, err
+		}
+		loss, err := t.Loss.Forward(output, y)
+		if err != nil {
+			// The pass still needs its backward pass.
+			t.Net.Backward(make([]float64, len(output)))
+			return 0.0, fmt.Errorf("sample %d: %w", ii, err)
+		}
+		if err := t.Net.Backward(t.Loss.Backward()); err != nil {
+			return 0.0, err
+		}
+		total += loss
+	}
+	return total / float64(b.Len()), nil
+}
+
+// notify calls f on each callback in order, stopping at the first error.
+func (t *Trainer) notify(f func(c Callback) error) error {
+	for _, c := range t.Callbacks {
+		if err := f(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopErr maps ErrStopTraining to a clean stop.
+func stopErr(err error) error {
+	if errors.Is(err, ErrStopTraining) {
+		logf(1, "Training stopped early\n")
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package neuron
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/clane9/go-neuron/data"
+)
+
+// linearData generates a noiseless linear regression dataset.
+func linearData(n int) *data.VectorDataset {
+	ds := &data.VectorDataset{}
+	for ii := 0; ii < n; ii++ {
+		x := []float64{rand.NormFloat64(), rand.NormFloat64()}
+		ds.Inputs = append(ds.Inputs, x)
+		ds.Targets = append(ds.Targets, []float64{0.5*x[0] - x[1]})
+	}
+	return ds
+}
+
+// Test that the trainer reduces the loss and calls the callbacks.
+func TestTrainer(t *testing.T) {
+	rand.Seed(15)
+	n := MustNewMLP([]int{2, 8, 1}, NewSGD(0.05, 0.9, 0.0), WithInitializer(HeNormal{}))
+	defer n.Stop()
+	loss, _ := GetLoss("mse")
+	var batches int
+	var losses []float64
+	tr := &Trainer{Net: n, Loss: loss, Data: linearData(50), BatchSize: 8, Shuffle: true,
+		Rand: rand.New(rand.NewSource(1)),
+		Callbacks: []Callback{CallbackFuncs{
+			BatchEnd: func(t *Trainer, batch int, loss float64) error {
+				batches++
+				return nil
+			},
+			EpochEnd: func(t *Trainer, epoch int, loss float64) error {
+				losses = append(losses, loss)
+				return nil
+			},
+		}}}
+	if _, err := tr.Fit(20); err != nil {
+		t.Fatalf("Fit returned an error: %v", err)
+	}
+	if batches != 20*7 {
+		t.Errorf("Got %d batches; expected %d", batches, 20*7)
+	}
+	if len(losses) != 20 || losses[19] > 0.1*losses[0] {
+		t.Errorf("Epoch losses %v didn't decrease", losses)
+	}
+	if n.Steps() != 20*50 {
+		t.Errorf("Network took %d steps; expected %d", n.Steps(), 20*50)
+	}
+
+	// Stopping early.
+	tr.Callbacks = []Callback{CallbackFuncs{
+		EpochEnd: func(t *Trainer, epoch int, loss float64) error {
+			if epoch == 1 {
+				return ErrStopTraining
+			}
+			return nil
+		},
+	}}
+	steps := n.Steps()
+	if _, err := tr.Fit(5); err != nil {
+		t.Errorf("Fit returned an error after stopping: %v", err)
+	}
+	if n.Steps()-steps != 2*50 {
+		t.Errorf("Network took %d steps; expected %d", n.Steps()-steps, 2*50)
+	}
+
+	// Other errors are returned.
+	errFail := errors.New("fail")
+	tr.Callbacks = []Callback{CallbackFuncs{
+		BatchEnd: func(t *Trainer, batch int, loss float64) error { return errFail },
+	}}
+	if _, err := tr.Fit(1); err != errFail {
+		t.Errorf("Fit returned %v; expected %v", err, errFail)
+	}
+}
+
+// Test periodic validation with accuracy.
+func TestTrainerValidation(t *testing.T) {
+	rand.Seed(17)
+	// Two Gaussian classes labeled +/- 1.
+	classData := func(n int) *data.VectorDataset {
+		ds := &data.VectorDataset{}
+		for ii := 0; ii < n; ii++ {
+			y := float64(2*(ii%2) - 1)
+			ds.Inputs = append(ds.Inputs, []float64{rand.NormFloat64() + 2*y,
+				rand.NormFloat64()})
+			ds.Targets = append(ds.Targets, []float64{y})
+		}
+		return ds
+	}
+	n := MustNewMLP([]int{2, 4, 1}, NewSGD(0.05, 0.0, 0.0), WithInitializer(HeNormal{}))
+	defer n.Stop()
+	loss, _ := GetLoss("margin")
+	var evals []Evaluation
+	cm := &ConfusionMatrix{}
+	tr := &Trainer{Net: n, Loss: loss, Data: classData(40), BatchSize: 4,
+		Validation: classData(20), ValidateEvery: 10, Accuracy: true,
+		Metrics: []Metric{cm, &F1{}},
+		Callbacks: []Callback{CallbackFuncs{
+			Validation: func(t *Trainer, e Evaluation) error {
+				evals = append(evals, e)
+				return nil
+			},
+		}}}
+	before := n.Data()
+	n.Start(false, 0)
+	e, err := tr.Evaluate(tr.Validation)
+	if err != nil {
+		t.Fatalf("Evaluate returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(n.Data(), before) {
+		t.Errorf("Evaluate changed the weights")
+	}
+	if _, err := tr.Fit(3); err != nil {
+		t.Fatalf("Fit returned an error: %v", err)
+	}
+	if len(evals) != 12 {
+		t.Fatalf("Got %d validations; expected 12", len(evals))
+	}
+	last := evals[len(evals)-1]
+	if last.Step != 120 || !reflect.DeepEqual(tr.LastValidation, last) {
+		t.Errorf("Last validation is %+v; expected step 120", last)
+	}
+	if last.Loss >= e.Loss || last.Accuracy < 0.9 {
+		t.Errorf("Validation went from %+v to %+v", e, last)
+	}
+	if last.Metrics["accuracy"] != last.Accuracy || last.Metrics["f1"] < 0.8 {
+		t.Errorf("Validation metrics are %v; expected accuracy %.3f", last.Metrics,
+			last.Accuracy)
+	}
+	if total := cm.Count(-1, -1) + cm.Count(-1, 1) + cm.Count(1, -1) + cm.Count(1, 1); total != 20 {
+		t.Errorf("Confusion matrix has %d samples; expected 20\n%v", total, cm)
+	}
+}
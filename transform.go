@@ -0,0 +1,80 @@
+package neuron
+
+import (
+	"math/rand"
+)
+
+// A Transform augments the input of a training sample, e.g. by adding noise,
+// to regularize training on small datasets. Apply returns the augmented
+// input, drawing from rng, and must not modify x, which may be shared with
+// the dataset. Transforms are applied per sample by a DataLoader, see
+// DataLoader.Transform.
+type Transform interface {
+	Apply(x []float64, rng *rand.Rand) []float64
+}
+
+// TransformFunc adapts a function to a Transform.
+type TransformFunc func(x []float64, rng *rand.Rand) []float64
+
+// Apply calls f.
+func (f TransformFunc) Apply(x []float64, rng *rand.Rand) []float64 {
+	return f(x, rng)
+}
+
+// Compose chains transforms, applying them in order.
+func Compose(ts ...Transform) Transform {
+	return TransformFunc(func(x []float64, rng *rand.Rand) []float64 {
+		for _, t := range ts {
+			x = t.Apply(x, rng)
+		}
+		return x
+	})
+}
+
+// GaussianNoise adds independent Gaussian noise with standard deviation Std to
+// each feature.
+type GaussianNoise struct {
+	Std float64
+}
+
+// Apply adds the noise.
+func (t GaussianNoise) Apply(x []float64, rng *rand.Rand) []float64 {
+	out := make([]float64, len(x))
+	for ii, v := range x {
+		out[ii] = v + t.Std*rng.NormFloat64()
+	}
+	return out
+}
+
+// RandomScale multiplies the whole input by a factor drawn uniformly from
+// [Min, Max].
+type RandomScale struct {
+	Min, Max float64
+}
+
+// Apply scales the input.
+func (t RandomScale) Apply(x []float64, rng *rand.Rand) []float64 {
+	scale := t.Min + (t.Max-t.Min)*rng.Float64()
+	out := make([]float64, len(x))
+	for ii, v := range x {
+		out[ii] = scale * v
+	}
+	return out
+}
+
+// FeatureDropout zeroes each feature with probability P, and scales the
+// others by 1 / (1 - P) so the expected input is unchanged, like dropout.
+type FeatureDropout struct {
+	P float64
+}
+
+// Apply drops the features.
+func (t FeatureDropout) Apply(x []float64, rng *rand.Rand) []float64 {
+	out := make([]float64, len(x))
+	for ii, v := range x {
+		if rng.Float64() >= t.P {
+			out[ii] = v / (1.0 - t.P)
+		}
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package neuron
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// Test the augmentation transforms.
+func TestTransforms(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	x := make([]float64, 10000)
+	for ii := range x {
+		x[ii] = 1.0
+	}
+
+	noisy := GaussianNoise{Std: 0.5}.Apply(x, rng)
+	mean, sq := 0.0, 0.0
+	for _, v := range noisy {
+		mean += v / float64(len(x))
+		sq += (v - 1.0) * (v - 1.0) / float64(len(x))
+	}
+	if math.Abs(mean-1.0) > 0.05 || math.Abs(math.Sqrt(sq)-0.5) > 0.05 {
+		t.Errorf("Noise has mean %.3f and std %.3f; expected 1 and 0.5", mean,
+			math.Sqrt(sq))
+	}
+	if x[0] != 1.0 {
+		t.Errorf("Transform modified its input")
+	}
+
+	scaled := RandomScale{Min: 2.0, Max: 3.0}.Apply(x, rng)
+	if scaled[0] < 2.0 || scaled[0] > 3.0 || scaled[1] != scaled[0] {
+		t.Errorf("Scaled input to %v", scaled[:2])
+	}
+
+	dropped := FeatureDropout{P: 0.25}.Apply(x, rng)
+	zeros, sum := 0, 0.0
+	for _, v := range dropped {
+		if v == 0.0 {
+			zeros++
+		}
+		sum += v
+	}
+	if frac := float64(zeros) / float64(len(x)); math.Abs(frac-0.25) > 0.02 ||
+		math.Abs(sum/float64(len(x))-1.0) > 0.05 {
+		t.Errorf("Dropped %.3f of features with mean %.3f; expected 0.25 and 1",
+			frac, sum/float64(len(x)))
+	}
+
+	c := Compose(RandomScale{Min: 2.0, Max: 2.0}, TransformFunc(
+		func(x []float64, rng *rand.Rand) []float64 {
+			return append([]float64{}, x[0]+1.0)
+		}))
+	if got := c.Apply(x, rng); !reflect.DeepEqual(got, []float64{3.0}) {
+		t.Errorf("Composed transform returned %v; expected [3]", got)
+	}
+}
+
+// Test that data loader augmentations don't depend on the number of workers.
+func TestDataLoaderTransform(t *testing.T) {
+	ds := linearData(12)
+	epoch := func(workers int) []Batch {
+		l := &DataLoader{Data: ds, BatchSize: 2, Rand: rand.New(rand.NewSource(1)),
+			Workers: workers, Transform: GaussianNoise{Std: 1.0}}
+		var batches []Batch
+		for b := range l.Batches(context.Background()) {
+			batches = append(batches, b)
+		}
+		return batches
+	}
+	want := epoch(1)
+	if got := epoch(3); !reflect.DeepEqual(got, want) {
+		t.Errorf("Augmented batches with 3 workers differ from 1 worker")
+	}
+	x, _ := ds.Get(0)
+	if reflect.DeepEqual(want[0].Inputs[0], x) {
+		t.Errorf("Sample wasn't augmented")
+	}
+}
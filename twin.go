@@ -0,0 +1,75 @@
+package neuron
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NewTwin creates a twin of n that shares all of n's trainable parameters,
+// e.g. the second branch of a Siamese network trained with ContrastiveLoss or
+// TripletLoss. Like LoadNet, the twin is constructed with NewMLP(n.Arch,
+// opt, opts...), where opt is n's optimizer, and opts should match the
+// options of n.
+//
+// The shared parameters are owned by n, as with WithTiedWeights: the twin
+// accumulates its gradients separately, and n adds them to its own before
+// each update, so the twin never updates the parameters itself. Both networks
+// run their own units, and are started, stopped, and fed separately. To
+// accumulate the gradients of both branches before stepping, back-propagate
+// through the twin before n, from the same goroutine:
+//
+//	a, _ := n.Forward(x1)
+//	b, _ := twin.Forward(x2)
+//	_, ga, gb, _ := ContrastiveLoss(a, b, similar, 1.0)
+//	twin.Backward(gb)
+//	n.Backward(ga)
+//
+// A twin of a twin shares the parameters of n too. Twins don't support weight
+// normalization, Bayesian weights, or activation parameters, and don't run on
+// Dense.
+func NewTwin(n *Net, opts ...Option) (*Net, error) {
+	t, err := NewMLP(n.Arch, n.opt, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for ii, l := range n.Layers {
+		for jj, u := range l {
+			if err := shareParams(u, t.Layers[ii][jj]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	t.Scaler = n.Scaler
+	return t, nil
+}
+
+// shareParams ties the parameters of twin unit tu to the parameters of u.
+func shareParams(u, tu *Unit) error {
+	if len(tu.W.Params) != len(u.W.Params) {
+		return fmt.Errorf("unit %s has %d parameters in its twin; expected %d",
+			u.ID, len(tu.W.Params), len(u.W.Params))
+	}
+	for id, p := range u.W.Params {
+		tp, ok := tu.W.Params[id]
+		if !ok {
+			return fmt.Errorf("parameter %s/%s not in twin", u.ID, id)
+		}
+		if id == gainID || isLogVar(id) || isActivParam(id) {
+			if p.RequiresGrad {
+				return errors.New("twins don't support weight normalization, " +
+					"Bayesian weights, or activation parameters")
+			}
+			continue
+		}
+		// Tied copies share the owner's value, so the twin shares the owner
+		// too. Ties within the twin are replaced.
+		owner := p
+		if p.tied != nil {
+			owner = p.tied
+		}
+		tp.Data, tp.masked, tp.ties = owner.Data, p.masked, nil
+		tp.tied = owner
+		owner.ties = append(owner.ties, tp)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test that a twin shares its parameters, and that the gradients of both
+// branches are accumulated before stepping.
+func TestTwin(t *testing.T) {
+	a := []float64{0.5, -1.0, 0.3}
+	b := []float64{-0.2, 0.4, 0.9}
+	ga := []float64{1.0, -0.5}
+	gb := []float64{-0.3, 0.8}
+	newNet := func(opts ...Option) *Net {
+		rand.Seed(23)
+		return MustNewMLP([]int{3, 4, 2}, NewSGD(0.1, 0.0, 0.0), opts...)
+	}
+
+	for _, layers := range []bool{false, true} {
+		var opts []Option
+		if layers {
+			opts = append(opts, WithLayerEngine())
+		}
+
+		// Reference: both branches as a batch of one network, updated once.
+		ref := newNet(opts...)
+		ref.Start(true, 2)
+		ref.ForwardBatch([][]float64{a, b})
+		if err := ref.BackwardBatch([][]float64{ga, gb}); err != nil {
+			t.Fatal(err)
+		}
+		ref.Stop()
+
+		n := newNet(opts...)
+		twin, err := NewTwin(n, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.Start(true, 1)
+		twin.Start(true, 1)
+		outA := n.MustForward(a)
+		if out := twin.MustForward(a); !almostEqual(out[0], outA[0]) || !almostEqual(out[1], outA[1]) {
+			t.Errorf("(layers=%v) Twin output %v; expected %v", layers, out, outA)
+		}
+		twin.MustBackward(make([]float64, len(gb)))
+		n.MustBackward(make([]float64, len(ga)))
+
+		n.MustForward(a)
+		twin.MustForward(b)
+		twin.MustBackward(gb)
+		n.MustBackward(ga)
+		theta, thetaR := n.Data(), ref.Data()
+		for uid, ud := range thetaR {
+			for id, v := range ud {
+				if !almostEqual(theta[uid][id], v) {
+					t.Errorf("(layers=%v) Param %s/%s is %.6f; expected %.6f", layers,
+						uid, id, theta[uid][id], v)
+				}
+			}
+		}
+		// The twin picks up the new values in its next pass.
+		outB := n.MustForward(b)
+		if out := twin.MustForward(b); !almostEqual(out[0], outB[0]) || !almostEqual(out[1], outB[1]) {
+			t.Errorf("(layers=%v) Updated twin output %v; expected %v", layers, out,
+				outB)
+		}
+		twin.MustBackward(make([]float64, len(gb)))
+		n.MustBackward(make([]float64, len(ga)))
+		n.Stop()
+		twin.Stop()
+	}
+
+	if _, err := NewTwin(newNet(WithWeightNorm())); err == nil {
+		t.Error("Twin of a weight normalized network didn't fail")
+	}
+	if _, err := NewTwin(newNet(), WithWeightNorm()); err == nil {
+		t.Error("Twin with mismatched options didn't fail")
+	}
+}
+
+// Test training a Siamese network on pairs with a twin.
+func TestTwinContrastive(t *testing.T) {
+	rand.Seed(29)
+	n := MustNewMLP([]int{2, 4, 2}, NewSGD(0.05, 0.0, 0.0))
+	twin, err := NewTwin(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Start(true, 1)
+	twin.Start(true, 1)
+	defer n.Stop()
+	defer twin.Stop()
+
+	pairs := []struct {
+		x1, x2  []float64
+		similar bool
+	}{
+		{[]float64{1.0, 0.0}, []float64{0.9, 0.1}, true},
+		{[]float64{1.0, 0.0}, []float64{0.0, 1.0}, false},
+	}
+	var first, loss float64
+	for ii := 0; ii < 50; ii++ {
+		loss = 0.0
+		for _, p := range pairs {
+			a := n.MustForward(p.x1)
+			b := twin.MustForward(p.x2)
+			l, ga, gb, _ := ContrastiveLoss(a, b, p.similar, 2.0)
+			twin.MustBackward(gb)
+			n.MustBackward(ga)
+			loss += l
+		}
+		if ii == 0 {
+			first = loss
+		}
+	}
+	if loss >= first {
+		t.Errorf("Contrastive loss didn't decrease: %.4f -> %.4f", first, loss)
+	}
+}
@@ -21,3 +21,8 @@ func almostEqual(a, b float64) bool {
 	const tol = 1.0e-06
 	return math.Abs(a-b)/math.Abs(b) < tol
 }
+
+// Test whether two values are equal up to a given relative tolerance.
+func almostEqualTol(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol*math.Max(math.Abs(b), 1.0e-08)
+}
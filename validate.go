@@ -0,0 +1,178 @@
+package neuron
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A TopologyError reports the problems found when validating a network's
+// connections.
+type TopologyError struct {
+	Problems []string
+}
+
+func (e *TopologyError) Error() string {
+	return fmt.Sprintf("invalid topology (%d problems): %s", len(e.Problems),
+		strings.Join(e.Problems, "; "))
+}
+
+// Validate checks the network's connections for problems that would deadlock
+// or corrupt a pass at runtime: cycles, units unreachable from the input,
+// fan-in counts that don't match the incoming weights, and forward edges
+// without matching backward edges (or vice versa). It returns a
+// *TopologyError listing every problem found, or nil. Validate should be
+// called before Start.
+func (n *Net) Validate() error {
+	var problems []string
+	report := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	units := make(map[string]*Unit)
+	var order []*Unit
+	for _, l := range n.Layers {
+		for _, u := range l {
+			if _, ok := units[u.ID]; ok {
+				report("duplicate unit ID %s", u.ID)
+				continue
+			}
+			units[u.ID] = u
+			order = append(order, u)
+		}
+	}
+
+	for _, u := range order {
+		// Forward edges need a weight and a backward edge.
+		for _, k := range sortedKeys(u.output) {
+			if k == outputID {
+				continue
+			}
+			u2, ok := units[k]
+			if !ok {
+				report("%s -> %s: unknown unit", u.ID, k)
+				continue
+			}
+			if _, ok := u2.W.Params[u.ID]; !ok {
+				report("%s -> %s: missing weight", u.ID, k)
+			}
+			if _, ok := u2.outputB[u.ID]; !ok {
+				report("%s -> %s: missing backward edge", u.ID, k)
+			}
+		}
+
+		// Backward edges need a forward edge.
+		for _, k := range sortedKeys(u.outputB) {
+			u1, ok := units[k]
+			if !ok {
+				report("%s <- %s: unknown unit", k, u.ID)
+				continue
+			}
+			if _, ok := u1.output[u.ID]; !ok {
+				report("%s <- %s: backward edge without forward edge", k, u.ID)
+			}
+		}
+
+		// Fan-in should match the weights on incoming connections.
+		fanIn := 0
+		for k := range u.W.Params {
+			if k == inputID {
+				fanIn++
+			} else if u1, ok := units[k]; ok {
+				if _, ok := u1.output[u.ID]; ok {
+					fanIn++
+				} else {
+					report("%s: weight %s has no incoming connection", u.ID, k)
+				}
+			}
+		}
+		if fanIn != u.nin {
+			report("%s: fan-in is %d but has %d incoming connections", u.ID,
+				u.nin, fanIn)
+		}
+	}
+
+	for _, id := range findCycles(order, units) {
+		report("cycle through %s", id)
+	}
+
+	reached := reachable(order, units)
+	for _, u := range order {
+		if !reached[u.ID] {
+			report("%s: unreachable from input", u.ID)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &TopologyError{Problems: problems}
+	}
+	return nil
+}
+
+// findCycles runs a depth-first search over the forward edges and returns the
+// ID of one unit on each cycle found.
+func findCycles(order []*Unit, units map[string]*Unit) (cycles []string) {
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make(map[string]int)
+	var visit func(u *Unit)
+	visit = func(u *Unit) {
+		state[u.ID] = active
+		for _, k := range sortedKeys(u.output) {
+			u2, ok := units[k]
+			if !ok {
+				continue
+			}
+			switch state[k] {
+			case unvisited:
+				visit(u2)
+			case active:
+				cycles = append(cycles, k)
+			}
+		}
+		state[u.ID] = done
+	}
+	for _, u := range order {
+		if state[u.ID] == unvisited {
+			visit(u)
+		}
+	}
+	return
+}
+
+// reachable returns the set of units reachable from the input units along
+// forward edges.
+func reachable(order []*Unit, units map[string]*Unit) map[string]bool {
+	reached := make(map[string]bool)
+	var queue []*Unit
+	for _, u := range order {
+		if _, ok := u.W.Params[inputID]; ok {
+			reached[u.ID] = true
+			queue = append(queue, u)
+		}
+	}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for k := range u.output {
+			if u2, ok := units[k]; ok && !reached[k] {
+				reached[k] = true
+				queue = append(queue, u2)
+			}
+		}
+	}
+	return reached
+}
+
+// sortedKeys returns the keys of a channel map in sorted order.
+func sortedKeys(m map[string](chan signal)) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,50 @@
+package neuron
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test topology validation.
+func TestValidate(t *testing.T) {
+	arch := []int{2, 3, 2, 1}
+	opt := NewSGD(1.0, 0.0, 0.0)
+
+	n := MustNewMLP(arch, opt)
+	if err := n.Validate(); err != nil {
+		t.Fatalf("Valid MLP failed validation: %v", err)
+	}
+
+	expectProblem := func(n *Net, want string) {
+		err := n.Validate()
+		if err == nil {
+			t.Errorf("Expected validation problem %q", want)
+			return
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validation error %q does not contain %q", err, want)
+		}
+	}
+
+	// Cycle.
+	n = MustNewMLP(arch, opt)
+	n.Layers[2][0].connect(n.Layers[1][0], globalRand)
+	expectProblem(n, "cycle through 001_000000")
+
+	// Missing backward edge.
+	n = MustNewMLP(arch, opt)
+	delete(n.Layers[2][1].outputB, "001_000002")
+	expectProblem(n, "001_000002 -> 002_000001: missing backward edge")
+
+	// Fan-in mismatch.
+	n = MustNewMLP(arch, opt)
+	n.Layers[3][0].nin++
+	expectProblem(n, "003_000000: fan-in is 3")
+
+	// Unreachable unit.
+	n = MustNewMLP(arch, opt)
+	u := newHiddenUnit("002_000002", opt.New())
+	u.connect(n.Layers[3][0], globalRand)
+	n.Layers[2] = append(n.Layers[2], u)
+	expectProblem(n, "002_000002: unreachable from input")
+}
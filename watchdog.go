@@ -0,0 +1,159 @@
+package neuron
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WithWatchdog sets a timeout for every pass through the network. If a call
+// to Forward or Backward (or ForwardSequence or BackwardSequence) doesn't
+// complete within timeout, e.g. because Backward was called without a
+// matching Forward, the pass is aborted with a *DeadlockError reporting where
+// the network and each unit are blocked, instead of blocking forever. Like an
+// aborted ForwardContext, the network is then stopped and has to be started
+// again before it's reused.
+//
+// While the watchdog is enabled, units keep track of the channel they're
+// blocked on, which adds a little overhead to every signal.
+func WithWatchdog(timeout time.Duration) Option {
+	return func(c *netConfig) {
+		c.watchdog = timeout
+	}
+}
+
+// A BlockedUnit describes a unit blocked on a channel.
+type BlockedUnit struct {
+	ID string
+	// What the unit is waiting for, e.g. "receive on input" or
+	// "send to 002_000001".
+	Op string
+	// How long the unit has been blocked.
+	For time.Duration
+}
+
+func (b BlockedUnit) String() string {
+	return fmt.Sprintf("%s: %s (%v)", b.ID, b.Op, b.For.Round(time.Millisecond))
+}
+
+// A DeadlockError reports a pass that didn't complete within the watchdog
+// timeout.
+type DeadlockError struct {
+	// The pass that timed out, "forward" or "backward".
+	Pass    string
+	Timeout time.Duration
+	// What the network was waiting for when the pass timed out.
+	Net string
+	// The units that were blocked, in layer order.
+	Blocked []BlockedUnit
+}
+
+// maxBlockedReport is the number of blocked units listed by
+// DeadlockError.Error.
+const maxBlockedReport = 5
+
+func (e *DeadlockError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s pass did not complete within %v: net blocked %s",
+		e.Pass, e.Timeout, e.Net)
+	if len(e.Blocked) == 0 {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "; %d units blocked: ", len(e.Blocked))
+	for ii, u := range e.Blocked {
+		if ii == maxBlockedReport {
+			fmt.Fprintf(&b, ", ... (%d more)", len(e.Blocked)-ii)
+			break
+		}
+		if ii > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(u.String())
+	}
+	return b.String()
+}
+
+// Operations a unit can be blocked on.
+const (
+	waitNone = iota
+	waitRecv
+	waitSend
+	waitDone
+	waitIdle
+)
+
+// waiting records the operation the unit is about to block on, if the
+// watchdog is enabled. c is the channel of a send or receive.
+func (u *Unit) waiting(op int, c chan signal) {
+	if !u.watch {
+		return
+	}
+	u.waitMu.Lock()
+	u.waitOp, u.waitCh = op, c
+	if op != waitNone {
+		u.waitSince = time.Now()
+	}
+	u.waitMu.Unlock()
+}
+
+// blocked reports what the unit is blocked on. ok is false if it isn't
+// blocked.
+func (u *Unit) blocked() (b BlockedUnit, ok bool) {
+	u.waitMu.Lock()
+	defer u.waitMu.Unlock()
+	if u.waitOp == waitNone {
+		return b, false
+	}
+	b = BlockedUnit{ID: u.ID, For: time.Since(u.waitSince)}
+	switch u.waitOp {
+	case waitRecv:
+		b.Op = "receive on input"
+		if u.waitCh == u.inputB {
+			b.Op = "receive on gradient input"
+		}
+	case waitSend:
+		b.Op = "send"
+		for k, c := range u.output {
+			if c == u.waitCh {
+				b.Op = "send to " + k
+			}
+		}
+		for k, c := range u.outputB {
+			if c == u.waitCh {
+				b.Op = "send gradient to " + k
+			}
+		}
+	case waitDone:
+		b.Op = "send step done"
+	case waitIdle:
+		b.Op = "receive on input or gradient input"
+	}
+	return b, true
+}
+
+// watch starts the watchdog timer for a pass. The returned channel is closed
+// when the timer fires, and stop should be called when the pass completes. If
+// the watchdog is disabled, the channel is nil.
+func (n *Net) watch() (done <-chan struct{}, stop func()) {
+	if n.watchdog <= 0 {
+		return nil, func() {}
+	}
+	c := make(chan struct{})
+	t := time.AfterFunc(n.watchdog, func() { close(c) })
+	return c, func() { t.Stop() }
+}
+
+// deadlock builds the error for a pass aborted by the watchdog, and stops the
+// network.
+func (n *Net) deadlock(pass string) error {
+	err := &DeadlockError{Pass: pass, Timeout: n.watchdog, Net: n.stuck}
+	for _, l := range n.Layers {
+		for _, u := range l {
+			if b, ok := u.blocked(); ok {
+				err.Blocked = append(err.Blocked, b)
+			}
+		}
+	}
+	n.Stop()
+	return err
+}
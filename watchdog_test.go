@@ -0,0 +1,57 @@
+package neuron
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that the watchdog reports a backward pass without a forward pass.
+func TestWatchdog(t *testing.T) {
+	n := MustNewMLP([]int{2, 2, 1}, NewSGD(1e-2, 0.0, 0.0),
+		WithWatchdog(50*time.Millisecond))
+	n.Start(true, 1)
+	defer n.Stop()
+
+	err := n.Backward([]float64{1.0})
+	var derr *DeadlockError
+	if !errors.As(err, &derr) {
+		t.Fatalf("Backward without forward returned %v; expected a DeadlockError", err)
+	}
+	if derr.Pass != "backward" || derr.Net != "sending gradient to 002_000000" {
+		t.Errorf("Deadlock reported as %q, %q", derr.Pass, derr.Net)
+	}
+	if len(derr.Blocked) != 5 {
+		t.Fatalf("%d blocked units; expected 5", len(derr.Blocked))
+	}
+	for _, b := range derr.Blocked {
+		if b.Op != "receive on input" {
+			t.Errorf("Unit %s blocked on %q; expected receive on input", b.ID, b.Op)
+		}
+	}
+	if msg := err.Error(); !strings.Contains(msg, "002_000000: receive on input") {
+		t.Errorf("Error %q doesn't report the output unit", msg)
+	}
+
+	// The net was stopped and can be started again.
+	n.Start(true, 1)
+	if _, err := n.Forward([]float64{1.0, -1.0}); err != nil {
+		t.Fatalf("Forward failed after restart: %v", err)
+	}
+	if err := n.Backward([]float64{1.0}); err != nil {
+		t.Fatalf("Backward failed after restart: %v", err)
+	}
+
+	// A second forward pass without a backward pass is reported too.
+	n.MustForward([]float64{1.0, -1.0})
+	_, err = n.Forward([]float64{1.0, -1.0})
+	if !errors.As(err, &derr) || derr.Pass != "forward" {
+		t.Fatalf("Repeated Forward returned %v; expected a DeadlockError", err)
+	}
+	for _, b := range derr.Blocked {
+		if b.ID == "001_000000" && b.Op != "receive on gradient input" {
+			t.Errorf("Unit %s blocked on %q; expected receive on gradient input", b.ID, b.Op)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package neuron
+
+import (
+	"math"
+)
+
+// normalize enables weight normalization. The effective connection weights
+// become
+//
+//	w = g * v / ||v||
+//
+// where v are the connection Params and g is a separate trainable gain Param.
+// The gain is initialized to ||v|| so the effective weights are unchanged.
+func (w *Weight) normalize() {
+	w.norm = true
+	w.dw = make(map[string]float64)
+	w.init(gainID, w.connNorm(), true)
+}
+
+// connNorm computes the norm of the unmasked connection weights.
+func (w *Weight) connNorm() float64 {
+	r := 0.0
+	for k, p := range w.Params {
+		if isConn(k) && !p.masked {
+			r += p.Data * p.Data
+		}
+	}
+	return math.Sqrt(r)
+}
+
+// prepareNorm computes the weight normalization scale g / ||v||.
+func (w *Weight) prepareNorm() {
+	w.r = w.connNorm()
+	if w.r > 0 {
+		w.scale = w.Params[gainID].Data / w.r
+	} else {
+		w.scale = 0.0
+	}
+}
+
+// finishNorm accumulates the gain and direction gradients from the
+// effective weight gradients d collected during backward:
+//
+//	dg = d . v / ||v||
+//	dv = (g / ||v||) * (d - dg * v / ||v||)
+func (w *Weight) finishNorm() {
+	if w.r == 0 {
+		return
+	}
+	gain := w.Params[gainID]
+	dg := 0.0
+	for k, d := range w.dw {
+		dg += d * w.Params[k].Data
+	}
+	dg /= w.r
+
+	if gain.trainable() {
+		gain.grad += dg
+	}
+	for k, d := range w.dw {
+		p := w.Params[k]
+		p.grad += w.scale * (d - dg*p.Data/w.r)
+		w.dw[k] = 0.0
+	}
+}
@@ -0,0 +1,64 @@
+package neuron
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test that weight normalization preserves the initial function and computes
+// correct gradients.
+func TestWeightNorm(t *testing.T) {
+	arch := []int{3, 4, 2}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	data := []float64{1.0, -0.5, 2.0}
+
+	rand.Seed(12)
+	n := MustNewMLP(arch, opt)
+	rand.Seed(12)
+	nn := MustNewMLP(arch, opt, WithWeightNorm())
+	if _, ok := nn.Layers[1][0].W.Params[gainID]; !ok {
+		t.Fatalf("Missing gain param")
+	}
+
+	n.Start(false, 0)
+	nn.Start(true, 0)
+	output := n.MustForward(data)
+	outputNorm := nn.MustForward(data)
+	for ii := range output {
+		if !almostEqual(outputNorm[ii], output[ii]) {
+			t.Errorf("Normalized output %d is %.6e; expected %.6e", ii,
+				outputNorm[ii], output[ii])
+		}
+	}
+
+	// Compare analytic gradients of the sum of outputs against central
+	// differences.
+	lossGrad := []float64{1.0, 1.0}
+	nn.MustBackward(lossGrad)
+	grads := nn.Grads()
+	theta := nn.Data()
+
+	const eps = 1.0e-06
+	zeros := []float64{0.0, 0.0}
+	loss := func() float64 {
+		out := nn.MustForward(data)
+		nn.MustBackward(zeros)
+		return out[0] + out[1]
+	}
+	for _, uid := range []string{"001_000002", "002_000001"} {
+		for id := range grads[uid] {
+			v := theta[uid][id]
+			nn.SetData(ParamVector{uid: {id: v + eps}})
+			lossPlus := loss()
+			nn.SetData(ParamVector{uid: {id: v - eps}})
+			lossMinus := loss()
+			nn.SetData(ParamVector{uid: {id: v}})
+
+			numeric := (lossPlus - lossMinus) / (2 * eps)
+			if !almostEqualTol(grads[uid][id], numeric, 1.0e-04) {
+				t.Errorf("Grad[%s][%s] is %.6e; expected %.6e", uid, id,
+					grads[uid][id], numeric)
+			}
+		}
+	}
+}
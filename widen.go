@@ -0,0 +1,174 @@
+package neuron
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+)
+
+// WidenLayer grows a hidden layer to newSize units while preserving the
+// function computed by the network (Net2Net). Each new unit is a copy of a
+// randomly chosen existing unit, with the same incoming weights and bias. The
+// outgoing weights of each copied unit are split between it and its copies
+// so that the downstream units receive the same total input. Splits are
+// random rather than even, so that the copies don't stay in lockstep during
+// fine-tuning. Copies of frozen units are frozen too.
+//
+// Like AddUnit, WidenLayer can be called while the network is running: it
+// waits for the pass in progress, and restarts the network with the new
+// units. Layers with tied weights can't be widened, since the copies would
+// break the ties.
+func (n *Net) WidenLayer(layer, newSize int) error {
+	if layer < 1 || layer >= len(n.Layers)-1 {
+		return fmt.Errorf("can only widen hidden layers; got layer %d", layer)
+	}
+	if n.hasSkip(layer) {
+		return fmt.Errorf("can't widen layer %d with skip connections", layer)
+	}
+	if n.Layers[layer][0].W.bayes {
+		return fmt.Errorf("can't widen Bayesian layer %d", layer)
+	}
+	if gatedLayer(n.Layers[layer]) || gatedLayer(n.Layers[layer+1]) {
+		return fmt.Errorf("can't widen layer %d with gated units", layer)
+	}
+	if attentionLayer(n.Layers[layer]) || attentionLayer(n.Layers[layer+1]) {
+		return fmt.Errorf("can't widen layer %d with attention units", layer)
+	}
+	_, conv := n.convs[layer]
+	if _, next := n.convs[layer+1]; conv || next {
+		return fmt.Errorf("can't widen layer %d of or into a convolution", layer)
+	}
+	if tiedLayer(n.Layers[layer]) || tiedLayer(n.Layers[layer+1]) {
+		return fmt.Errorf("can't widen layer %d with tied weights", layer)
+	}
+	oldSize := len(n.Layers[layer])
+	if newSize < oldSize {
+		return fmt.Errorf("new size (%d) smaller than current size (%d)", newSize,
+			oldSize)
+	}
+	n.modify(func() {
+		n.widen(layer, newSize)
+	})
+	return nil
+}
+
+// widen grows layer to newSize units, see WidenLayer. It must be called while
+// the network is stopped.
+func (n *Net) widen(layer, newSize int) {
+	oldSize := len(n.Layers[layer])
+	logf(1, "Widening layer %d from %d to %d units\n", layer, oldSize, newSize)
+
+	// Pick a source for each new unit, and group the replicas of each source.
+	l := n.Layers[layer]
+	groups := make([][]*Unit, oldSize)
+	for ii, u := range l {
+		groups[ii] = []*Unit{u}
+	}
+	for jj := oldSize; jj < newSize; jj++ {
+		src := n.rand().Intn(oldSize)
+		u := n.replicate(l[src], unitID(layer, jj), n.Layers[layer-1])
+		groups[src] = append(groups[src], u)
+		l = append(l, u)
+	}
+
+	// Connect the replicas downstream and split the outgoing weights.
+	next := n.Layers[layer+1]
+	oldNorms := make([]float64, len(next))
+	for ii, u2 := range next {
+		oldNorms[ii] = u2.W.connNorm()
+	}
+	for _, g := range groups {
+		if len(g) == 1 {
+			continue
+		}
+		split := randomSplit(n.rand(), len(g))
+		for _, u2 := range next {
+			if _, ok := u2.W.Params[g[0].ID]; !ok {
+				continue
+			}
+			w := u2.W.Params[g[0].ID].Data
+			for ii, u := range g {
+				if ii > 0 {
+					u.connect(u2, n.rand())
+					u2.W.Params[u.ID].frozen = u2.W.Params[g[0].ID].frozen
+				}
+				u2.W.Params[u.ID].Data = split[ii] * w
+			}
+		}
+	}
+
+	// Weight normalized units need their gain rescaled to keep the same
+	// effective weights.
+	for ii, u2 := range next {
+		if u2.W.norm && oldNorms[ii] > 0 {
+			u2.W.Params[gainID].Data *= u2.W.connNorm() / oldNorms[ii]
+		}
+	}
+
+	n.Layers[layer] = l
+	n.Arch[layer] = newSize
+	n.sched = schedStates(n.Layers)
+}
+
+// replicate creates a copy of unit u with a new ID, connected to the units in
+// prev that u is connected to, with the same incoming weights.
+func (n *Net) replicate(u *Unit, id string, prev []*Unit) *Unit {
+	r := NewUnit(id, cloneActivation(u.activ), u.opt.New())
+	r.stepDone = n.stepDone
+	r.clipValue, r.netStep = u.clipValue, u.netStep
+	r.steps = n.steps
+	r.noise, r.W.noise = u.noise, u.W.noise
+	for _, p := range prev {
+		if _, ok := u.W.Params[p.ID]; ok {
+			p.connect(r, n.rand())
+		}
+	}
+	for k, p := range u.W.Params {
+		rp, ok := r.W.Params[k]
+		if !ok {
+			r.W.init(k, p.Data, p.RequiresGrad)
+			rp = r.W.Params[k]
+		}
+		rp.Data = p.Data
+		rp.masked = p.masked
+	}
+	if u.W.norm {
+		r.W.norm = true
+		r.W.dw = make(map[string]float64)
+	}
+	n.seedUnit(r)
+	if u.W.qbits > 0 {
+		r.W.quantize(u.W.qbits)
+	}
+	r.setFrozen(u.Frozen())
+	return r
+}
+
+// randomSplit draws n positive proportions summing to 1.
+func randomSplit(rng *rand.Rand, n int) []float64 {
+	split := make([]float64, n)
+	sum := 0.0
+	for ii := range split {
+		split[ii] = randUnif(rng, 0.5, 1.5)
+		sum += split[ii]
+	}
+	for ii := range split {
+		split[ii] /= sum
+	}
+	return split
+}
+
+// cloneActivation creates a new activation of the same type and settings as
+// a, so that the clone doesn't share any cached state.
+func cloneActivation(a Activation) Activation {
+	if c, ok := a.(interface{ clone() Activation }); ok {
+		return c.clone()
+	}
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Ptr {
+		return a
+	}
+	c := reflect.New(v.Elem().Type())
+	c.Elem().Set(v.Elem())
+	return c.Interface().(Activation)
+}
@@ -0,0 +1,164 @@
+package neuron
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// Test function-preserving layer widening.
+func TestWidenLayer(t *testing.T) {
+	arch := []int{3, 4, 3, 2}
+	opt := NewSGD(1.0, 0.0, 0.0)
+	data := []float64{1.0, -0.5, 2.0}
+
+	for _, opts := range [][]Option{nil, {WithWeightNorm()}, {WithFanIn(2)}} {
+		rand.Seed(12)
+		n := MustNewMLP(arch, opt, opts...)
+		rand.Seed(12)
+		wide := MustNewMLP(arch, opt, opts...)
+		if err := wide.WidenLayer(1, 7); err != nil {
+			t.Fatal(err)
+		}
+		if err := wide.WidenLayer(2, 5); err != nil {
+			t.Fatal(err)
+		}
+		if len(wide.Layers[1]) != 7 || wide.Arch[2] != 5 {
+			t.Fatalf("Widened arch is %v", wide.Arch)
+		}
+		if err := wide.Validate(); err != nil {
+			t.Fatalf("Widened net failed validation: %v", err)
+		}
+
+		n.Start(true, 1)
+		wide.Start(true, 1)
+		output := n.MustForward(data)
+		outputWide := wide.MustForward(data)
+		for ii := range output {
+			if !almostEqual(outputWide[ii], output[ii]) {
+				t.Errorf("Widened output %d is %.6e; expected %.6e", ii,
+					outputWide[ii], output[ii])
+			}
+		}
+		wide.MustBackward([]float64{1.0, 1.0})
+	}
+
+	n := MustNewMLP(arch, opt)
+	if err := n.WidenLayer(0, 5); err == nil {
+		t.Errorf("Expected error widening input layer")
+	}
+	if err := n.WidenLayer(1, 2); err == nil {
+		t.Errorf("Expected error shrinking layer")
+	}
+}
+
+// Test that the copies of a quantized unit are quantized too, so that they
+// compute the same activation as their source.
+func TestWidenQuantized(t *testing.T) {
+	rand.Seed(14)
+	n := MustNewMLP([]int{3, 4, 2}, NewSGD(0.1, 0.0, 0.0), WithQuantization(3))
+	if err := n.WidenLayer(1, 7); err != nil {
+		t.Fatal(err)
+	}
+	n.Start(false, 0)
+	defer n.Stop()
+	_, acts, err := n.ForwardWithActivations([]float64{1.0, -0.5, 2.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for jj := 4; jj < 7; jj++ {
+		r := n.Layers[1][jj]
+		if r.W.qbits != 3 {
+			t.Errorf("Copy %s quantizes to %d bits; expected 3", r.ID, r.W.qbits)
+		}
+		found := false
+		for _, u := range n.Layers[1][:4] {
+			found = found || acts[u.ID] == acts[r.ID]
+		}
+		if !found {
+			t.Errorf("Copy %s has activation %.6f matching no source", r.ID, acts[r.ID])
+		}
+	}
+}
+
+// Test that the copies of widened units are clipped along with the rest of
+// the network.
+func TestWidenClipNorm(t *testing.T) {
+	const maxNorm = 0.1
+	rand.Seed(15)
+	n := MustNewMLP([]int{2, 3, 2}, NewSGD(1.0, 0.0, 0.0), WithGradClipNorm(maxNorm),
+		WithGradClipValue(1.0))
+	if err := n.WidenLayer(1, 5); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range n.Layers[1][3:] {
+		if !u.netStep || u.clipValue != 1.0 {
+			t.Errorf("Copy %s has netStep %v and clip value %v", u.ID, u.netStep, u.clipValue)
+		}
+	}
+	before := n.Data()
+
+	n.Start(true, 1)
+	defer n.Stop()
+	n.MustForward([]float64{1.0, -1.0})
+	n.MustBackward([]float64{5.0, -5.0})
+
+	// The update with lr 1 is the clipped gradient.
+	after := n.Data()
+	norm := 0.0
+	for uid, params := range after {
+		for id, v := range params {
+			d := v - before[uid][id]
+			norm += d * d
+		}
+	}
+	if norm = math.Sqrt(norm); !almostEqual(norm, maxNorm) {
+		t.Errorf("Update norm is %.4f; expected %.4f", norm, maxNorm)
+	}
+}
+
+// Test widening a running network with frozen layers, and that layers with
+// tied weights can't be widened.
+func TestWidenRunning(t *testing.T) {
+	rand.Seed(16)
+	arch := []int{3, 4, 3, 2}
+	data := []float64{1.0, -0.5, 2.0}
+	n := MustNewMLP(arch, NewSGD(0.5, 0.0, 0.0))
+	n.Freeze(1)
+	n.Start(true, 1)
+	defer n.Stop()
+	want := n.MustForward(data)
+	n.MustBackward([]float64{0.0, 0.0})
+	if err := n.WidenLayer(1, 6); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range n.Layers[1][4:] {
+		if !u.Frozen() {
+			t.Errorf("Copy %s of a frozen unit isn't frozen", u.ID)
+		}
+	}
+	got, err := n.ForwardTimeout(data, time.Second)
+	if err != nil {
+		t.Fatalf("Forward after widening a running network failed: %v", err)
+	}
+	for ii := range want {
+		if !almostEqual(got[ii], want[ii]) {
+			t.Errorf("Widened output %d is %.6e; expected %.6e", ii, got[ii], want[ii])
+		}
+	}
+	n.MustBackward([]float64{1.0, 1.0})
+	if err := n.Validate(); err != nil {
+		t.Errorf("Widened net failed validation: %v", err)
+	}
+
+	tied, err := NewAutoencoder([]int{4, 3, 2}, NewSGD(0.1, 0.0, 0.0), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, layer := range []int{1, 2, 3} {
+		if err := tied.WidenLayer(layer, 5); err == nil {
+			t.Errorf("Widening layer %d of a tied autoencoder didn't fail", layer)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package neuron
+
+import (
+	"runtime"
+	"sync"
+)
+
+// WithWorkers runs the forward and backward computations of the units as
+// tasks on a pool of n worker goroutines, instead of a goroutine per unit. n
+// <= 0 uses GOMAXPROCS workers. Layers are scheduled like with
+// WithLayerEngine, and hand the work of their units to the pool, so a running
+// network has one goroutine per layer plus the workers, however wide its
+// layers are. This takes pressure off the scheduler for large architectures.
+func WithWorkers(n int) Option {
+	return func(c *netConfig) {
+		if n <= 0 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		c.layers = true
+		c.workers = n
+	}
+}
+
+// A workerPool runs tasks on a fixed number of goroutines, see WithWorkers.
+type workerPool struct {
+	tasks chan func()
+	quit  chan struct{}
+}
+
+// startWorkers starts the network's worker pool. The workers exit when the
+// network is stopped.
+func (n *Net) startWorkers() *workerPool {
+	p := &workerPool{tasks: make(chan func()), quit: n.quit}
+	for ii := 0; ii < n.workers; ii++ {
+		n.units.Add(1)
+		go func() {
+			defer n.units.Done()
+			p.work()
+		}()
+	}
+	return p
+}
+
+// work runs tasks until the pool is stopped.
+func (p *workerPool) work() {
+	for {
+		select {
+		case f := <-p.tasks:
+			f()
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// run calls f(ii) for ii in [0, count) on the workers, and waits for the
+// calls to return. Tasks never block, so a task handed to a worker always
+// completes, even if the pool is stopped. If the pool is stopped before all
+// tasks are handed out, the calling goroutine exits.
+func (p *workerPool) run(count int, f func(ii int)) {
+	var wg sync.WaitGroup
+	wg.Add(count)
+	for ii := 0; ii < count; ii++ {
+		ii := ii
+		task := func() {
+			defer wg.Done()
+			f(ii)
+		}
+		select {
+		case p.tasks <- task:
+		case <-p.quit:
+			runtime.Goexit()
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,30 @@
+package neuron
+
+import (
+	"runtime"
+	"testing"
+)
+
+// Test that the worker pool caps the number of goroutines.
+func TestWorkers(t *testing.T) {
+	arch := []int{20, 50, 50, 2}
+	n := MustNewMLP(arch, NewSGD(0.1, 0.0, 0.0), WithWorkers(4))
+	before := runtime.NumGoroutine()
+	n.Start(true, 1)
+	if got, want := runtime.NumGoroutine()-before, len(arch)+4; got > want {
+		t.Errorf("Started %d goroutines; expected at most %d", got, want)
+	}
+
+	data := make([]float64, arch[0])
+	for ii := range data {
+		data[ii] = float64(ii) / 10
+	}
+	for ii := 0; ii < 3; ii++ {
+		n.MustForward(data)
+		n.MustBackward([]float64{1.0, -1.0})
+	}
+	n.Stop()
+	if got := runtime.NumGoroutine(); got > before {
+		t.Errorf("%d goroutines left after Stop; expected %d", got, before)
+	}
+}